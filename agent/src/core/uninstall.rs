@@ -0,0 +1,41 @@
+// agent/src/core/uninstall.rs
+//
+// UNINSTALL_AGENT 任务：清理本地残留数据并注销 Windows 服务。
+// 客户端证书在向服务端确认卸载之后才删除，确认前仍需用它建立 mTLS 连接。
+
+use std::error::Error;
+use std::fs;
+use shred::Shred;
+use windows_service::service::ServiceAccess;
+use windows_service::service_manager::{ServiceManager, ServiceManagerAccess};
+
+/// 服务名，与 service_dispatcher::start 使用的名称一致
+pub const SERVICE_NAME: &str = "GuardianAgent";
+
+/// 客户端证书与私钥，确认卸载后删除
+pub const CREDENTIAL_FILES: [&str; 2] = ["client.crt", "client.key"];
+
+/// 清理采集过程中可能残留的临时数据库副本，并将服务标记为删除（服务停止后由系统移除）
+pub fn uninstall() -> Result<(), Box<dyn Error>> {
+    for entry in fs::read_dir(std::env::temp_dir())? {
+        let path = entry?.path();
+        let name = path.file_name().map(|n| n.to_string_lossy().to_string()).unwrap_or_default();
+        if name.starts_with("wechat_msg_") && name.ends_with("_tmp.db") {
+            path.shred()?;
+        }
+    }
+    let manager = ServiceManager::local_computer(None::<&str>, ServiceManagerAccess::CONNECT)?;
+    let service = manager.open_service(SERVICE_NAME, ServiceAccess::DELETE)?;
+    service.delete()?;
+    slog::info!("Agent service marked for deletion");
+    Ok(())
+}
+
+/// 删除客户端证书与私钥；只在服务端确认卸载后调用，此后 agent 无法再连接服务端
+pub fn remove_credentials() {
+    for file in CREDENTIAL_FILES {
+        if let Err(e) = fs::remove_file(file) {
+            slog::error!("Failed to remove credential file"; "path" => file, "error" => e.to_string());
+        }
+    }
+}
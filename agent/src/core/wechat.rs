@@ -18,6 +18,8 @@ fn has_sufficient_disk_space(path: &Path, required_bytes: u64) -> bool {
 pub struct ChatMessage {
     pub content: String,
     pub timestamp: i64,
+    // 会话标识：单聊为对方 wxid，群聊为 xxx@chatroom；服务端据此执行排除会话过滤
+    pub conversation_id: String,
 }
 use std::{cell::RefCell, collections::HashMap, rc::Rc};
 use windows::core::PWSTR;
@@ -271,12 +273,13 @@ pub fn get_wechat_data(config: &SignatureConfig) -> Result<Vec<ChatMessage>, Box
                 let mut conn = Connection::open(&tmp_path)?;
                 conn.pragma_update(None, "key", &key_hex)?;
 
-                let sql = format!("SELECT StrContent, CreateTime FROM {}", msg_table);
+                let sql = format!("SELECT StrContent, CreateTime, StrTalker FROM {}", msg_table);
                 let mut stmt = conn.prepare(&sql)?;
                 let rows = stmt.query_map([], |row| {
                     let content: String = row.get(0)?;
                     let timestamp: i64 = row.get(1)?;
-                    Ok(ChatMessage { content, timestamp })
+                    let conversation_id: String = row.get(2)?;
+                    Ok(ChatMessage { content, timestamp, conversation_id })
                 })?;
                 for msg in rows.flatten() {
                     messages.push(msg);
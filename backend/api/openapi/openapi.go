@@ -0,0 +1,19 @@
+// Package openapi 内嵌由 guardian.proto 生成的 OpenAPI 文档，控制台与脚本共用这一份接口契约。
+//
+// 修改 proto 后在本目录执行 go generate 重新生成（需安装 protoc 与 protoc-gen-openapiv2）。
+package openapi
+
+import _ "embed"
+
+//go:generate protoc -I ../proto -I ../../third_party --openapiv2_out=. --openapiv2_opt=json_names_for_fields=false,disable_default_errors=true,openapi_naming_strategy=simple,allow_delete_body=true guardian.proto
+//go:generate go run ../../cmd/openapi-v3 -in guardian.swagger.json -out guardian.openapi.json
+
+// V2 为 OpenAPI v2（Swagger）文档
+//
+//go:embed guardian.swagger.json
+var V2 []byte
+
+// V3 为由 V2 转换得到的 OpenAPI v3 文档
+//
+//go:embed guardian.openapi.json
+var V3 []byte
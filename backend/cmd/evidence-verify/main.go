@@ -0,0 +1,65 @@
+// evidence-verify 校验案件证据导出包：验签清单并比对包内每个文件的 SHA-256。
+// 用法：evidence-verify -pubkey export-signing.pub case-7-<bundle>.zip
+// 未指定 -pubkey 时只能用包内自带的公钥验签，仅证明包内自洽，不能证明来源。
+package main
+
+import (
+	"crypto/ed25519"
+	"flag"
+	"fmt"
+	"os"
+
+	"guardian-backend/internal/evidence"
+)
+
+func main() {
+	pubkey := flag.String("pubkey", "", "trusted Ed25519 public key (PEM) of the exporting server")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [-pubkey file] bundle.zip\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var trusted ed25519.PublicKey
+	if *pubkey != "" {
+		k, err := evidence.LoadPublicKey(*pubkey)
+		if err != nil {
+			fail("failed to load public key: %v", err)
+		}
+		trusted = k
+	}
+	f, err := os.Open(flag.Arg(0))
+	if err != nil {
+		fail("%v", err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		fail("%v", err)
+	}
+	res, err := evidence.Verify(f, info.Size(), trusted)
+	if err != nil {
+		fail("FAILED: %v", err)
+	}
+	m := res.Manifest
+	fmt.Printf("bundle:    %s\n", m.BundleID)
+	fmt.Printf("case:      %d (%s)\n", m.CaseID, m.CaseName)
+	fmt.Printf("exported:  %s by %s\n", m.ExportedAt.Format("2006-01-02 15:04:05 MST"), m.ExportedBy)
+	fmt.Printf("messages:  %d\n", m.MessageCount)
+	for _, file := range m.Files {
+		fmt.Printf("  ok  %s  %s\n", file.SHA256, file.Name)
+	}
+	if !res.Trusted {
+		fmt.Println("WARNING: verified with the public key embedded in the bundle; pass -pubkey to verify its origin")
+	}
+	fmt.Printf("OK: signature by key %q and all file hashes verified\n", m.KeyID)
+}
+
+func fail(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	os.Exit(1)
+}
@@ -0,0 +1,529 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"guardian-backend/pkg/client"
+	api "guardian-backend/pkg/grpc/api/guardian/pkg/grpc/api"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// parseArgs 解析 fs 的参数，允许标志与位置参数交错（如 tasks create 3 -case 1），
+// 并检查位置参数个数为 want
+func parseArgs(fs *flag.FlagSet, args []string, want int) ([]string, error) {
+	fs.SetOutput(io.Discard)
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, usagef("%v", err)
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			break
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+	if len(positional) != want {
+		return nil, usagef("expected %d argument(s), got %d", want, len(positional))
+	}
+	return positional, nil
+}
+
+func parseID(name, s string) (int64, error) {
+	id, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || id <= 0 {
+		return 0, usagef("invalid %s %q", name, s)
+	}
+	return id, nil
+}
+
+// parseTime 解析 RFC3339 时间；空串返回 nil
+func parseTime(name, s string) (*timestamppb.Timestamp, error) {
+	if s == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return nil, usagef("invalid -%s %q: want RFC3339, e.g. 2024-05-01T00:00:00+08:00", name, s)
+	}
+	return timestamppb.New(t), nil
+}
+
+func cmdLogin(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("login", flag.ContinueOnError)
+	username := fs.String("u", "", "username")
+	passwordStdin := fs.Bool("password-stdin", false, "read the password from stdin instead of GUARDIAN_PASSWORD")
+	code := fs.String("code", "", "TOTP code")
+	recoveryCode := fs.String("recovery-code", "", "MFA recovery code")
+	if _, err := parseArgs(fs, args, 0); err != nil {
+		return err
+	}
+	if *username == "" {
+		return usagef("-u is required")
+	}
+	password := os.Getenv("GUARDIAN_PASSWORD")
+	if *passwordStdin || password == "" {
+		var err error
+		if password, err = a.readLine("Password: "); err != nil {
+			return fmt.Errorf("read password: %w", err)
+		}
+	}
+
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+	res, err := c.Login(ctx, *username, password)
+	if err != nil {
+		return err
+	}
+	if res.MFARequired {
+		if *recoveryCode != "" {
+			if err := c.LoginRecoveryCode(ctx, res.MFAToken, *recoveryCode); err != nil {
+				return err
+			}
+			fmt.Fprintln(os.Stderr, "Logged in with a recovery code; it cannot be used again.")
+			return nil
+		}
+		if res.MFAEnrollmentRequired {
+			enroll, err := c.EnrollMFA(ctx, res.MFAToken)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "MFA enrollment required. Add this account to your authenticator app:\n  %s\n  secret: %s\n", enroll.ProvisioningURI, enroll.Secret)
+		}
+		if *code == "" {
+			if *code, err = a.readLine("MFA code: "); err != nil {
+				return fmt.Errorf("read MFA code: %w", err)
+			}
+		}
+		recovery, err := c.LoginMFA(ctx, res.MFAToken, *code)
+		if err != nil {
+			return err
+		}
+		if len(recovery) > 0 {
+			fmt.Fprintln(os.Stderr, "Save these recovery codes; they are shown only once:")
+			for _, rc := range recovery {
+				fmt.Fprintln(os.Stderr, "  "+rc)
+			}
+		}
+	}
+	fmt.Fprintln(os.Stderr, "Login succeeded.")
+	return nil
+}
+
+func cmdLogout(ctx context.Context, a *app, args []string) error {
+	if _, err := parseArgs(flag.NewFlagSet("logout", flag.ContinueOnError), args, 0); err != nil {
+		return err
+	}
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+	// 会话已失效时服务端返回 401，同样清除本地令牌
+	var apiErr *client.Error
+	if err := c.Logout(ctx); err != nil && !(errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusUnauthorized) {
+		return err
+	}
+	path, err := credentialsPath(a.credentialsFile)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func cmdUsersRevokeSessions(ctx context.Context, a *app, args []string) error {
+	pos, err := parseArgs(flag.NewFlagSet("users revoke-sessions", flag.ContinueOnError), args, 1)
+	if err != nil {
+		return err
+	}
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+	n, err := c.RevokeUserSessions(ctx, pos[0])
+	if err != nil {
+		return err
+	}
+	return a.out.print(map[string]any{"user_id": pos[0], "revoked": n},
+		[]string{"USER", "REVOKED"}, [][]string{{pos[0], strconv.Itoa(n)}})
+}
+
+func cmdUsersResetMFA(ctx context.Context, a *app, args []string) error {
+	pos, err := parseArgs(flag.NewFlagSet("users reset-mfa", flag.ContinueOnError), args, 1)
+	if err != nil {
+		return err
+	}
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+	if err := c.ResetUserMFA(ctx, pos[0]); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "MFA reset for user %s; they must enroll again at next login.\n", pos[0])
+	return nil
+}
+
+func cmdAgentsList(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("agents list", flag.ContinueOnError)
+	page := fs.Int("page", 1, "page number")
+	pageSize := fs.Int("page-size", 50, "agents per page")
+	if _, err := parseArgs(fs, args, 0); err != nil {
+		return err
+	}
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+	resp, err := c.ListAgents(ctx, &api.ListAgentsRequest{Page: int32(*page), PageSize: int32(*pageSize)})
+	if err != nil {
+		return err
+	}
+	rows := make([][]string, 0, len(resp.Agents))
+	for _, ag := range resp.Agents {
+		rows = append(rows, []string{strconv.Itoa(int(ag.Id)), ag.Hostname, ag.Status})
+	}
+	return a.out.print(resp, []string{"ID", "HOSTNAME", "STATUS"}, rows)
+}
+
+func cmdAgentsDecommission(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("agents decommission", flag.ContinueOnError)
+	disposition := fs.String("disposition", "", "what to do with collected data: retain or purge")
+	reason := fs.String("reason", "", "reason, recorded in the audit log")
+	pos, err := parseArgs(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	agentID, err := parseID("agent id", pos[0])
+	if err != nil {
+		return err
+	}
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+	resp, err := c.DecommissionAgent(ctx, &api.DecommissionAgentRequest{AgentId: int32(agentID), DataDisposition: *disposition, Reason: *reason})
+	if err != nil {
+		return err
+	}
+	return a.out.print(resp, []string{"AGENT", "TASK"}, [][]string{{pos[0], strconv.FormatInt(resp.TaskId, 10)}})
+}
+
+// cmdTasksCreate 向 agent 下发采集任务（admin、approver）。服务端没有独立的审批步骤：
+// 创建者与时间记录在审计日志（task.create）中，导出时作为监管链的 approvals
+func cmdTasksCreate(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("tasks create", flag.ContinueOnError)
+	caseID := fs.Int64("case", 0, "case the collected data belongs to")
+	from := fs.String("from", "", "collect messages from this time (RFC3339)")
+	to := fs.String("to", "", "collect messages until this time (RFC3339)")
+	exclude := fs.String("exclude", "", "comma-separated conversation ids to exclude")
+	pos, err := parseArgs(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	agentID, err := parseID("agent id", pos[0])
+	if err != nil {
+		return err
+	}
+	req := &api.CreateTaskRequest{AgentId: int32(agentID), CaseId: *caseID}
+	if req.ScopeStart, err = parseTime("from", *from); err != nil {
+		return err
+	}
+	if req.ScopeEnd, err = parseTime("to", *to); err != nil {
+		return err
+	}
+	for _, conv := range strings.Split(*exclude, ",") {
+		if conv = strings.TrimSpace(conv); conv != "" {
+			req.ExcludedConversations = append(req.ExcludedConversations, conv)
+		}
+	}
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+	resp, err := c.CreateTask(ctx, req)
+	if err != nil {
+		return err
+	}
+	return a.out.print(resp, []string{"AGENT", "TASK"}, [][]string{{pos[0], strconv.FormatInt(resp.TaskId, 10)}})
+}
+
+func cmdTasksPurge(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("tasks purge", flag.ContinueOnError)
+	confirm := fs.Int64("confirm", 0, "repeat the task id to confirm")
+	reason := fs.String("reason", "", "reason, recorded in the audit log")
+	pos, err := parseArgs(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	taskID, err := parseID("task id", pos[0])
+	if err != nil {
+		return err
+	}
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+	resp, err := c.PurgeTaskMessages(ctx, &api.PurgeTaskMessagesRequest{TaskId: taskID, Confirm: *confirm, Reason: *reason})
+	if err != nil {
+		return err
+	}
+	return a.out.print(resp, []string{"TASK", "MESSAGES PURGED"},
+		[][]string{{strconv.FormatInt(resp.TaskId, 10), strconv.FormatInt(resp.MessagesPurged, 10)}})
+}
+
+func cmdMessagesList(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("messages list", flag.ContinueOnError)
+	agentID := fs.Int("agent", 0, "list messages of this agent")
+	taskID := fs.Int64("task", 0, "list messages collected by this task")
+	q := fs.String("q", "", "full-text search")
+	unmask := fs.Bool("unmask", false, "show unredacted content (admin, requires -reason)")
+	reason := fs.String("reason", "", "reason for -unmask, recorded in the audit log")
+	page := fs.Int("page", 1, "page number")
+	pageSize := fs.Int("page-size", 50, "messages per page")
+	if _, err := parseArgs(fs, args, 0); err != nil {
+		return err
+	}
+	if (*agentID == 0) == (*taskID == 0) {
+		return usagef("exactly one of -agent or -task is required")
+	}
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+	resp, err := c.ListMessages(ctx, &api.ListMessagesRequest{
+		AgentId: int32(*agentID), TaskId: *taskID, Q: *q, Unmask: *unmask, Reason: *reason,
+		Page: int32(*page), PageSize: int32(*pageSize),
+	})
+	if err != nil {
+		return err
+	}
+	rows := make([][]string, 0, len(resp.Messages))
+	for _, m := range resp.Messages {
+		task := "-"
+		if m.TaskId != nil {
+			task = strconv.FormatInt(*m.TaskId, 10)
+		}
+		rows = append(rows, []string{formatTime(m.Timestamp), task, m.Content})
+	}
+	return a.out.print(resp, []string{"TIME", "TASK", "CONTENT"}, rows)
+}
+
+func cmdCasesList(ctx context.Context, a *app, args []string) error {
+	if _, err := parseArgs(flag.NewFlagSet("cases list", flag.ContinueOnError), args, 0); err != nil {
+		return err
+	}
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+	resp, err := c.ListCases(ctx)
+	if err != nil {
+		return err
+	}
+	rows := make([][]string, 0, len(resp.Cases))
+	for _, cs := range resp.Cases {
+		rows = append(rows, caseRow(cs))
+	}
+	return a.out.print(resp, caseHeader, rows)
+}
+
+func cmdCasesCreate(ctx context.Context, a *app, args []string) error {
+	pos, err := parseArgs(flag.NewFlagSet("cases create", flag.ContinueOnError), args, 1)
+	if err != nil {
+		return err
+	}
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+	cs, err := c.CreateCase(ctx, pos[0])
+	if err != nil {
+		return err
+	}
+	return a.out.print(cs, caseHeader, [][]string{caseRow(cs)})
+}
+
+var caseHeader = []string{"ID", "NAME", "STATUS", "DEFAULT AGENT", "CREATED", "KEY DESTROYED"}
+
+func caseRow(cs *api.Case) []string {
+	agent := "-"
+	if cs.DefaultAgentId != nil {
+		agent = strconv.Itoa(int(*cs.DefaultAgentId))
+	}
+	return []string{strconv.FormatInt(cs.Id, 10), cs.Name, cs.Status, agent, formatTime(cs.CreatedAt), formatTime(cs.KeyDestroyedAt)}
+}
+
+func cmdCasesDestroyKey(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("cases destroy-key", flag.ContinueOnError)
+	confirm := fs.Int64("confirm", 0, "repeat the case id to confirm")
+	reason := fs.String("reason", "", "reason, recorded in the audit log")
+	pos, err := parseArgs(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	caseID, err := parseID("case id", pos[0])
+	if err != nil {
+		return err
+	}
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+	if err := c.DestroyCaseKey(ctx, &api.DestroyCaseKeyRequest{CaseId: caseID, Confirm: *confirm, Reason: *reason}); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Key of case %d destroyed; its messages can no longer be decrypted.\n", caseID)
+	return nil
+}
+
+func cmdAuditList(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("audit list", flag.ContinueOnError)
+	req := &api.ListAuditLogsRequest{}
+	fs.StringVar(&req.Actor, "actor", "", "filter by actor user id")
+	fs.StringVar(&req.Action, "action", "", "filter by action, e.g. task.create")
+	fs.StringVar(&req.TargetType, "target-type", "", "filter by target type, e.g. case")
+	fs.StringVar(&req.TargetId, "target-id", "", "filter by target id (requires -target-type)")
+	since := fs.String("since", "", "only entries at or after this time (RFC3339)")
+	until := fs.String("until", "", "only entries before this time (RFC3339)")
+	limit := fs.Int("limit", 100, "maximum entries to fetch; 0 fetches all")
+	if _, err := parseArgs(fs, args, 0); err != nil {
+		return err
+	}
+	var err error
+	if req.Since, err = parseTime("since", *since); err != nil {
+		return err
+	}
+	if req.Until, err = parseTime("until", *until); err != nil {
+		return err
+	}
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+	// 逐页拉取直到满足 -limit；errStop 提前结束分页
+	errStop := errors.New("stop")
+	out := &api.ListAuditLogsResponse{}
+	err = c.AllAuditLogs(ctx, req, func(page []*api.AuditLog) error {
+		out.Entries = append(out.Entries, page...)
+		if *limit > 0 && len(out.Entries) >= *limit {
+			out.Entries = out.Entries[:*limit]
+			return errStop
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errStop) {
+		return err
+	}
+	rows := make([][]string, 0, len(out.Entries))
+	for _, e := range out.Entries {
+		rows = append(rows, []string{strconv.FormatInt(e.Id, 10), formatTime(e.CreatedAt), e.Actor, e.Action, e.TargetType + ":" + e.TargetId, e.Outcome})
+	}
+	return a.out.print(out, []string{"ID", "TIME", "ACTOR", "ACTION", "TARGET", "OUTCOME"}, rows)
+}
+
+// exportNamePattern 为服务端建议的导出文件名：case-<案件编号>-<包编号>.zip
+var exportNamePattern = regexp.MustCompile(`^case-([0-9]+)-([0-9a-f]{1,64})\.zip$`)
+
+// exportFileName 返回下载文件的保存名。服务端建议的文件名来自 Content-Disposition，
+// 必须完全符合 case-<caseID>-<包编号>.zip，不做截取，其余名称（路径、隐藏文件等）一律拒绝；
+// 服务端未建议时按案件编号命名
+func exportFileName(suggested string, caseID int64) (string, error) {
+	if suggested == "" {
+		return fmt.Sprintf("case-%d.zip", caseID), nil
+	}
+	m := exportNamePattern.FindStringSubmatch(suggested)
+	if m == nil || m[1] != strconv.FormatInt(caseID, 10) {
+		return "", fmt.Errorf("server suggested an unexpected file name %q; use -out", suggested)
+	}
+	return suggested, nil
+}
+
+// saveExport 把下载完成的临时文件移到 path。overwrite 为 false 时以硬链接落盘，
+// 目标已存在即失败，不会覆盖已有文件
+func saveExport(tmp, path string, overwrite bool) error {
+	if overwrite {
+		return os.Rename(tmp, path)
+	}
+	if err := os.Link(tmp, path); err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return fmt.Errorf("%s already exists; use -out to choose the output file", path)
+		}
+		return err
+	}
+	return os.Remove(tmp)
+}
+
+func cmdExportsDownload(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("exports download", flag.ContinueOnError)
+	reason := fs.String("reason", "", "reason for the export, recorded in the manifest and audit log")
+	redact := fs.Bool("redact", false, "redact message content")
+	outFile := fs.String("out", "", "output file (default: the file name suggested by the server)")
+	pubkey := fs.String("pubkey", "", "verify the downloaded bundle against this trusted public key (PEM)")
+	pos, err := parseArgs(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	caseID, err := parseID("case id", pos[0])
+	if err != nil {
+		return err
+	}
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+
+	// 先写入临时文件，文件名以服务端建议为准
+	tmp, err := os.CreateTemp(".", ".guardian-export-*.zip")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	exp, err := c.ExportCase(ctx, caseID, *reason, *redact, tmp)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	// 只有明确指定 -out 时才覆盖已有文件
+	path := *outFile
+	if path == "" {
+		if path, err = exportFileName(exp.Filename, caseID); err != nil {
+			return err
+		}
+	}
+	if err := saveExport(tmp.Name(), path, *outFile != ""); err != nil {
+		return err
+	}
+
+	result := map[string]any{"file": path, "size": exp.Size, "manifest_sha256": exp.ManifestSHA256}
+	rows := [][]string{{path, strconv.FormatInt(exp.Size, 10), exp.ManifestSHA256}}
+	if *pubkey != "" {
+		report, err := verifyBundleFile(path, *pubkey)
+		if err != nil {
+			return err
+		}
+		if !report.OK() {
+			return fmt.Errorf("downloaded bundle %s failed verification: %s", path, strings.Join(report.Problems, "; "))
+		}
+		result["verified"] = true
+	}
+	return a.out.print(result, []string{"FILE", "SIZE", "MANIFEST SHA256"}, rows)
+}
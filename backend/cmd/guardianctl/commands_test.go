@@ -0,0 +1,66 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportFileName(t *testing.T) {
+	for _, tc := range []struct {
+		suggested string
+		want      string
+		wantErr   bool
+	}{
+		{suggested: "", want: "case-7.zip"},
+		{suggested: "case-7-abc.zip", want: "case-7-abc.zip"},
+		{suggested: "../../.bashrc", wantErr: true},
+		{suggested: ".bashrc", wantErr: true},
+		{suggested: "/etc/cron.d/guardian", wantErr: true},
+		{suggested: "../case-7-abc.zip", wantErr: true},
+		{suggested: "case-8-abc.zip", wantErr: true},
+		{suggested: "case-7-abc.zip.sh", wantErr: true},
+		{suggested: "..", wantErr: true},
+		{suggested: ".", wantErr: true},
+		{suggested: "/", wantErr: true},
+	} {
+		t.Run(tc.suggested, func(t *testing.T) {
+			got, err := exportFileName(tc.suggested, 7)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestSaveExport_DoesNotOverwriteWithoutOut(t *testing.T) {
+	dir := t.TempDir()
+	tmp := filepath.Join(dir, ".guardian-export-1.zip")
+	path := filepath.Join(dir, "case-7-abc.zip")
+	require.NoError(t, os.WriteFile(tmp, []byte("new"), 0o600))
+	require.NoError(t, os.WriteFile(path, []byte("old"), 0o600))
+
+	err := saveExport(tmp, path, false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "already exists")
+	got, _ := os.ReadFile(path)
+	assert.Equal(t, "old", string(got))
+
+	// 显式指定 -out 时覆盖
+	require.NoError(t, saveExport(tmp, path, true))
+	got, _ = os.ReadFile(path)
+	assert.Equal(t, "new", string(got))
+	assert.NoFileExists(t, tmp)
+
+	fresh := filepath.Join(dir, "case-7-def.zip")
+	require.NoError(t, os.WriteFile(tmp, []byte("another"), 0o600))
+	require.NoError(t, saveExport(tmp, fresh, false))
+	assert.FileExists(t, fresh)
+	assert.NoFileExists(t, tmp)
+}
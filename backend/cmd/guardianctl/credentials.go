@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"guardian-backend/pkg/client"
+)
+
+// credentials 是保存在本地的登录状态
+type credentials struct {
+	Server string        `json:"server"`
+	Tokens client.Tokens `json:"tokens"`
+}
+
+func credentialsPath(file string) (string, error) {
+	if file != "" {
+		return file, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "guardianctl", "credentials.json"), nil
+}
+
+// loadCredentials 读取凭据文件；文件不存在时返回空凭据
+func loadCredentials(file string) (credentials, error) {
+	var c credentials
+	path, err := credentialsPath(file)
+	if err != nil {
+		return c, err
+	}
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return c, err
+	}
+	return c, json.Unmarshal(raw, &c)
+}
+
+// saveCredentials 写入凭据文件；文件含刷新令牌，仅当前用户可读写
+func saveCredentials(file string, c credentials) error {
+	path, err := credentialsPath(file)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	raw, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,163 @@
+// guardianctl 是 Guardian 控制台 API 的命令行工具，基于 pkg/client。
+//
+// 用法：guardianctl [-server URL] [-o table|json] <命令> [参数]
+// 先执行 guardianctl login 登录，令牌保存在凭据文件中（默认 <用户配置目录>/guardianctl/credentials.json），
+// 过期后自动刷新。
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+
+	"guardian-backend/pkg/client"
+)
+
+// command 是一个子命令；run 收到的 args 不含命令名
+type command struct {
+	usage string
+	help  string
+	run   func(ctx context.Context, app *app, args []string) error
+}
+
+var commands = map[string]command{
+	"login":                 {"login -u USER [-password-stdin] [-code CODE | -recovery-code CODE]", "log in and save tokens", cmdLogin},
+	"logout":                {"logout", "revoke the current session and forget saved tokens", cmdLogout},
+	"users revoke-sessions": {"users revoke-sessions USER_ID", "revoke all sessions of a user (admin)", cmdUsersRevokeSessions},
+	"users reset-mfa":       {"users reset-mfa USER_ID", "reset a user's MFA and revoke their sessions (admin)", cmdUsersResetMFA},
+	"agents list":           {"agents list [-page N] [-page-size N]", "list agents", cmdAgentsList},
+	"agents decommission":   {"agents decommission -disposition retain|purge [-reason TEXT] AGENT_ID", "schedule agent uninstall", cmdAgentsDecommission},
+	"tasks create":          {"tasks create [-case ID] [-from RFC3339] [-to RFC3339] [-exclude WXID,...] AGENT_ID", "issue a collection task to an agent (admin, approver)", cmdTasksCreate},
+	"tasks purge":           {"tasks purge -confirm TASK_ID -reason TEXT TASK_ID", "purge all messages collected by a task (admin)", cmdTasksPurge},
+	"messages list":         {"messages list (-agent ID | -task ID) [-q TEXT] [-unmask -reason TEXT] [-page N] [-page-size N]", "list stored messages", cmdMessagesList},
+	"cases list":            {"cases list", "list cases", cmdCasesList},
+	"cases create":          {"cases create NAME", "create a case", cmdCasesCreate},
+	"cases destroy-key":     {"cases destroy-key -confirm CASE_ID -reason TEXT CASE_ID", "crypto-erase a case (admin)", cmdCasesDestroyKey},
+	"audit list":            {"audit list [-actor ID] [-action A] [-target-type T] [-target-id ID] [-since RFC3339] [-until RFC3339] [-limit N]", "list audit logs, newest first (admin, auditor)", cmdAuditList},
+	"audit verify-chain":    {"audit verify-chain [-pubkey FILE] BUNDLE.zip", "verify an evidence bundle's signature and chain of custody", cmdAuditVerifyChain},
+	"exports download":      {"exports download -reason TEXT [-redact] [-out FILE] [-pubkey FILE] CASE_ID", "export a case evidence bundle (admin)", cmdExportsDownload},
+}
+
+func main() {
+	server := flag.String("server", os.Getenv("GUARDIAN_SERVER"), "Guardian HTTP base URL (default from saved credentials, env GUARDIAN_SERVER)")
+	output := flag.String("o", "table", "output format: table or json")
+	credentials := flag.String("credentials", os.Getenv("GUARDIANCTL_CREDENTIALS"), "credentials file (env GUARDIANCTL_CREDENTIALS)")
+	flag.Usage = usage
+	flag.Parse()
+
+	name, args := lookup(flag.Args())
+	cmd, ok := commands[name]
+	if !ok {
+		usage()
+		os.Exit(2)
+	}
+	if *output != "table" && *output != "json" {
+		fail("unknown output format %q", *output)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	app := &app{serverFlag: *server, credentialsFile: *credentials, out: newPrinter(os.Stdout, *output), stdin: bufio.NewReader(os.Stdin)}
+	if err := cmd.run(ctx, app, args); err != nil {
+		var usageErr usageError
+		if errors.As(err, &usageErr) {
+			fmt.Fprintf(os.Stderr, "%v\nusage: guardianctl %s\n", err, cmd.usage)
+			os.Exit(2)
+		}
+		fail("%v", err)
+	}
+}
+
+// lookup 按最长匹配取出命令名（一或两个词）
+func lookup(args []string) (string, []string) {
+	if len(args) >= 2 {
+		if _, ok := commands[args[0]+" "+args[1]]; ok {
+			return args[0] + " " + args[1], args[2:]
+		}
+	}
+	if len(args) >= 1 {
+		if _, ok := commands[args[0]]; ok {
+			return args[0], args[1:]
+		}
+	}
+	return "", nil
+}
+
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintln(out, "usage: guardianctl [flags] <command> [args]")
+	fmt.Fprintln(out, "\ncommands:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(out, "  %-22s %s\n", name, commands[name].help)
+	}
+	fmt.Fprintln(out, "\nflags:")
+	flag.PrintDefaults()
+}
+
+// usageError 表示参数错误，输出命令用法并以 2 退出
+type usageError struct{ msg string }
+
+func (e usageError) Error() string { return e.msg }
+
+func usagef(format string, args ...any) error {
+	return usageError{fmt.Sprintf(format, args...)}
+}
+
+// app 是各命令共用的状态
+type app struct {
+	serverFlag      string
+	credentialsFile string
+	out             *printer
+	stdin           *bufio.Reader
+}
+
+// client 返回使用已保存令牌的客户端，刷新后的令牌写回凭据文件
+func (a *app) client() (*client.Client, error) {
+	creds, err := loadCredentials(a.credentialsFile)
+	if err != nil {
+		return nil, err
+	}
+	server := a.serverFlag
+	if server == "" {
+		server = creds.Server
+	}
+	if server == "" {
+		return nil, errors.New("no server configured: pass -server or run guardianctl -server URL login")
+	}
+	if creds.Server != server {
+		// 凭据属于另一台服务器，不发送
+		creds = credentials{Server: server}
+	}
+	return client.New(server, client.WithTokens(creds.Tokens), client.WithTokenHook(func(t client.Tokens) {
+		if err := saveCredentials(a.credentialsFile, credentials{Server: server, Tokens: t}); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: failed to save credentials: %v\n", err)
+		}
+	}))
+}
+
+// readLine 从标准输入读取一行（去除首尾空白）
+func (a *app) readLine(prompt string) (string, error) {
+	if prompt != "" {
+		fmt.Fprint(os.Stderr, prompt)
+	}
+	line, err := a.stdin.ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
+
+func fail(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, "guardianctl: "+format+"\n", args...)
+	os.Exit(1)
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"guardian-backend/internal/evidence"
+)
+
+// chainReport 是导出包监管链的校验结果
+type chainReport struct {
+	File         string    `json:"file"`
+	BundleID     string    `json:"bundle_id"`
+	CaseID       int64     `json:"case_id"`
+	CaseName     string    `json:"case_name"`
+	ExportedBy   string    `json:"exported_by"`
+	ExportedAt   time.Time `json:"exported_at"`
+	KeyID        string    `json:"key_id"`
+	TrustedKey   bool      `json:"trusted_key"`
+	Messages     int       `json:"messages"`
+	Unattributed int       `json:"unattributed_messages"`
+	Tasks        int       `json:"tasks"`
+	Approvals    int       `json:"approvals"`
+	Accessors    int       `json:"accessors"`
+	Problems     []string  `json:"problems"`
+}
+
+func (r chainReport) OK() bool { return len(r.Problems) == 0 }
+
+func cmdAuditVerifyChain(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("audit verify-chain", flag.ContinueOnError)
+	pubkey := fs.String("pubkey", "", "trusted Ed25519 public key (PEM) of the exporting server")
+	pos, err := parseArgs(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	report, err := verifyBundleFile(pos[0], *pubkey)
+	if err != nil {
+		return err
+	}
+	rows := [][]string{
+		{"bundle", report.BundleID},
+		{"case", fmt.Sprintf("%d (%s)", report.CaseID, report.CaseName)},
+		{"exported", report.ExportedAt.Local().Format(time.DateTime) + " by " + report.ExportedBy},
+		{"signature", signatureSummary(report)},
+		{"messages", fmt.Sprintf("%d (%d without task)", report.Messages, report.Unattributed)},
+		{"tasks", strconv.Itoa(report.Tasks)},
+		{"approvals", strconv.Itoa(report.Approvals)},
+		{"accessors", strconv.Itoa(report.Accessors)},
+	}
+	for _, p := range report.Problems {
+		rows = append(rows, []string{"PROBLEM", p})
+	}
+	if err := a.out.print(report, []string{"CHECK", "RESULT"}, rows); err != nil {
+		return err
+	}
+	if !report.OK() {
+		return fmt.Errorf("chain of custody has %d problem(s)", len(report.Problems))
+	}
+	return nil
+}
+
+func signatureSummary(r chainReport) string {
+	if r.TrustedKey {
+		return fmt.Sprintf("ok, key %q (trusted)", r.KeyID)
+	}
+	return fmt.Sprintf("ok, key %q (embedded key only; pass -pubkey to verify origin)", r.KeyID)
+}
+
+// verifyBundleFile 校验导出包文件；pubkeyFile 为空时仅用包内公钥验签
+func verifyBundleFile(path, pubkeyFile string) (chainReport, error) {
+	var trusted ed25519.PublicKey
+	if pubkeyFile != "" {
+		k, err := evidence.LoadPublicKey(pubkeyFile)
+		if err != nil {
+			return chainReport{}, fmt.Errorf("load public key: %w", err)
+		}
+		trusted = k
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return chainReport{}, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return chainReport{}, err
+	}
+	report, err := verifyBundle(f, info.Size(), trusted)
+	report.File = path
+	return report, err
+}
+
+// verifyBundle 先验签清单与文件哈希（失败即返回错误），再核对监管链：
+// 每个任务都有批准记录（task.create），每条消息都来自监管链中的任务
+func verifyBundle(r io.ReaderAt, size int64, trusted ed25519.PublicKey) (chainReport, error) {
+	res, err := evidence.Verify(r, size, trusted)
+	if err != nil {
+		return chainReport{}, fmt.Errorf("bundle verification failed: %w", err)
+	}
+	m := res.Manifest
+	report := chainReport{
+		BundleID: m.BundleID, CaseID: m.CaseID, CaseName: m.CaseName,
+		ExportedBy: m.ExportedBy, ExportedAt: m.ExportedAt,
+		KeyID: m.KeyID, TrustedKey: res.Trusted, Problems: []string{},
+	}
+
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return report, err
+	}
+	var custody evidence.Custody
+	raw, err := readEntry(zr, evidence.FileCustody)
+	if err != nil {
+		return report, err
+	}
+	if err := json.Unmarshal(raw, &custody); err != nil {
+		return report, fmt.Errorf("%s: %w", evidence.FileCustody, err)
+	}
+	raw, err = readEntry(zr, evidence.FileMessagesJSONL)
+	if err != nil {
+		return report, err
+	}
+	var messages []evidence.Message
+	sc := bufio.NewScanner(bytes.NewReader(raw))
+	sc.Buffer(nil, 16<<20)
+	for sc.Scan() {
+		if len(bytes.TrimSpace(sc.Bytes())) == 0 {
+			continue
+		}
+		var msg evidence.Message
+		if err := json.Unmarshal(sc.Bytes(), &msg); err != nil {
+			return report, fmt.Errorf("%s: %w", evidence.FileMessagesJSONL, err)
+		}
+		messages = append(messages, msg)
+	}
+	if err := sc.Err(); err != nil {
+		return report, fmt.Errorf("%s: %w", evidence.FileMessagesJSONL, err)
+	}
+
+	report.Messages = len(messages)
+	report.Tasks = len(custody.Tasks)
+	report.Approvals = len(custody.Approvals)
+	report.Accessors = len(custody.Accessors)
+	if m.MessageCount != len(messages) {
+		report.Problems = append(report.Problems, fmt.Sprintf("manifest lists %d messages but %s has %d", m.MessageCount, evidence.FileMessagesJSONL, len(messages)))
+	}
+	report.Unattributed, report.Problems = checkCustody(custody, messages, report.Problems)
+	return report, nil
+}
+
+// checkCustody 核对监管链，返回无任务归属的消息数与追加后的问题列表
+func checkCustody(custody evidence.Custody, messages []evidence.Message, problems []string) (int, []string) {
+	approved := make(map[string]bool, len(custody.Approvals))
+	for _, e := range custody.Approvals {
+		if e.Action == "task.create" && e.Outcome == "success" && e.TargetType == "task" {
+			approved[e.TargetID] = true
+		}
+	}
+	tasks := make(map[int64]bool, len(custody.Tasks))
+	for _, t := range custody.Tasks {
+		tasks[t.ID] = true
+		if !approved[strconv.FormatInt(t.ID, 10)] {
+			problems = append(problems, fmt.Sprintf("task %d has no recorded approval", t.ID))
+		}
+	}
+	unattributed := 0
+	missing := map[int64]int{}
+	var order []int64
+	for _, msg := range messages {
+		if msg.TaskID == nil {
+			unattributed++
+			continue
+		}
+		if !tasks[*msg.TaskID] {
+			if missing[*msg.TaskID] == 0 {
+				order = append(order, *msg.TaskID)
+			}
+			missing[*msg.TaskID]++
+		}
+	}
+	for _, id := range order {
+		problems = append(problems, fmt.Sprintf("%d message(s) collected by task %d, which is not in the chain of custody", missing[id], id))
+	}
+	return unattributed, problems
+}
+
+func readEntry(zr *zip.Reader, name string) ([]byte, error) {
+	for _, f := range zr.File {
+		if f.Name != name {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		return io.ReadAll(rc)
+	}
+	return nil, fmt.Errorf("bundle has no %s", name)
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"guardian-backend/internal/evidence"
+)
+
+func writeBundle(t *testing.T, b evidence.Bundle) (ed25519.PublicKey, []byte) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	_, _, err = evidence.Write(&buf, b, &evidence.Signer{KeyID: "export-1", Key: priv})
+	require.NoError(t, err)
+	return pub, buf.Bytes()
+}
+
+func taskID(id int64) *int64 { return &id }
+
+func TestVerifyBundle_ChainOK(t *testing.T) {
+	now := time.Now().UTC()
+	pub, zipped := writeBundle(t, evidence.Bundle{
+		BundleID: "b1", CaseID: 7, CaseName: "case", ExportedBy: "1", ExportedAt: now,
+		Messages: []evidence.Message{
+			{ID: 1, AgentID: 3, TaskID: taskID(10), Timestamp: now, Content: "hi"},
+			{ID: 2, AgentID: 3, Timestamp: now, Content: "legacy"},
+		},
+		Custody: evidence.Custody{
+			Tasks:     []evidence.Task{{ID: 10, AgentID: 3, TaskType: "DUMP_WECHAT_DATA", Status: "COMPLETED"}},
+			Approvals: []evidence.Event{{Time: now, Actor: "1", Action: "task.create", TargetType: "task", TargetID: "10", Outcome: "success"}},
+		},
+	})
+
+	report, err := verifyBundle(bytes.NewReader(zipped), int64(len(zipped)), pub)
+	require.NoError(t, err)
+	assert.True(t, report.OK(), report.Problems)
+	assert.True(t, report.TrustedKey)
+	assert.Equal(t, 2, report.Messages)
+	assert.Equal(t, 1, report.Unattributed)
+	assert.Equal(t, 1, report.Approvals)
+}
+
+func TestVerifyBundle_ReportsBrokenChain(t *testing.T) {
+	now := time.Now().UTC()
+	_, zipped := writeBundle(t, evidence.Bundle{
+		BundleID: "b2", CaseID: 7, ExportedAt: now,
+		Messages: []evidence.Message{
+			{ID: 1, AgentID: 3, TaskID: taskID(11), Timestamp: now, Content: "a"},
+			{ID: 2, AgentID: 3, TaskID: taskID(99), Timestamp: now, Content: "b"},
+		},
+		Custody: evidence.Custody{
+			Tasks: []evidence.Task{{ID: 11, AgentID: 3}},
+			// 被拒绝的创建不算批准
+			Approvals: []evidence.Event{{Action: "task.create", TargetType: "task", TargetID: "11", Outcome: "denied"}},
+		},
+	})
+
+	report, err := verifyBundle(bytes.NewReader(zipped), int64(len(zipped)), nil)
+	require.NoError(t, err)
+	assert.False(t, report.TrustedKey)
+	assert.Equal(t, []string{
+		"task 11 has no recorded approval",
+		"1 message(s) collected by task 99, which is not in the chain of custody",
+	}, report.Problems)
+}
+
+func TestVerifyBundle_RejectsUntrustedKey(t *testing.T) {
+	_, zipped := writeBundle(t, evidence.Bundle{BundleID: "b3", ExportedAt: time.Now()})
+	other, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	_, err = verifyBundle(bytes.NewReader(zipped), int64(len(zipped)), other)
+	assert.ErrorIs(t, err, evidence.ErrInvalidSignature)
+}
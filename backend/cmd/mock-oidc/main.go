@@ -0,0 +1,33 @@
+// mock-oidc 在本地启动模拟 OIDC 提供方，用于联调控制台单点登录。
+// 所有授权请求都以 -user 指定的身份自动同意。
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"strings"
+
+	"guardian-backend/internal/auth/oidctest"
+)
+
+func main() {
+	addr := flag.String("addr", ":9000", "listen address")
+	issuer := flag.String("issuer", "http://localhost:9000", "issuer URL, must match auth.oidc.issuer_url")
+	clientID := flag.String("client-id", "guardian-console", "OIDC client id")
+	user := flag.String("user", "alice", "preferred_username and subject of the simulated user")
+	groups := flag.String("groups", "guardian-auditors", "comma separated group claim values")
+	flag.Parse()
+
+	p, err := oidctest.New(*issuer, *clientID, oidctest.User{
+		Subject:           *user,
+		PreferredUsername: *user,
+		Email:             *user + "@example.com",
+		Groups:            strings.Split(*groups, ","),
+	})
+	if err != nil {
+		log.Fatalf("failed to create provider: %v", err)
+	}
+	log.Printf("mock OIDC provider listening on %s (issuer %s)", *addr, *issuer)
+	log.Fatal(http.ListenAndServe(*addr, p))
+}
@@ -0,0 +1,48 @@
+// openapi-v3 把 protoc-gen-openapiv2 生成的 OpenAPI v2 文档转换为 OpenAPI v3，两份文档保持同一来源。
+// 用法：openapi-v3 -in guardian.swagger.json -out guardian.openapi.json（由 api/openapi 中的 go:generate 调用）
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/getkin/kin-openapi/openapi2"
+	"github.com/getkin/kin-openapi/openapi2conv"
+)
+
+func main() {
+	in := flag.String("in", "", "OpenAPI v2 document to convert")
+	out := flag.String("out", "", "output path of the OpenAPI v3 document")
+	flag.Parse()
+	if *in == "" || *out == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	data, err := os.ReadFile(*in)
+	if err != nil {
+		fail("%v", err)
+	}
+	var v2 openapi2.T
+	if err := json.Unmarshal(data, &v2); err != nil {
+		fail("failed to parse %s: %v", *in, err)
+	}
+	v3, err := openapi2conv.ToV3(&v2)
+	if err != nil {
+		fail("failed to convert: %v", err)
+	}
+	result, err := json.MarshalIndent(v3, "", "  ")
+	if err != nil {
+		fail("%v", err)
+	}
+	if err := os.WriteFile(*out, append(result, '\n'), 0o644); err != nil {
+		fail("%v", err)
+	}
+}
+
+func fail(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	os.Exit(1)
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"guardian-backend/internal/config"
+)
+
+// runConfigCommand 实现 guardian config 子命令，返回进程退出码
+func runConfigCommand(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] != "check" {
+		fmt.Fprintln(stderr, "usage: guardian config check [-config path/to/config.yaml]")
+		return 2
+	}
+	fs := flag.NewFlagSet("config check", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	path := fs.String("config", os.Getenv("GUARDIAN_CONFIG"), "config file (default: config.yaml in ./backend or ./)")
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+	return configCheck(*path, stdout, stderr)
+}
+
+// configCheck 按服务启动时的规则加载配置（含环境变量与 *_FILE 密钥文件），并检查引用的密钥文件是否可读；
+// 有错误时退出码为 1，仅有警告时为 0
+func configCheck(path string, stdout, stderr io.Writer) int {
+	cfg, err := config.LoadFile(path)
+	if err == nil {
+		err = cfg.CheckFiles()
+	}
+	if err != nil {
+		var verr *config.ValidationError
+		if errors.As(err, &verr) {
+			fmt.Fprintln(stderr, "configuration is invalid:")
+			for _, p := range verr.Problems {
+				fmt.Fprintln(stderr, "  -", p)
+			}
+		} else {
+			fmt.Fprintln(stderr, "failed to load configuration:", err)
+		}
+		return 1
+	}
+	for _, w := range cfg.Warnings() {
+		fmt.Fprintln(stderr, "warning:", w)
+	}
+	env := cfg.Environment
+	if env == "" {
+		env = config.EnvDevelopment
+	}
+	fmt.Fprintf(stdout, "configuration OK (environment: %s)\n", env)
+	return 0
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const checkYAML = `
+server:
+  port: ":8080"
+  grpc_port: ":50051"
+database:
+  dsn: "postgres://guardian:s3cure-db-pass@db:5432/guardian"
+auth:
+  jwt_secret: "0123456789abcdef0123456789abcdef"
+  admin_username: "admin"
+  admin_password: "correct-horse-battery"
+encryption:
+  master_key_id: "k1"
+  master_keys:
+    - { id: "k1", file: "KEY_FILE" }
+`
+
+// writeCheckConfig 在临时目录写入配置与主密钥文件；withKey 为 false 时不创建密钥文件
+func writeCheckConfig(t *testing.T, extra string, withKey bool) string {
+	t.Helper()
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "k1.key")
+	if withKey {
+		require.NoError(t, os.WriteFile(keyFile, bytes.Repeat([]byte{1}, 32), 0o600))
+	}
+	content := bytes.ReplaceAll([]byte(checkYAML+extra), []byte("KEY_FILE"), []byte(keyFile))
+	path := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(path, content, 0o600))
+	return path
+}
+
+func TestRunConfigCommand(t *testing.T) {
+	valid := writeCheckConfig(t, "", true)
+	invalid := writeCheckConfig(t, `
+log:
+  level: "verbose"
+tracing:
+  exporter: "zipkin"
+`, true)
+	missingKey := writeCheckConfig(t, "", false)
+
+	for _, tc := range []struct {
+		name       string
+		args       []string
+		wantCode   int
+		wantStdout []string
+		wantStderr []string
+	}{
+		{
+			name:       "valid config",
+			args:       []string{"check", "-config", valid},
+			wantCode:   0,
+			wantStdout: []string{"configuration OK (environment: development)"},
+		},
+		{
+			name:       "validation errors are all listed",
+			args:       []string{"check", "-config", invalid},
+			wantCode:   1,
+			wantStderr: []string{"configuration is invalid:", "  - log.level:", "  - tracing.exporter:"},
+		},
+		{
+			name:       "missing key file",
+			args:       []string{"check", "-config", missingKey},
+			wantCode:   1,
+			wantStderr: []string{"configuration is invalid:", "  - encryption.master_keys[0].file:"},
+		},
+		{
+			name:       "missing config file",
+			args:       []string{"check", "-config", filepath.Join(t.TempDir(), "absent.yaml")},
+			wantCode:   1,
+			wantStderr: []string{"failed to load configuration:"},
+		},
+		{
+			name:       "no subcommand",
+			args:       nil,
+			wantCode:   2,
+			wantStderr: []string{"usage: guardian config check"},
+		},
+		{
+			name:       "unknown subcommand",
+			args:       []string{"validate"},
+			wantCode:   2,
+			wantStderr: []string{"usage: guardian config check"},
+		},
+		{
+			name:     "unknown flag",
+			args:     []string{"check", "-strict"},
+			wantCode: 2,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("GUARDIAN_ENV", "")
+			var stdout, stderr bytes.Buffer
+			code := runConfigCommand(tc.args, &stdout, &stderr)
+			assert.Equal(t, tc.wantCode, code, "stderr: %s", stderr.String())
+			for _, s := range tc.wantStdout {
+				assert.Contains(t, stdout.String(), s)
+			}
+			for _, s := range tc.wantStderr {
+				assert.Contains(t, stderr.String(), s)
+			}
+			if tc.wantCode != 0 {
+				assert.Empty(t, stdout.String())
+			}
+		})
+	}
+}
@@ -3,60 +3,173 @@ package main
 
 import (
     "context"
+    "errors"
     "crypto/tls"
     "crypto/x509"
     "io/ioutil"
-    "log"
     "log/slog"
     "net"
     "net/http"
+    "net/http/pprof"
+    "os"
+    "os/signal"
+    "syscall"
     "time"
 
     "github.com/go-chi/chi/v5"
     "github.com/go-chi/chi/v5/middleware"
-    // "github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
     "google.golang.org/grpc"
     "google.golang.org/grpc/credentials"
 
     api "guardian-backend/pkg/grpc/api/guardian/pkg/grpc/api"
+    "guardian-backend/api/openapi"
+    "guardian-backend/internal/auth"
     "guardian-backend/internal/service"
     "guardian-backend/internal/database"
     "guardian-backend/internal/handler"
     "guardian-backend/internal/config"
+    "guardian-backend/internal/envelope"
+    "guardian-backend/internal/evidence"
+    "guardian-backend/internal/gateway"
+    "guardian-backend/internal/kms"
+    "guardian-backend/internal/live"
+    "guardian-backend/internal/redact"
+    "guardian-backend/internal/ingest"
+    "guardian-backend/internal/interceptor"
+    "guardian-backend/internal/ratelimit"
+    "guardian-backend/internal/webhook"
+    "guardian-backend/pkg/logger"
+    "guardian-backend/internal/health"
     m "guardian-backend/pkg/metrics"
+    "guardian-backend/pkg/tracing"
+    "github.com/prometheus/client_golang/prometheus"
     promhttp "github.com/prometheus/client_golang/prometheus/promhttp"
+    grpchealth "google.golang.org/grpc/health"
+    healthpb "google.golang.org/grpc/health/grpc_health_v1"
 )
 
 
 
 func main() {
+	// guardian config check：只校验配置，不启动服务
+	if len(os.Args) > 1 && os.Args[1] == "config" {
+		os.Exit(runConfigCommand(os.Args[2:], os.Stdout, os.Stderr))
+	}
 	ctx := context.Background()
 	cfg, err := config.Load()
 	if err != nil {
-		log.Panicf("failed to load config: %v", err)
+		fatal("failed to load config", "error", err)
+	}
+	if _, err := logger.Setup(os.Stdout, logger.Options{Level: cfg.Log.Level, Format: cfg.Log.Format, AddSource: cfg.Log.AddSource}); err != nil {
+		fatal("failed to configure logging", "error", err)
+	}
+	// development 下默认或弱密钥只告警；production 下 config.Load 已拒绝
+	for _, w := range cfg.Warnings() {
+		slog.Warn("insecure configuration", "problem", w)
+	}
+	shutdownTracing, err := tracing.Setup(ctx, tracing.Options{
+		Exporter:    cfg.Tracing.Exporter,
+		Endpoint:    cfg.Tracing.Endpoint,
+		Insecure:    cfg.Tracing.Insecure,
+		SampleRatio: cfg.Tracing.SampleRatio,
+		ServiceName: cfg.Tracing.ServiceName,
+	})
+	if err != nil {
+		fatal("failed to configure tracing", "error", err)
 	}
 
 	pool, err := database.NewConnectionWithDSN(ctx, cfg.Database.DSN)
 	if err != nil {
-		log.Panicf("failed to connect to database: %v", err)
+		fatal("failed to connect to database", "error", err)
+	}
+	pool.RequiredNoticeVersion = cfg.Compliance.NoticeVersion
+
+	// 就绪检查：数据库可达且迁移版本不落后于代码；后台任务状态一并报告
+	checker := health.New()
+	checker.AddCheck("database", pool.Pool.Ping)
+	checker.AddCheck("schema", pool.CheckSchema)
+	// 任务与 agent 状态计数在抓取时查询数据库，连接池统计直接读取 pgxpool
+	prometheus.MustRegister(&m.StateCollector{Source: pool}, &m.PoolCollector{Pool: pool.Pool})
+
+	// 消息内容静态加密：按案件生成数据密钥，由 KMS 主密钥包装
+	masterKeys := make([]kms.MasterKeyConfig, 0, len(cfg.Encryption.MasterKeys))
+	for _, k := range cfg.Encryption.MasterKeys {
+		masterKeys = append(masterKeys, kms.MasterKeyConfig{ID: k.ID, File: k.File})
+	}
+	keyService, err := kms.NewLocalKMS(cfg.Encryption.MasterKeyID, masterKeys)
+	if err != nil {
+		fatal("failed to load encryption master keys", "error", err)
+	}
+	cipher := envelope.New(keyService, pool)
+	if cfg.Encryption.KeyCacheSeconds > 0 {
+		cipher.CacheTTL = time.Duration(cfg.Encryption.KeyCacheSeconds) * time.Second
+	}
+	pool.Cipher = cipher
+	// 存量明文消息在后台分批加密
+	legacyJob := checker.Job("legacy_encryption", 0)
+	go func() {
+		n, err := pool.EncryptLegacyMessages(ctx, 500)
+		legacyJob.Record(err)
+		if err != nil {
+			slog.Error("failed to encrypt legacy messages", "error", err)
+			return
+		}
+		if n > 0 {
+			slog.Info("encrypted legacy messages", "messages", n)
+		}
+	}()
+
+	// 消息敏感字段脱敏
+	detectors := make([]redact.Detector, 0, len(cfg.Redaction.Detectors)+len(cfg.Redaction.Custom))
+	for _, name := range cfg.Redaction.Detectors {
+		d, ok := redact.Builtin(name)
+		if !ok {
+			fatal("unknown redaction detector", "name", name)
+		}
+		detectors = append(detectors, d)
+	}
+	for _, c := range cfg.Redaction.Custom {
+		d, err := redact.Custom(c.Name, c.Pattern)
+		if err != nil {
+			fatal("invalid redaction detector", "error", err)
+		}
+		detectors = append(detectors, d)
+	}
+	redactor := redact.New(detectors, redact.Policy{Clear: cfg.Redaction.ClearRoles, UnmaskRoles: cfg.Redaction.UnmaskRoles})
+
+	// JWT 密钥环：未配置 keys 时沿用 jwt_secret 作为唯一 HS256 密钥
+	keyConfigs := make([]auth.KeyConfig, 0, len(cfg.Auth.Keys))
+	for _, k := range cfg.Auth.Keys {
+		keyConfigs = append(keyConfigs, auth.KeyConfig{ID: k.ID, Algorithm: k.Algorithm, Secret: k.Secret, PrivateKeyFile: k.PrivateKeyFile})
+	}
+	signingKeyID := cfg.Auth.SigningKeyID
+	if len(keyConfigs) == 0 {
+		keyConfigs = append(keyConfigs, auth.KeyConfig{ID: "default", Algorithm: auth.AlgHS256, Secret: cfg.Auth.JWTSecret})
+		signingKeyID = "default"
+	}
+	if cfg.Auth.Issuer == "" { cfg.Auth.Issuer = "guardian" }
+	if cfg.Auth.Audience == "" { cfg.Auth.Audience = "guardian-console" }
+	keyring, err := auth.NewKeyring(cfg.Auth.Issuer, cfg.Auth.Audience, signingKeyID, keyConfigs)
+	if err != nil {
+		fatal("failed to load jwt keys", "error", err)
 	}
 
 	// gRPC 服务器 (mTLS)
 	lis, err := net.Listen("tcp", cfg.Server.GrpcPort)
 	if err != nil {
-		log.Fatalf("failed to listen: %v", err)
+		fatal("failed to listen", "error", err)
 	}
 	// 加载 CA 证书
 	caCert, err := ioutil.ReadFile("ca.crt")
 	if err != nil {
-		log.Fatalf("failed to read CA cert: %v", err)
+		fatal("failed to read CA cert", "error", err)
 	}
 	caPool := x509.NewCertPool()
 	caPool.AppendCertsFromPEM(caCert)
 	// 加载服务器证书和私钥
 	cert, err := tls.LoadX509KeyPair("server.crt", "server.key")
 	if err != nil {
-		log.Fatalf("failed to load server cert/key: %v", err)
+		fatal("failed to load server cert/key", "error", err)
 	}
 	tlsConfig := &tls.Config{
 		Certificates: []tls.Certificate{cert},
@@ -64,63 +177,367 @@ func main() {
 		ClientAuth:   tls.RequireAndVerifyClientCert,
 	}
 	creds := credentials.NewTLS(tlsConfig)
-	grpcServer := grpc.NewServer(grpc.Creds(creds))
-    agentSrv := &service.AgentServer{DB: pool.Pool}
+	// 拦截器链：请求 ID、结构化日志、RPC 指标、panic 恢复与截止时间上限
+	deadlines := interceptor.DeadlinePolicy{
+		Default: time.Duration(cfg.Server.GrpcMaxDeadlineSeconds) * time.Second,
+		Methods: map[string]time.Duration{},
+	}
+	for _, d := range cfg.Server.GrpcMethodDeadlines {
+		deadlines.Methods[d.Method] = time.Duration(d.Seconds) * time.Second
+	}
+	// 控制台服务另需用户访问令牌，与 HTTP 接口相同
+	consoleAuth := interceptor.ConsoleAuth(keyring, pool, "/guardian.ConsoleService/")
+	grpcServer := grpc.NewServer(append([]grpc.ServerOption{grpc.Creds(creds), tracing.GRPCServerOption()}, interceptor.ServerOptions(deadlines, consoleAuth)...)...)
+    if cfg.Retention.RetiredAgentDays <= 0 { cfg.Retention.RetiredAgentDays = 365 }
+    agentSrv := &service.AgentServer{DB: pool.Pool, RetentionPeriod: time.Duration(cfg.Retention.RetiredAgentDays) * 24 * time.Hour}
 	api.RegisterAgentServiceServer(grpcServer, agentSrv)
-    dataSrv := &service.DataServer{DB: pool.Pool}
+    dataSrv := &service.DataServer{DB: pool.Pool, Cipher: cipher, Filter: &ingest.Filter{ExcludedConversations: cfg.Ingestion.ExcludedConversations}}
 	api.RegisterDataServiceServer(grpcServer, dataSrv)
+	// 控制台服务：gRPC 与 /v2 转码接口共用同一实现与角色授权
+	consoleSrv := service.AuthorizeConsole(&service.ConsoleServer{Agents: pool, Cases: pool, Audit: pool, Redactor: redactor})
+	api.RegisterConsoleServiceServer(grpcServer, consoleSrv)
+	// 标准 grpc.health.v1 服务，供 agent 与负载均衡探测；状态随就绪检查刷新。
+	// gRPC 端口要求客户端证书，负载均衡改用管理端口上以 h2c 提供的同一服务
+	healthSrv := grpchealth.NewServer()
+	healthpb.RegisterHealthServer(grpcServer, healthSrv)
+	adminGRPC := grpc.NewServer()
+	healthpb.RegisterHealthServer(adminGRPC, healthSrv)
+	healthEvery := time.Duration(cfg.Server.HealthCheckIntervalSeconds) * time.Second
+	if healthEvery <= 0 { healthEvery = 10 * time.Second }
+	go checker.Watch(ctx, healthSrv, healthEvery,
+		api.AgentService_ServiceDesc.ServiceName,
+		api.DataService_ServiceDesc.ServiceName,
+		api.ConsoleService_ServiceDesc.ServiceName,
+	)
 	go func() {
-		log.Printf("gRPC server listening on %s", cfg.Server.GrpcPort)
+		slog.Info("gRPC server listening", "port", cfg.Server.GrpcPort)
+		// GracefulStop 后 Serve 返回 nil
 		if err := grpcServer.Serve(lis); err != nil {
-			log.Fatalf("failed to serve: %v", err)
+			fatal("failed to serve", "error", err)
+		}
+	}()
+
+	// 定期清除保留期已到的退役 agent 数据
+	purgeEvery := time.Duration(cfg.Retention.PurgeIntervalMinutes) * time.Minute
+	if purgeEvery <= 0 { purgeEvery = time.Hour }
+	purgeJob := checker.Job("retention_purge", purgeEvery)
+	go func() {
+		ticker := time.NewTicker(purgeEvery)
+		defer ticker.Stop()
+		for range ticker.C {
+			n, err := pool.PurgeExpiredRetainedData(ctx)
+			purgeJob.Record(err)
+			if err != nil {
+				slog.Error("failed to purge expired retained data", "error", err)
+				continue
+			}
+			if n > 0 {
+				slog.Info("purged expired retained data", "messages", n)
+			}
+		}
+	}()
+
+	// 任务超时与 agent 离线判定，状态变更与事件在同一事务内写入 event_outbox
+	checkEvery := time.Duration(cfg.Monitoring.CheckIntervalSeconds) * time.Second
+	if checkEvery <= 0 { checkEvery = time.Minute }
+	monitorJob := checker.Job("monitoring", checkEvery)
+	go func() {
+		ticker := time.NewTicker(checkEvery)
+		defer ticker.Stop()
+		for range ticker.C {
+			var errs []error
+			if cfg.Monitoring.TaskTimeoutMinutes > 0 {
+				n, err := pool.TimeoutStaleTasks(ctx, time.Duration(cfg.Monitoring.TaskTimeoutMinutes)*time.Minute)
+				if err != nil {
+					slog.Error("failed to time out stale tasks", "error", err)
+					errs = append(errs, err)
+				} else if n > 0 {
+					slog.Info("timed out stale tasks", "tasks", n)
+				}
+			}
+			if cfg.Monitoring.AgentOfflineMinutes > 0 {
+				n, err := pool.MarkOfflineAgents(ctx, time.Duration(cfg.Monitoring.AgentOfflineMinutes)*time.Minute)
+				if err != nil {
+					slog.Error("failed to mark offline agents", "error", err)
+					errs = append(errs, err)
+				} else if n > 0 {
+					slog.Info("marked agents offline", "agents", n)
+				}
+			}
+			monitorJob.Record(errors.Join(errs...))
 		}
 	}()
 
+	// 控制台实时事件：各实例独立 LISTEN，任一副本上的变化都能推送到所有连接
+	liveHub := live.NewHub()
+	go live.Listen(ctx, pool.Pool.Config().ConnConfig, liveHub)
+
+	// Webhook 投递器：扇出 event_outbox 中的新事件并投递到订阅方
+	if cfg.Webhooks.Enabled {
+		dispatcher := &webhook.Dispatcher{
+			Store:       pool,
+			Client:      &http.Client{Timeout: time.Duration(cfg.Webhooks.TimeoutSeconds) * time.Second},
+			MaxAttempts: cfg.Webhooks.MaxAttempts,
+			BaseBackoff: time.Duration(cfg.Webhooks.BaseBackoffSeconds) * time.Second,
+			MaxBackoff:  time.Duration(cfg.Webhooks.MaxBackoffSeconds) * time.Second,
+		}
+		pollEvery := time.Duration(cfg.Webhooks.PollIntervalSeconds) * time.Second
+		if pollEvery <= 0 { pollEvery = 5 * time.Second }
+		dispatcher.OnRun = checker.Job("webhooks", pollEvery).Record
+		go dispatcher.Run(ctx, pollEvery)
+	}
+
+	// 证据导出包签名密钥
+	var exportSigner *evidence.Signer
+	if cfg.Export.SigningKeyFile != "" {
+		exportSigner, err = evidence.LoadSigner(cfg.Export.SigningKeyID, cfg.Export.SigningKeyFile)
+		if err != nil {
+			fatal("failed to load export signing key", "error", err)
+		}
+	}
+
 	// HTTP/REST 服务器 (chi + grpc-gateway)
 	r := chi.NewRouter()
     r.Use(middleware.RequestID)
+    // 追踪需在 RequestLogger 之前，请求级 logger 才能带上 trace_id
+    r.Use(tracing.HTTPMiddleware())
     r.Use(handler.RequestLogger)
-    r.Use(middleware.Logger)
     r.Use(middleware.Recoverer)
     r.Use(handler.CORSWithOrigins(cfg.Server.CORSOrigins))
     r.Use(m.HTTPMetrics)
     if cfg.Server.RequestTimeoutSeconds <= 0 { cfg.Server.RequestTimeoutSeconds = 15 }
-    r.Use(handler.WithRequestTimeout(time.Duration(cfg.Server.RequestTimeoutSeconds) * time.Second))
+    // SSE 为长连接、证据导出包可能很大，均不受请求超时限制（超时处理会在内存中缓冲整个响应）
+    r.Use(handler.WithRequestTimeout(time.Duration(cfg.Server.RequestTimeoutSeconds) * time.Second, "/v1/events", "/v1/cases/*/exports"))
 
 
+	// 引导管理员：仅在用户名不存在时创建，已有账户的口令与 MFA 状态不受配置影响
+	if cfg.Auth.AdminUsername != "" && cfg.Auth.AdminPassword != "" {
+		hash, err := auth.HashPassword(cfg.Auth.AdminPassword)
+		if err != nil {
+			fatal("failed to hash admin password", "error", err)
+		}
+		if err := pool.EnsureUser(context.Background(), cfg.Auth.AdminUsername, hash, "admin"); err != nil {
+			fatal("failed to bootstrap admin user", "error", err)
+		}
+	}
+
 	// 登录API
 	// 实例化 AuthHandler
-    authHandler := &handler.AuthHandler{JWTSecret: cfg.Auth.JWTSecret, AdminUsername: cfg.Auth.AdminUsername, AdminPassword: cfg.Auth.AdminPassword}
-    // 登录独立限流（每秒 5 次，突发 10）
+    authHandler := &handler.AuthHandler{
+        Keys:             keyring,
+        AccessTokenTTL:   time.Duration(cfg.Auth.AccessTokenTTLMinutes) * time.Minute,
+        RefreshTokenTTL:  time.Duration(cfg.Auth.RefreshTokenTTLHours) * time.Hour,
+        Sessions:         pool,
+        Users:            pool,
+        MFARequiredRoles: cfg.Auth.MFARequiredRoles,
+    }
+    // OIDC 单点登录（可选）：启动时完成 IdP 发现
+    if oc := cfg.Auth.OIDC; oc.Enabled {
+        groupRoles := make([]auth.GroupRole, 0, len(oc.GroupRoles))
+        for _, gr := range oc.GroupRoles {
+            groupRoles = append(groupRoles, auth.GroupRole{Group: gr.Group, Role: gr.Role})
+        }
+        discoverCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+        provider, err := auth.NewOIDCProvider(discoverCtx, auth.OIDCConfig{
+            IssuerURL: oc.IssuerURL, ClientID: oc.ClientID, ClientSecret: oc.ClientSecret,
+            RedirectURL: oc.RedirectURL, Scopes: oc.Scopes, GroupsClaim: oc.GroupsClaim, GroupRoles: groupRoles,
+        })
+        cancel()
+        if err != nil {
+            fatal("failed to initialise oidc", "error", err)
+        }
+        authHandler.OIDC = provider
+        authHandler.PostLoginRedirect = oc.PostLoginRedirect
+    }
+    // 登录按来源 IP 与用户名分别限流（默认每秒 5 次，突发 10），连续失败后渐进锁定
     loginRPS := cfg.Server.RateLimit.LoginRPS; if loginRPS <= 0 { loginRPS = 5 }
     loginBurst := cfg.Server.RateLimit.LoginBurst; if loginBurst <= 0 { loginBurst = 10 }
-    r.With(handler.TokenBucketLimiter(loginRPS, loginBurst)).Post("/login", authHandler.Login)
+    maxKeys := cfg.Server.RateLimit.MaxKeys
+    loginLimiter := handler.RateLimit(ratelimit.NewLimiter(float64(loginRPS), loginBurst, maxKeys), handler.KeyByIP)
+    authHandler.UsernameLimiter = ratelimit.NewLimiter(float64(loginRPS), loginBurst, maxKeys)
+    lc := cfg.Auth.Lockout
+    authHandler.Lockout = ratelimit.NewLockout(ratelimit.LockoutPolicy{
+        Threshold:    lc.Threshold,
+        Window:       time.Duration(lc.WindowMinutes) * time.Minute,
+        BaseDuration: time.Duration(lc.BaseMinutes) * time.Minute,
+        MaxDuration:  time.Duration(lc.MaxMinutes) * time.Minute,
+    }, maxKeys)
+    authHandler.SecurityEvents = pool
+    r.With(loginLimiter).Post("/login", authHandler.Login)
+    r.With(loginLimiter).Post("/login/mfa", authHandler.LoginMFA)
+    r.With(loginLimiter).Post("/login/mfa/enroll", authHandler.LoginMFAEnroll)
+    r.With(loginLimiter).Post("/token/refresh", authHandler.Refresh)
+    if authHandler.OIDC != nil {
+        r.With(loginLimiter).Get("/login/oidc", authHandler.OIDCLogin)
+        r.With(loginLimiter).Get("/login/oidc/callback", authHandler.OIDCCallback)
+    }
+    r.Get("/.well-known/jwks.json", authHandler.JWKS)
 
 	// 受保护API
     r.Group(func(protected chi.Router) {
-		protected.Use(handler.JWTAuth(cfg.Auth.JWTSecret))
-        // 健康/就绪探针（无需鉴权也可考虑暴露在 /healthz）
-        r.Get("/healthz", func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK); w.Write([]byte("ok")) })
-        r.Get("/readyz", func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK); w.Write([]byte("ready")) })
-        r.Handle("/metrics", promhttp.Handler())
-        taskHandler := &handler.TaskHandler{DB: pool}
+		protected.Use(handler.JWTAuth(keyring, pool))
+		// 受保护接口按用户 ID 限流（默认每秒 50 次，突发 100）
+		prps := cfg.Server.RateLimit.ProtectedRPS; if prps <= 0 { prps = 50 }
+		pburst := cfg.Server.RateLimit.ProtectedBurst; if pburst <= 0 { pburst = 100 }
+		protected.Use(handler.RateLimit(ratelimit.NewLimiter(float64(prps), pburst, maxKeys), handler.KeyByUser))
+		protected.Post("/logout", authHandler.Logout)
+		protected.Post("/v1/me/mfa/enroll", authHandler.EnrollMFA)
+		protected.Post("/v1/me/mfa/activate", authHandler.ActivateMFA)
+		protected.With(handler.RequireRole("admin")).Post("/v1/admin/users/{userID}/revoke-sessions", authHandler.RevokeUserSessions)
+		protected.With(handler.RequireRole("admin")).Post("/v1/admin/users/{userID}/mfa/reset", authHandler.ResetUserMFA)
+        // 由 guardian.proto 生成的接口契约，无需鉴权
+        r.Get("/openapi.json", serveJSON(openapi.V3))
+        r.Get("/openapi.v2.json", serveJSON(openapi.V2))
+        // /v1 控制台路由是 ConsoleService 的兼容适配层，保留原有 JSON 格式
+        taskHandler := &handler.TaskHandler{DB: pool, Redactor: redactor}
         // 列表：GET /v1/agents
-        // 对受保护接口设置较高阈值（每秒 50 次，突发 100）
-        prps := cfg.Server.RateLimit.ProtectedRPS; if prps <= 0 { prps = 50 }
-        pburst := cfg.Server.RateLimit.ProtectedBurst; if pburst <= 0 { pburst = 100 }
-        protected.With(handler.TokenBucketLimiter(prps, pburst)).Get("/v1/agents", taskHandler.Agents)
+        protected.Get("/v1/agents", taskHandler.Agents)
         // 需要 agentID 的路由组
+        personHandler := &handler.PersonHandler{DB: pool}
         protected.Route("/v1/agents/{agentID}", func(agent chi.Router) {
             agent.Use(handler.AgentCtx)
-            agent.Post("/tasks", taskHandler.Create)
+            agent.With(handler.RequireRole("admin", "approver")).Post("/tasks", taskHandler.Create)
             agent.Get("/messages", taskHandler.MessagesByAgent) // GET /v1/agents/{agentID}/messages
+            personHandler.AgentRoutes(agent)
+            agent.With(handler.RequireRole("admin")).Post("/decommission", taskHandler.Decommission)
         })
-        // TODO: 如需启用 HTTP 转码，请生成 *.gw.go 并在此注册 gRPC-Gateway
+        // 按任务查看与清除采集数据
+        protected.Get("/v1/tasks/{taskID}/messages", taskHandler.MessagesByTask)
+        protected.With(handler.RequireRole("admin")).Delete("/v1/tasks/{taskID}/messages", taskHandler.PurgeTaskData)
+        // 实时事件推送（SSE）
+        eventsHandler := &handler.EventsHandler{
+            Hub:        liveHub,
+            Sessions:   pool,
+            TopicRoles: cfg.Events.TopicRoles,
+            KeepAlive:  time.Duration(cfg.Events.KeepAliveSeconds) * time.Second,
+        }
+        protected.Get("/v1/events", eventsHandler.Stream)
+        // 被监测人员与告知确认记录（写操作仅 admin）
+        personHandler.Routes(protected)
+        // 入库完整性校验
+        integrityHandler := &handler.IntegrityHandler{DB: pool}
+        protected.With(handler.RequireRole("admin", "auditor")).Post("/v1/admin/integrity-check", integrityHandler.Check)
+        // Webhook 订阅与投递日志
+        webhookHandler := &handler.WebhookHandler{DB: pool}
+        protected.With(handler.RequireRole("admin")).Get("/v1/admin/webhooks", webhookHandler.List)
+        protected.With(handler.RequireRole("admin")).Post("/v1/admin/webhooks", webhookHandler.Create)
+        protected.With(handler.RequireRole("admin")).Delete("/v1/admin/webhooks/{webhookID}", webhookHandler.Delete)
+        protected.With(handler.RequireRole("admin")).Get("/v1/admin/webhooks/{webhookID}/deliveries", webhookHandler.Deliveries)
+        // 案件与加密擦除
+        caseHandler := &handler.CaseHandler{DB: pool}
+        protected.Get("/v1/cases", caseHandler.List)
+        protected.Post("/v1/cases", caseHandler.Create)
+        protected.With(handler.RequireRole("admin")).Delete("/v1/cases/{caseID}/key", caseHandler.DestroyKey)
+        if exportSigner != nil {
+            exportHandler := &handler.ExportHandler{DB: pool, Signer: exportSigner, Redactor: redactor}
+            protected.With(handler.RequireRole("admin")).Post("/v1/cases/{caseID}/exports", exportHandler.Create)
+        }
+        // gRPC-Gateway 转码接口；REST 上传没有 agent 证书，仅 admin 可用，上传者登记在批次上并写入审计
+        gw := gateway.NewMux()
+        if err := api.RegisterDataServiceHandlerServer(ctx, gw, dataSrv); err != nil {
+            fatal("failed to register DataService gateway", "error", err)
+        }
+        protected.With(handler.RequireRole("admin"), handler.AgentCtx).Post("/v1/messages/{agentID}", gw.ServeHTTP)
+        // 控制台接口 v2：由 ConsoleService 转码，角色授权在服务内完成
+        if err := api.RegisterConsoleServiceHandlerServer(ctx, gw, consoleSrv); err != nil {
+            fatal("failed to register ConsoleService gateway", "error", err)
+        }
+        protected.Handle("/v2/*", gw)
 	})
 
-   slog.Info("HTTP server listening", "port", cfg.Server.Port)
-   if err := http.ListenAndServe(cfg.Server.Port, r); err != nil {
-	   slog.Error("failed to serve HTTP", "error", err)
-	   panic(err)
+   // 管理监听与业务端口分离，探针、指标与 pprof 不暴露在公网路由上
+   var adminServer *http.Server
+   if cfg.Server.AdminPort != "" {
+       adminServer = &http.Server{Addr: cfg.Server.AdminPort, Handler: health.WithGRPC(adminRouter(checker), adminGRPC)}
+       go func() {
+           slog.Info("admin server listening", "port", cfg.Server.AdminPort)
+           if err := adminServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+               fatal("failed to serve admin HTTP", "error", err)
+           }
+       }()
+   }
+
+   httpServer := &http.Server{Addr: cfg.Server.Port, Handler: r}
+   // 事件推送是长连接，关闭时先断开，否则 Shutdown 会一直等到超时
+   httpServer.RegisterOnShutdown(liveHub.Close)
+   go func() {
+       slog.Info("HTTP server listening", "port", cfg.Server.Port)
+       if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+           fatal("failed to serve HTTP", "error", err)
+       }
+   }()
+
+   // 收到退出信号后停止接受新连接，等待进行中的请求完成，最后上报缓冲中的 span
+   sig := make(chan os.Signal, 1)
+   signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+   slog.Info("shutting down", "signal", (<-sig).String())
+   shutdown(healthSrv, grpcServer, httpServer, adminServer)
+   flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+   defer cancel()
+   if err := shutdownTracing(flushCtx); err != nil {
+       slog.Warn("failed to flush traces", "error", err)
    }
 }
+
+// shutdownTimeout 为等待进行中的请求完成的时长，短于 Kubernetes 默认的 30 秒终止宽限期
+const shutdownTimeout = 25 * time.Second
+
+// shutdown 先把 grpc.health.v1 状态置为 NOT_SERVING，再并行优雅关闭 gRPC 与 HTTP 服务；
+// 超过 shutdownTimeout 仍未结束的 gRPC 调用与 HTTP 连接被强制关闭。adminServer 为 nil 表示未启用管理监听
+func shutdown(healthSrv *grpchealth.Server, grpcServer *grpc.Server, httpServer, adminServer *http.Server) {
+    healthSrv.Shutdown()
+    ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+    defer cancel()
+
+    stopped := make(chan struct{})
+    go func() {
+        grpcServer.GracefulStop()
+        close(stopped)
+    }()
+    servers := []*http.Server{httpServer}
+    if adminServer != nil {
+        servers = append(servers, adminServer)
+    }
+    for _, srv := range servers {
+        if err := srv.Shutdown(ctx); err != nil {
+            slog.Warn("HTTP server did not shut down cleanly", "addr", srv.Addr, "error", err)
+            _ = srv.Close()
+        }
+    }
+    select {
+    case <-stopped:
+    case <-ctx.Done():
+        slog.Warn("gRPC server did not stop in time, closing remaining connections")
+        grpcServer.Stop()
+    }
+}
+
+// fatal 记录启动失败原因并退出
+func fatal(msg string, args ...any) {
+    slog.Error(msg, args...)
+    os.Exit(1)
+}
+
+// adminRouter 为管理监听的路由：存活/就绪探针、Prometheus 指标、Grafana 仪表盘与 pprof
+func adminRouter(checker *health.Checker) http.Handler {
+    r := chi.NewRouter()
+    r.Use(middleware.Recoverer)
+    r.Get("/healthz", health.LiveHandler)
+    r.Get("/readyz", checker.ReadyHandler)
+    r.Handle("/metrics", promhttp.Handler())
+    r.Get("/dashboards/guardian.json", serveJSON(m.GrafanaDashboard))
+    r.HandleFunc("/debug/pprof/*", pprof.Index)
+    r.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+    r.HandleFunc("/debug/pprof/profile", pprof.Profile)
+    r.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+    r.HandleFunc("/debug/pprof/trace", pprof.Trace)
+    return r
+}
+
+// serveJSON 返回输出固定 JSON 文档的处理函数
+func serveJSON(doc []byte) http.HandlerFunc {
+    return func(w http.ResponseWriter, _ *http.Request) {
+        w.Header().Set("Content-Type", "application/json")
+        _, _ = w.Write(doc)
+    }
+}
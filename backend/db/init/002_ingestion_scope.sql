@@ -0,0 +1,22 @@
+-- 服务端采集范围过滤：任务授权时间窗、排除会话与丢弃统计
+
+-- tasks: 授权的采集时间窗（为空表示不限）与排除会话列表
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS scope_start TIMESTAMPTZ;
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS scope_end TIMESTAMPTZ;
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS excluded_conversations TEXT[] NOT NULL DEFAULT '{}';
+
+-- wechat_messages: 会话标识
+ALTER TABLE wechat_messages ADD COLUMN IF NOT EXISTS conversation_id VARCHAR(255);
+
+-- ingestion_discards: 仅记录被丢弃消息的聚合计数，不保存任何内容
+CREATE TABLE IF NOT EXISTS ingestion_discards (
+  id BIGSERIAL PRIMARY KEY,
+  agent_id INTEGER NOT NULL REFERENCES agents(id) ON DELETE CASCADE,
+  task_id BIGINT REFERENCES tasks(id) ON DELETE SET NULL,
+  reason VARCHAR(64) NOT NULL,
+  discarded_count INTEGER NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_ingestion_discards_agent_time
+  ON ingestion_discards(agent_id, created_at DESC);
@@ -0,0 +1,42 @@
+-- 被监测人员、告知与确认记录、审计日志
+
+-- monitored_persons: 使用终端的员工
+CREATE TABLE IF NOT EXISTS monitored_persons (
+  id SERIAL PRIMARY KEY,
+  full_name VARCHAR(255) NOT NULL,
+  employee_id VARCHAR(128) UNIQUE,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- agents: 关联到被监测人员
+ALTER TABLE agents ADD COLUMN IF NOT EXISTS monitored_person_id INTEGER REFERENCES monitored_persons(id) ON DELETE SET NULL;
+
+-- monitoring_acknowledgements: 每次告知的版本、告知时间与本人确认时间
+CREATE TABLE IF NOT EXISTS monitoring_acknowledgements (
+  id BIGSERIAL PRIMARY KEY,
+  person_id INTEGER NOT NULL REFERENCES monitored_persons(id) ON DELETE CASCADE,
+  notice_version VARCHAR(64) NOT NULL,
+  notified_at TIMESTAMPTZ NOT NULL,
+  acknowledged_at TIMESTAMPTZ,
+  recorded_by VARCHAR(255) NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_monitoring_ack_person
+  ON monitoring_acknowledgements(person_id, created_at DESC);
+
+-- audit_logs: 控制台操作审计（含被拒绝的操作）
+CREATE TABLE IF NOT EXISTS audit_logs (
+  id BIGSERIAL PRIMARY KEY,
+  actor VARCHAR(255) NOT NULL,
+  action VARCHAR(128) NOT NULL,
+  target_type VARCHAR(64),
+  target_id VARCHAR(64),
+  outcome VARCHAR(32) NOT NULL,
+  detail JSONB,
+  ip_address VARCHAR(100),
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_logs_time
+  ON audit_logs(created_at DESC);
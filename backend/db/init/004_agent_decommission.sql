@@ -0,0 +1,27 @@
+-- Agent 退役：卸载任务、凭据吊销与数据处置
+
+-- agents: 退役时间与数据处置决定（retain 按保留期保存 / purge 立即清除）
+ALTER TABLE agents ADD COLUMN IF NOT EXISTS retired_at TIMESTAMPTZ;
+ALTER TABLE agents ADD COLUMN IF NOT EXISTS data_disposition VARCHAR(16);
+ALTER TABLE agents ADD COLUMN IF NOT EXISTS retain_until TIMESTAMPTZ;
+
+-- tasks: 任务参数（如卸载任务的数据处置方式）与结果说明
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS params JSONB NOT NULL DEFAULT '{}';
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS result_message TEXT;
+
+-- agent_credentials: agent 首次接入时绑定的客户端证书指纹，退役后吊销
+CREATE TABLE IF NOT EXISTS agent_credentials (
+  id BIGSERIAL PRIMARY KEY,
+  agent_id INTEGER NOT NULL REFERENCES agents(id) ON DELETE CASCADE,
+  cert_fingerprint CHAR(64) NOT NULL UNIQUE,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  revoked_at TIMESTAMPTZ
+);
+
+-- 删除 agents 行不再级联删除证据，须先通过退役流程处置数据
+ALTER TABLE wechat_messages DROP CONSTRAINT IF EXISTS wechat_messages_agent_id_fkey;
+ALTER TABLE wechat_messages ADD CONSTRAINT wechat_messages_agent_id_fkey
+  FOREIGN KEY (agent_id) REFERENCES agents(id) ON DELETE RESTRICT;
+ALTER TABLE tasks DROP CONSTRAINT IF EXISTS tasks_agent_id_fkey;
+ALTER TABLE tasks ADD CONSTRAINT tasks_agent_id_fkey
+  FOREIGN KEY (agent_id) REFERENCES agents(id) ON DELETE RESTRICT;
@@ -0,0 +1,21 @@
+-- 控制台登录会话：刷新令牌仅保存 SHA-256 哈希，轮换后保留上一枚以识别重放
+
+CREATE TABLE IF NOT EXISTS auth_sessions (
+  id VARCHAR(64) PRIMARY KEY,
+  user_id VARCHAR(255) NOT NULL,
+  role VARCHAR(64) NOT NULL,
+  refresh_token_hash CHAR(64) NOT NULL UNIQUE,
+  previous_token_hash CHAR(64),
+  user_agent TEXT,
+  ip_address VARCHAR(100),
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  last_used_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  expires_at TIMESTAMPTZ NOT NULL,
+  revoked_at TIMESTAMPTZ,
+  revoked_reason VARCHAR(64)
+);
+
+CREATE INDEX IF NOT EXISTS idx_auth_sessions_user
+  ON auth_sessions(user_id) WHERE revoked_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_auth_sessions_previous_hash
+  ON auth_sessions(previous_token_hash);
@@ -0,0 +1,25 @@
+-- 控制台用户与 TOTP 多因素认证
+
+-- audit_users: 控制台账户；role 取值 admin / approver / auditor
+CREATE TABLE IF NOT EXISTS audit_users (
+  id SERIAL PRIMARY KEY,
+  username VARCHAR(255) UNIQUE NOT NULL,
+  password_hash VARCHAR(255) NOT NULL,
+  role VARCHAR(50) NOT NULL,
+  -- TOTP 密钥（base32）；mfa_enabled_at 为空表示尚未完成绑定
+  mfa_secret VARCHAR(64),
+  mfa_enabled_at TIMESTAMPTZ,
+  -- 最近一次成功使用的 TOTP 时间步，防止同一验证码被重放
+  mfa_last_step BIGINT,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- mfa_recovery_codes: 一次性恢复码，仅保存哈希
+CREATE TABLE IF NOT EXISTS mfa_recovery_codes (
+  id BIGSERIAL PRIMARY KEY,
+  user_id INTEGER NOT NULL REFERENCES audit_users(id) ON DELETE CASCADE,
+  code_hash CHAR(64) NOT NULL,
+  used_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  UNIQUE (user_id, code_hash)
+);
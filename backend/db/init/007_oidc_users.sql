@@ -0,0 +1,10 @@
+-- OIDC 单点登录：IdP 用户首次登录时按 (issuer, subject) 即时创建到 audit_users
+
+-- SSO 用户没有本地口令
+ALTER TABLE audit_users ALTER COLUMN password_hash DROP NOT NULL;
+ALTER TABLE audit_users ADD COLUMN IF NOT EXISTS oidc_issuer VARCHAR(255);
+ALTER TABLE audit_users ADD COLUMN IF NOT EXISTS oidc_subject VARCHAR(255);
+ALTER TABLE audit_users ADD COLUMN IF NOT EXISTS email VARCHAR(255);
+-- 最近一次 SSO 登录时间，角色在每次登录时按 IdP 组重新映射
+ALTER TABLE audit_users ADD COLUMN IF NOT EXISTS last_login_at TIMESTAMPTZ;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_audit_users_oidc ON audit_users(oidc_issuer, oidc_subject) WHERE oidc_issuer IS NOT NULL;
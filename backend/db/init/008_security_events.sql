@@ -0,0 +1,12 @@
+-- 安全事件：登录锁定等需要安全团队关注的事件，与操作审计日志分开存放便于告警与对接 SIEM
+
+CREATE TABLE IF NOT EXISTS security_events (
+  id BIGSERIAL PRIMARY KEY,
+  event_type VARCHAR(64) NOT NULL,
+  -- 登录时提交的用户名（可能并不存在）
+  username VARCHAR(255),
+  ip_address VARCHAR(64),
+  detail JSONB,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_security_events_type_time ON security_events(event_type, created_at DESC);
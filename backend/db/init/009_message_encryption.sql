@@ -0,0 +1,29 @@
+-- 消息内容的信封加密：每个案件一把数据密钥，由 KMS 主密钥包装后存放在 case_keys
+
+-- cases: 调查案件；default_agent_id 非空表示该 agent 未指定案件时使用的默认案件
+CREATE TABLE IF NOT EXISTS cases (
+  id BIGSERIAL PRIMARY KEY,
+  name VARCHAR(255) NOT NULL,
+  default_agent_id INTEGER UNIQUE REFERENCES agents(id),
+  -- open / key_destroyed
+  status VARCHAR(32) NOT NULL DEFAULT 'open',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  key_destroyed_at TIMESTAMPTZ
+);
+
+-- case_keys: 已包装的数据密钥；销毁时清空 wrapped_key，密文随之不可恢复
+CREATE TABLE IF NOT EXISTS case_keys (
+  case_id BIGINT PRIMARY KEY REFERENCES cases(id),
+  wrapped_key BYTEA,
+  master_key_id VARCHAR(64) NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  destroyed_at TIMESTAMPTZ
+);
+
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS case_id BIGINT REFERENCES cases(id);
+
+-- 新消息只写 content_enc，content 仅保留给加密迁移前的历史数据，迁移后置空
+ALTER TABLE wechat_messages ADD COLUMN IF NOT EXISTS case_id BIGINT REFERENCES cases(id);
+ALTER TABLE wechat_messages ADD COLUMN IF NOT EXISTS content_enc BYTEA;
+ALTER TABLE wechat_messages ALTER COLUMN content DROP NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_wechat_messages_plaintext ON wechat_messages(id) WHERE content IS NOT NULL;
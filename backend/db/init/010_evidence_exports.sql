@@ -0,0 +1,17 @@
+-- 证据导出登记：每次导出记录清单哈希，事后可据此核对交付出去的导出包
+
+CREATE TABLE IF NOT EXISTS evidence_exports (
+  id BIGSERIAL PRIMARY KEY,
+  bundle_id VARCHAR(64) NOT NULL UNIQUE,
+  case_id BIGINT NOT NULL REFERENCES cases(id),
+  exported_by VARCHAR(255) NOT NULL,
+  reason TEXT NOT NULL,
+  redacted BOOLEAN NOT NULL DEFAULT FALSE,
+  message_count INTEGER NOT NULL,
+  manifest_sha256 CHAR(64) NOT NULL,
+  signing_key_id VARCHAR(64) NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_evidence_exports_case
+  ON evidence_exports(case_id, created_at DESC);
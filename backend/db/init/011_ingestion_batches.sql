@@ -0,0 +1,23 @@
+-- 入库回执与完整性校验：每个上传批次一行，消息通过 batch_id 关联到所属批次
+
+-- ingestion_batches: request_sha256 为收到的完整请求的哈希（回执），
+-- content_sha256 只覆盖实际入库的消息，可从库中数据重算以发现入库后的篡改
+CREATE TABLE IF NOT EXISTS ingestion_batches (
+  id BIGSERIAL PRIMARY KEY,
+  agent_id INTEGER NOT NULL REFERENCES agents(id),
+  task_id BIGINT REFERENCES tasks(id) ON DELETE SET NULL,
+  case_id BIGINT REFERENCES cases(id),
+  received_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  received_count INTEGER NOT NULL,
+  accepted_count INTEGER NOT NULL,
+  request_sha256 CHAR(64) NOT NULL,
+  content_sha256 CHAR(64) NOT NULL,
+  -- 消息按退役处置或保留期被清除后置位，此后不再参与完整性校验
+  purged_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_ingestion_batches_agent_time
+  ON ingestion_batches(agent_id, received_at DESC);
+
+ALTER TABLE wechat_messages ADD COLUMN IF NOT EXISTS batch_id BIGINT REFERENCES ingestion_batches(id);
+CREATE INDEX IF NOT EXISTS idx_wechat_messages_batch ON wechat_messages(batch_id);
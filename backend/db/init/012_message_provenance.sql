@@ -0,0 +1,14 @@
+-- 消息来源：每条消息关联采集它的授权任务，支持按任务查看与清除
+
+ALTER TABLE wechat_messages ADD COLUMN IF NOT EXISTS task_id BIGINT REFERENCES tasks(id);
+CREATE INDEX IF NOT EXISTS idx_wechat_messages_task_time
+  ON wechat_messages(task_id, timestamp DESC);
+
+-- 已登记批次的消息可从批次回填任务
+UPDATE wechat_messages m SET task_id = b.task_id
+FROM ingestion_batches b
+WHERE m.batch_id = b.id AND m.task_id IS NULL AND b.task_id IS NOT NULL;
+
+-- tasks: 任务数据被清除（例如事后认定任务不当）的时间与理由
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS data_purged_at TIMESTAMPTZ;
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS data_purge_reason TEXT;
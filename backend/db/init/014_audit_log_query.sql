@@ -0,0 +1,7 @@
+-- 审计日志查询（ConsoleService.ListAuditLogs）：按操作者或对象过滤，按 id 倒序游标分页
+
+CREATE INDEX IF NOT EXISTS idx_audit_logs_actor
+  ON audit_logs(actor, id DESC);
+
+CREATE INDEX IF NOT EXISTS idx_audit_logs_target
+  ON audit_logs(target_type, target_id, id DESC);
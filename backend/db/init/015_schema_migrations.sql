@@ -0,0 +1,26 @@
+-- 迁移版本记录：就绪检查比较最大版本号与服务端期望的版本（database.SchemaVersion），
+-- 数据库落后于代码时 /readyz 与 gRPC 健康检查报告未就绪。此后每个迁移文件末尾插入自身版本
+
+CREATE TABLE IF NOT EXISTS schema_migrations (
+  version INT PRIMARY KEY,
+  name VARCHAR(128) NOT NULL,
+  applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO schema_migrations(version, name) VALUES
+  (1, '001_schema'),
+  (2, '002_ingestion_scope'),
+  (3, '003_monitored_persons'),
+  (4, '004_agent_decommission'),
+  (5, '005_auth_sessions'),
+  (6, '006_audit_users_mfa'),
+  (7, '007_oidc_users'),
+  (8, '008_security_events'),
+  (9, '009_message_encryption'),
+  (10, '010_evidence_exports'),
+  (11, '011_ingestion_batches'),
+  (12, '012_message_provenance'),
+  (13, '013_webhooks'),
+  (14, '014_audit_log_query'),
+  (15, '015_schema_migrations')
+ON CONFLICT (version) DO NOTHING;
@@ -0,0 +1,6 @@
+-- 入库批次的上传者：agent 经 mTLS 上传时为空；经 REST（gRPC-Gateway）由控制台用户上传时为其用户 ID，
+-- 这类批次不是 agent 采集所得，导出与完整性报告据此区分来源
+
+ALTER TABLE ingestion_batches ADD COLUMN IF NOT EXISTS uploaded_by TEXT;
+
+INSERT INTO schema_migrations(version, name) VALUES (16, '016_batch_uploader') ON CONFLICT (version) DO NOTHING;
@@ -0,0 +1,22 @@
+-- 案件功能上线前创建的任务、消息与入库批次没有 case_id：归入各自 agent 的默认案件（不存在时创建），
+-- 之后这些任务的上传、消息查询与证据导出均按该案件处理。加密迁移前的明文消息同样归入默认案件，
+-- 由启动时的 legacy_encryption 任务以该案件密钥加密
+
+INSERT INTO cases (name, default_agent_id)
+SELECT 'agent-' || a.id || '-default', a.id
+FROM agents a
+WHERE EXISTS (SELECT 1 FROM tasks t WHERE t.agent_id=a.id AND t.case_id IS NULL)
+   OR EXISTS (SELECT 1 FROM wechat_messages m WHERE m.agent_id=a.id AND m.case_id IS NULL)
+   OR EXISTS (SELECT 1 FROM ingestion_batches b WHERE b.agent_id=a.id AND b.case_id IS NULL)
+ON CONFLICT (default_agent_id) DO NOTHING;
+
+UPDATE tasks t SET case_id=c.id
+FROM cases c WHERE t.case_id IS NULL AND c.default_agent_id=t.agent_id;
+
+UPDATE wechat_messages m SET case_id=c.id
+FROM cases c WHERE m.case_id IS NULL AND c.default_agent_id=m.agent_id;
+
+UPDATE ingestion_batches b SET case_id=c.id
+FROM cases c WHERE b.case_id IS NULL AND c.default_agent_id=b.agent_id;
+
+INSERT INTO schema_migrations(version, name) VALUES (17, '017_legacy_task_cases') ON CONFLICT (version) DO NOTHING;
@@ -0,0 +1,7 @@
+-- 上传去重：agent 重试或重放同一请求时按 (agent_id, request_sha256) 查找已登记的批次，直接返回原回执。
+-- 历史数据中可能已有重复批次，因此只建普通索引；并发写入由 SaveMessages 内的事务级 advisory lock 串行化
+
+CREATE INDEX IF NOT EXISTS idx_ingestion_batches_agent_request
+  ON ingestion_batches(agent_id, request_sha256);
+
+INSERT INTO schema_migrations(version, name) VALUES (18, '018_batch_request_dedup') ON CONFLICT (version) DO NOTHING;
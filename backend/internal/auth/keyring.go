@@ -0,0 +1,209 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// 支持的签名算法
+const (
+	AlgHS256 = "HS256"
+	AlgEdDSA = "EdDSA"
+)
+
+// ErrUnknownKey 表示令牌头中的 kid 不在密钥环中
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// Key 是密钥环中的一把密钥；HS256 使用 Secret，EdDSA 使用 Ed25519 密钥对
+type Key struct {
+	ID         string
+	Algorithm  string
+	Secret     []byte
+	PrivateKey ed25519.PrivateKey
+	PublicKey  ed25519.PublicKey
+}
+
+// KeyConfig 是单把密钥的配置；PrivateKeyFile 为 PKCS#8 PEM 格式的 Ed25519 私钥
+type KeyConfig struct {
+	ID             string
+	Algorithm      string
+	Secret         string
+	PrivateKeyFile string
+}
+
+// Keyring 按 kid 管理签名/验签密钥。只有 SigningKeyID 指定的密钥用于签发，
+// 其余密钥仅用于验签，轮换时旧密钥保留到其签发的令牌过期即可，不会让用户被迫重新登录。
+type Keyring struct {
+	Issuer   string
+	Audience string
+	signing  *Key
+	keys     map[string]*Key
+}
+
+// NewKeyring 根据配置构建密钥环
+func NewKeyring(issuer, audience, signingKeyID string, configs []KeyConfig) (*Keyring, error) {
+	if issuer == "" || audience == "" {
+		return nil, errors.New("jwt issuer and audience are required")
+	}
+	kr := &Keyring{Issuer: issuer, Audience: audience, keys: map[string]*Key{}}
+	for _, c := range configs {
+		k, err := loadKey(c)
+		if err != nil {
+			return nil, err
+		}
+		if _, dup := kr.keys[k.ID]; dup {
+			return nil, fmt.Errorf("duplicate jwt key id %q", k.ID)
+		}
+		kr.keys[k.ID] = k
+	}
+	signing, ok := kr.keys[signingKeyID]
+	if !ok {
+		return nil, fmt.Errorf("signing key %q not found in keyring", signingKeyID)
+	}
+	kr.signing = signing
+	return kr, nil
+}
+
+func loadKey(c KeyConfig) (*Key, error) {
+	if c.ID == "" {
+		return nil, errors.New("jwt key id is required")
+	}
+	k := &Key{ID: c.ID, Algorithm: c.Algorithm}
+	switch c.Algorithm {
+	case AlgHS256:
+		if len(c.Secret) < 32 {
+			return nil, fmt.Errorf("jwt key %q: HS256 secret must be at least 32 bytes", c.ID)
+		}
+		k.Secret = []byte(c.Secret)
+	case AlgEdDSA:
+		raw, err := os.ReadFile(c.PrivateKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("jwt key %q: %w", c.ID, err)
+		}
+		priv, err := parseEd25519PrivateKey(raw)
+		if err != nil {
+			return nil, fmt.Errorf("jwt key %q: %w", c.ID, err)
+		}
+		k.PrivateKey = priv
+		k.PublicKey = priv.Public().(ed25519.PublicKey)
+	default:
+		return nil, fmt.Errorf("jwt key %q: unsupported algorithm %q", c.ID, c.Algorithm)
+	}
+	return k, nil
+}
+
+func parseEd25519PrivateKey(raw []byte) (ed25519.PrivateKey, error) {
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, errors.New("invalid PEM private key")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	priv, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, errors.New("private key is not Ed25519")
+	}
+	return priv, nil
+}
+
+// Sign 使用当前签名密钥签发令牌，补全 iss/aud/iat/nbf 与 kid 头；调用方须设置 exp
+func (kr *Keyring) Sign(claims jwt.MapClaims) (string, error) {
+	now := time.Now()
+	claims["iss"] = kr.Issuer
+	claims["aud"] = kr.Audience
+	claims["iat"] = now.Unix()
+	claims["nbf"] = now.Unix()
+	var (
+		token *jwt.Token
+		key   any
+	)
+	switch kr.signing.Algorithm {
+	case AlgHS256:
+		token, key = jwt.NewWithClaims(jwt.SigningMethodHS256, claims), kr.signing.Secret
+	case AlgEdDSA:
+		token, key = jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims), kr.signing.PrivateKey
+	}
+	token.Header["kid"] = kr.signing.ID
+	return token.SignedString(key)
+}
+
+// Parse 校验令牌并返回声明：算法必须与 kid 对应密钥一致，且 iss/aud/exp/nbf 均须存在且有效
+func (kr *Keyring) Parse(tokenStr string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(tokenStr, claims, kr.keyFunc,
+		jwt.WithValidMethods([]string{AlgHS256, AlgEdDSA}),
+		jwt.WithIssuer(kr.Issuer),
+		jwt.WithAudience(kr.Audience),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := claims["nbf"]; !ok {
+		return nil, errors.New("token is missing nbf claim")
+	}
+	return claims, nil
+}
+
+// keyFunc 按 kid 选择验签密钥，并将算法固定为该密钥的算法，防止算法混淆
+func (kr *Keyring) keyFunc(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+	k, ok := kr.keys[kid]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	if token.Method.Alg() != k.Algorithm {
+		return nil, fmt.Errorf("unexpected signing method %q for key %q", token.Method.Alg(), kid)
+	}
+	if k.Algorithm == AlgEdDSA {
+		return k.PublicKey, nil
+	}
+	return k.Secret, nil
+}
+
+// JWK 是 RFC 8037 格式的 Ed25519 公钥
+type JWK struct {
+	KeyType   string `json:"kty"`
+	Curve     string `json:"crv"`
+	X         string `json:"x"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+}
+
+// JWKS 是公开的验签密钥集合
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS 返回全部 EdDSA 公钥，供其他内部服务验证令牌；HS256 共享密钥不会公开
+func (kr *Keyring) JWKS() JWKS {
+	set := JWKS{Keys: []JWK{}}
+	for _, k := range kr.keys {
+		if k.Algorithm != AlgEdDSA {
+			continue
+		}
+		set.Keys = append(set.Keys, JWK{
+			KeyType:   "OKP",
+			Curve:     "Ed25519",
+			X:         base64.RawURLEncoding.EncodeToString(k.PublicKey),
+			KeyID:     k.ID,
+			Use:       "sig",
+			Algorithm: AlgEdDSA,
+		})
+	}
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].KeyID < set.Keys[j].KeyID })
+	return set
+}
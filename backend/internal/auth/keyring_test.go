@@ -0,0 +1,135 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	secretA = "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+	secretB = "bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"
+)
+
+func writeEd25519Key(t *testing.T) string {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "jwt.pem")
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600))
+	return path
+}
+
+func claims() jwt.MapClaims {
+	return jwt.MapClaims{"sub": "1", "exp": time.Now().Add(time.Minute).Unix()}
+}
+
+func TestKeyring_RotationKeepsOldTokensValid(t *testing.T) {
+	old, err := NewKeyring("guardian", "console", "a", []KeyConfig{{ID: "a", Algorithm: AlgHS256, Secret: secretA}})
+	require.NoError(t, err)
+	token, err := old.Sign(claims())
+	require.NoError(t, err)
+
+	rotated, err := NewKeyring("guardian", "console", "b", []KeyConfig{
+		{ID: "a", Algorithm: AlgHS256, Secret: secretA},
+		{ID: "b", Algorithm: AlgHS256, Secret: secretB},
+	})
+	require.NoError(t, err)
+	_, err = rotated.Parse(token)
+	assert.NoError(t, err, "token signed by retired key must still verify")
+
+	fresh, err := rotated.Sign(claims())
+	require.NoError(t, err)
+	parsed, _, err := jwt.NewParser().ParseUnverified(fresh, jwt.MapClaims{})
+	require.NoError(t, err)
+	assert.Equal(t, "b", parsed.Header["kid"])
+}
+
+func TestKeyring_RejectsInvalidTokens(t *testing.T) {
+	kr, err := NewKeyring("guardian", "console", "a", []KeyConfig{{ID: "a", Algorithm: AlgHS256, Secret: secretA}})
+	require.NoError(t, err)
+	sign := func(method jwt.SigningMethod, kid string, c jwt.MapClaims, key any) string {
+		tok := jwt.NewWithClaims(method, c)
+		if kid != "" {
+			tok.Header["kid"] = kid
+		}
+		s, err := tok.SignedString(key)
+		require.NoError(t, err)
+		return s
+	}
+	exp := time.Now().Add(time.Minute).Unix()
+	now := time.Now().Unix()
+	valid := jwt.MapClaims{"iss": "guardian", "aud": "console", "exp": exp, "nbf": now, "iat": now}
+	with := func(k string, v any) jwt.MapClaims {
+		c := jwt.MapClaims{}
+		for kk, vv := range valid {
+			c[kk] = vv
+		}
+		if v == nil {
+			delete(c, k)
+		} else {
+			c[k] = v
+		}
+		return c
+	}
+
+	_, err = kr.Parse(sign(jwt.SigningMethodHS256, "a", valid, []byte(secretA)))
+	require.NoError(t, err)
+
+	cases := map[string]string{
+		"alg none":        sign(jwt.SigningMethodNone, "a", valid, jwt.UnsafeAllowNoneSignatureType),
+		"wrong alg":       sign(jwt.SigningMethodHS512, "a", valid, []byte(secretA)),
+		"unknown kid":     sign(jwt.SigningMethodHS256, "x", valid, []byte(secretA)),
+		"missing kid":     sign(jwt.SigningMethodHS256, "", valid, []byte(secretA)),
+		"wrong issuer":    sign(jwt.SigningMethodHS256, "a", with("iss", "evil"), []byte(secretA)),
+		"wrong audience":  sign(jwt.SigningMethodHS256, "a", with("aud", "other"), []byte(secretA)),
+		"missing exp":     sign(jwt.SigningMethodHS256, "a", with("exp", nil), []byte(secretA)),
+		"missing nbf":     sign(jwt.SigningMethodHS256, "a", with("nbf", nil), []byte(secretA)),
+		"not yet valid":   sign(jwt.SigningMethodHS256, "a", with("nbf", time.Now().Add(time.Hour).Unix()), []byte(secretA)),
+		"wrong signature": sign(jwt.SigningMethodHS256, "a", valid, []byte(secretB)),
+	}
+	for name, token := range cases {
+		_, err := kr.Parse(token)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestKeyring_EdDSAAndJWKS(t *testing.T) {
+	kr, err := NewKeyring("guardian", "console", "ed", []KeyConfig{
+		{ID: "hs", Algorithm: AlgHS256, Secret: secretA},
+		{ID: "ed", Algorithm: AlgEdDSA, PrivateKeyFile: writeEd25519Key(t)},
+	})
+	require.NoError(t, err)
+	token, err := kr.Sign(claims())
+	require.NoError(t, err)
+	c, err := kr.Parse(token)
+	require.NoError(t, err)
+	assert.Equal(t, "1", c["sub"])
+
+	set := kr.JWKS()
+	require.Len(t, set.Keys, 1, "HS256 secrets must never be published")
+	assert.Equal(t, "ed", set.Keys[0].KeyID)
+	assert.Equal(t, "OKP", set.Keys[0].KeyType)
+}
+
+func TestNewKeyring_Validation(t *testing.T) {
+	_, err := NewKeyring("guardian", "console", "a", []KeyConfig{{ID: "a", Algorithm: AlgHS256, Secret: "short"}})
+	assert.Error(t, err)
+	_, err = NewKeyring("guardian", "console", "missing", []KeyConfig{{ID: "a", Algorithm: AlgHS256, Secret: secretA}})
+	assert.Error(t, err)
+	_, err = NewKeyring("", "console", "a", []KeyConfig{{ID: "a", Algorithm: AlgHS256, Secret: secretA}})
+	assert.Error(t, err)
+	_, err = NewKeyring("guardian", "console", "a", []KeyConfig{{ID: "a", Algorithm: "RS256"}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,166 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+// ErrNoMappedRole 表示 IdP 用户所在的组都没有映射到 Guardian 角色
+var ErrNoMappedRole = errors.New("no guardian role mapped from identity provider groups")
+
+// GroupRole 把 IdP 组映射到 Guardian 角色
+type GroupRole struct {
+	Group string
+	Role  string
+}
+
+// OIDCConfig 是 OIDC 授权码流程的配置
+type OIDCConfig struct {
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+	// GroupsClaim 为 ID Token 中携带组信息的声明名，默认 groups
+	GroupsClaim string
+	// GroupRoles 按顺序匹配，先命中者生效，因此高权限角色应排在前面
+	GroupRoles []GroupRole
+}
+
+// OIDCIdentity 是从已校验的 ID Token 中提取的用户身份
+type OIDCIdentity struct {
+	Issuer   string
+	Subject  string
+	Username string
+	Email    string
+	Groups   []string
+}
+
+// OIDCProvider 封装 IdP 发现、授权地址生成与授权码交换
+type OIDCProvider struct {
+	cfg      OIDCConfig
+	oauth    oauth2.Config
+	verifier *oidc.IDTokenVerifier
+}
+
+// NewOIDCProvider 通过 IdP 的 /.well-known/openid-configuration 完成发现
+func NewOIDCProvider(ctx context.Context, cfg OIDCConfig) (*OIDCProvider, error) {
+	if cfg.IssuerURL == "" || cfg.ClientID == "" || cfg.RedirectURL == "" {
+		return nil, errors.New("oidc issuer_url, client_id and redirect_url are required")
+	}
+	if len(cfg.GroupRoles) == 0 {
+		return nil, errors.New("oidc group_roles must map at least one group")
+	}
+	if cfg.GroupsClaim == "" {
+		cfg.GroupsClaim = "groups"
+	}
+	provider, err := oidc.NewProvider(ctx, cfg.IssuerURL)
+	if err != nil {
+		return nil, fmt.Errorf("oidc discovery: %w", err)
+	}
+	scopes := cfg.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{oidc.ScopeOpenID, "profile", "email", "groups"}
+	}
+	return &OIDCProvider{
+		cfg: cfg,
+		oauth: oauth2.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			RedirectURL:  cfg.RedirectURL,
+			Endpoint:     provider.Endpoint(),
+			Scopes:       scopes,
+		},
+		verifier: provider.Verifier(&oidc.Config{ClientID: cfg.ClientID}),
+	}, nil
+}
+
+// NewOIDCState 生成 state、nonce 与 PKCE code_verifier
+func NewOIDCState() (state, nonce, verifier string, err error) {
+	b := make([]byte, 32)
+	if _, err = rand.Read(b); err != nil {
+		return "", "", "", err
+	}
+	state = base64.RawURLEncoding.EncodeToString(b[:16])
+	nonce = base64.RawURLEncoding.EncodeToString(b[16:])
+	return state, nonce, oauth2.GenerateVerifier(), nil
+}
+
+// AuthCodeURL 返回 IdP 授权地址，携带 S256 code_challenge 与 nonce
+func (p *OIDCProvider) AuthCodeURL(state, nonce, verifier string) string {
+	return p.oauth.AuthCodeURL(state, oauth2.S256ChallengeOption(verifier), oidc.Nonce(nonce))
+}
+
+// Exchange 以授权码和 code_verifier 换取令牌，校验 ID Token 签名、aud 与 nonce 后返回身份
+func (p *OIDCProvider) Exchange(ctx context.Context, code, verifier, nonce string) (OIDCIdentity, error) {
+	token, err := p.oauth.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return OIDCIdentity{}, fmt.Errorf("oidc code exchange: %w", err)
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok || rawIDToken == "" {
+		return OIDCIdentity{}, errors.New("oidc token response has no id_token")
+	}
+	idToken, err := p.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return OIDCIdentity{}, fmt.Errorf("oidc id_token: %w", err)
+	}
+	if idToken.Nonce != nonce {
+		return OIDCIdentity{}, errors.New("oidc id_token nonce mismatch")
+	}
+	claims := map[string]any{}
+	if err := idToken.Claims(&claims); err != nil {
+		return OIDCIdentity{}, err
+	}
+	id := OIDCIdentity{
+		Issuer:  idToken.Issuer,
+		Subject: idToken.Subject,
+		Groups:  stringList(claims[p.cfg.GroupsClaim]),
+	}
+	id.Email, _ = claims["email"].(string)
+	id.Username, _ = claims["preferred_username"].(string)
+	if id.Username == "" {
+		id.Username = id.Email
+	}
+	if id.Username == "" {
+		id.Username = id.Subject
+	}
+	return id, nil
+}
+
+// MapRole 按配置顺序返回第一个命中的角色
+func (p *OIDCProvider) MapRole(groups []string) (string, error) {
+	member := make(map[string]bool, len(groups))
+	for _, g := range groups {
+		member[g] = true
+	}
+	for _, gr := range p.cfg.GroupRoles {
+		if member[gr.Group] {
+			return gr.Role, nil
+		}
+	}
+	return "", ErrNoMappedRole
+}
+
+// stringList 兼容组声明为字符串数组或单个字符串两种形式
+func stringList(v any) []string {
+	switch t := v.(type) {
+	case string:
+		return []string{t}
+	case []any:
+		out := make([]string, 0, len(t))
+		for _, e := range t {
+			if s, ok := e.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
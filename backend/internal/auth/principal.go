@@ -0,0 +1,85 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"guardian-backend/pkg/apperr"
+)
+
+// TokenUseAccess 为访问令牌的 token_use；MFA 中间令牌等其他用途的令牌不能直接访问 API
+const TokenUseAccess = "access"
+
+type contextKey string
+
+// PrincipalKey 为 context 中存放 Principal 的键
+const PrincipalKey contextKey = "principal"
+
+const clientIPKey contextKey = "client_ip"
+
+// Principal 是通过 JWT 认证的控制台用户
+type Principal struct {
+	UserID    string
+	Role      string
+	SessionID string
+}
+
+// SessionChecker 用于在每次请求时确认令牌所属会话未被吊销
+type SessionChecker interface {
+	IsSessionActive(ctx context.Context, sessionID string) (bool, error)
+}
+
+// WithPrincipal 把已认证用户存入 context
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, PrincipalKey, p)
+}
+
+// PrincipalFrom 从 context 中取出当前用户；未认证时返回零值
+func PrincipalFrom(ctx context.Context) Principal {
+	p, _ := ctx.Value(PrincipalKey).(Principal)
+	return p
+}
+
+// WithClientIP 记录请求来源 IP，供审计日志使用
+func WithClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, clientIPKey, ip)
+}
+
+// ClientIPFrom 返回请求来源 IP；未记录时为空
+func ClientIPFrom(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIPKey).(string)
+	return ip
+}
+
+// Authenticate 校验 Authorization 头中的 Bearer 访问令牌；sessions 非空时还要求令牌所属会话仍然有效。
+// HTTP 中间件与 gRPC 拦截器共用，返回 apperr 错误。
+func Authenticate(ctx context.Context, keys *Keyring, sessions SessionChecker, authorization string) (Principal, error) {
+	tokenStr, ok := strings.CutPrefix(authorization, "Bearer ")
+	if !ok || tokenStr == "" {
+		return Principal{}, apperr.New(apperr.KindUnauthenticated, "", "missing token")
+	}
+	// 算法、kid、iss/aud/exp/nbf 均由密钥环校验
+	claims, err := keys.Parse(tokenStr)
+	if err != nil || claims["token_use"] != TokenUseAccess {
+		return Principal{}, apperr.New(apperr.KindUnauthenticated, "", "invalid token")
+	}
+	var p Principal
+	p.UserID, _ = claims["sub"].(string)
+	p.Role, _ = claims["role"].(string)
+	p.SessionID, _ = claims["sid"].(string)
+	if sessions == nil {
+		return p, nil
+	}
+	if p.SessionID == "" {
+		return Principal{}, apperr.New(apperr.KindUnauthenticated, "", "invalid token")
+	}
+	active, err := sessions.IsSessionActive(ctx, p.SessionID)
+	if err != nil {
+		return Principal{}, apperr.Internal(fmt.Errorf("check session: %w", err))
+	}
+	if !active {
+		return Principal{}, apperr.New(apperr.KindUnauthenticated, "SESSION_REVOKED", "session revoked")
+	}
+	return p, nil
+}
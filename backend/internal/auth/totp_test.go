@@ -0,0 +1,55 @@
+package auth
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// RFC 6238 附录 B 的 SHA1 测试向量（取低 6 位）
+func TestValidateTOTP_RFC6238Vectors(t *testing.T) {
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+	vectors := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+	for ts, code := range vectors {
+		step, ok := ValidateTOTP(secret, code, time.Unix(ts, 0))
+		assert.True(t, ok, "t=%d", ts)
+		assert.Equal(t, ts/30, step)
+	}
+}
+
+func TestValidateTOTP_SkewWindow(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	require.NoError(t, err)
+	key, err := b32.DecodeString(secret)
+	require.NoError(t, err)
+	now := time.Unix(1_700_000_000, 0)
+	step := now.Unix() / totpPeriod
+
+	_, ok := ValidateTOTP(secret, totpCode(key, step-1), now)
+	assert.True(t, ok, "previous step is within skew")
+	_, ok = ValidateTOTP(secret, totpCode(key, step+2), now)
+	assert.False(t, ok, "two steps ahead is outside skew")
+	_, ok = ValidateTOTP(secret, "12345", now)
+	assert.False(t, ok)
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := GenerateRecoveryCodes(10)
+	require.NoError(t, err)
+	require.Len(t, codes, 10)
+	seen := map[string]bool{}
+	for _, c := range codes {
+		assert.Len(t, c, 11)
+		assert.False(t, seen[c])
+		seen[c] = true
+	}
+	assert.Equal(t, NormalizeRecoveryCode("abcde-fghij"), NormalizeRecoveryCode(" ABCDE FGHIJ "))
+}
@@ -1,60 +1,387 @@
 package config
 
-import "github.com/spf13/viper"
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/spf13/viper"
+)
 
 type Config struct {
+	// Environment 为 development（默认）或 production；production 下拒绝默认或弱密钥
+	Environment string `mapstructure:"environment"`
 	Server   ServerConfig   `mapstructure:"server"`
 	Database DatabaseConfig `mapstructure:"database"`
 	Auth     AuthConfig     `mapstructure:"auth"`
+	Ingestion IngestionConfig `mapstructure:"ingestion"`
+	Compliance ComplianceConfig `mapstructure:"compliance"`
+	Retention RetentionConfig `mapstructure:"retention"`
+	Encryption EncryptionConfig `mapstructure:"encryption"`
+	Redaction RedactionConfig `mapstructure:"redaction"`
+	Export ExportConfig `mapstructure:"export"`
+	Monitoring MonitoringConfig `mapstructure:"monitoring"`
+	Webhooks WebhooksConfig `mapstructure:"webhooks"`
+	Events EventsConfig `mapstructure:"events"`
+	Log LogConfig `mapstructure:"log"`
+	Tracing TracingConfig `mapstructure:"tracing"`
+}
+
+// TracingConfig 控制 OpenTelemetry 链路追踪；span 中只记录 SQL 文本与数量，不记录消息内容与参数
+type TracingConfig struct {
+    // Exporter 为 none（默认）、otlp 或 stdout
+    Exporter string `mapstructure:"exporter"`
+    // Endpoint 为 OTLP/gRPC 接收端（host:port），为空时使用 OTEL_EXPORTER_OTLP_ENDPOINT
+    Endpoint string `mapstructure:"endpoint"`
+    Insecure bool `mapstructure:"insecure"`
+    // SampleRatio 为根 span 的采样比例（0-1]，上游已带采样决策时跟随上游
+    SampleRatio float64 `mapstructure:"sample_ratio"`
+    ServiceName string `mapstructure:"service_name"`
+}
+
+// LogConfig 控制服务端日志；无论格式与级别如何，消息内容、口令与令牌都不会写入日志
+type LogConfig struct {
+    // Level 为 debug / info / warn / error
+    Level string `mapstructure:"level"`
+    // Format 为 json（默认）或 text
+    Format string `mapstructure:"format"`
+    // AddSource 为 true 时附带源码位置
+    AddSource bool `mapstructure:"add_source"`
+}
+
+// EventsConfig 控制 GET /v1/events 实时推送
+type EventsConfig struct {
+    // TopicRoles 为各主题（agent / task / ingestion）允许接收的角色
+    TopicRoles map[string][]string `mapstructure:"topic_roles"`
+    // KeepAliveSeconds 为心跳间隔，同时也是检查会话是否被吊销的间隔
+    KeepAliveSeconds int `mapstructure:"keepalive_seconds"`
+}
+
+// MonitoringConfig 控制任务超时与 agent 离线的判定，判定结果以事件形式通知 webhook 订阅方
+type MonitoringConfig struct {
+    // TaskTimeoutMinutes 为已下发任务无结果上报时判定超时的时长
+    TaskTimeoutMinutes int `mapstructure:"task_timeout_minutes"`
+    // AgentOfflineMinutes 为 agent 无心跳时判定离线的时长，应大于 agent 心跳间隔
+    AgentOfflineMinutes int `mapstructure:"agent_offline_minutes"`
+    CheckIntervalSeconds int `mapstructure:"check_interval_seconds"`
+}
+
+// WebhooksConfig 控制 webhook 投递器；订阅本身通过管理接口维护
+type WebhooksConfig struct {
+    Enabled bool `mapstructure:"enabled"`
+    PollIntervalSeconds int `mapstructure:"poll_interval_seconds"`
+    // TimeoutSeconds 为单次投递请求的超时
+    TimeoutSeconds int `mapstructure:"timeout_seconds"`
+    // MaxAttempts 次均失败后投递置为 failed；重试间隔从 BaseBackoffSeconds 起每次翻倍，最长 MaxBackoffSeconds
+    MaxAttempts        int `mapstructure:"max_attempts"`
+    BaseBackoffSeconds int `mapstructure:"base_backoff_seconds"`
+    MaxBackoffSeconds  int `mapstructure:"max_backoff_seconds"`
+}
+
+// RetentionConfig 控制退役 agent 数据的保留期限
+// ExportConfig 为证据导出包的签名密钥；未配置时不提供导出接口
+type ExportConfig struct {
+    SigningKeyID   string `mapstructure:"signing_key_id"`
+    // SigningKeyFile 为 PKCS#8 PEM 格式的 Ed25519 私钥
+    SigningKeyFile string `mapstructure:"signing_key_file"`
+}
+
+// RedactionConfig 为消息返回前的敏感字段脱敏策略
+type RedactionConfig struct {
+    // Detectors 启用的内置检测器（phone / id_card / bank_card），按顺序匹配，重叠时前者优先
+    Detectors []string `mapstructure:"detectors"`
+    Custom    []CustomDetectorConfig `mapstructure:"custom"`
+    // ClearRoles 各角色可直接看到明文的检测器；未列出的角色全部脱敏
+    ClearRoles map[string][]string `mapstructure:"clear_roles"`
+    // UnmaskRoles 可提交取消脱敏请求的角色，每次请求写入审计日志
+    UnmaskRoles []string `mapstructure:"unmask_roles"`
+}
+
+type CustomDetectorConfig struct {
+    Name    string `mapstructure:"name"`
+    Pattern string `mapstructure:"pattern"`
+}
+
+// EncryptionConfig 为消息内容静态加密的主密钥配置
+type EncryptionConfig struct {
+    // MasterKeyID 为包装新数据密钥所用的当前主密钥
+    MasterKeyID string `mapstructure:"master_key_id"`
+    // MasterKeys 列出全部可用主密钥；轮换后旧密钥须保留以解包历史数据密钥
+    MasterKeys []MasterKeyConfig `mapstructure:"master_keys"`
+    // KeyCacheSeconds 为解包后数据密钥的内存缓存时间，默认 300
+    KeyCacheSeconds int `mapstructure:"key_cache_seconds"`
+}
+
+type MasterKeyConfig struct {
+    ID   string `mapstructure:"id"`
+    File string `mapstructure:"file"`
+}
+
+type RetentionConfig struct {
+    // RetiredAgentDays 为选择 retain 处置时数据的保留天数，到期后自动清除
+    RetiredAgentDays int `mapstructure:"retired_agent_days"`
+    // PurgeIntervalMinutes 为保留期到期清理任务的执行间隔
+    PurgeIntervalMinutes int `mapstructure:"purge_interval_minutes"`
+}
+
+// ComplianceConfig 控制监测告知相关的合规要求
+type ComplianceConfig struct {
+    // NoticeVersion 为当前生效的告知版本；被监测人员须确认该版本后才能向其终端下发任务
+    NoticeVersion string `mapstructure:"notice_version"`
+}
+
+// IngestionConfig 控制数据入库前的范围过滤
+type IngestionConfig struct {
+    // ExcludedConversations 全局排除的会话（wxid），这些会话的消息一律不入库
+    ExcludedConversations []string `mapstructure:"excluded_conversations"`
 }
 
 type AuthConfig struct {
+	// JWTSecret 为兼容旧配置的 HS256 密钥；未配置 keys 时以 kid "default" 载入
 	JWTSecret string `mapstructure:"jwt_secret"`
+    // Issuer / Audience 为签发与校验令牌时要求的 iss / aud
+    Issuer   string `mapstructure:"issuer"`
+    Audience string `mapstructure:"audience"`
+    // SigningKeyID 指定用于签发的密钥，其余密钥仅用于验签（轮换期间保留）
+    SigningKeyID string         `mapstructure:"signing_key_id"`
+    Keys         []JWTKeyConfig `mapstructure:"keys"`
+    // AdminUsername / AdminPassword 仅用于首次启动时创建引导管理员账户，之后以数据库为准
     AdminUsername string `mapstructure:"admin_username"`
     AdminPassword string `mapstructure:"admin_password"`
+    // MFARequiredRoles 中的角色登录时必须完成 TOTP 验证（未绑定的须先绑定）
+    MFARequiredRoles []string `mapstructure:"mfa_required_roles"`
+    OIDC             OIDCConfig `mapstructure:"oidc"`
+    Lockout          LockoutConfig `mapstructure:"lockout"`
+    // AccessTokenTTLMinutes 访问令牌有效期（分钟）；RefreshTokenTTLHours 刷新令牌有效期（小时）
+    AccessTokenTTLMinutes int `mapstructure:"access_token_ttl_minutes"`
+    RefreshTokenTTLHours  int `mapstructure:"refresh_token_ttl_hours"`
+}
+
+// OIDCConfig 是控制台单点登录配置（授权码 + PKCE）
+type OIDCConfig struct {
+    Enabled      bool     `mapstructure:"enabled"`
+    IssuerURL    string   `mapstructure:"issuer_url"`
+    ClientID     string   `mapstructure:"client_id"`
+    ClientSecret string   `mapstructure:"client_secret"`
+    // RedirectURL 为本服务的回调地址，即 <外部地址>/login/oidc/callback
+    RedirectURL string   `mapstructure:"redirect_url"`
+    Scopes      []string `mapstructure:"scopes"`
+    GroupsClaim string   `mapstructure:"groups_claim"`
+    // GroupRoles 按顺序匹配 IdP 组，先命中者生效；未命中任何组的用户被拒绝登录
+    GroupRoles []OIDCGroupRole `mapstructure:"group_roles"`
+    // PostLoginRedirect 为登录完成后携带令牌（URL fragment）跳转的前端地址；为空时回调直接返回 JSON
+    PostLoginRedirect string `mapstructure:"post_login_redirect"`
+}
+
+// OIDCGroupRole 把 IdP 组映射到 Guardian 角色
+type OIDCGroupRole struct {
+    Group string `mapstructure:"group"`
+    Role  string `mapstructure:"role"`
+}
+
+// JWTKeyConfig 是密钥环中的单把密钥；algorithm 为 HS256（secret）或 EdDSA（private_key_file，PKCS#8 PEM）
+type JWTKeyConfig struct {
+    ID             string `mapstructure:"id"`
+    Algorithm      string `mapstructure:"algorithm"`
+    Secret         string `mapstructure:"secret"`
+    PrivateKeyFile string `mapstructure:"private_key_file"`
 }
 
 type ServerConfig struct {
 	Port     string `mapstructure:"port"`
 	GrpcPort string `mapstructure:"grpc_port"`
+    // AdminPort 为管理监听地址，提供 /healthz、/readyz、/metrics 与 /debug/pprof；
+    // 不经过认证，只应绑定在内网或本机地址
+    AdminPort string `mapstructure:"admin_port"`
+    // HealthCheckIntervalSeconds 为 gRPC 健康服务状态的刷新间隔
+    HealthCheckIntervalSeconds int `mapstructure:"health_check_interval_seconds"`
     RequestTimeoutSeconds int `mapstructure:"request_timeout_seconds"`
     CORSOrigins []string `mapstructure:"cors_origins"`
     RateLimit   RateLimitConfig `mapstructure:"rate_limit"`
+    // GrpcMaxDeadlineSeconds 为 gRPC 请求的最长处理时间，客户端设置更长或未设置时按此截断
+    GrpcMaxDeadlineSeconds int `mapstructure:"grpc_max_deadline_seconds"`
+    // GrpcMethodDeadlines 按方法覆盖最长处理时间（方法名含 "."，因此使用列表而非映射）
+    GrpcMethodDeadlines []GrpcMethodDeadline `mapstructure:"grpc_method_deadlines"`
 }
 
+type GrpcMethodDeadline struct {
+    // Method 为完整方法名，如 /guardian.DataService/UploadMessages
+    Method  string `mapstructure:"method"`
+    Seconds int    `mapstructure:"seconds"`
+}
+
+// RateLimitConfig 中的速率均按单个客户端计算：登录接口按来源 IP 与用户名，受保护接口按用户 ID
 type RateLimitConfig struct {
     LoginRPS     int `mapstructure:"login_rps"`
     LoginBurst   int `mapstructure:"login_burst"`
     ProtectedRPS int `mapstructure:"protected_rps"`
     ProtectedBurst int `mapstructure:"protected_burst"`
+    // MaxKeys 为每个限流器跟踪的客户端上限，超出后淘汰最久未访问的客户端
+    MaxKeys int `mapstructure:"max_keys"`
+}
+
+// LockoutConfig 是登录失败的渐进式锁定策略
+type LockoutConfig struct {
+    // Threshold 为 WindowMinutes 内连续失败多少次后锁定
+    Threshold     int `mapstructure:"threshold"`
+    WindowMinutes int `mapstructure:"window_minutes"`
+    // 锁定时长从 BaseMinutes 起每次翻倍，最长 MaxMinutes
+    BaseMinutes int `mapstructure:"base_minutes"`
+    MaxMinutes  int `mapstructure:"max_minutes"`
 }
 
 type DatabaseConfig struct {
 	DSN string `mapstructure:"dsn"`
 }
 
+// EnvPrefix 为环境变量前缀：配置键中的 "." 换成 "_" 并转为大写，
+// 如 auth.oidc.client_secret 对应 GUARDIAN_AUTH_OIDC_CLIENT_SECRET
+const EnvPrefix = "GUARDIAN"
+
+// legacyEnv 为早先支持的环境变量名，仍然有效；与 GUARDIAN_ 前缀的变量同时设置时后者优先
+var legacyEnv = map[string]string{
+    "environment":             "GUARDIAN_ENV",
+    "database.dsn":            "DATABASE_URL",
+    "auth.admin_username":     "ADMIN_USERNAME",
+    "auth.admin_password":     "ADMIN_PASSWORD",
+    "auth.oidc.client_secret": "OIDC_CLIENT_SECRET",
+    "log.level":               "LOG_LEVEL",
+    "tracing.exporter":        "OTEL_TRACES_EXPORTER",
+}
+
+// secretKeys 可从文件读取：配置 <键>_file 或设置环境变量 GUARDIAN_<键>_FILE（如 GUARDIAN_AUTH_JWT_SECRET_FILE）时，
+// 以文件内容（去掉末尾换行）为值，优先于直接配置的值；便于使用 Docker / Kubernetes secret
+var secretKeys = []string{"database.dsn", "auth.jwt_secret", "auth.admin_password", "auth.oidc.client_secret"}
+
+// Load 读取 GUARDIAN_CONFIG 指定的配置文件，未设置时在 ./backend 与 ./ 下查找 config.yaml
 func Load() (*Config, error) {
-	viper.SetConfigName("config")
-	viper.SetConfigType("yaml")
-	viper.AddConfigPath("./backend") // 在 backend 目录寻找 config.yaml
-	viper.AddConfigPath("./")        // 兼容直接在根目录运行
-	viper.AutomaticEnv()
-
-    // Bind environment variables
-    vip_err := viper.BindEnv("database.dsn", "DATABASE_URL")
-	if vip_err != nil {
-		return nil, vip_err
-	}
-    // Optional env override for admin credentials
-    _ = viper.BindEnv("auth.admin_username", "ADMIN_USERNAME")
-    _ = viper.BindEnv("auth.admin_password", "ADMIN_PASSWORD")
-
-	if err := viper.ReadInConfig(); err != nil {
-		return nil, err
-	}
-	var cfg Config
-	if err := viper.Unmarshal(&cfg); err != nil {
-		return nil, err
-	}
-	return &cfg, nil
+    return LoadFile(os.Getenv("GUARDIAN_CONFIG"))
+}
+
+// LoadFile 读取 path 处的配置文件（为空时按 Load 的规则查找），叠加环境变量与密钥文件后校验；
+// 校验不通过时返回 *ValidationError
+func LoadFile(path string) (*Config, error) {
+    v := viper.New()
+    if path != "" {
+        v.SetConfigFile(path)
+    } else {
+        v.SetConfigName("config")
+        v.SetConfigType("yaml")
+        v.AddConfigPath("./backend") // 在 backend 目录寻找 config.yaml
+        v.AddConfigPath("./")        // 兼容直接在根目录运行
+    }
+    v.SetEnvPrefix(EnvPrefix)
+    v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+    v.AutomaticEnv()
+    setDefaults(v)
+    // AutomaticEnv 只对已知的键生效，逐一绑定后未出现在配置文件中的键也能由环境变量设置
+    if err := bindEnvs(v, reflect.TypeOf(Config{}), ""); err != nil {
+        return nil, err
+    }
+
+    if err := v.ReadInConfig(); err != nil {
+        return nil, err
+    }
+    if err := loadSecretFiles(v); err != nil {
+        return nil, err
+    }
+    var cfg Config
+    if err := v.Unmarshal(&cfg); err != nil {
+        return nil, err
+    }
+    // 主密钥路径常由部署环境挂载，允许覆盖当前主密钥的文件位置
+    if f := os.Getenv("ENCRYPTION_MASTER_KEY_FILE"); f != "" {
+        for i := range cfg.Encryption.MasterKeys {
+            if cfg.Encryption.MasterKeys[i].ID == cfg.Encryption.MasterKeyID {
+                cfg.Encryption.MasterKeys[i].File = f
+            }
+        }
+    }
+    if err := cfg.Validate(); err != nil {
+        return nil, err
+    }
+    return &cfg, nil
+}
+
+func setDefaults(v *viper.Viper) {
+    v.SetDefault("environment", EnvDevelopment)
+    v.SetDefault("server.port", ":8080")
+    v.SetDefault("server.grpc_port", ":50051")
+    v.SetDefault("auth.mfa_required_roles", []string{"admin", "approver"})
+    v.SetDefault("redaction.detectors", []string{"id_card", "bank_card", "phone"})
+    v.SetDefault("redaction.unmask_roles", []string{"admin"})
+    v.SetDefault("server.grpc_max_deadline_seconds", 30)
+    v.SetDefault("monitoring.task_timeout_minutes", 120)
+    v.SetDefault("monitoring.agent_offline_minutes", 15)
+    v.SetDefault("monitoring.check_interval_seconds", 60)
+    v.SetDefault("webhooks.enabled", true)
+    v.SetDefault("webhooks.poll_interval_seconds", 5)
+    v.SetDefault("webhooks.timeout_seconds", 10)
+    v.SetDefault("webhooks.max_attempts", 8)
+    v.SetDefault("webhooks.base_backoff_seconds", 10)
+    v.SetDefault("webhooks.max_backoff_seconds", 3600)
+    v.SetDefault("events.topic_roles", map[string][]string{
+        "agent":     {"admin", "approver", "auditor"},
+        "task":      {"admin", "approver", "auditor"},
+        "ingestion": {"admin", "auditor"},
+    })
+    v.SetDefault("events.keepalive_seconds", 25)
+    v.SetDefault("server.admin_port", "127.0.0.1:9090")
+    v.SetDefault("server.health_check_interval_seconds", 10)
+    v.SetDefault("log.level", "info")
+    v.SetDefault("log.format", "json")
+    v.SetDefault("tracing.exporter", "none")
+    v.SetDefault("tracing.sample_ratio", 1.0)
+    v.SetDefault("tracing.service_name", "guardian-backend")
+}
+
+// bindEnvs 为 t 中每个可由单个字符串表示的字段绑定环境变量；结构体列表与映射（如 auth.keys）只能在配置文件中设置
+func bindEnvs(v *viper.Viper, t reflect.Type, prefix string) error {
+    for i := 0; i < t.NumField(); i++ {
+        f := t.Field(i)
+        tag := f.Tag.Get("mapstructure")
+        if tag == "" || tag == "-" {
+            continue
+        }
+        key := prefix + tag
+        switch {
+        case f.Type.Kind() == reflect.Struct:
+            if err := bindEnvs(v, f.Type, key+"."); err != nil {
+                return err
+            }
+            continue
+        case f.Type.Kind() == reflect.Map,
+            f.Type.Kind() == reflect.Slice && f.Type.Elem().Kind() == reflect.Struct:
+            continue
+        }
+        names := []string{EnvName(key)}
+        if legacy, ok := legacyEnv[key]; ok {
+            names = append(names, legacy)
+        }
+        if err := v.BindEnv(append([]string{key}, names...)...); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// EnvName 返回配置键对应的环境变量名
+func EnvName(key string) string {
+    return EnvPrefix + "_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+func loadSecretFiles(v *viper.Viper) error {
+    for _, key := range secretKeys {
+        path := v.GetString(key + "_file")
+        if path == "" {
+            continue
+        }
+        raw, err := os.ReadFile(path)
+        if err != nil {
+            return fmt.Errorf("%s_file: %w", key, err)
+        }
+        v.Set(key, strings.TrimRight(string(raw), "\r\n"))
+    }
+    return nil
 }
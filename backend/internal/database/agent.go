@@ -2,8 +2,10 @@ package database
 
 import (
 	"context"
+	"errors"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -31,3 +33,76 @@ func CreateAgent(ctx context.Context, pool *pgxpool.Pool, hostname, osVersion st
 	}
 	return agentID, nil
 }
+
+// TouchAgent 记录 agent 心跳时间；已被判定离线的 agent 恢复为在线
+func (p *DB) TouchAgent(ctx context.Context, agentID int) error {
+	tx, err := p.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	var previous string
+	err = tx.QueryRow(ctx, `
+		UPDATE agents a
+		SET last_seen_at = NOW(), status = CASE WHEN prev.status = 'offline' THEN 'online' ELSE prev.status END
+		FROM (SELECT id, status FROM agents WHERE id = $1 FOR UPDATE) prev
+		WHERE a.id = prev.id
+		RETURNING prev.status
+	`, agentID).Scan(&previous)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrAgentNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if previous == "offline" {
+		if err := notifyAgentStatus(ctx, tx, agentID, "online"); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// MarkOfflineAgents 将超过 threshold 未发送心跳的在线 agent 置为离线，
+// 并在同一事务内为每个 agent 写入 agent.offline 事件，返回置为离线的数量
+func (p *DB) MarkOfflineAgents(ctx context.Context, threshold time.Duration) (int64, error) {
+	tx, err := p.Pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+	rows, err := tx.Query(ctx, `
+		UPDATE agents SET status = 'offline'
+		WHERE status = 'online' AND last_seen_at < NOW() - make_interval(secs => $1)
+		RETURNING id, hostname, last_seen_at
+	`, threshold.Seconds())
+	if err != nil {
+		return 0, err
+	}
+	var events []map[string]any
+	for rows.Next() {
+		var (
+			id       int
+			hostname string
+			lastSeen time.Time
+		)
+		if err := rows.Scan(&id, &hostname, &lastSeen); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		events = append(events, map[string]any{"agent_id": id, "hostname": hostname, "last_seen_at": lastSeen.UTC().Format(time.RFC3339)})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	for _, e := range events {
+		if err := enqueueEvent(ctx, tx, EventAgentOffline, e); err != nil {
+			return 0, err
+		}
+		if err := notifyAgentStatus(ctx, tx, e["agent_id"].(int), "offline"); err != nil {
+			return 0, err
+		}
+	}
+	return int64(len(events)), tx.Commit(ctx)
+}
@@ -0,0 +1,121 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// 审计结果
+const (
+	AuditOutcomeSuccess = "success"
+	AuditOutcomeDenied  = "denied"
+)
+
+// AuditEntry 是一条审计日志
+type AuditEntry struct {
+	Actor      string
+	Action     string
+	TargetType string
+	TargetID   string
+	Outcome    string
+	Detail     map[string]any
+	IPAddress  string
+}
+
+// RecordAudit 写入一条审计日志
+func (p *DB) RecordAudit(ctx context.Context, e AuditEntry) error {
+	var detail []byte
+	if len(e.Detail) > 0 {
+		b, err := json.Marshal(e.Detail)
+		if err != nil {
+			return err
+		}
+		detail = b
+	}
+	_, err := p.Pool.Exec(ctx, `
+		INSERT INTO audit_logs (actor, action, target_type, target_id, outcome, detail, ip_address)
+		VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''), $5, $6, NULLIF($7, ''))
+	`, e.Actor, e.Action, e.TargetType, e.TargetID, e.Outcome, detail, e.IPAddress)
+	return err
+}
+
+// AuditFilter 是审计日志查询条件；零值字段不参与过滤
+type AuditFilter struct {
+	Actor      string
+	Action     string
+	TargetType string
+	TargetID   string
+	Since      *time.Time
+	Until      *time.Time
+	// BeforeID 为游标：只返回 id 小于该值的记录；0 表示从最新一条开始
+	BeforeID int64
+	Limit    int
+}
+
+// ListAuditLogs 按 id 倒序（即由新到旧）返回符合条件的审计日志
+func (p *DB) ListAuditLogs(ctx context.Context, f AuditFilter) ([]AuditRecord, error) {
+	var (
+		conds []string
+		args  []any
+	)
+	add := func(cond string, v any) {
+		args = append(args, v)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+	if f.Actor != "" {
+		add("actor=$%d", f.Actor)
+	}
+	if f.Action != "" {
+		add("action=$%d", f.Action)
+	}
+	if f.TargetType != "" {
+		add("target_type=$%d", f.TargetType)
+	}
+	if f.TargetID != "" {
+		add("target_id=$%d", f.TargetID)
+	}
+	if f.Since != nil {
+		add("created_at>=$%d", *f.Since)
+	}
+	if f.Until != nil {
+		add("created_at<$%d", *f.Until)
+	}
+	if f.BeforeID > 0 {
+		add("id<$%d", f.BeforeID)
+	}
+	where := ""
+	if len(conds) > 0 {
+		where = "WHERE " + strings.Join(conds, " AND ")
+	}
+	args = append(args, f.Limit)
+	rows, err := p.Pool.Query(ctx, fmt.Sprintf(`
+		SELECT id, actor, action, COALESCE(target_type, ''), COALESCE(target_id, ''), outcome, detail,
+		       COALESCE(ip_address, ''), created_at
+		FROM audit_logs %s
+		ORDER BY id DESC LIMIT $%d
+	`, where, len(args)), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []AuditRecord
+	for rows.Next() {
+		var (
+			a      AuditRecord
+			detail []byte
+		)
+		if err := rows.Scan(&a.ID, &a.Actor, &a.Action, &a.TargetType, &a.TargetID, &a.Outcome, &detail, &a.IPAddress, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		if len(detail) > 0 {
+			if err := json.Unmarshal(detail, &a.Detail); err != nil {
+				return nil, err
+			}
+		}
+		result = append(result, a)
+	}
+	return result, rows.Err()
+}
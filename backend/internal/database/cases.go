@@ -0,0 +1,196 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"guardian-backend/internal/envelope"
+	"guardian-backend/pkg/apperr"
+)
+
+var (
+	// ErrCaseNotFound 用于案件不存在时返回
+	ErrCaseNotFound = apperr.New(apperr.KindNotFound, "", "case not found")
+	// ErrCaseKeyDestroyed 表示案件密钥已销毁，不能再写入或读取该案件的数据
+	ErrCaseKeyDestroyed = apperr.New(apperr.KindFailedPrecondition, "CASE_KEY_DESTROYED", "case key destroyed")
+)
+
+// 案件状态
+const (
+	CaseStatusOpen         = "open"
+	CaseStatusKeyDestroyed = "key_destroyed"
+)
+
+// Case 是调查案件，消息按案件分别加密
+type Case struct {
+	ID             int64
+	Name           string
+	DefaultAgentID *int
+	Status         string
+	CreatedAt      time.Time
+	KeyDestroyedAt *time.Time
+}
+
+// CreateCase 新建案件
+func (p *DB) CreateCase(ctx context.Context, name string) (Case, error) {
+	var c Case
+	err := p.Pool.QueryRow(ctx, `
+		INSERT INTO cases (name) VALUES ($1)
+		RETURNING id, name, default_agent_id, status, created_at, key_destroyed_at
+	`, name).Scan(&c.ID, &c.Name, &c.DefaultAgentID, &c.Status, &c.CreatedAt, &c.KeyDestroyedAt)
+	return c, err
+}
+
+// ListCases 列出全部案件
+func (p *DB) ListCases(ctx context.Context) ([]Case, error) {
+	rows, err := p.Pool.Query(ctx, `SELECT id, name, default_agent_id, status, created_at, key_destroyed_at FROM cases ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []Case
+	for rows.Next() {
+		var c Case
+		if err := rows.Scan(&c.ID, &c.Name, &c.DefaultAgentID, &c.Status, &c.CreatedAt, &c.KeyDestroyedAt); err != nil {
+			return nil, err
+		}
+		result = append(result, c)
+	}
+	return result, rows.Err()
+}
+
+// DestroyCaseKey 销毁案件数据密钥（加密擦除）：清空已包装密钥，该案件全部密文从此不可解密。操作不可逆。
+func (p *DB) DestroyCaseKey(ctx context.Context, caseID int64) error {
+	tx, err := p.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	tag, err := tx.Exec(ctx, `UPDATE cases SET status=$2, key_destroyed_at=NOW() WHERE id=$1 AND status<>$2`, caseID, CaseStatusKeyDestroyed)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		var status string
+		err := tx.QueryRow(ctx, `SELECT status FROM cases WHERE id=$1`, caseID).Scan(&status)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrCaseNotFound
+		}
+		if err != nil {
+			return err
+		}
+		return ErrCaseKeyDestroyed
+	}
+	// 加密迁移前残留的明文同样删除
+	if _, err := tx.Exec(ctx, `
+		DELETE FROM wechat_messages m USING cases c
+		WHERE c.id=$1 AND m.content IS NOT NULL
+		  AND (m.case_id=c.id OR (m.case_id IS NULL AND m.agent_id=c.default_agent_id))
+	`, caseID); err != nil {
+		return err
+	}
+	// 尚未生成过密钥的案件也写入一条已销毁记录，防止之后再生成新密钥
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO case_keys (case_id, wrapped_key, master_key_id, destroyed_at) VALUES ($1, NULL, '', NOW())
+		ON CONFLICT (case_id) DO UPDATE SET wrapped_key=NULL, destroyed_at=NOW()
+	`, caseID); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	if p.Cipher != nil {
+		p.Cipher.Forget(caseID)
+	}
+	return nil
+}
+
+// GetCaseKey 实现 envelope.KeyStore
+func (p *DB) GetCaseKey(ctx context.Context, caseID int64) (envelope.CaseKey, error) {
+	var k envelope.CaseKey
+	var destroyedAt *time.Time
+	err := p.Pool.QueryRow(ctx, `SELECT wrapped_key, master_key_id, destroyed_at FROM case_keys WHERE case_id=$1`, caseID).
+		Scan(&k.Wrapped, &k.MasterKeyID, &destroyedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return k, envelope.ErrNoKey
+	}
+	k.Destroyed = destroyedAt != nil
+	return k, err
+}
+
+// CreateCaseKey 实现 envelope.KeyStore；并发创建时以先写入者为准
+func (p *DB) CreateCaseKey(ctx context.Context, caseID int64, k envelope.CaseKey) (envelope.CaseKey, error) {
+	if _, err := p.Pool.Exec(ctx, `
+		INSERT INTO case_keys (case_id, wrapped_key, master_key_id) VALUES ($1, $2, $3)
+		ON CONFLICT (case_id) DO NOTHING
+	`, caseID, k.Wrapped, k.MasterKeyID); err != nil {
+		return envelope.CaseKey{}, err
+	}
+	return p.GetCaseKey(ctx, caseID)
+}
+
+// resolveTaskCase 返回任务使用的案件：指定案件须存在且密钥未销毁；未指定时使用（必要时创建）agent 的默认案件
+func resolveTaskCase(ctx context.Context, tx pgx.Tx, agentID int, caseID int64) (int64, error) {
+	if caseID != 0 {
+		var status string
+		err := tx.QueryRow(ctx, `SELECT status FROM cases WHERE id=$1`, caseID).Scan(&status)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, ErrCaseNotFound
+		}
+		if err != nil {
+			return 0, err
+		}
+		if status == CaseStatusKeyDestroyed {
+			return 0, ErrCaseKeyDestroyed
+		}
+		return caseID, nil
+	}
+	return defaultCaseForAgent(ctx, tx, agentID)
+}
+
+// assignDefaultCase 为案件功能上线前创建、未记录案件的任务补记 agent 的默认案件（必要时创建）并返回案件 ID。
+// 默认案件密钥已销毁时同样补记，随后的上传由 SaveMessages 以 ErrCaseKeyDestroyed 拒绝
+func assignDefaultCase(ctx context.Context, db txBeginner, agentID int, taskID int64) (int64, error) {
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+	var caseID int64
+	err = tx.QueryRow(ctx, `
+		INSERT INTO cases (name, default_agent_id) VALUES ($1, $2)
+		ON CONFLICT (default_agent_id) DO UPDATE SET default_agent_id=EXCLUDED.default_agent_id
+		RETURNING id
+	`, fmt.Sprintf("agent-%d-default", agentID), agentID).Scan(&caseID)
+	if err != nil {
+		return 0, err
+	}
+	// 并发请求可能已补记，以已写入的为准
+	if err := tx.QueryRow(ctx, `
+		UPDATE tasks SET case_id=COALESCE(case_id, $2) WHERE id=$1 RETURNING case_id
+	`, taskID, caseID).Scan(&caseID); err != nil {
+		return 0, err
+	}
+	return caseID, tx.Commit(ctx)
+}
+
+// defaultCaseForAgent 返回 agent 的默认案件，不存在时创建；默认案件密钥已销毁时返回 ErrCaseKeyDestroyed
+func defaultCaseForAgent(ctx context.Context, tx pgx.Tx, agentID int) (int64, error) {
+	var id int64
+	var status string
+	err := tx.QueryRow(ctx, `
+		INSERT INTO cases (name, default_agent_id) VALUES ($1, $2)
+		ON CONFLICT (default_agent_id) DO UPDATE SET default_agent_id=EXCLUDED.default_agent_id
+		RETURNING id, status
+	`, fmt.Sprintf("agent-%d-default", agentID), agentID).Scan(&id, &status)
+	if err != nil {
+		return 0, err
+	}
+	if status == CaseStatusKeyDestroyed {
+		return 0, ErrCaseKeyDestroyed
+	}
+	return id, nil
+}
@@ -0,0 +1,29 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pashagolub/pgxmock/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAssignDefaultCase_LegacyTaskUsesAgentDefaultCase(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO cases \(name, default_agent_id\)`).WithArgs("agent-3-default", 3).
+		WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(int64(11)))
+	mock.ExpectQuery(`UPDATE tasks SET case_id=COALESCE\(case_id, \$2\)`).WithArgs(int64(7), int64(11)).
+		WillReturnRows(pgxmock.NewRows([]string{"case_id"}).AddRow(int64(11)))
+	mock.ExpectCommit()
+	mock.ExpectRollback()
+
+	caseID, err := assignDefaultCase(context.Background(), mock, 3, 7)
+	require.NoError(t, err)
+	assert.Equal(t, int64(11), caseID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -8,86 +8,314 @@ import (
     "time"
 
     api "guardian-backend/pkg/grpc/api/guardian/pkg/grpc/api"
+    "guardian-backend/internal/envelope"
+    "guardian-backend/pkg/apperr"
+    "guardian-backend/pkg/metrics"
     "github.com/jackc/pgx/v5"
     "github.com/jackc/pgx/v5/pgxpool"
+    "go.opentelemetry.io/otel"
+    "go.opentelemetry.io/otel/attribute"
+    "go.opentelemetry.io/otel/trace"
 )
 
 // DB 封装 pgxpool.Pool，便于在本地定义方法
 type DB struct {
     Pool *pgxpool.Pool
+    // RequiredNoticeVersion 为创建任务前要求确认的告知版本；为空时任一已确认的告知均有效
+    RequiredNoticeVersion string
+    // Cipher 负责消息内容的按案件加解密；未配置时拒绝写入消息
+    Cipher *envelope.Cipher
 }
 
-// TimeoutStaleTasks 将状态为 'sent' 且在指定时间内未更新的任务标记为 'timeout'
+// TimeoutStaleTasks 将状态为 'sent' 且在指定时间内未更新的任务标记为 'timeout'，
+// 并在同一事务内为每个任务写入 task.timed_out 事件
 func (p *DB) TimeoutStaleTasks(ctx context.Context, timeoutDuration time.Duration) (int64, error) {
-	// SQL 语句的逻辑是：
-	// UPDATE tasks
-	// SET status = 'timeout', updated_at = NOW()
-	// WHERE status = 'sent' AND updated_at < NOW() - $1::interval;
-	// $1 的值应该是类似 "1 hour" 这样的字符串
 	timeoutStr := fmt.Sprintf("%f seconds", timeoutDuration.Seconds())
-    tag, err := p.Pool.Exec(ctx, "UPDATE tasks SET status = 'timeout', updated_at = NOW() WHERE status = 'sent' AND updated_at < NOW() - $1::interval", timeoutStr)
+	tx, err := p.Pool.Begin(ctx)
 	if err != nil {
 		return 0, err
 	}
-	return tag.RowsAffected(), nil
+	defer tx.Rollback(ctx)
+	rows, err := tx.Query(ctx, `
+		UPDATE tasks SET status = $2, updated_at = NOW()
+		WHERE status = 'sent' AND updated_at < NOW() - $1::interval
+		RETURNING id, agent_id, task_type
+	`, timeoutStr, TaskStatusTimeout)
+	if err != nil {
+		return 0, err
+	}
+	var events []map[string]any
+	for rows.Next() {
+		var (
+			taskID   int64
+			agentID  int
+			taskType string
+		)
+		if err := rows.Scan(&taskID, &agentID, &taskType); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		events = append(events, map[string]any{"task_id": taskID, "agent_id": agentID, "task_type": taskType, "status": TaskStatusTimeout})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	for _, e := range events {
+		if err := enqueueEvent(ctx, tx, EventTaskTimedOut, e); err != nil {
+			return 0, err
+		}
+		if err := notifyTaskStatus(ctx, tx, e["agent_id"].(int), e["task_id"].(int64), e["task_type"].(string), TaskStatusTimeout); err != nil {
+			return 0, err
+		}
+	}
+	return int64(len(events)), tx.Commit(ctx)
 }
 // ...existing code...
 
 // ErrAgentNotFound 用于 agent_id 不存在时返回
-var ErrAgentNotFound = errors.New("agent not found")
+var ErrAgentNotFound = apperr.New(apperr.KindNotFound, "", "agent not found")
 
-// GetAndDispatchPendingTaskForAgent 查询指定 agent 是否有待执行任务，有则返回类型并将其状态置为 sent
-func (p *DB) GetAndDispatchPendingTaskForAgent(ctx context.Context, agentID int) (string, error) {
+// GetAndDispatchPendingTaskForAgent 查询指定 agent 是否有待执行任务，有则返回任务 ID 与类型并将其状态置为 sent；无任务时返回 0
+func (p *DB) GetAndDispatchPendingTaskForAgent(ctx context.Context, agentID int) (int64, string, error) {
+	var taskID int64
 	var taskType string
     tx, err := p.Pool.Begin(ctx)
 	if err != nil {
-		return "", err
+		return 0, "", err
 	}
 	defer tx.Rollback(ctx)
-	err = tx.QueryRow(ctx, `SELECT id, task_type FROM tasks WHERE agent_id=$1 AND status='pending' ORDER BY created_at LIMIT 1`, agentID).Scan(new(int), &taskType)
+	err = tx.QueryRow(ctx, `SELECT id, task_type FROM tasks WHERE agent_id=$1 AND status='pending' ORDER BY created_at LIMIT 1 FOR UPDATE SKIP LOCKED`, agentID).Scan(&taskID, &taskType)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, "", nil // 没有待执行任务
+	}
 	if err != nil {
-		return "", nil // 没有待执行任务
+		return 0, "", err
 	}
-	_, err = tx.Exec(ctx, `UPDATE tasks SET status='sent', updated_at=NOW() WHERE agent_id=$1 AND status='pending' AND task_type=$2`, agentID, taskType)
+	_, err = tx.Exec(ctx, `UPDATE tasks SET status='sent', updated_at=NOW() WHERE id=$1`, taskID)
 	if err != nil {
-		return "", err
+		return 0, "", err
+	}
+	if err := notifyTaskStatus(ctx, tx, agentID, taskID, taskType, "sent"); err != nil {
+		return 0, "", err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, "", err
 	}
-	tx.Commit(ctx)
-	return taskType, nil
+	return taskID, taskType, nil
 }
-// CreateTaskForAgent 在数据库中为指定的 agent 创建一个新任务
-func (p *DB) CreateTaskForAgent(ctx context.Context, agentID int, taskType string) error {
-	// 先检查 agent 是否存在
-	var count int
-    err := p.Pool.QueryRow(ctx, `SELECT COUNT(*) FROM agents WHERE id=$1`, agentID).Scan(&count)
+// CreateTaskForAgent 在数据库中为指定的 agent 创建一个新任务，scope 为授权的采集范围，返回新任务 ID。
+// agent 关联人员没有有效的监测告知确认时返回 ErrAcknowledgementRequired。
+// scope.CaseID 为 0 时任务归入 agent 的默认案件。
+func (p *DB) CreateTaskForAgent(ctx context.Context, agentID int, taskType string, scope TaskScope) (int64, error) {
+    tx, err := p.Pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+	// 先检查 agent 是否存在且仍在役
+	var status string
+    err = tx.QueryRow(ctx, `SELECT status FROM agents WHERE id=$1`, agentID).Scan(&status)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, ErrAgentNotFound
+	}
 	if err != nil {
-		return err
+		return 0, err
+	}
+	if status == "retired" || status == "decommissioning" {
+		return 0, ErrAgentRetired
+	}
+	// 再检查告知确认
+	ok, err := hasValidAcknowledgement(ctx, tx, agentID, p.RequiredNoticeVersion)
+	if err != nil {
+		return 0, err
 	}
-	if count == 0 {
-		return ErrAgentNotFound
+	if !ok {
+		return 0, ErrAcknowledgementRequired
 	}
-    _, err = p.Pool.Exec(ctx, `
-		INSERT INTO tasks (agent_id, task_type, status, created_at, updated_at)
-		VALUES ($1, $2, 'pending', NOW(), NOW())
-	`, agentID, taskType)
-	return err
+	caseID, err := resolveTaskCase(ctx, tx, agentID, scope.CaseID)
+	if err != nil {
+		return 0, err
+	}
+	excluded := scope.ExcludedConversations
+	if excluded == nil {
+		excluded = []string{}
+	}
+	var taskID int64
+    err = tx.QueryRow(ctx, `
+		INSERT INTO tasks (agent_id, task_type, status, scope_start, scope_end, excluded_conversations, case_id, created_at, updated_at)
+		VALUES ($1, $2, 'pending', $3, $4, $5, $6, NOW(), NOW())
+		RETURNING id
+	`, agentID, taskType, scope.ScopeStart, scope.ScopeEnd, excluded, caseID).Scan(&taskID)
+	if err != nil {
+		return 0, err
+	}
+	if err := notifyTaskStatus(ctx, tx, agentID, taskID, taskType, "pending"); err != nil {
+		return 0, err
+	}
+	return taskID, tx.Commit(ctx)
 }
-// SaveMessages 批量写入 wechat_messages 表
-func (p *DB) SaveMessages(ctx context.Context, agentID int, messages []*api.ChatMessage) error {
+// SaveMessages 登记上传批次并在同一事务内批量写入 wechat_messages，返回批次 ID。
+// 消息内容以案件密钥加密后存入 content_enc；messages 为空时只登记批次（全部被过滤）。
+// 该 agent 已登记过相同 request_sha256 的批次时不写入任何数据，返回 ErrDuplicateBatch。
+func (p *DB) SaveMessages(ctx context.Context, batch IngestionBatch, messages []*api.ChatMessage) (int64, error) {
+	if len(messages) > 0 && p.Cipher == nil {
+		return 0, errors.New("message encryption is not configured")
+	}
+	encrypted, err := p.encryptMessages(ctx, batch.CaseID, messages)
+	if err != nil {
+		return 0, err
+	}
+	tx, err := p.Pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+	if err := claimBatchRequest(ctx, tx, batch.AgentID, batch.RequestSHA256); err != nil {
+		return 0, err
+	}
+	var batchID int64
+	err = tx.QueryRow(ctx, `
+		INSERT INTO ingestion_batches (agent_id, task_id, case_id, received_count, accepted_count, request_sha256, content_sha256, uploaded_by)
+		VALUES ($1, NULLIF($2, 0), NULLIF($3, 0), $4, $5, $6, $7, NULLIF($8, ''))
+		RETURNING id
+	`, batch.AgentID, batch.TaskID, batch.CaseID, batch.ReceivedCount, len(messages), batch.RequestSHA256, batch.ContentSHA256, batch.UploadedBy).Scan(&batchID)
+	if err != nil {
+		return 0, err
+	}
 	rows := make([][]interface{}, 0, len(messages))
-	for _, m := range messages {
+	for i, m := range messages {
+		var conversationID *string
+		if m.ConversationId != "" {
+			conversationID = &m.ConversationId
+		}
 		rows = append(rows, []interface{}{
-			agentID,
-			m.Content,
+			batch.AgentID,
+			batch.CaseID,
+			batch.TaskID,
+			batchID,
+			encrypted[i],
 			m.Timestamp.AsTime(),
+			conversationID,
 		})
 	}
-    _, err := p.Pool.CopyFrom(ctx,
-		pgx.Identifier{"wechat_messages"},
-		[]string{"agent_id", "content", "timestamp"},
-		pgx.CopyFromRows(rows),
-	)
-	return err
+	if len(rows) > 0 {
+		// COPY 按行序写入，id 顺序即批次内顺序，完整性校验据此重算
+		start := time.Now()
+		n, err := tx.CopyFrom(ctx,
+			pgx.Identifier{"wechat_messages"},
+			[]string{"agent_id", "case_id", "task_id", "batch_id", "content_enc", "timestamp", "conversation_id"},
+			pgx.CopyFromRows(rows),
+		)
+		if err != nil {
+			return 0, err
+		}
+		metrics.CopyFrom("wechat_messages", n, time.Since(start))
+	}
+	err = notifyLive(ctx, tx, LiveEvent{Type: LiveIngestionBatch, AgentID: batch.AgentID, TaskID: batch.TaskID, Data: map[string]any{
+		"batch_id": batchID,
+		"received": batch.ReceivedCount,
+		"accepted": len(messages),
+	}})
+	if err != nil {
+		return 0, err
+	}
+	return batchID, tx.Commit(ctx)
+}
+
+// encryptMessages 以案件密钥逐条加密消息内容
+func (p *DB) encryptMessages(ctx context.Context, caseID int64, messages []*api.ChatMessage) ([][]byte, error) {
+	ctx, span := otel.Tracer(tracerName).Start(ctx, "messages.encrypt", trace.WithAttributes(attribute.Int("message_count", len(messages))))
+	defer span.End()
+	encrypted := make([][]byte, len(messages))
+	for i, m := range messages {
+		enc, err := p.Cipher.Encrypt(ctx, caseID, []byte(m.Content))
+		if errors.Is(err, envelope.ErrKeyDestroyed) {
+			return nil, ErrCaseKeyDestroyed
+		}
+		if err != nil {
+			return nil, err
+		}
+		encrypted[i] = enc
+	}
+	return encrypted, nil
+}
+
+// EncryptLegacyMessages 把加密上线前写入的明文消息按 agent 默认案件加密，返回处理的条数。
+// 默认案件密钥已销毁的明文直接删除。
+func (p *DB) EncryptLegacyMessages(ctx context.Context, batchSize int) (int64, error) {
+	if p.Cipher == nil {
+		return 0, errors.New("message encryption is not configured")
+	}
+	var total int64
+	for {
+		n, err := p.encryptLegacyBatch(ctx, batchSize)
+		total += n
+		if err != nil || n == 0 {
+			return total, err
+		}
+	}
+}
+
+func (p *DB) encryptLegacyBatch(ctx context.Context, batchSize int) (int64, error) {
+	tx, err := p.Pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+	rows, err := tx.Query(ctx, `
+		SELECT id, agent_id, case_id, content FROM wechat_messages
+		WHERE content IS NOT NULL ORDER BY id LIMIT $1 FOR UPDATE SKIP LOCKED
+	`, batchSize)
+	if err != nil {
+		return 0, err
+	}
+	type legacy struct {
+		id      int64
+		agentID int
+		caseID  *int64
+		content string
+	}
+	var batch []legacy
+	for rows.Next() {
+		var l legacy
+		if err := rows.Scan(&l.id, &l.agentID, &l.caseID, &l.content); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		batch = append(batch, l)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	for _, l := range batch {
+		caseID := int64(0)
+		if l.caseID != nil {
+			caseID = *l.caseID
+		} else if caseID, err = defaultCaseForAgent(ctx, tx, l.agentID); errors.Is(err, ErrCaseKeyDestroyed) {
+			caseID = 0
+		} else if err != nil {
+			return 0, err
+		}
+		var enc []byte
+		if caseID != 0 {
+			enc, err = p.Cipher.Encrypt(ctx, caseID, []byte(l.content))
+			if err != nil && !errors.Is(err, envelope.ErrKeyDestroyed) {
+				return 0, err
+			}
+		}
+		if enc == nil {
+			if _, err := tx.Exec(ctx, `DELETE FROM wechat_messages WHERE id=$1`, l.id); err != nil {
+				return 0, err
+			}
+			continue
+		}
+		if _, err := tx.Exec(ctx, `UPDATE wechat_messages SET content=NULL, content_enc=$2, case_id=$3 WHERE id=$1`, l.id, enc, caseID); err != nil {
+			return 0, err
+		}
+	}
+	return int64(len(batch)), tx.Commit(ctx)
 }
 // ...existing code...
 
@@ -100,12 +328,17 @@ func NewConnection(ctx context.Context) (*DB, error) {
     return NewConnectionWithDSN(ctx, dsn)
 }
 
-// NewConnectionWithDSN 通过参数传递 DSN，便于 config 驱动
+// NewConnectionWithDSN 通过参数传递 DSN，便于 config 驱动；连接上的查询、批量与 COPY 均生成 trace span
 func NewConnectionWithDSN(ctx context.Context, dsn string) (*DB, error) {
 	if dsn == "" {
 		return nil, fmt.Errorf("DSN is empty")
 	}
-    pool, err := pgxpool.New(ctx, dsn)
+    cfg, err := pgxpool.ParseConfig(dsn)
+    if err != nil {
+        return nil, err
+    }
+    cfg.ConnConfig.Tracer = queryTracer{}
+    pool, err := pgxpool.NewWithConfig(ctx, cfg)
 	if err != nil {
 		return nil, err
 	}
@@ -116,11 +349,12 @@ func NewConnectionWithDSN(ctx context.Context, dsn string) (*DB, error) {
 type AgentInfo struct {
     ID       int
     Hostname string
+    Status   string
 }
 
 // ListAgents 查询所有 agents 的基础信息
 func (p *DB) ListAgents(ctx context.Context) ([]AgentInfo, error) {
-    rows, err := p.Pool.Query(ctx, `SELECT id, hostname FROM agents ORDER BY id ASC`)
+    rows, err := p.Pool.Query(ctx, `SELECT id, hostname, status FROM agents ORDER BY id ASC`)
     if err != nil {
         return nil, err
     }
@@ -128,7 +362,7 @@ func (p *DB) ListAgents(ctx context.Context) ([]AgentInfo, error) {
     var result []AgentInfo
     for rows.Next() {
         var it AgentInfo
-        if err := rows.Scan(&it.ID, &it.Hostname); err != nil {
+        if err := rows.Scan(&it.ID, &it.Hostname, &it.Status); err != nil {
             return nil, err
         }
         result = append(result, it)
@@ -140,22 +374,71 @@ func (p *DB) ListAgents(ctx context.Context) ([]AgentInfo, error) {
 type WechatMessageRecord struct {
     Content   string
     Timestamp time.Time
+    // TaskID 为采集该消息的任务；早于任务关联上线的消息为 nil
+    TaskID *int64
+}
+
+// ListMessagesByAgent 按时间倒序分页查询指定 agent 的消息并解密；案件密钥已销毁的消息不可恢复，直接略过
+func (p *DB) ListMessagesByAgent(ctx context.Context, agentID int, limit, offset int) ([]WechatMessageRecord, error) {
+    return p.listMessages(ctx, "m.agent_id=$1", agentID, limit, offset)
 }
 
-// ListMessagesByAgent 查询指定 agent 的消息
-func (p *DB) ListMessagesByAgent(ctx context.Context, agentID int) ([]WechatMessageRecord, error) {
-    rows, err := p.Pool.Query(ctx, `SELECT content, timestamp FROM wechat_messages WHERE agent_id=$1 ORDER BY timestamp DESC LIMIT 500`, agentID)
+// listMessages 在数据库中完成排序与 LIMIT/OFFSET，偏移量不受单次返回条数限制。
+// 密钥已销毁的密文在 SQL 中即排除，使返回条数少于 limit 可作为已到末尾的依据。
+func (p *DB) listMessages(ctx context.Context, where string, arg any, limit, offset int) ([]WechatMessageRecord, error) {
+    rows, err := p.Pool.Query(ctx, `
+        SELECT m.case_id, m.task_id, m.content, m.content_enc, m.timestamp FROM wechat_messages m
+        WHERE `+where+`
+          AND (m.content_enc IS NULL OR NOT EXISTS (
+              SELECT 1 FROM case_keys k WHERE k.case_id=m.case_id AND k.destroyed_at IS NOT NULL))
+        ORDER BY m.timestamp DESC, m.id DESC LIMIT $2 OFFSET $3
+    `, arg, limit, offset)
     if err != nil {
         return nil, err
     }
     defer rows.Close()
     var result []WechatMessageRecord
     for rows.Next() {
-        var it WechatMessageRecord
-        if err := rows.Scan(&it.Content, &it.Timestamp); err != nil {
+        var (
+            it      WechatMessageRecord
+            caseID  *int64
+            content *string
+            enc     []byte
+        )
+        if err := rows.Scan(&caseID, &it.TaskID, &content, &enc, &it.Timestamp); err != nil {
             return nil, err
         }
-        result = append(result, it)
+        // 查询与销毁并发时仍可能遇到已销毁的密钥
+        ok, err := p.decryptContent(ctx, caseID, content, enc, &it.Content)
+        if err != nil {
+            return nil, err
+        }
+        if ok {
+            result = append(result, it)
+        }
     }
     return result, rows.Err()
 }
+
+// decryptContent 还原消息内容；返回 false 表示案件密钥已销毁、内容不可恢复
+func (p *DB) decryptContent(ctx context.Context, caseID *int64, content *string, enc []byte, out *string) (bool, error) {
+    if enc == nil {
+        // 尚未完成加密迁移的历史明文
+        if content != nil {
+            *out = *content
+        }
+        return true, nil
+    }
+    if p.Cipher == nil || caseID == nil {
+        return false, errors.New("message encryption is not configured")
+    }
+    pt, err := p.Cipher.Decrypt(ctx, *caseID, enc)
+    if errors.Is(err, envelope.ErrKeyDestroyed) {
+        return false, nil
+    }
+    if err != nil {
+        return false, err
+    }
+    *out = string(pt)
+    return true, nil
+}
@@ -0,0 +1,363 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"guardian-backend/pkg/apperr"
+)
+
+// 退役 agent 的数据处置方式
+const (
+	DispositionRetain = "retain" // 按保留期保存，到期后清除
+	DispositionPurge  = "purge"  // 确认卸载后立即清除
+)
+
+// 任务类型与状态
+const (
+	TaskTypeDumpWechatData = "DUMP_WECHAT_DATA"
+	TaskTypeUninstallAgent = "UNINSTALL_AGENT"
+
+	TaskStatusCompleted = "completed"
+	TaskStatusFailed    = "failed"
+	TaskStatusTimeout   = "timeout"
+	// TaskStatusCancelled 表示任务数据被清除时任务尚未结束，随之取消
+	TaskStatusCancelled = "cancelled"
+)
+
+var (
+	// ErrAgentRetired 表示 agent 已退役，不再接受任何任务或上报
+	ErrAgentRetired = apperr.New(apperr.KindFailedPrecondition, "AGENT_RETIRED", "agent is retired")
+	// ErrDecommissionInProgress 表示 agent 已有未完成的卸载任务
+	ErrDecommissionInProgress = apperr.New(apperr.KindFailedPrecondition, "DECOMMISSION_IN_PROGRESS", "agent decommission already in progress")
+	// ErrTaskNotFound 表示任务不存在、不属于该 agent 或不在执行中
+	ErrTaskNotFound = apperr.New(apperr.KindNotFound, "", "task not found")
+	// ErrCredentialRevoked 表示 agent 出示的客户端证书已被吊销
+	ErrCredentialRevoked = apperr.New(apperr.KindPermissionDenied, "CREDENTIAL_REVOKED", "agent credential revoked")
+	// ErrCredentialRequired 表示请求未出示 agent 客户端证书
+	ErrCredentialRequired = apperr.New(apperr.KindPermissionDenied, "CREDENTIAL_REQUIRED", "agent client certificate required")
+	// ErrCredentialMismatch 表示客户端证书已绑定到其他 agent 或证书身份与 agent_id 不符，不能冒用其 agent_id
+	ErrCredentialMismatch = apperr.New(apperr.KindPermissionDenied, "CREDENTIAL_MISMATCH", "agent credential belongs to another agent")
+	// ErrCredentialAlreadyBound 表示 agent 已有未吊销的证书，新证书须在旧证书吊销后才能绑定
+	ErrCredentialAlreadyBound = apperr.New(apperr.KindPermissionDenied, "CREDENTIAL_ALREADY_BOUND", "agent already has an active credential")
+)
+
+// TaskCompletion 描述一次任务结果上报带来的状态变化
+type TaskCompletion struct {
+	TaskType       string
+	Status         string
+	Retired        bool
+	Disposition    string
+	PurgedMessages int64
+}
+
+// CreateDecommissionTask 为 agent 创建卸载任务并记录管理员选择的数据处置方式，agent 状态置为 decommissioning
+func (p *DB) CreateDecommissionTask(ctx context.Context, agentID int, disposition string) (int64, error) {
+	if disposition != DispositionRetain && disposition != DispositionPurge {
+		return 0, fmt.Errorf("invalid data disposition %q", disposition)
+	}
+	tx, err := p.Pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+	var status string
+	err = tx.QueryRow(ctx, `SELECT status FROM agents WHERE id=$1 FOR UPDATE`, agentID).Scan(&status)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, ErrAgentNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	if status == "retired" {
+		return 0, ErrAgentRetired
+	}
+	var inProgress bool
+	err = tx.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM tasks WHERE agent_id=$1 AND task_type=$2 AND status IN ('pending', 'sent'))
+	`, agentID, TaskTypeUninstallAgent).Scan(&inProgress)
+	if err != nil {
+		return 0, err
+	}
+	if inProgress {
+		return 0, ErrDecommissionInProgress
+	}
+	var taskID int64
+	err = tx.QueryRow(ctx, `
+		INSERT INTO tasks (agent_id, task_type, status, params, created_at, updated_at)
+		VALUES ($1, $2, 'pending', jsonb_build_object('data_disposition', $3::text), NOW(), NOW())
+		RETURNING id
+	`, agentID, TaskTypeUninstallAgent, disposition).Scan(&taskID)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(ctx, `UPDATE agents SET status='decommissioning' WHERE id=$1`, agentID); err != nil {
+		return 0, err
+	}
+	if err := notifyTaskStatus(ctx, tx, agentID, taskID, TaskTypeUninstallAgent, "pending"); err != nil {
+		return 0, err
+	}
+	if err := notifyAgentStatus(ctx, tx, agentID, "decommissioning"); err != nil {
+		return 0, err
+	}
+	return taskID, tx.Commit(ctx)
+}
+
+// CompleteTask 记录 agent 上报的任务结果。卸载任务成功完成时在同一事务内将 agent 置为退役、
+// 吊销其全部凭据，并按处置方式立即清除数据或设置保留期限。
+func (p *DB) CompleteTask(ctx context.Context, agentID int, taskID int64, status, message string, retention time.Duration) (TaskCompletion, error) {
+	return completeTask(ctx, p.Pool, agentID, taskID, status, message, retention)
+}
+
+// txBeginner 为 pgxpool.Pool 中开启事务的方法，测试中由 pgxmock 提供
+type txBeginner interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+func completeTask(ctx context.Context, db txBeginner, agentID int, taskID int64, status, message string, retention time.Duration) (TaskCompletion, error) {
+	var c TaskCompletion
+	if status != TaskStatusCompleted && status != TaskStatusFailed {
+		return c, fmt.Errorf("invalid task status %q", status)
+	}
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return c, err
+	}
+	defer tx.Rollback(ctx)
+	err = tx.QueryRow(ctx, `
+		SELECT task_type, COALESCE(params->>'data_disposition', '')
+		FROM tasks
+		WHERE id=$1 AND agent_id=$2 AND status='sent'
+		FOR UPDATE
+	`, taskID, agentID).Scan(&c.TaskType, &c.Disposition)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return c, ErrTaskNotFound
+	}
+	if err != nil {
+		return c, err
+	}
+	c.Status = status
+	_, err = tx.Exec(ctx, `UPDATE tasks SET status=$2, result_message=NULLIF($3, ''), updated_at=NOW() WHERE id=$1`, taskID, status, message)
+	if err != nil {
+		return c, err
+	}
+	event := EventTaskCompleted
+	if status == TaskStatusFailed {
+		event = EventTaskFailed
+	}
+	if err := enqueueEvent(ctx, tx, event, map[string]any{
+		"task_id":        taskID,
+		"agent_id":       agentID,
+		"task_type":      c.TaskType,
+		"status":         status,
+		"result_message": message,
+	}); err != nil {
+		return c, err
+	}
+	if err := notifyTaskStatus(ctx, tx, agentID, taskID, c.TaskType, status); err != nil {
+		return c, err
+	}
+	if c.TaskType == TaskTypeUninstallAgent {
+		if status == TaskStatusCompleted {
+			if err := retireAgent(ctx, tx, agentID, &c, retention); err != nil {
+				return c, err
+			}
+		} else {
+			// 卸载失败：agent 仍在运行，恢复为在线以便重新下发
+			tag, err := tx.Exec(ctx, `UPDATE agents SET status='online' WHERE id=$1 AND status='decommissioning'`, agentID)
+			if err != nil {
+				return c, err
+			}
+			if tag.RowsAffected() > 0 {
+				if err := notifyAgentStatus(ctx, tx, agentID, "online"); err != nil {
+					return c, err
+				}
+			}
+		}
+	}
+	return c, tx.Commit(ctx)
+}
+
+// retireAgent 将 agent 置为退役、吊销凭据并处置其数据
+func retireAgent(ctx context.Context, tx pgx.Tx, agentID int, c *TaskCompletion, retention time.Duration) error {
+	var retainUntil *time.Time
+	if c.Disposition == DispositionRetain {
+		t := time.Now().Add(retention)
+		retainUntil = &t
+	}
+	_, err := tx.Exec(ctx, `
+		UPDATE agents SET status='retired', retired_at=NOW(), data_disposition=$2, retain_until=$3
+		WHERE id=$1
+	`, agentID, c.Disposition, retainUntil)
+	if err != nil {
+		return err
+	}
+	if err := notifyAgentStatus(ctx, tx, agentID, "retired"); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `UPDATE agent_credentials SET revoked_at=NOW() WHERE agent_id=$1 AND revoked_at IS NULL`, agentID); err != nil {
+		return err
+	}
+	if c.Disposition == DispositionPurge {
+		tag, err := tx.Exec(ctx, `DELETE FROM wechat_messages WHERE agent_id=$1`, agentID)
+		if err != nil {
+			return err
+		}
+		c.PurgedMessages = tag.RowsAffected()
+		if err := markBatchesPurged(ctx, tx, agentID); err != nil {
+			return err
+		}
+	}
+	c.Retired = true
+	return nil
+}
+
+// markBatchesPurged 标记 agent 的入库批次已清除，清除后的批次不再参与完整性校验
+func markBatchesPurged(ctx context.Context, tx pgx.Tx, agentID int) error {
+	_, err := tx.Exec(ctx, `UPDATE ingestion_batches SET purged_at=NOW() WHERE agent_id=$1 AND purged_at IS NULL`, agentID)
+	return err
+}
+
+// PurgeExpiredRetainedData 清除保留期已到的退役 agent 的消息，返回删除条数
+func (p *DB) PurgeExpiredRetainedData(ctx context.Context) (int64, error) {
+	tx, err := p.Pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+	tag, err := tx.Exec(ctx, `
+		DELETE FROM wechat_messages
+		WHERE agent_id IN (SELECT id FROM agents WHERE status='retired' AND retain_until < NOW())
+	`)
+	if err != nil {
+		return 0, err
+	}
+	_, err = tx.Exec(ctx, `
+		UPDATE ingestion_batches SET purged_at=NOW()
+		WHERE purged_at IS NULL
+		  AND agent_id IN (SELECT id FROM agents WHERE status='retired' AND retain_until < NOW())
+	`)
+	if err != nil {
+		return 0, err
+	}
+	_, err = tx.Exec(ctx, `
+		UPDATE agents SET data_disposition='expired', retain_until=NULL
+		WHERE status='retired' AND retain_until < NOW()
+	`)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), tx.Commit(ctx)
+}
+
+// AuthorizeAgentCredential 校验 agent 未退役、出示的证书指纹未被吊销且属于该 agent。
+// 首次出现的指纹仅在 agent 尚无未吊销证书时绑定到该 agent；fingerprint 为空（请求未经 mTLS）时拒绝。
+// 证书身份（CN）与 agent_id 是否相符由调用方在此之前校验。
+func (p *DB) AuthorizeAgentCredential(ctx context.Context, agentID int, fingerprint string) error {
+	if fingerprint == "" {
+		return ErrCredentialRequired
+	}
+	if err := p.CheckAgentActive(ctx, agentID); err != nil {
+		return err
+	}
+	cred, err := p.lookupCredential(ctx, fingerprint)
+	if err != nil {
+		return err
+	}
+	if cred == nil {
+		bound, err := bindCredential(ctx, p.Pool, agentID, fingerprint)
+		if err != nil || bound {
+			return err
+		}
+		// 并发请求已先行绑定该指纹，按已有绑定校验
+		if cred, err = p.lookupCredential(ctx, fingerprint); err != nil {
+			return err
+		}
+	}
+	return checkCredential(agentID, cred)
+}
+
+// bindCredential 把指纹绑定到 agent，返回是否新绑定。锁定 agents 行使同一 agent 的绑定串行进行；
+// agent 已有未吊销的证书时返回 ErrCredentialAlreadyBound，防止另一张 CA 签发的证书冒用该 agent
+func bindCredential(ctx context.Context, db txBeginner, agentID int, fingerprint string) (bool, error) {
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+	if _, err := tx.Exec(ctx, `SELECT 1 FROM agents WHERE id=$1 FOR UPDATE`, agentID); err != nil {
+		return false, err
+	}
+	var active bool
+	err = tx.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM agent_credentials WHERE agent_id=$1 AND revoked_at IS NULL)
+	`, agentID).Scan(&active)
+	if err != nil {
+		return false, err
+	}
+	if active {
+		return false, ErrCredentialAlreadyBound
+	}
+	tag, err := tx.Exec(ctx, `
+		INSERT INTO agent_credentials (agent_id, cert_fingerprint) VALUES ($1, $2)
+		ON CONFLICT (cert_fingerprint) DO NOTHING
+	`, agentID, fingerprint)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, tx.Commit(ctx)
+}
+
+// CheckAgentActive 确认 agent 存在且未退役
+func (p *DB) CheckAgentActive(ctx context.Context, agentID int) error {
+	var status string
+	err := p.Pool.QueryRow(ctx, `SELECT status FROM agents WHERE id=$1`, agentID).Scan(&status)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrAgentNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if status == "retired" {
+		return ErrAgentRetired
+	}
+	return nil
+}
+
+// boundCredential 为证书指纹已有的绑定
+type boundCredential struct {
+	AgentID int
+	Revoked bool
+}
+
+// lookupCredential 返回指纹已有的绑定，未绑定时返回 nil
+func (p *DB) lookupCredential(ctx context.Context, fingerprint string) (*boundCredential, error) {
+	var c boundCredential
+	err := p.Pool.QueryRow(ctx, `
+		SELECT agent_id, revoked_at IS NOT NULL FROM agent_credentials WHERE cert_fingerprint=$1
+	`, fingerprint).Scan(&c.AgentID, &c.Revoked)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// checkCredential 判断已绑定的证书能否以 agentID 的身份访问：已吊销或绑定到其他 agent 时拒绝
+func checkCredential(agentID int, c *boundCredential) error {
+	if c == nil {
+		return nil
+	}
+	if c.Revoked {
+		return ErrCredentialRevoked
+	}
+	if c.AgentID != agentID {
+		return ErrCredentialMismatch
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pashagolub/pgxmock/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckCredential(t *testing.T) {
+	// 未绑定的指纹由调用方绑定到请求的 agent
+	assert.NoError(t, checkCredential(1, nil))
+	assert.NoError(t, checkCredential(1, &boundCredential{AgentID: 1}))
+	assert.ErrorIs(t, checkCredential(1, &boundCredential{AgentID: 1, Revoked: true}), ErrCredentialRevoked)
+}
+
+func TestCheckCredential_RejectsCertOfAnotherAgent(t *testing.T) {
+	// agent 1 的证书不能以 agent 2 的身份上传或领取任务
+	assert.ErrorIs(t, checkCredential(2, &boundCredential{AgentID: 1}), ErrCredentialMismatch)
+	assert.ErrorIs(t, checkCredential(2, &boundCredential{AgentID: 1, Revoked: true}), ErrCredentialRevoked)
+}
+
+func TestBindCredential(t *testing.T) {
+	const fp = "9c1e4b7a2d5f8e0b3a6c9d2e5f8a1b4c7d0e3f6a9b2c5d8e1f4a7b0c3d6e9f2a"
+	for _, tc := range []struct {
+		name      string
+		active    bool
+		wantBound bool
+		wantErr   error
+	}{
+		{name: "first certificate is bound", wantBound: true},
+		// 另一张 CA 签发的证书不能顶替已有证书绑定到该 agent
+		{name: "agent with an active credential", active: true, wantErr: ErrCredentialAlreadyBound},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mock, err := pgxmock.NewPool()
+			require.NoError(t, err)
+			defer mock.Close()
+
+			mock.ExpectBegin()
+			mock.ExpectExec(`SELECT 1 FROM agents WHERE id=\$1 FOR UPDATE`).WithArgs(3).
+				WillReturnResult(pgxmock.NewResult("SELECT", 1))
+			mock.ExpectQuery(`SELECT EXISTS \(SELECT 1 FROM agent_credentials WHERE agent_id=\$1 AND revoked_at IS NULL\)`).WithArgs(3).
+				WillReturnRows(pgxmock.NewRows([]string{"exists"}).AddRow(tc.active))
+			if !tc.active {
+				mock.ExpectExec(`INSERT INTO agent_credentials`).WithArgs(3, fp).
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+				mock.ExpectCommit()
+			}
+			mock.ExpectRollback()
+
+			bound, err := bindCredential(context.Background(), mock, 3, fp)
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, err, tc.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tc.wantBound, bound)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestCompleteTask_ConfirmedUninstallRetiresAgent(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT task_type, COALESCE\(params->>'data_disposition', ''\)`).
+		WithArgs(int64(7), 3).
+		WillReturnRows(pgxmock.NewRows([]string{"task_type", "data_disposition"}).AddRow(TaskTypeUninstallAgent, DispositionPurge))
+	mock.ExpectExec(`UPDATE tasks SET status=\$2`).
+		WithArgs(int64(7), TaskStatusCompleted, "").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectExec(`INSERT INTO event_outbox`).WithArgs(EventTaskCompleted, pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectExec(`SELECT pg_notify`).WithArgs(LiveChannel, pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("SELECT", 1))
+	mock.ExpectExec(`UPDATE agents SET status='retired'`).WithArgs(3, DispositionPurge, (*time.Time)(nil)).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectExec(`SELECT pg_notify`).WithArgs(LiveChannel, `{"type":"agent.status","agent_id":3,"data":{"status":"retired"}}`).
+		WillReturnResult(pgxmock.NewResult("SELECT", 1))
+	mock.ExpectExec(`UPDATE agent_credentials SET revoked_at=NOW\(\) WHERE agent_id=\$1`).WithArgs(3).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 2))
+	mock.ExpectExec(`DELETE FROM wechat_messages WHERE agent_id=\$1`).WithArgs(3).
+		WillReturnResult(pgxmock.NewResult("DELETE", 40))
+	mock.ExpectExec(`UPDATE ingestion_batches SET purged_at=NOW\(\)`).WithArgs(3).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 2))
+	mock.ExpectCommit()
+	mock.ExpectRollback()
+
+	c, err := completeTask(context.Background(), mock, 3, 7, TaskStatusCompleted, "", time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, TaskCompletion{
+		TaskType:       TaskTypeUninstallAgent,
+		Status:         TaskStatusCompleted,
+		Retired:        true,
+		Disposition:    DispositionPurge,
+		PurgedMessages: 40,
+	}, c)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
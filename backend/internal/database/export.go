@@ -0,0 +1,167 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// CaseMessage 是案件下的一条已解密消息
+type CaseMessage struct {
+	ID             int64
+	AgentID        int
+	TaskID         *int64
+	ConversationID string
+	Timestamp      time.Time
+	Content        string
+}
+
+// CaseTask 是案件下的采集任务
+type CaseTask struct {
+	ID         int64
+	AgentID    int
+	TaskType   string
+	Status     string
+	ScopeStart *time.Time
+	ScopeEnd   *time.Time
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// AuditRecord 是已写入的审计日志
+type AuditRecord struct {
+	ID int64
+	AuditEntry
+	CreatedAt time.Time
+}
+
+// EvidenceExport 是一次证据导出的登记
+type EvidenceExport struct {
+	BundleID       string
+	CaseID         int64
+	ExportedBy     string
+	Reason         string
+	Redacted       bool
+	MessageCount   int
+	ManifestSHA256 string
+	SigningKeyID   string
+}
+
+// GetCase 查询单个案件
+func (p *DB) GetCase(ctx context.Context, caseID int64) (Case, error) {
+	var c Case
+	err := p.Pool.QueryRow(ctx, `
+		SELECT id, name, default_agent_id, status, created_at, key_destroyed_at FROM cases WHERE id=$1
+	`, caseID).Scan(&c.ID, &c.Name, &c.DefaultAgentID, &c.Status, &c.CreatedAt, &c.KeyDestroyedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return c, ErrCaseNotFound
+	}
+	return c, err
+}
+
+// ListCaseMessages 按时间顺序返回案件的全部消息（已解密）；案件密钥已销毁时返回 ErrCaseKeyDestroyed。
+// 未记录案件的历史消息（迁移 017 之前写入）归入其 agent 的默认案件
+func (p *DB) ListCaseMessages(ctx context.Context, caseID int64) ([]CaseMessage, error) {
+	rows, err := p.Pool.Query(ctx, `
+		SELECT m.id, m.agent_id, m.task_id, COALESCE(m.conversation_id, ''), m.timestamp, m.content, m.content_enc
+		FROM wechat_messages m JOIN cases c ON c.id=$1
+		WHERE m.case_id=c.id OR (m.case_id IS NULL AND m.agent_id=c.default_agent_id)
+		ORDER BY m.timestamp, m.id
+	`, caseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []CaseMessage
+	for rows.Next() {
+		var (
+			m       CaseMessage
+			content *string
+			enc     []byte
+		)
+		if err := rows.Scan(&m.ID, &m.AgentID, &m.TaskID, &m.ConversationID, &m.Timestamp, &content, &enc); err != nil {
+			return nil, err
+		}
+		ok, err := p.decryptContent(ctx, &caseID, content, enc, &m.Content)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, ErrCaseKeyDestroyed
+		}
+		result = append(result, m)
+	}
+	return result, rows.Err()
+}
+
+// ListCaseTasks 返回案件下的全部任务
+func (p *DB) ListCaseTasks(ctx context.Context, caseID int64) ([]CaseTask, error) {
+	rows, err := p.Pool.Query(ctx, `
+		SELECT id, agent_id, task_type, status, scope_start, scope_end, created_at, updated_at
+		FROM tasks WHERE case_id=$1 ORDER BY id
+	`, caseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []CaseTask
+	for rows.Next() {
+		var t CaseTask
+		if err := rows.Scan(&t.ID, &t.AgentID, &t.TaskType, &t.Status, &t.ScopeStart, &t.ScopeEnd, &t.CreatedAt, &t.UpdatedAt); err != nil {
+			return nil, err
+		}
+		result = append(result, t)
+	}
+	return result, rows.Err()
+}
+
+// ListCaseAuditTrail 按时间顺序返回与案件相关的审计日志：
+// 以案件、其任务或其涉及的 agent 为对象的全部操作
+func (p *DB) ListCaseAuditTrail(ctx context.Context, caseID int64) ([]AuditRecord, error) {
+	rows, err := p.Pool.Query(ctx, `
+		WITH case_agents AS (
+			SELECT agent_id FROM tasks WHERE case_id=$1
+			UNION SELECT default_agent_id FROM cases WHERE id=$1 AND default_agent_id IS NOT NULL
+		)
+		SELECT id, actor, action, COALESCE(target_type, ''), COALESCE(target_id, ''), outcome, detail,
+		       COALESCE(ip_address, ''), created_at
+		FROM audit_logs
+		WHERE (target_type='case' AND target_id=$1::text)
+		   OR (target_type='task' AND target_id IN (SELECT id::text FROM tasks WHERE case_id=$1))
+		   OR (target_type='agent' AND target_id IN (SELECT agent_id::text FROM case_agents))
+		ORDER BY created_at, id
+	`, caseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []AuditRecord
+	for rows.Next() {
+		var (
+			a      AuditRecord
+			detail []byte
+		)
+		if err := rows.Scan(&a.ID, &a.Actor, &a.Action, &a.TargetType, &a.TargetID, &a.Outcome, &detail, &a.IPAddress, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		if len(detail) > 0 {
+			if err := json.Unmarshal(detail, &a.Detail); err != nil {
+				return nil, err
+			}
+		}
+		result = append(result, a)
+	}
+	return result, rows.Err()
+}
+
+// RecordEvidenceExport 登记一次证据导出
+func (p *DB) RecordEvidenceExport(ctx context.Context, e EvidenceExport) error {
+	_, err := p.Pool.Exec(ctx, `
+		INSERT INTO evidence_exports (bundle_id, case_id, exported_by, reason, redacted, message_count, manifest_sha256, signing_key_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`, e.BundleID, e.CaseID, e.ExportedBy, e.Reason, e.Redacted, e.MessageCount, e.ManifestSHA256, e.SigningKeyID)
+	return err
+}
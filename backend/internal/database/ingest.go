@@ -0,0 +1,241 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"guardian-backend/internal/envelope"
+	"guardian-backend/internal/integrity"
+	"guardian-backend/pkg/apperr"
+)
+
+// TaskScope 描述某个已下发任务授权的采集范围
+type TaskScope struct {
+	TaskID int64
+	// CaseID 为任务所属案件，决定消息使用的加密密钥；创建任务时为 0 表示使用 agent 默认案件
+	CaseID                int64
+	ScopeStart            *time.Time
+	ScopeEnd              *time.Time
+	ExcludedConversations []string
+}
+
+// GetActiveTaskScope 返回 agent 当前正在执行（已下发）任务的授权范围；无执行中任务时返回 nil
+func (p *DB) GetActiveTaskScope(ctx context.Context, agentID int) (*TaskScope, error) {
+	var s TaskScope
+	var caseID *int64
+	err := p.Pool.QueryRow(ctx, `
+		SELECT id, case_id, scope_start, scope_end, excluded_conversations
+		FROM tasks
+		WHERE agent_id=$1 AND status='sent'
+		ORDER BY updated_at DESC
+		LIMIT 1
+	`, agentID).Scan(&s.TaskID, &caseID, &s.ScopeStart, &s.ScopeEnd, &s.ExcludedConversations)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if caseID != nil {
+		s.CaseID = *caseID
+	} else if s.CaseID, err = assignDefaultCase(ctx, p.Pool, agentID, s.TaskID); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// RecordIngestionDiscards 按原因记录被丢弃消息的聚合计数；taskID 为 0 表示无关联任务
+func (p *DB) RecordIngestionDiscards(ctx context.Context, agentID int, taskID int64, counts map[string]int) error {
+	if len(counts) == 0 {
+		return nil
+	}
+	var tid *int64
+	if taskID != 0 {
+		tid = &taskID
+	}
+	batch := &pgx.Batch{}
+	for reason, n := range counts {
+		if n <= 0 {
+			continue
+		}
+		batch.Queue(`INSERT INTO ingestion_discards (agent_id, task_id, reason, discarded_count) VALUES ($1, $2, $3, $4)`, agentID, tid, reason, n)
+	}
+	if batch.Len() == 0 {
+		return nil
+	}
+	return p.Pool.SendBatch(ctx, batch).Close()
+}
+
+// IngestionBatch 是一次上传的入库回执；TaskID、CaseID 为 0 表示无关联
+type IngestionBatch struct {
+	AgentID       int
+	TaskID        int64
+	CaseID        int64
+	ReceivedCount int
+	RequestSHA256 string
+	ContentSHA256 string
+	// UploadedBy 为经 REST 上传的控制台用户 ID；agent 经 mTLS 上传时为空
+	UploadedBy string
+}
+
+// ErrDuplicateBatch 表示该 agent 已登记过相同 request_sha256 的批次，本次上传未写入任何数据
+var ErrDuplicateBatch = apperr.New(apperr.KindAlreadyExists, "DUPLICATE_BATCH", "batch already ingested")
+
+// BatchReceipt 是已登记批次的回执，用于对重放的上传返回原结果
+type BatchReceipt struct {
+	BatchID       int64
+	ReceivedCount int
+	AcceptedCount int
+}
+
+// FindBatchByRequest 按请求哈希查找该 agent 已登记的批次；未找到时返回 nil
+func (p *DB) FindBatchByRequest(ctx context.Context, agentID int, requestSHA256 string) (*BatchReceipt, error) {
+	var r BatchReceipt
+	err := p.Pool.QueryRow(ctx, `
+		SELECT id, received_count, accepted_count
+		FROM ingestion_batches
+		WHERE agent_id=$1 AND request_sha256=$2
+		ORDER BY id
+		LIMIT 1
+	`, agentID, requestSHA256).Scan(&r.BatchID, &r.ReceivedCount, &r.AcceptedCount)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// claimBatchRequest 在事务内锁定 (agentID, requestSHA256)，同一请求的并发重试在此串行化，
+// 后到者看到已提交的批次并得到 ErrDuplicateBatch
+func claimBatchRequest(ctx context.Context, tx pgx.Tx, agentID int, requestSHA256 string) error {
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1, hashtext($2))`, agentID, requestSHA256); err != nil {
+		return err
+	}
+	var exists bool
+	err := tx.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM ingestion_batches WHERE agent_id=$1 AND request_sha256=$2)
+	`, agentID, requestSHA256).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return ErrDuplicateBatch
+	}
+	return nil
+}
+
+// 完整性校验不一致的原因
+const (
+	IntegrityCountMismatch   = "count_mismatch"
+	IntegrityContentMismatch = "content_hash_mismatch"
+)
+
+// IntegrityMismatch 是一个与入库哈希不一致的批次
+type IntegrityMismatch struct {
+	BatchID    int64
+	AgentID    int
+	ReceivedAt time.Time
+	Reason     string
+}
+
+// IntegrityReport 是一次完整性校验的结果
+type IntegrityReport struct {
+	Checked int
+	// Unverifiable 为案件密钥已销毁、无法解密重算的批次数
+	Unverifiable int
+	Mismatches   []IntegrityMismatch
+}
+
+// CheckIngestionIntegrity 从库中解密重算每个批次的内容哈希并与入库时记录的值比对；
+// agentID 为 0 表示校验全部 agent。已按处置或保留期清除的批次不参与校验。
+func (p *DB) CheckIngestionIntegrity(ctx context.Context, agentID int) (IntegrityReport, error) {
+	var report IntegrityReport
+	rows, err := p.Pool.Query(ctx, `
+		SELECT b.id, b.agent_id, b.received_at, b.accepted_count, b.content_sha256,
+		       m.id, m.case_id, COALESCE(m.conversation_id, ''), m.timestamp, m.content, m.content_enc
+		FROM ingestion_batches b
+		LEFT JOIN wechat_messages m ON m.batch_id = b.id
+		WHERE b.purged_at IS NULL AND ($1 = 0 OR b.agent_id = $1)
+		ORDER BY b.id, m.id
+	`, agentID)
+	if err != nil {
+		return report, err
+	}
+	defer rows.Close()
+
+	var (
+		cur          IntegrityMismatch
+		expectCount  int
+		expectHash   string
+		digest       *integrity.ContentDigest
+		unverifiable bool
+	)
+	flush := func() {
+		if digest == nil {
+			return
+		}
+		switch {
+		case unverifiable:
+			report.Unverifiable++
+			return
+		case digest.Count() != expectCount:
+			cur.Reason = IntegrityCountMismatch
+		case digest.Sum() != expectHash:
+			cur.Reason = IntegrityContentMismatch
+		default:
+			report.Checked++
+			return
+		}
+		report.Checked++
+		report.Mismatches = append(report.Mismatches, cur)
+	}
+	for rows.Next() {
+		var (
+			batchID    int64
+			batchAgent int
+			receivedAt time.Time
+			count      int
+			hash       string
+			msgID      *int64
+			caseID     *int64
+			convID     string
+			ts         *time.Time
+			content    *string
+			enc        []byte
+		)
+		if err := rows.Scan(&batchID, &batchAgent, &receivedAt, &count, &hash, &msgID, &caseID, &convID, &ts, &content, &enc); err != nil {
+			return report, err
+		}
+		if digest == nil || batchID != cur.BatchID {
+			flush()
+			cur = IntegrityMismatch{BatchID: batchID, AgentID: batchAgent, ReceivedAt: receivedAt}
+			expectCount, expectHash = count, hash
+			digest = integrity.NewContentDigest()
+			unverifiable = false
+		}
+		if msgID == nil || unverifiable {
+			continue
+		}
+		var text string
+		ok, err := p.decryptContent(ctx, caseID, content, enc, &text)
+		if errors.Is(err, envelope.ErrMalformed) || errors.Is(err, envelope.ErrAuthFailed) || errors.Is(err, envelope.ErrNoKey) {
+			// 密文被改动、截断或密钥记录缺失，同样视为内容不一致
+			text = "\x00undecryptable"
+		} else if err != nil {
+			return report, err
+		} else if !ok {
+			unverifiable = true
+			continue
+		}
+		digest.Add(convID, *ts, text)
+	}
+	if err := rows.Err(); err != nil {
+		return report, err
+	}
+	flush()
+	return report, nil
+}
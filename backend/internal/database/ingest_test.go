@@ -0,0 +1,44 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pashagolub/pgxmock/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClaimBatchRequest(t *testing.T) {
+	const hash = "4f0c3a1e9b7d2c5a8e6f1b3d7c9a2e4f6b8d0c1a3e5f7b9d2c4a6e8f0b1d3c5a"
+	for _, tc := range []struct {
+		name    string
+		exists  bool
+		wantErr error
+	}{
+		{"new request", false, nil},
+		{"replayed request", true, ErrDuplicateBatch},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mock, err := pgxmock.NewPool()
+			require.NoError(t, err)
+			defer mock.Close()
+
+			mock.ExpectBegin()
+			mock.ExpectExec(`SELECT pg_advisory_xact_lock\(\$1, hashtext\(\$2\)\)`).WithArgs(5, hash).
+				WillReturnResult(pgxmock.NewResult("SELECT", 1))
+			mock.ExpectQuery(`SELECT EXISTS \(SELECT 1 FROM ingestion_batches WHERE agent_id=\$1 AND request_sha256=\$2\)`).WithArgs(5, hash).
+				WillReturnRows(pgxmock.NewRows([]string{"exists"}).AddRow(tc.exists))
+
+			tx, err := mock.Begin(context.Background())
+			require.NoError(t, err)
+			err = claimBatchRequest(context.Background(), tx, 5, hash)
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, err, tc.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
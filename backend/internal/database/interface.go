@@ -6,7 +6,8 @@ import (
 )
 
 type DBOperations interface {
-	CreateTaskForAgent(ctx context.Context, agentID int, taskType string) error
-    SaveMessages(ctx context.Context, agentID int, messages []*api.ChatMessage) error
+	CreateTaskForAgent(ctx context.Context, agentID int, taskType string, scope TaskScope) (int64, error)
+    SaveMessages(ctx context.Context, batch IngestionBatch, messages []*api.ChatMessage) (int64, error)
+    RecordAudit(ctx context.Context, e AuditEntry) error
     // 未来可以添加更多方法，如 GetAgentByID 等
 }
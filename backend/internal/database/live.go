@@ -0,0 +1,45 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// LiveChannel 为控制台实时事件使用的 LISTEN/NOTIFY 通道
+const LiveChannel = "guardian_live"
+
+// 控制台实时事件类型；"." 之前的部分为主题，用于按角色过滤
+const (
+	LiveAgentStatus    = "agent.status"
+	LiveTaskStatus     = "task.status"
+	LiveIngestionBatch = "ingestion.batch"
+)
+
+// LiveEvent 是推送给控制台的状态变化，只携带标识与状态，不含消息内容
+type LiveEvent struct {
+	Type    string         `json:"type"`
+	AgentID int            `json:"agent_id"`
+	TaskID  int64          `json:"task_id,omitempty"`
+	Data    map[string]any `json:"data,omitempty"`
+}
+
+// notifyLive 在调用方事务内发出通知；NOTIFY 在事务提交时才送达，回滚则不送达
+func notifyLive(ctx context.Context, tx pgx.Tx, ev LiveEvent) error {
+	payload, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, `SELECT pg_notify($1, $2)`, LiveChannel, string(payload))
+	return err
+}
+
+// notifyAgentStatus 与 notifyTaskStatus 为最常用的两类事件提供简写
+func notifyAgentStatus(ctx context.Context, tx pgx.Tx, agentID int, status string) error {
+	return notifyLive(ctx, tx, LiveEvent{Type: LiveAgentStatus, AgentID: agentID, Data: map[string]any{"status": status}})
+}
+
+func notifyTaskStatus(ctx context.Context, tx pgx.Tx, agentID int, taskID int64, taskType, status string) error {
+	return notifyLive(ctx, tx, LiveEvent{Type: LiveTaskStatus, AgentID: agentID, TaskID: taskID, Data: map[string]any{"task_type": taskType, "status": status}})
+}
@@ -0,0 +1,143 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"guardian-backend/pkg/apperr"
+)
+
+// ErrPersonNotFound 用于被监测人员不存在时返回
+var ErrPersonNotFound = apperr.New(apperr.KindNotFound, "", "monitored person not found")
+
+// ErrAcknowledgementRequired 表示 agent 未关联人员，或该人员没有当前版本告知的有效确认记录
+var ErrAcknowledgementRequired = apperr.New(apperr.KindPermissionDenied, "ACKNOWLEDGEMENT_REQUIRED", "no valid monitoring acknowledgement on file")
+
+// MonitoredPerson 是使用终端的员工
+type MonitoredPerson struct {
+	ID         int
+	FullName   string
+	EmployeeID string
+	CreatedAt  time.Time
+	// LatestAck 是最近一条告知/确认记录，可能为空
+	LatestAck *Acknowledgement
+}
+
+// Acknowledgement 是一次监测告知及本人确认的记录
+type Acknowledgement struct {
+	ID             int64
+	PersonID       int
+	NoticeVersion  string
+	NotifiedAt     time.Time
+	AcknowledgedAt *time.Time
+	RecordedBy     string
+	CreatedAt      time.Time
+}
+
+// CreateMonitoredPerson 新建被监测人员并返回 ID
+func (p *DB) CreateMonitoredPerson(ctx context.Context, fullName, employeeID string) (int, error) {
+	var id int
+	err := p.Pool.QueryRow(ctx, `
+		INSERT INTO monitored_persons (full_name, employee_id)
+		VALUES ($1, NULLIF($2, ''))
+		RETURNING id
+	`, fullName, employeeID).Scan(&id)
+	return id, err
+}
+
+// ListMonitoredPersons 查询所有被监测人员及其最近一条告知记录
+func (p *DB) ListMonitoredPersons(ctx context.Context) ([]MonitoredPerson, error) {
+	rows, err := p.Pool.Query(ctx, `
+		SELECT mp.id, mp.full_name, COALESCE(mp.employee_id, ''), mp.created_at,
+		       a.id, a.notice_version, a.notified_at, a.acknowledged_at, a.recorded_by, a.created_at
+		FROM monitored_persons mp
+		LEFT JOIN LATERAL (
+			SELECT * FROM monitoring_acknowledgements
+			WHERE person_id = mp.id
+			ORDER BY created_at DESC
+			LIMIT 1
+		) a ON TRUE
+		ORDER BY mp.id ASC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []MonitoredPerson
+	for rows.Next() {
+		var it MonitoredPerson
+		var ackID *int64
+		var version, recordedBy *string
+		var notifiedAt, createdAt *time.Time
+		var ackAt *time.Time
+		if err := rows.Scan(&it.ID, &it.FullName, &it.EmployeeID, &it.CreatedAt,
+			&ackID, &version, &notifiedAt, &ackAt, &recordedBy, &createdAt); err != nil {
+			return nil, err
+		}
+		if ackID != nil {
+			it.LatestAck = &Acknowledgement{
+				ID:             *ackID,
+				PersonID:       it.ID,
+				NoticeVersion:  *version,
+				NotifiedAt:     *notifiedAt,
+				AcknowledgedAt: ackAt,
+				RecordedBy:     *recordedBy,
+				CreatedAt:      *createdAt,
+			}
+		}
+		result = append(result, it)
+	}
+	return result, rows.Err()
+}
+
+// RecordAcknowledgement 为人员记录一次告知（及确认），返回记录 ID
+func (p *DB) RecordAcknowledgement(ctx context.Context, ack Acknowledgement) (int64, error) {
+	var id int64
+	err := p.Pool.QueryRow(ctx, `
+		INSERT INTO monitoring_acknowledgements (person_id, notice_version, notified_at, acknowledged_at, recorded_by)
+		SELECT $1, $2, $3, $4, $5
+		WHERE EXISTS (SELECT 1 FROM monitored_persons WHERE id=$1)
+		RETURNING id
+	`, ack.PersonID, ack.NoticeVersion, ack.NotifiedAt, ack.AcknowledgedAt, ack.RecordedBy).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, ErrPersonNotFound
+	}
+	return id, err
+}
+
+// LinkAgentToPerson 将 agent 关联到被监测人员
+func (p *DB) LinkAgentToPerson(ctx context.Context, agentID, personID int) error {
+	var exists bool
+	if err := p.Pool.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM monitored_persons WHERE id=$1)`, personID).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return ErrPersonNotFound
+	}
+	tag, err := p.Pool.Exec(ctx, `UPDATE agents SET monitored_person_id=$2 WHERE id=$1`, agentID, personID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrAgentNotFound
+	}
+	return nil
+}
+
+// hasValidAcknowledgement 判断 agent 关联人员最近一条记录是否已确认；requiredVersion 非空时还需版本一致
+func hasValidAcknowledgement(ctx context.Context, q pgx.Tx, agentID int, requiredVersion string) (bool, error) {
+	var ok bool
+	err := q.QueryRow(ctx, `
+		SELECT COALESCE((
+			SELECT a.acknowledged_at IS NOT NULL AND ($2 = '' OR a.notice_version = $2)
+			FROM agents ag
+			JOIN monitoring_acknowledgements a ON a.person_id = ag.monitored_person_id
+			WHERE ag.id = $1
+			ORDER BY a.created_at DESC
+			LIMIT 1
+		), FALSE)
+	`, agentID, requiredVersion).Scan(&ok)
+	return ok, err
+}
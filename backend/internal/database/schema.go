@@ -0,0 +1,21 @@
+package database
+
+import (
+	"context"
+	"fmt"
+)
+
+// SchemaVersion 为本版本代码依赖的迁移版本（db/init 中最大的文件编号）；新增迁移时同步递增
+const SchemaVersion = 18
+
+// CheckSchema 确认数据库已应用到 SchemaVersion；schema_migrations 不存在或版本落后时返回错误
+func (p *DB) CheckSchema(ctx context.Context) error {
+	var version int
+	if err := p.Pool.QueryRow(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version); err != nil {
+		return fmt.Errorf("read schema version: %w", err)
+	}
+	if version < SchemaVersion {
+		return fmt.Errorf("schema version %d is behind required %d", version, SchemaVersion)
+	}
+	return nil
+}
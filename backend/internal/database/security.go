@@ -0,0 +1,37 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// 安全事件类型
+const (
+	// SecurityEventLoginLockout 表示连续登录失败触发锁定
+	SecurityEventLoginLockout = "login.lockout"
+)
+
+// SecurityEvent 是一条安全事件
+type SecurityEvent struct {
+	Type      string
+	Username  string
+	IPAddress string
+	Detail    map[string]any
+}
+
+// RecordSecurityEvent 写入一条安全事件
+func (p *DB) RecordSecurityEvent(ctx context.Context, e SecurityEvent) error {
+	var detail []byte
+	if len(e.Detail) > 0 {
+		b, err := json.Marshal(e.Detail)
+		if err != nil {
+			return err
+		}
+		detail = b
+	}
+	_, err := p.Pool.Exec(ctx, `
+		INSERT INTO security_events (event_type, username, ip_address, detail)
+		VALUES ($1, NULLIF($2, ''), NULLIF($3, ''), $4)
+	`, e.Type, e.Username, e.IPAddress, detail)
+	return err
+}
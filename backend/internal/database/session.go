@@ -0,0 +1,149 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"guardian-backend/pkg/apperr"
+)
+
+var (
+	// ErrSessionNotFound 表示刷新令牌未对应任何会话
+	ErrSessionNotFound = apperr.New(apperr.KindUnauthenticated, "", "session not found")
+	// ErrSessionInactive 表示会话已吊销或已过期
+	ErrSessionInactive = apperr.New(apperr.KindUnauthenticated, "SESSION_REVOKED", "session revoked or expired")
+	// ErrRefreshTokenReused 表示已轮换掉的刷新令牌被再次使用，会话已被整体吊销
+	ErrRefreshTokenReused = apperr.New(apperr.KindUnauthenticated, "SESSION_REVOKED", "refresh token reuse detected")
+)
+
+// 会话吊销原因
+const (
+	RevokeReasonLogout       = "logout"
+	RevokeReasonAdmin        = "admin_revoked"
+	RevokeReasonRefreshReuse = "refresh_reuse"
+	RevokeReasonUserDeleted  = "user_deleted"
+)
+
+// Session 是一次控制台登录会话
+type Session struct {
+	ID               string
+	UserID           string
+	Role             string
+	RefreshTokenHash string
+	UserAgent        string
+	IPAddress        string
+	ExpiresAt        time.Time
+}
+
+// CreateSession 保存新会话
+func (p *DB) CreateSession(ctx context.Context, s Session) error {
+	_, err := p.Pool.Exec(ctx, `
+		INSERT INTO auth_sessions (id, user_id, role, refresh_token_hash, user_agent, ip_address, expires_at)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), NULLIF($6, ''), $7)
+	`, s.ID, s.UserID, s.Role, s.RefreshTokenHash, s.UserAgent, s.IPAddress, s.ExpiresAt)
+	return err
+}
+
+// RotateSession 用出示的刷新令牌哈希换取新哈希并延长有效期，返回会话信息。
+// 会话角色按用户当前角色重新加载，降级或移出 IdP 组在下次刷新时生效；用户已删除时吊销会话并返回 ErrSessionInactive。
+// 若出示的是已被轮换掉的旧令牌，视为令牌泄露，整个会话被吊销并返回 ErrRefreshTokenReused。
+func (p *DB) RotateSession(ctx context.Context, presentedHash, newHash string, expiresAt time.Time) (Session, error) {
+	return rotateSession(ctx, p.Pool, presentedHash, newHash, expiresAt)
+}
+
+func rotateSession(ctx context.Context, db txBeginner, presentedHash, newHash string, expiresAt time.Time) (Session, error) {
+	var s Session
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return s, err
+	}
+	defer tx.Rollback(ctx)
+	var revokedAt *time.Time
+	var currentExpiry time.Time
+	var role *string
+	err = tx.QueryRow(ctx, `
+		SELECT s.id, s.user_id, u.role, s.revoked_at, s.expires_at
+		FROM auth_sessions s
+		LEFT JOIN audit_users u ON u.id::text = s.user_id
+		WHERE s.refresh_token_hash=$1
+		FOR UPDATE OF s
+	`, presentedHash).Scan(&s.ID, &s.UserID, &role, &revokedAt, &currentExpiry)
+	if errors.Is(err, pgx.ErrNoRows) {
+		tag, err := tx.Exec(ctx, `
+			UPDATE auth_sessions SET revoked_at=NOW(), revoked_reason=$2
+			WHERE previous_token_hash=$1 AND revoked_at IS NULL
+		`, presentedHash, RevokeReasonRefreshReuse)
+		if err != nil {
+			return s, err
+		}
+		if tag.RowsAffected() > 0 {
+			if err := tx.Commit(ctx); err != nil {
+				return s, err
+			}
+			return s, ErrRefreshTokenReused
+		}
+		return s, ErrSessionNotFound
+	}
+	if err != nil {
+		return s, err
+	}
+	if revokedAt != nil || time.Now().After(currentExpiry) {
+		return s, ErrSessionInactive
+	}
+	if role == nil {
+		_, err := tx.Exec(ctx, `
+			UPDATE auth_sessions SET revoked_at=NOW(), revoked_reason=$2 WHERE id=$1
+		`, s.ID, RevokeReasonUserDeleted)
+		if err != nil {
+			return s, err
+		}
+		if err := tx.Commit(ctx); err != nil {
+			return s, err
+		}
+		return s, ErrSessionInactive
+	}
+	s.Role = *role
+	_, err = tx.Exec(ctx, `
+		UPDATE auth_sessions
+		SET previous_token_hash=refresh_token_hash, refresh_token_hash=$2, last_used_at=NOW(), expires_at=$3, role=$4
+		WHERE id=$1
+	`, s.ID, newHash, expiresAt, s.Role)
+	if err != nil {
+		return s, err
+	}
+	s.RefreshTokenHash = newHash
+	s.ExpiresAt = expiresAt
+	return s, tx.Commit(ctx)
+}
+
+// RevokeSession 吊销单个会话
+func (p *DB) RevokeSession(ctx context.Context, sessionID, reason string) error {
+	_, err := p.Pool.Exec(ctx, `
+		UPDATE auth_sessions SET revoked_at=NOW(), revoked_reason=$2
+		WHERE id=$1 AND revoked_at IS NULL
+	`, sessionID, reason)
+	return err
+}
+
+// RevokeUserSessions 吊销某用户的全部会话，返回吊销数量
+func (p *DB) RevokeUserSessions(ctx context.Context, userID, reason string) (int64, error) {
+	tag, err := p.Pool.Exec(ctx, `
+		UPDATE auth_sessions SET revoked_at=NOW(), revoked_reason=$2
+		WHERE user_id=$1 AND revoked_at IS NULL
+	`, userID, reason)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// IsSessionActive 判断会话是否仍有效（存在、未吊销、未过期）
+func (p *DB) IsSessionActive(ctx context.Context, sessionID string) (bool, error) {
+	var active bool
+	err := p.Pool.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM auth_sessions WHERE id=$1 AND revoked_at IS NULL AND expires_at > NOW())
+	`, sessionID).Scan(&active)
+	return active, err
+}
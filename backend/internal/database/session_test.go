@@ -0,0 +1,56 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pashagolub/pgxmock/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRotateSession_ReloadsCurrentRole(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+	expiresAt := time.Now().Add(time.Hour)
+
+	// 会话以 admin 创建，用户随后被降级为 approver：刷新后的令牌只能带 approver
+	approver := "approver"
+	mock.ExpectBegin()
+	mock.ExpectQuery(`LEFT JOIN audit_users u ON u.id::text = s.user_id`).WithArgs("old-hash").
+		WillReturnRows(pgxmock.NewRows([]string{"id", "user_id", "role", "revoked_at", "expires_at"}).
+			AddRow("sess-1", "7", &approver, (*time.Time)(nil), expiresAt))
+	mock.ExpectExec(`UPDATE auth_sessions`).WithArgs("sess-1", "new-hash", expiresAt, "approver").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectCommit()
+	mock.ExpectRollback()
+
+	s, err := rotateSession(context.Background(), mock, "old-hash", "new-hash", expiresAt)
+	require.NoError(t, err)
+	assert.Equal(t, "approver", s.Role)
+	assert.Equal(t, "7", s.UserID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRotateSession_RejectsDeletedUser(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+	expiresAt := time.Now().Add(time.Hour)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`LEFT JOIN audit_users u ON u.id::text = s.user_id`).WithArgs("old-hash").
+		WillReturnRows(pgxmock.NewRows([]string{"id", "user_id", "role", "revoked_at", "expires_at"}).
+			AddRow("sess-1", "7", (*string)(nil), (*time.Time)(nil), expiresAt))
+	mock.ExpectExec(`UPDATE auth_sessions SET revoked_at=NOW\(\), revoked_reason=\$2 WHERE id=\$1`).
+		WithArgs("sess-1", RevokeReasonUserDeleted).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectCommit()
+	mock.ExpectRollback()
+
+	_, err = rotateSession(context.Background(), mock, "old-hash", "new-hash", expiresAt)
+	assert.ErrorIs(t, err, ErrSessionInactive)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
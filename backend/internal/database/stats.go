@@ -0,0 +1,31 @@
+package database
+
+import "context"
+
+// TaskStatusCounts 返回各状态的任务数，供指标采集
+func (p *DB) TaskStatusCounts(ctx context.Context) (map[string]int, error) {
+	return p.statusCounts(ctx, `SELECT status, COUNT(*) FROM tasks GROUP BY status`)
+}
+
+// AgentStatusCounts 返回各状态（online / offline / decommissioning / retired）的 agent 数，供指标采集
+func (p *DB) AgentStatusCounts(ctx context.Context) (map[string]int, error) {
+	return p.statusCounts(ctx, `SELECT status, COUNT(*) FROM agents GROUP BY status`)
+}
+
+func (p *DB) statusCounts(ctx context.Context, sql string) (map[string]int, error) {
+	rows, err := p.Pool.Query(ctx, sql)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	counts := map[string]int{}
+	for rows.Next() {
+		var status string
+		var n int
+		if err := rows.Scan(&status, &n); err != nil {
+			return nil, err
+		}
+		counts[status] = n
+	}
+	return counts, rows.Err()
+}
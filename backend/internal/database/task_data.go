@@ -0,0 +1,62 @@
+package database
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ListMessagesByTask 按时间倒序分页查询某任务采集的消息并解密；案件密钥已销毁的消息直接略过
+func (p *DB) ListMessagesByTask(ctx context.Context, taskID int64, limit, offset int) ([]WechatMessageRecord, error) {
+	var exists bool
+	if err := p.Pool.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM tasks WHERE id=$1)`, taskID).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrTaskNotFound
+	}
+	return p.listMessages(ctx, "m.task_id=$1", taskID, limit, offset)
+}
+
+// PurgeTaskMessages 清除某任务采集的全部消息并记录理由，返回删除条数。
+// 尚未结束的任务同时取消，之后不再接受该任务的上传。
+func (p *DB) PurgeTaskMessages(ctx context.Context, taskID int64, reason string) (int64, error) {
+	tx, err := p.Pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+	var (
+		status   string
+		taskType string
+		agentID  int
+	)
+	err = tx.QueryRow(ctx, `SELECT status, task_type, agent_id FROM tasks WHERE id=$1 FOR UPDATE`, taskID).Scan(&status, &taskType, &agentID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, ErrTaskNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	tag, err := tx.Exec(ctx, `DELETE FROM wechat_messages WHERE task_id=$1`, taskID)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(ctx, `UPDATE ingestion_batches SET purged_at=NOW() WHERE task_id=$1 AND purged_at IS NULL`, taskID); err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(ctx, `
+		UPDATE tasks SET data_purged_at=NOW(), data_purge_reason=$2, updated_at=NOW(),
+		       status=CASE WHEN status IN ('pending', 'sent') THEN $3 ELSE status END
+		WHERE id=$1
+	`, taskID, reason, TaskStatusCancelled); err != nil {
+		return 0, err
+	}
+	if status == "pending" || status == "sent" {
+		if err := notifyTaskStatus(ctx, tx, agentID, taskID, taskType, TaskStatusCancelled); err != nil {
+			return 0, err
+		}
+	}
+	return tag.RowsAffected(), tx.Commit(ctx)
+}
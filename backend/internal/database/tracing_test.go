@@ -0,0 +1,14 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOperation(t *testing.T) {
+	assert.Equal(t, "SELECT", operation("select id from agents where id = $1"))
+	assert.Equal(t, "WITH", operation("\n\t  WITH latest AS (SELECT 1) SELECT * FROM latest"))
+	assert.Equal(t, "INSERT", operation("insert into audit_logs(action) values ($1)"))
+	assert.Equal(t, "BEGIN", operation("begin"))
+}
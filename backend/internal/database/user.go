@@ -0,0 +1,168 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"guardian-backend/pkg/apperr"
+)
+
+var (
+	// ErrUserNotFound 用于用户不存在时返回
+	ErrUserNotFound = apperr.New(apperr.KindNotFound, "", "user not found")
+	// ErrUsernameTaken 表示 SSO 用户名已被其他账户（本地账户或其他 IdP 身份）占用
+	ErrUsernameTaken = apperr.New(apperr.KindAlreadyExists, "USERNAME_TAKEN", "username already taken")
+)
+
+// User 是控制台账户
+type User struct {
+	ID           int
+	Username     string
+	PasswordHash string
+	Role         string
+	MFASecret    string
+	MFAEnabledAt *time.Time
+	CreatedAt    time.Time
+}
+
+// MFAEnabled 判断用户是否已完成 TOTP 绑定
+func (u User) MFAEnabled() bool {
+	return u.MFAEnabledAt != nil
+}
+
+const userColumns = `id, username, COALESCE(password_hash, ''), role, COALESCE(mfa_secret, ''), mfa_enabled_at, created_at`
+
+func scanUser(row pgx.Row) (User, error) {
+	var u User
+	err := row.Scan(&u.ID, &u.Username, &u.PasswordHash, &u.Role, &u.MFASecret, &u.MFAEnabledAt, &u.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return u, ErrUserNotFound
+	}
+	return u, err
+}
+
+// GetUserByUsername 按用户名查询用户
+func (p *DB) GetUserByUsername(ctx context.Context, username string) (User, error) {
+	return scanUser(p.Pool.QueryRow(ctx, `SELECT `+userColumns+` FROM audit_users WHERE username=$1`, username))
+}
+
+// GetUserByID 按 ID 查询用户
+func (p *DB) GetUserByID(ctx context.Context, id int) (User, error) {
+	return scanUser(p.Pool.QueryRow(ctx, `SELECT `+userColumns+` FROM audit_users WHERE id=$1`, id))
+}
+
+// EnsureUser 在用户名不存在时创建用户（用于引导管理员），已存在时不做修改
+func (p *DB) EnsureUser(ctx context.Context, username, passwordHash, role string) error {
+	_, err := p.Pool.Exec(ctx, `
+		INSERT INTO audit_users (username, password_hash, role) VALUES ($1, $2, $3)
+		ON CONFLICT (username) DO NOTHING
+	`, username, passwordHash, role)
+	return err
+}
+
+// UpsertOIDCUser 按 (issuer, subject) 即时创建或更新 SSO 用户；角色每次登录按 IdP 组重新映射
+func (p *DB) UpsertOIDCUser(ctx context.Context, issuer, subject, username, email, role string) (User, error) {
+	u, err := scanUser(p.Pool.QueryRow(ctx, `
+		INSERT INTO audit_users (username, role, oidc_issuer, oidc_subject, email, last_login_at)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), NOW())
+		ON CONFLICT (oidc_issuer, oidc_subject) WHERE oidc_issuer IS NOT NULL
+		DO UPDATE SET role=EXCLUDED.role, email=EXCLUDED.email, last_login_at=NOW()
+		RETURNING `+userColumns, username, role, issuer, subject, email))
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return User{}, ErrUsernameTaken
+	}
+	return u, err
+}
+
+// SetPendingMFASecret 为尚未启用 MFA 的用户保存待确认的 TOTP 密钥
+func (p *DB) SetPendingMFASecret(ctx context.Context, userID int, secret string) error {
+	tag, err := p.Pool.Exec(ctx, `
+		UPDATE audit_users SET mfa_secret=$2, mfa_last_step=NULL
+		WHERE id=$1 AND mfa_enabled_at IS NULL
+	`, userID, secret)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// ConsumeTOTPStep 记录已使用的 TOTP 时间步；时间步不大于上次已用值时返回 false（验证码重放）
+func (p *DB) ConsumeTOTPStep(ctx context.Context, userID int, step int64) (bool, error) {
+	tag, err := p.Pool.Exec(ctx, `
+		UPDATE audit_users SET mfa_last_step=$2
+		WHERE id=$1 AND (mfa_last_step IS NULL OR mfa_last_step < $2)
+	`, userID, step)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// ActivateMFA 启用 MFA 并替换恢复码（仅保存哈希）
+func (p *DB) ActivateMFA(ctx context.Context, userID int, recoveryCodeHashes []string) error {
+	tx, err := p.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	tag, err := tx.Exec(ctx, `
+		UPDATE audit_users SET mfa_enabled_at=NOW()
+		WHERE id=$1 AND mfa_secret IS NOT NULL AND mfa_enabled_at IS NULL
+	`, userID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id=$1`, userID); err != nil {
+		return err
+	}
+	for _, h := range recoveryCodeHashes {
+		if _, err := tx.Exec(ctx, `INSERT INTO mfa_recovery_codes (user_id, code_hash) VALUES ($1, $2)`, userID, h); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// UseRecoveryCode 消耗一个未使用的恢复码，返回是否成功
+func (p *DB) UseRecoveryCode(ctx context.Context, userID int, codeHash string) (bool, error) {
+	tag, err := p.Pool.Exec(ctx, `
+		UPDATE mfa_recovery_codes SET used_at=NOW()
+		WHERE user_id=$1 AND code_hash=$2 AND used_at IS NULL
+	`, userID, codeHash)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// ResetMFA 清除用户的 TOTP 绑定与恢复码，用户下次登录需重新绑定
+func (p *DB) ResetMFA(ctx context.Context, userID int) error {
+	tx, err := p.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	tag, err := tx.Exec(ctx, `
+		UPDATE audit_users SET mfa_secret=NULL, mfa_enabled_at=NULL, mfa_last_step=NULL WHERE id=$1
+	`, userID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id=$1`, userID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
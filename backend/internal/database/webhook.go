@@ -0,0 +1,296 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"guardian-backend/pkg/apperr"
+)
+
+// 可订阅的事件类型
+const (
+	EventTaskCompleted = "task.completed"
+	EventTaskFailed    = "task.failed"
+	EventTaskTimedOut  = "task.timed_out"
+	EventAgentOffline  = "agent.offline"
+)
+
+// EventTypes 列出全部可订阅的事件类型
+var EventTypes = []string{EventTaskCompleted, EventTaskFailed, EventTaskTimedOut, EventAgentOffline}
+
+// Webhook 投递状态
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+	// DeliveryCancelled 表示订阅被删除时尚未投递成功
+	DeliveryCancelled = "cancelled"
+)
+
+// ErrWebhookNotFound 用于订阅不存在或已删除时返回
+var ErrWebhookNotFound = apperr.New(apperr.KindNotFound, "", "webhook subscription not found")
+
+// WebhookSubscription 是一个 webhook 订阅；EventTypes 为空表示订阅全部事件
+type WebhookSubscription struct {
+	ID          int
+	URL         string
+	Secret      string
+	EventTypes  []string
+	Description string
+	CreatedBy   string
+	CreatedAt   time.Time
+}
+
+// WebhookDelivery 是一个事件对一个订阅的投递，领取时附带订阅地址、密钥与事件内容
+type WebhookDelivery struct {
+	ID             int64
+	SubscriptionID int
+	EventID        int64
+	EventType      string
+	Payload        json.RawMessage
+	EventCreatedAt time.Time
+	URL            string
+	Secret         string
+	Status         string
+	// Attempts 为已发起的投递次数（含本次领取）
+	Attempts      int
+	NextAttemptAt time.Time
+	CreatedAt     time.Time
+	CompletedAt   *time.Time
+	// Log 为历次投递尝试，仅在查询投递日志时填充
+	Log []WebhookAttempt
+}
+
+// WebhookAttempt 是一次 HTTP 投递尝试的结果；ResponseStatus 为 0 表示未收到响应
+type WebhookAttempt struct {
+	AttemptedAt    time.Time
+	ResponseStatus int
+	Error          string
+	Duration       time.Duration
+}
+
+// enqueueEvent 在调用方事务内写入待投递事件，事件与状态变更同时提交或回滚
+func enqueueEvent(ctx context.Context, tx pgx.Tx, eventType string, payload map[string]any) error {
+	_, err := tx.Exec(ctx, `INSERT INTO event_outbox (event_type, payload) VALUES ($1, $2)`, eventType, payload)
+	return err
+}
+
+// CreateWebhookSubscription 新建订阅并返回 ID
+func (p *DB) CreateWebhookSubscription(ctx context.Context, s WebhookSubscription) (int, error) {
+	eventTypes := s.EventTypes
+	if eventTypes == nil {
+		eventTypes = []string{}
+	}
+	var id int
+	err := p.Pool.QueryRow(ctx, `
+		INSERT INTO webhook_subscriptions (url, secret, event_types, description, created_by)
+		VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''))
+		RETURNING id
+	`, s.URL, s.Secret, eventTypes, s.Description, s.CreatedBy).Scan(&id)
+	return id, err
+}
+
+// ListWebhookSubscriptions 查询未删除的订阅（不含密钥）
+func (p *DB) ListWebhookSubscriptions(ctx context.Context) ([]WebhookSubscription, error) {
+	rows, err := p.Pool.Query(ctx, `
+		SELECT id, url, event_types, COALESCE(description, ''), COALESCE(created_by, ''), created_at
+		FROM webhook_subscriptions
+		WHERE deleted_at IS NULL
+		ORDER BY id ASC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []WebhookSubscription
+	for rows.Next() {
+		var s WebhookSubscription
+		if err := rows.Scan(&s.ID, &s.URL, &s.EventTypes, &s.Description, &s.CreatedBy, &s.CreatedAt); err != nil {
+			return nil, err
+		}
+		result = append(result, s)
+	}
+	return result, rows.Err()
+}
+
+// DeleteWebhookSubscription 删除订阅并取消其尚未成功的投递；投递日志保留
+func (p *DB) DeleteWebhookSubscription(ctx context.Context, id int) error {
+	tx, err := p.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	tag, err := tx.Exec(ctx, `UPDATE webhook_subscriptions SET deleted_at=NOW() WHERE id=$1 AND deleted_at IS NULL`, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrWebhookNotFound
+	}
+	_, err = tx.Exec(ctx, `
+		UPDATE webhook_deliveries SET status=$2, completed_at=NOW()
+		WHERE subscription_id=$1 AND status=$3
+	`, id, DeliveryCancelled, DeliveryPending)
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// ListWebhookDeliveries 查询订阅最近的投递及每次尝试的结果，按投递 ID 倒序
+func (p *DB) ListWebhookDeliveries(ctx context.Context, subscriptionID, limit int) ([]WebhookDelivery, error) {
+	var exists bool
+	if err := p.Pool.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM webhook_subscriptions WHERE id=$1)`, subscriptionID).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrWebhookNotFound
+	}
+	rows, err := p.Pool.Query(ctx, `
+		SELECT d.id, d.subscription_id, d.event_id, e.event_type, e.payload, e.created_at,
+		       d.status, d.attempts, d.next_attempt_at, d.created_at, d.completed_at
+		FROM webhook_deliveries d
+		JOIN event_outbox e ON e.id = d.event_id
+		WHERE d.subscription_id=$1
+		ORDER BY d.id DESC
+		LIMIT $2
+	`, subscriptionID, limit)
+	if err != nil {
+		return nil, err
+	}
+	var result []WebhookDelivery
+	index := map[int64]int{}
+	for rows.Next() {
+		var d WebhookDelivery
+		if err := rows.Scan(&d.ID, &d.SubscriptionID, &d.EventID, &d.EventType, &d.Payload, &d.EventCreatedAt,
+			&d.Status, &d.Attempts, &d.NextAttemptAt, &d.CreatedAt, &d.CompletedAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		index[d.ID] = len(result)
+		result = append(result, d)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(result) == 0 {
+		return result, nil
+	}
+	ids := make([]int64, len(result))
+	for i, d := range result {
+		ids[i] = d.ID
+	}
+	rows, err = p.Pool.Query(ctx, `
+		SELECT delivery_id, attempted_at, COALESCE(response_status, 0), COALESCE(error, ''), duration_ms
+		FROM webhook_delivery_attempts
+		WHERE delivery_id = ANY($1)
+		ORDER BY id ASC
+	`, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			deliveryID int64
+			a          WebhookAttempt
+			ms         int64
+		)
+		if err := rows.Scan(&deliveryID, &a.AttemptedAt, &a.ResponseStatus, &a.Error, &ms); err != nil {
+			return nil, err
+		}
+		a.Duration = time.Duration(ms) * time.Millisecond
+		d := &result[index[deliveryID]]
+		d.Log = append(d.Log, a)
+	}
+	return result, rows.Err()
+}
+
+// FanOutEvents 把尚未扇出的事件按订阅展开为投递记录，返回处理的事件数。
+// 事件在同一语句内标记为已扇出，多实例并发执行时互不重复。
+func (p *DB) FanOutEvents(ctx context.Context, limit int) (int64, error) {
+	tag, err := p.Pool.Exec(ctx, `
+		WITH ev AS (
+			SELECT id, event_type FROM event_outbox
+			WHERE dispatched_at IS NULL
+			ORDER BY id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		), ins AS (
+			INSERT INTO webhook_deliveries (subscription_id, event_id)
+			SELECT s.id, ev.id
+			FROM ev
+			JOIN webhook_subscriptions s
+			  ON s.deleted_at IS NULL AND (cardinality(s.event_types) = 0 OR ev.event_type = ANY(s.event_types))
+			ON CONFLICT (subscription_id, event_id) DO NOTHING
+		)
+		UPDATE event_outbox SET dispatched_at=NOW() WHERE id IN (SELECT id FROM ev)
+	`, limit)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// ClaimWebhookDeliveries 领取到期的待投递记录并计入一次尝试。
+// 领取后 next_attempt_at 推迟 lease，投递器在此期间崩溃时该记录会在租期结束后被重新领取。
+func (p *DB) ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]WebhookDelivery, error) {
+	rows, err := p.Pool.Query(ctx, `
+		WITH due AS (
+			SELECT id FROM webhook_deliveries
+			WHERE status=$3 AND next_attempt_at <= NOW()
+			ORDER BY next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE webhook_deliveries d
+		SET attempts = d.attempts + 1, next_attempt_at = NOW() + make_interval(secs => $2)
+		FROM due, webhook_subscriptions s, event_outbox e
+		WHERE d.id = due.id AND s.id = d.subscription_id AND e.id = d.event_id
+		RETURNING d.id, d.subscription_id, d.event_id, e.event_type, e.payload, e.created_at,
+		          s.url, s.secret, d.status, d.attempts, d.next_attempt_at, d.created_at
+	`, limit, lease.Seconds(), DeliveryPending)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []WebhookDelivery
+	for rows.Next() {
+		var d WebhookDelivery
+		if err := rows.Scan(&d.ID, &d.SubscriptionID, &d.EventID, &d.EventType, &d.Payload, &d.EventCreatedAt,
+			&d.URL, &d.Secret, &d.Status, &d.Attempts, &d.NextAttemptAt, &d.CreatedAt); err != nil {
+			return nil, err
+		}
+		result = append(result, d)
+	}
+	return result, rows.Err()
+}
+
+// RecordWebhookAttempt 记录一次投递尝试并更新投递状态；status 仍为 pending 时于 nextAttemptAt 重试
+func (p *DB) RecordWebhookAttempt(ctx context.Context, deliveryID int64, a WebhookAttempt, status string, nextAttemptAt time.Time) error {
+	tx, err := p.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	_, err = tx.Exec(ctx, `
+		INSERT INTO webhook_delivery_attempts (delivery_id, attempted_at, response_status, error, duration_ms)
+		VALUES ($1, $2, NULLIF($3, 0), NULLIF($4, ''), $5)
+	`, deliveryID, a.AttemptedAt, a.ResponseStatus, a.Error, a.Duration.Milliseconds())
+	if err != nil {
+		return err
+	}
+	// 订阅在投递期间被删除时保持 cancelled
+	_, err = tx.Exec(ctx, `
+		UPDATE webhook_deliveries
+		SET status=$2, next_attempt_at=$3, completed_at = CASE WHEN $2 = $4 THEN NULL ELSE NOW() END
+		WHERE id=$1 AND status=$4
+	`, deliveryID, status, nextAttemptAt, DeliveryPending)
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
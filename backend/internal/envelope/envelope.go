@@ -0,0 +1,157 @@
+// Package envelope 实现字段级信封加密：每个案件一把 AES-256 数据密钥（DEK），
+// DEK 由 KMS 主密钥包装后存库。销毁案件密钥即可使该案件的全部密文不可恢复（加密擦除）。
+package envelope
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"guardian-backend/internal/kms"
+)
+
+var (
+	// ErrNoKey 表示案件尚未生成数据密钥
+	ErrNoKey = errors.New("case key not found")
+	// ErrKeyDestroyed 表示案件密钥已销毁，其数据不可恢复
+	ErrKeyDestroyed = errors.New("case key destroyed")
+	// ErrMalformed 表示密文格式无法识别
+	ErrMalformed = errors.New("malformed ciphertext")
+	// ErrAuthFailed 表示密文未通过 GCM 认证，已被改动或不属于该案件
+	ErrAuthFailed = errors.New("ciphertext authentication failed")
+)
+
+// version 为密文格式版本：version(1) || nonce(12) || AES-GCM 密文
+const version byte = 1
+
+// CaseKey 是存库的已包装数据密钥
+type CaseKey struct {
+	Wrapped     []byte
+	MasterKeyID string
+	Destroyed   bool
+}
+
+// KeyStore 持久化已包装的案件数据密钥
+type KeyStore interface {
+	// GetCaseKey 返回案件密钥；不存在时返回 ErrNoKey
+	GetCaseKey(ctx context.Context, caseID int64) (CaseKey, error)
+	// CreateCaseKey 保存新密钥；并发创建时返回已存在的密钥
+	CreateCaseKey(ctx context.Context, caseID int64, k CaseKey) (CaseKey, error)
+}
+
+type cachedKey struct {
+	aead    cipher.AEAD
+	expires time.Time
+}
+
+// Cipher 按案件加解密字段。解包后的 DEK 在内存中缓存 CacheTTL，
+// 多实例部署时其他实例销毁密钥后最多经过 CacheTTL 生效。
+type Cipher struct {
+	KMS      kms.KMS
+	Store    KeyStore
+	CacheTTL time.Duration
+
+	mu    sync.Mutex
+	cache map[int64]cachedKey
+}
+
+// New 创建 Cipher，默认缓存 5 分钟
+func New(k kms.KMS, store KeyStore) *Cipher {
+	return &Cipher{KMS: k, Store: store, CacheTTL: 5 * time.Minute, cache: map[int64]cachedKey{}}
+}
+
+// Encrypt 使用案件密钥加密，案件尚无密钥时生成一把
+func (c *Cipher) Encrypt(ctx context.Context, caseID int64, plaintext []byte) ([]byte, error) {
+	aead, err := c.key(ctx, caseID, true)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, 1+aead.NonceSize(), 1+aead.NonceSize()+len(plaintext)+aead.Overhead())
+	out[0] = version
+	if _, err := rand.Read(out[1:]); err != nil {
+		return nil, err
+	}
+	return aead.Seal(out, out[1:], plaintext, aad(caseID)), nil
+}
+
+// Decrypt 解密案件密文；密钥已销毁时返回 ErrKeyDestroyed
+func (c *Cipher) Decrypt(ctx context.Context, caseID int64, ciphertext []byte) ([]byte, error) {
+	aead, err := c.key(ctx, caseID, false)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < 1+aead.NonceSize() || ciphertext[0] != version {
+		return nil, ErrMalformed
+	}
+	nonce := ciphertext[1 : 1+aead.NonceSize()]
+	pt, err := aead.Open(nil, nonce, ciphertext[1+aead.NonceSize():], aad(caseID))
+	if err != nil {
+		return nil, ErrAuthFailed
+	}
+	return pt, nil
+}
+
+// Forget 从缓存中移除案件密钥，销毁密钥后调用
+func (c *Cipher) Forget(caseID int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.cache, caseID)
+}
+
+// aad 把密文绑定到案件，防止密文被挪到其他案件下解密
+func aad(caseID int64) []byte {
+	return []byte("guardian-case:" + strconv.FormatInt(caseID, 10))
+}
+
+func (c *Cipher) key(ctx context.Context, caseID int64, create bool) (cipher.AEAD, error) {
+	c.mu.Lock()
+	if k, ok := c.cache[caseID]; ok && time.Now().Before(k.expires) {
+		c.mu.Unlock()
+		return k.aead, nil
+	}
+	c.mu.Unlock()
+
+	ck, err := c.Store.GetCaseKey(ctx, caseID)
+	if errors.Is(err, ErrNoKey) && create {
+		ck, err = c.newKey(ctx, caseID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if ck.Destroyed {
+		return nil, ErrKeyDestroyed
+	}
+	dek, err := c.KMS.Unwrap(ctx, ck.Wrapped, ck.MasterKeyID)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(dek)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.cache[caseID] = cachedKey{aead: aead, expires: time.Now().Add(c.CacheTTL)}
+	c.mu.Unlock()
+	return aead, nil
+}
+
+func (c *Cipher) newKey(ctx context.Context, caseID int64) (CaseKey, error) {
+	dek := make([]byte, 32)
+	if _, err := rand.Read(dek); err != nil {
+		return CaseKey{}, err
+	}
+	wrapped, masterKeyID, err := c.KMS.Wrap(ctx, dek)
+	if err != nil {
+		return CaseKey{}, err
+	}
+	return c.Store.CreateCaseKey(ctx, caseID, CaseKey{Wrapped: wrapped, MasterKeyID: masterKeyID})
+}
@@ -0,0 +1,85 @@
+package envelope
+
+import (
+	"context"
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"guardian-backend/internal/kms"
+)
+
+type memStore map[int64]CaseKey
+
+func (m memStore) GetCaseKey(_ context.Context, caseID int64) (CaseKey, error) {
+	k, ok := m[caseID]
+	if !ok {
+		return CaseKey{}, ErrNoKey
+	}
+	return k, nil
+}
+
+func (m memStore) CreateCaseKey(_ context.Context, caseID int64, k CaseKey) (CaseKey, error) {
+	if existing, ok := m[caseID]; ok {
+		return existing, nil
+	}
+	m[caseID] = k
+	return k, nil
+}
+
+func newCipher(t *testing.T) (*Cipher, memStore) {
+	t.Helper()
+	key := make([]byte, 32)
+	_, err := rand.Read(key)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "master.key")
+	require.NoError(t, os.WriteFile(path, key, 0o600))
+	local, err := kms.NewLocalKMS("m1", []kms.MasterKeyConfig{{ID: "m1", File: path}})
+	require.NoError(t, err)
+	store := memStore{}
+	return New(local, store), store
+}
+
+func TestCipher_RoundTripPerCase(t *testing.T) {
+	ctx := context.Background()
+	c, store := newCipher(t)
+
+	ct, err := c.Encrypt(ctx, 1, []byte("你好，世界"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(ct), "你好")
+	pt, err := c.Decrypt(ctx, 1, ct)
+	require.NoError(t, err)
+	assert.Equal(t, "你好，世界", string(pt))
+
+	_, err = c.Encrypt(ctx, 2, []byte("other case"))
+	require.NoError(t, err)
+	assert.Len(t, store, 2, "each case gets its own data key")
+	assert.NotEqual(t, store[1].Wrapped, store[2].Wrapped)
+
+	// 密文绑定案件，不能在其他案件下解密
+	_, err = c.Decrypt(ctx, 2, ct)
+	assert.Error(t, err)
+}
+
+func TestCipher_DestroyedKeyMakesDataUnrecoverable(t *testing.T) {
+	ctx := context.Background()
+	c, store := newCipher(t)
+	ct, err := c.Encrypt(ctx, 7, []byte("secret"))
+	require.NoError(t, err)
+
+	store[7] = CaseKey{Destroyed: true}
+	c.Forget(7)
+	_, err = c.Decrypt(ctx, 7, ct)
+	assert.ErrorIs(t, err, ErrKeyDestroyed)
+	_, err = c.Encrypt(ctx, 7, []byte("more"))
+	assert.ErrorIs(t, err, ErrKeyDestroyed, "a destroyed case must not get a new key")
+}
+
+func TestCipher_DecryptWithoutKey(t *testing.T) {
+	c, _ := newCipher(t)
+	_, err := c.Decrypt(context.Background(), 9, []byte{version, 1, 2, 3})
+	assert.ErrorIs(t, err, ErrNoKey)
+}
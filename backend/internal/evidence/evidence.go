@@ -0,0 +1,162 @@
+// Package evidence 生成并校验案件证据导出包。
+//
+// 导出包是一个 ZIP：messages.jsonl 与 messages.csv 为消息，chain_of_custody.json 为监管链记录，
+// manifest.json 列出上述每个文件的 SHA-256，manifest.sig 为服务端 Ed25519 密钥对 manifest.json 的签名。
+// 校验时先验签 manifest，再逐个比对文件哈希，任何改动、增删文件都会被发现。
+package evidence
+
+import (
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// 包内文件名
+const (
+	FileMessagesJSONL = "messages.jsonl"
+	FileMessagesCSV   = "messages.csv"
+	FileCustody       = "chain_of_custody.json"
+	FileManifest      = "manifest.json"
+	FileSignature     = "manifest.sig"
+)
+
+// ManifestVersion 是当前清单格式版本
+const ManifestVersion = 1
+
+// Message 是导出的一条消息
+type Message struct {
+	ID             int64     `json:"id"`
+	AgentID        int       `json:"agent_id"`
+	TaskID         *int64    `json:"task_id,omitempty"`
+	ConversationID string    `json:"conversation_id,omitempty"`
+	Timestamp      time.Time `json:"timestamp"`
+	Content        string    `json:"content"`
+}
+
+// Task 是案件下的采集任务
+type Task struct {
+	ID         int64      `json:"id"`
+	AgentID    int        `json:"agent_id"`
+	TaskType   string     `json:"task_type"`
+	Status     string     `json:"status"`
+	ScopeStart *time.Time `json:"scope_start,omitempty"`
+	ScopeEnd   *time.Time `json:"scope_end,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// Event 是监管链中的一条审计记录
+type Event struct {
+	Time       time.Time      `json:"time"`
+	Actor      string         `json:"actor"`
+	Action     string         `json:"action"`
+	TargetType string         `json:"target_type,omitempty"`
+	TargetID   string         `json:"target_id,omitempty"`
+	Outcome    string         `json:"outcome"`
+	IPAddress  string         `json:"ip_address,omitempty"`
+	Detail     map[string]any `json:"detail,omitempty"`
+}
+
+// Custody 是监管链记录：案件下的任务、批准采集的操作与访问过案件数据的操作
+type Custody struct {
+	Tasks     []Task  `json:"tasks"`
+	Approvals []Event `json:"approvals"`
+	Accessors []Event `json:"accessors"`
+}
+
+// Bundle 是一次导出的全部内容
+type Bundle struct {
+	BundleID   string
+	CaseID     int64
+	CaseName   string
+	ExportedBy string
+	ExportedAt time.Time
+	Reason     string
+	Redacted   bool
+	Messages   []Message
+	Custody    Custody
+}
+
+// File 是清单中的一个文件
+type File struct {
+	Name   string `json:"name"`
+	SHA256 string `json:"sha256"`
+	Size   int64  `json:"size"`
+}
+
+// Manifest 是导出包清单，签名覆盖其完整字节
+type Manifest struct {
+	Version      int       `json:"version"`
+	BundleID     string    `json:"bundle_id"`
+	CaseID       int64     `json:"case_id"`
+	CaseName     string    `json:"case_name"`
+	ExportedBy   string    `json:"exported_by"`
+	ExportedAt   time.Time `json:"exported_at"`
+	Reason       string    `json:"reason"`
+	Redacted     bool      `json:"redacted"`
+	MessageCount int       `json:"message_count"`
+	KeyID        string    `json:"key_id"`
+	// PublicKey 为签名公钥（base64），仅供参考；校验时应使用独立获取的可信公钥
+	PublicKey string `json:"public_key"`
+	Files     []File `json:"files"`
+}
+
+// Signer 是导出包签名密钥
+type Signer struct {
+	KeyID string
+	Key   ed25519.PrivateKey
+}
+
+// LoadSigner 读取 PKCS#8 PEM 格式的 Ed25519 私钥
+func LoadSigner(keyID, file string) (*Signer, error) {
+	if keyID == "" {
+		return nil, errors.New("export signing key id is required")
+	}
+	raw, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("export signing key: %w", err)
+	}
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, errors.New("export signing key: invalid PEM")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("export signing key: %w", err)
+	}
+	priv, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, errors.New("export signing key is not Ed25519")
+	}
+	return &Signer{KeyID: keyID, Key: priv}, nil
+}
+
+// PublicKey 返回签名公钥
+func (s *Signer) PublicKey() ed25519.PublicKey {
+	return s.Key.Public().(ed25519.PublicKey)
+}
+
+// LoadPublicKey 读取 PKIX PEM 格式的 Ed25519 公钥（openssl pkey -pubout 的输出）
+func LoadPublicKey(file string) (ed25519.PublicKey, error) {
+	raw, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, errors.New("invalid PEM public key")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	pub, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, errors.New("public key is not Ed25519")
+	}
+	return pub, nil
+}
@@ -0,0 +1,101 @@
+package evidence
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testBundle(t *testing.T) (*Signer, []byte) {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	s := &Signer{KeyID: "export-1", Key: priv}
+	var buf bytes.Buffer
+	_, _, err = Write(&buf, Bundle{
+		BundleID:   "b1",
+		CaseID:     7,
+		CaseName:   "case seven",
+		ExportedBy: "1",
+		ExportedAt: time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC),
+		Reason:     "hand-off to legal",
+		Messages: []Message{
+			{ID: 1, AgentID: 2, ConversationID: "wxid_a", Timestamp: time.Unix(1700000000, 0), Content: "hello"},
+			{ID: 2, AgentID: 2, Timestamp: time.Unix(1700000100, 0), Content: "=HYPERLINK(\"x\")"},
+		},
+		Custody: Custody{Tasks: []Task{{ID: 3, AgentID: 2, TaskType: "DUMP_WECHAT_DATA", Status: "completed"}}},
+	}, s)
+	require.NoError(t, err)
+	return s, buf.Bytes()
+}
+
+// rewrite 复制导出包并用 edit 修改指定文件内容
+func rewrite(t *testing.T, bundle []byte, name string, edit func([]byte) []byte) []byte {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(bundle), int64(len(bundle)))
+	require.NoError(t, err)
+	var out bytes.Buffer
+	zw := zip.NewWriter(&out)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+		data, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		if f.Name == name {
+			data = edit(data)
+		}
+		w, err := zw.Create(f.Name)
+		require.NoError(t, err)
+		_, err = w.Write(data)
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	return out.Bytes()
+}
+
+func TestWriteAndVerify(t *testing.T) {
+	s, bundle := testBundle(t)
+	res, err := Verify(bytes.NewReader(bundle), int64(len(bundle)), s.PublicKey())
+	require.NoError(t, err)
+	assert.True(t, res.Trusted)
+	assert.Equal(t, 2, res.Manifest.MessageCount)
+	assert.Len(t, res.Manifest.Files, 3)
+
+	res, err = Verify(bytes.NewReader(bundle), int64(len(bundle)), nil)
+	require.NoError(t, err)
+	assert.False(t, res.Trusted)
+}
+
+func TestVerify_DetectsTampering(t *testing.T) {
+	s, bundle := testBundle(t)
+	tampered := rewrite(t, bundle, FileMessagesJSONL, func(b []byte) []byte {
+		return bytes.Replace(b, []byte("hello"), []byte("hellO"), 1)
+	})
+	_, err := Verify(bytes.NewReader(tampered), int64(len(tampered)), s.PublicKey())
+	assert.ErrorContains(t, err, FileMessagesJSONL)
+
+	tampered = rewrite(t, bundle, FileManifest, func(b []byte) []byte {
+		return bytes.Replace(b, []byte("hand-off to legal"), []byte("hand-off to HR"), 1)
+	})
+	_, err = Verify(bytes.NewReader(tampered), int64(len(tampered)), s.PublicKey())
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+
+	other, _, _ := ed25519.GenerateKey(rand.Reader)
+	_, err = Verify(bytes.NewReader(bundle), int64(len(bundle)), other)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+}
+
+func TestRenderCSV_NeutralizesFormulas(t *testing.T) {
+	out, err := renderCSV([]Message{{ID: 1, Content: "=1+1"}, {ID: 2, Content: "fine"}})
+	require.NoError(t, err)
+	assert.True(t, strings.Contains(string(out), ",'=1+1\n"))
+	assert.True(t, strings.Contains(string(out), ",fine\n"))
+}
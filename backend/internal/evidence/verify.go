@@ -0,0 +1,91 @@
+package evidence
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrInvalidSignature 表示清单签名校验失败
+var ErrInvalidSignature = errors.New("manifest signature is invalid")
+
+// VerifyResult 是校验结果
+type VerifyResult struct {
+	Manifest Manifest
+	// Trusted 为 true 表示使用调用方提供的可信公钥验签；
+	// 为 false 表示仅用包内自带的公钥验签，只能证明包内自洽
+	Trusted bool
+}
+
+// Verify 校验导出包：验签清单，并确认包内文件与清单完全一致。trusted 为 nil 时使用包内公钥。
+func Verify(r io.ReaderAt, size int64, trusted ed25519.PublicKey) (VerifyResult, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return VerifyResult{}, err
+	}
+	contents := make(map[string][]byte, len(zr.File))
+	for _, f := range zr.File {
+		if _, dup := contents[f.Name]; dup {
+			return VerifyResult{}, fmt.Errorf("duplicate entry %q", f.Name)
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return VerifyResult{}, err
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return VerifyResult{}, fmt.Errorf("%s: %w", f.Name, err)
+		}
+		contents[f.Name] = data
+	}
+	manifest, ok := contents[FileManifest]
+	if !ok {
+		return VerifyResult{}, errors.New("bundle has no " + FileManifest)
+	}
+	sig, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(contents[FileSignature])))
+	if err != nil || len(sig) != ed25519.SignatureSize {
+		return VerifyResult{}, errors.New("bundle has no valid " + FileSignature)
+	}
+	var res VerifyResult
+	if err := json.Unmarshal(manifest, &res.Manifest); err != nil {
+		return VerifyResult{}, fmt.Errorf("%s: %w", FileManifest, err)
+	}
+	pub := trusted
+	res.Trusted = trusted != nil
+	if pub == nil {
+		raw, err := base64.StdEncoding.DecodeString(res.Manifest.PublicKey)
+		if err != nil || len(raw) != ed25519.PublicKeySize {
+			return VerifyResult{}, errors.New("manifest has no valid public key")
+		}
+		pub = raw
+	}
+	if !ed25519.Verify(pub, manifest, sig) {
+		return res, ErrInvalidSignature
+	}
+	listed := map[string]bool{FileManifest: true, FileSignature: true}
+	for _, f := range res.Manifest.Files {
+		listed[f.Name] = true
+		data, ok := contents[f.Name]
+		if !ok {
+			return res, fmt.Errorf("%s is listed in the manifest but missing", f.Name)
+		}
+		sum := sha256.Sum256(data)
+		if hex.EncodeToString(sum[:]) != f.SHA256 || int64(len(data)) != f.Size {
+			return res, fmt.Errorf("%s does not match its manifest hash", f.Name)
+		}
+	}
+	for name := range contents {
+		if !listed[name] {
+			return res, fmt.Errorf("%s is not listed in the manifest", name)
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,156 @@
+package evidence
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Write 把导出包写入 w，返回清单及清单的 SHA-256（用于登记与比对）
+func Write(w io.Writer, b Bundle, s *Signer) (Manifest, string, error) {
+	files := []struct {
+		name   string
+		render func() ([]byte, error)
+	}{
+		{FileMessagesJSONL, func() ([]byte, error) { return renderJSONL(b.Messages) }},
+		{FileMessagesCSV, func() ([]byte, error) { return renderCSV(b.Messages) }},
+		{FileCustody, func() ([]byte, error) { return renderCustody(b) }},
+	}
+	m := Manifest{
+		Version:      ManifestVersion,
+		BundleID:     b.BundleID,
+		CaseID:       b.CaseID,
+		CaseName:     b.CaseName,
+		ExportedBy:   b.ExportedBy,
+		ExportedAt:   b.ExportedAt.UTC(),
+		Reason:       b.Reason,
+		Redacted:     b.Redacted,
+		MessageCount: len(b.Messages),
+		KeyID:        s.KeyID,
+		PublicKey:    base64.StdEncoding.EncodeToString(s.PublicKey()),
+	}
+	zw := zip.NewWriter(w)
+	for _, f := range files {
+		data, err := f.render()
+		if err != nil {
+			return Manifest{}, "", err
+		}
+		if err := writeEntry(zw, f.name, data, m.ExportedAt); err != nil {
+			return Manifest{}, "", err
+		}
+		sum := sha256.Sum256(data)
+		m.Files = append(m.Files, File{Name: f.name, SHA256: hex.EncodeToString(sum[:]), Size: int64(len(data))})
+	}
+	manifest, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return Manifest{}, "", err
+	}
+	if err := writeEntry(zw, FileManifest, manifest, m.ExportedAt); err != nil {
+		return Manifest{}, "", err
+	}
+	sig := ed25519.Sign(s.Key, manifest)
+	if err := writeEntry(zw, FileSignature, []byte(base64.StdEncoding.EncodeToString(sig)+"\n"), m.ExportedAt); err != nil {
+		return Manifest{}, "", err
+	}
+	if err := zw.Close(); err != nil {
+		return Manifest{}, "", err
+	}
+	sum := sha256.Sum256(manifest)
+	return m, hex.EncodeToString(sum[:]), nil
+}
+
+func writeEntry(zw *zip.Writer, name string, data []byte, modified time.Time) error {
+	f, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modified})
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	return err
+}
+
+func renderJSONL(messages []Message) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	for _, m := range messages {
+		m.Timestamp = m.Timestamp.UTC()
+		if err := enc.Encode(m); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// renderCSV 供表格软件查看；以 = + - @ 开头的单元格前加单引号，防止被当作公式执行。
+// 原始内容以 messages.jsonl 为准。
+func renderCSV(messages []Message) ([]byte, error) {
+	var buf bytes.Buffer
+	cw := csv.NewWriter(&buf)
+	if err := cw.Write([]string{"id", "agent_id", "task_id", "conversation_id", "timestamp", "content"}); err != nil {
+		return nil, err
+	}
+	for _, m := range messages {
+		taskID := ""
+		if m.TaskID != nil {
+			taskID = strconv.FormatInt(*m.TaskID, 10)
+		}
+		if err := cw.Write([]string{
+			strconv.FormatInt(m.ID, 10),
+			strconv.Itoa(m.AgentID),
+			taskID,
+			csvSafe(m.ConversationID),
+			m.Timestamp.UTC().Format(time.RFC3339Nano),
+			csvSafe(m.Content),
+		}); err != nil {
+			return nil, err
+		}
+	}
+	cw.Flush()
+	return buf.Bytes(), cw.Error()
+}
+
+func csvSafe(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+func renderCustody(b Bundle) ([]byte, error) {
+	c := b.Custody
+	if c.Tasks == nil {
+		c.Tasks = []Task{}
+	}
+	if c.Approvals == nil {
+		c.Approvals = []Event{}
+	}
+	if c.Accessors == nil {
+		c.Accessors = []Event{}
+	}
+	doc := struct {
+		CaseID int64 `json:"case_id"`
+		Custody
+		Export struct {
+			BundleID   string    `json:"bundle_id"`
+			ExportedBy string    `json:"exported_by"`
+			ExportedAt time.Time `json:"exported_at"`
+			Reason     string    `json:"reason"`
+			Redacted   bool      `json:"redacted"`
+		} `json:"export"`
+	}{CaseID: b.CaseID, Custody: c}
+	doc.Export.BundleID = b.BundleID
+	doc.Export.ExportedBy = b.ExportedBy
+	doc.Export.ExportedAt = b.ExportedAt.UTC()
+	doc.Export.Reason = b.Reason
+	doc.Export.Redacted = b.Redacted
+	return json.MarshalIndent(doc, "", "  ")
+}
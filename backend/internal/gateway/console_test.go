@@ -0,0 +1,189 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"guardian-backend/internal/auth"
+	"guardian-backend/internal/database"
+	"guardian-backend/internal/redact"
+	"guardian-backend/internal/service"
+	api "guardian-backend/pkg/grpc/api/guardian/pkg/grpc/api"
+)
+
+// fakeConsoleDB 实现 ConsoleServer 所需的全部存储接口
+type fakeConsoleDB struct {
+	messages  []database.WechatMessageRecord
+	audits    []database.AuditEntry
+	destroyed []int64
+	filter    database.AuditFilter
+	logs      []database.AuditRecord
+}
+
+func (f *fakeConsoleDB) RecordAudit(_ context.Context, e database.AuditEntry) error {
+	f.audits = append(f.audits, e)
+	return nil
+}
+
+func (f *fakeConsoleDB) ListAgents(context.Context) ([]database.AgentInfo, error) {
+	return []database.AgentInfo{{ID: 1, Hostname: "pc-1", Status: "online"}, {ID: 2, Hostname: "pc-2", Status: "offline"}}, nil
+}
+
+func (f *fakeConsoleDB) CreateTaskForAgent(context.Context, int, string, database.TaskScope) (int64, error) {
+	return 0, database.ErrAgentNotFound
+}
+
+func (f *fakeConsoleDB) CreateDecommissionTask(context.Context, int, string) (int64, error) {
+	return 0, database.ErrAgentRetired
+}
+
+func (f *fakeConsoleDB) ListMessagesByAgent(context.Context, int, int, int) ([]database.WechatMessageRecord, error) {
+	return f.messages, nil
+}
+
+func (f *fakeConsoleDB) ListMessagesByTask(context.Context, int64, int, int) ([]database.WechatMessageRecord, error) {
+	return nil, database.ErrTaskNotFound
+}
+
+func (f *fakeConsoleDB) PurgeTaskMessages(context.Context, int64, string) (int64, error) {
+	return 0, nil
+}
+
+func (f *fakeConsoleDB) CreateCase(_ context.Context, name string) (database.Case, error) {
+	return database.Case{ID: 3, Name: name, Status: "open", CreatedAt: time.Unix(1700000000, 0)}, nil
+}
+
+func (f *fakeConsoleDB) ListCases(context.Context) ([]database.Case, error) {
+	return nil, nil
+}
+
+func (f *fakeConsoleDB) DestroyCaseKey(_ context.Context, caseID int64) error {
+	f.destroyed = append(f.destroyed, caseID)
+	return nil
+}
+
+func (f *fakeConsoleDB) ListAuditLogs(_ context.Context, filter database.AuditFilter) ([]database.AuditRecord, error) {
+	f.filter = filter
+	return f.logs, nil
+}
+
+func serveConsole(t *testing.T, db *fakeConsoleDB, role, method, target, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	phone, _ := redact.Builtin(redact.DetectorPhone)
+	srv := service.AuthorizeConsole(&service.ConsoleServer{
+		Agents:   db,
+		Cases:    db,
+		Audit:    db,
+		Redactor: redact.New([]redact.Detector{phone}, redact.Policy{UnmaskRoles: []string{"admin"}}),
+	})
+	mux := NewMux()
+	require.NoError(t, api.RegisterConsoleServiceHandlerServer(context.Background(), mux, srv))
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if role != "" {
+		ctx := auth.WithClientIP(auth.WithPrincipal(req.Context(), auth.Principal{UserID: "7", Role: role}), "10.0.0.5")
+		req = req.WithContext(ctx)
+	}
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestConsole_ListAgents(t *testing.T) {
+	rr := serveConsole(t, &fakeConsoleDB{}, "auditor", http.MethodGet, "/v2/agents?page=2&page_size=1", "")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.JSONEq(t, `{"agents":[{"id":2,"hostname":"pc-2","status":"offline"}]}`, rr.Body.String())
+
+	rr = serveConsole(t, &fakeConsoleDB{}, "", http.MethodGet, "/v2/agents", "")
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+}
+
+func TestConsole_ListMessages(t *testing.T) {
+	db := &fakeConsoleDB{messages: []database.WechatMessageRecord{{Content: "call me at 13800138000", Timestamp: time.UnixMilli(2000).UTC()}}}
+	rr := serveConsole(t, db, "auditor", http.MethodGet, "/v2/agents/1/messages", "")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.JSONEq(t, `{"messages":[{"content":"call me at 138****8000","timestamp":"1970-01-01T00:00:02Z","redacted":true}]}`, rr.Body.String())
+
+	// 普通查询同样留痕，供证据导出的监管链列出访问者
+	require.Len(t, db.audits, 1)
+	assert.Equal(t, database.AuditEntry{Actor: "7", Action: "messages.read", TargetType: "agent", TargetID: "1", Outcome: database.AuditOutcomeSuccess, IPAddress: "10.0.0.5",
+		Detail: map[string]any{"query": "", "page": 1, "messages": 1}}, db.audits[0])
+
+	// 无权查看明文的角色被拒绝并留痕
+	rr = serveConsole(t, db, "auditor", http.MethodGet, "/v2/agents/1/messages?unmask=true&reason=x", "")
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	require.Len(t, db.audits, 2)
+	assert.Equal(t, database.AuditEntry{Actor: "7", Action: "messages.unmask", TargetType: "agent", TargetID: "1", Outcome: database.AuditOutcomeDenied, IPAddress: "10.0.0.5"}, db.audits[1])
+
+	rr = serveConsole(t, db, "admin", http.MethodGet, "/v2/tasks/9/messages", "")
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.JSONEq(t, `{"code":"NOT_FOUND","message":"task not found"}`, rr.Body.String())
+}
+
+func TestConsole_DestroyCaseKeyRequiresAdmin(t *testing.T) {
+	db := &fakeConsoleDB{}
+	rr := serveConsole(t, db, "approver", http.MethodDelete, "/v2/cases/3/key", `{"confirm":"3","reason":"retention expired"}`)
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	assert.Empty(t, db.destroyed)
+
+	rr = serveConsole(t, db, "admin", http.MethodDelete, "/v2/cases/3/key", `{"confirm":"4","reason":"retention expired"}`)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"code":"VALIDATION_FAILED"`)
+
+	rr = serveConsole(t, db, "admin", http.MethodDelete, "/v2/cases/3/key", `{"confirm":"3","reason":"retention expired"}`)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.Equal(t, []int64{3}, db.destroyed)
+	require.Len(t, db.audits, 1)
+	assert.Equal(t, "case.key.destroy", db.audits[0].Action)
+}
+
+func TestConsole_ListAuditLogs(t *testing.T) {
+	db := &fakeConsoleDB{logs: []database.AuditRecord{
+		{ID: 12, AuditEntry: database.AuditEntry{Actor: "7", Action: "case.create", Outcome: "success", Detail: map[string]any{"reason": "x"}}, CreatedAt: time.Unix(1700000000, 0)},
+		{ID: 11, AuditEntry: database.AuditEntry{Actor: "7", Action: "task.create", Outcome: "success"}, CreatedAt: time.Unix(1700000000, 0)},
+	}}
+	rr := serveConsole(t, db, "approver", http.MethodGet, "/v2/audit-logs", "")
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+
+	rr = serveConsole(t, db, "auditor", http.MethodGet, "/v2/audit-logs?actor=7&page_size=1&page_token=20&since=2023-11-01T00:00:00Z", "")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.Equal(t, "7", db.filter.Actor)
+	assert.Equal(t, int64(20), db.filter.BeforeID)
+	// 多取一条用于判断是否有下一页
+	assert.Equal(t, 2, db.filter.Limit)
+	require.NotNil(t, db.filter.Since)
+	assert.Contains(t, rr.Body.String(), `"next_page_token":"12"`)
+	assert.Contains(t, rr.Body.String(), `"detail":{"reason":"x"}`)
+	assert.NotContains(t, rr.Body.String(), `"id":"11"`)
+
+	rr = serveConsole(t, db, "auditor", http.MethodGet, "/v2/audit-logs?page_token=abc", "")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestConsole_CreateTaskRequiresAdminOrApprover(t *testing.T) {
+	rr := serveConsole(t, &fakeConsoleDB{}, "auditor", http.MethodPost, "/v2/agents/1/tasks", `{}`)
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+
+	// 通过授权后由服务实现处理（fake 中 agent 不存在）
+	for _, role := range []string{"admin", "approver"} {
+		rr = serveConsole(t, &fakeConsoleDB{}, role, http.MethodPost, "/v2/agents/1/tasks", `{}`)
+		assert.Equal(t, http.StatusNotFound, rr.Code, role)
+	}
+}
+
+func TestConsole_DecommissionAgentRequiresAdmin(t *testing.T) {
+	for _, role := range []string{"auditor", "approver"} {
+		rr := serveConsole(t, &fakeConsoleDB{}, role, http.MethodPost, "/v2/agents/1/decommission", `{"data_disposition":"purge"}`)
+		assert.Equal(t, http.StatusForbidden, rr.Code, role)
+	}
+
+	// fake 中 agent 已退役
+	rr := serveConsole(t, &fakeConsoleDB{}, "admin", http.MethodPost, "/v2/agents/1/decommission", `{"data_disposition":"purge"}`)
+	assert.Equal(t, http.StatusConflict, rr.Code)
+}
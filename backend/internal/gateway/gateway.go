@@ -0,0 +1,49 @@
+// Package gateway 通过 gRPC-Gateway 把带 google.api.http 注解的服务转码为 REST 接口，挂载到 chi 路由。
+//
+// 服务以进程内方式注册（Register*HandlerServer），请求不经过网络、mTLS 与 gRPC 拦截器，
+// 因此认证、限流等由外层 chi 中间件负责，请求上下文（含已认证的控制台用户）原样传给服务实现；
+// 角色授权由服务实现自身完成（见 service.AuthorizeConsole）。
+package gateway
+
+import (
+	"context"
+	"net/http"
+
+	chimid "github.com/go-chi/chi/v5/middleware"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"guardian-backend/internal/interceptor"
+	"guardian-backend/pkg/httpx"
+)
+
+// NewMux 返回转码用的 ServeMux：JSON 字段使用 proto 原名（snake_case，与其他 HTTP 接口一致），
+// 错误响应与 chi 处理函数一样输出 httpx.ErrorResponse
+func NewMux() *runtime.ServeMux {
+	return runtime.NewServeMux(
+		runtime.WithMarshalerOption(runtime.MIMEWildcard, &runtime.JSONPb{
+			MarshalOptions:   protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true},
+			UnmarshalOptions: protojson.UnmarshalOptions{DiscardUnknown: true},
+		}),
+		runtime.WithErrorHandler(writeError),
+		runtime.WithMetadata(requestMetadata),
+	)
+}
+
+// writeError 把服务返回的错误（apperr 或 gRPC 状态）按统一错误模型输出，内部错误细节不外泄
+func writeError(_ context.Context, _ *runtime.ServeMux, _ runtime.Marshaler, w http.ResponseWriter, r *http.Request, err error) {
+	httpx.WriteAppError(w, r, err)
+}
+
+// requestMetadata 把 HTTP 请求 ID 放入 gRPC 元数据，服务实现可与 gRPC 调用一样读取
+func requestMetadata(_ context.Context, r *http.Request) metadata.MD {
+	id := r.Header.Get("X-Request-Id")
+	if id == "" {
+		id = chimid.GetReqID(r.Context())
+	}
+	if id == "" {
+		return nil
+	}
+	return metadata.Pairs(interceptor.RequestIDMetadataKey, id)
+}
@@ -0,0 +1,134 @@
+package gateway
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+
+	"guardian-backend/internal/auth"
+	"guardian-backend/internal/database"
+	"guardian-backend/internal/interceptor"
+	"guardian-backend/internal/service"
+	"guardian-backend/pkg/apperr"
+	api "guardian-backend/pkg/grpc/api/guardian/pkg/grpc/api"
+)
+
+type ctxKey struct{}
+
+type fakeDataServer struct {
+	api.UnimplementedDataServiceServer
+	err       error
+	got       *api.UploadMessagesRequest
+	principal any
+	requestID []string
+}
+
+func (f *fakeDataServer) UploadMessages(ctx context.Context, req *api.UploadMessagesRequest) (*api.UploadMessagesResponse, error) {
+	f.got = req
+	f.principal = ctx.Value(ctxKey{})
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		f.requestID = md.Get(interceptor.RequestIDMetadataKey)
+	}
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &api.UploadMessagesResponse{Success: true, AcceptedCount: int32(len(req.Messages)), BatchId: 9}, nil
+}
+
+func serve(t *testing.T, srv api.DataServiceServer, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	mux := NewMux()
+	require.NoError(t, api.RegisterDataServiceHandlerServer(context.Background(), mux, srv))
+	req := httptest.NewRequest(http.MethodPost, "/v1/messages/7", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Request-Id", "req-1")
+	req = req.WithContext(context.WithValue(req.Context(), ctxKey{}, "admin"))
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestGateway_TranscodesUpload(t *testing.T) {
+	srv := &fakeDataServer{}
+	rr := serve(t, srv, `{"task_id":"5","messages":[{"content":"hi","conversation_id":"wxid_a","timestamp":"2024-01-01T00:00:00Z"}]}`)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.Equal(t, int32(7), srv.got.AgentId)
+	assert.Equal(t, "5", srv.got.TaskId)
+	assert.Equal(t, "wxid_a", srv.got.Messages[0].ConversationId)
+	// 请求上下文与请求 ID 传入服务实现
+	assert.Equal(t, "admin", srv.principal)
+	assert.Equal(t, []string{"req-1"}, srv.requestID)
+	assert.JSONEq(t, `{"success":true,"accepted_count":1,"discarded_count":0,"batch_id":"9","request_sha256":""}`, rr.Body.String())
+}
+
+func TestGateway_ErrorResponses(t *testing.T) {
+	rr := serve(t, &fakeDataServer{err: apperr.New(apperr.KindFailedPrecondition, "TASK_NOT_RUNNING", "task is not running").WithDetail("task_id", "5")}, `{"task_id":"5"}`)
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	assert.JSONEq(t, `{"code":"TASK_NOT_RUNNING","message":"task is not running","details":{"task_id":"5"},"requestId":"req-1"}`, rr.Body.String())
+
+	rr = serve(t, &fakeDataServer{err: apperr.Internal(assert.AnError)}, `{}`)
+	assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	assert.NotContains(t, rr.Body.String(), assert.AnError.Error())
+
+	rr = serve(t, &fakeDataServer{}, `{not json`)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"code":"BAD_REQUEST"`)
+}
+
+func TestGateway_UploadWithoutCertificateRequiresAdmin(t *testing.T) {
+	// 连接池惰性建连，下列请求均在访问数据库前被拒绝
+	pool, err := pgxpool.New(context.Background(), "postgres://guardian@127.0.0.1:1/guardian")
+	require.NoError(t, err)
+	defer pool.Close()
+	srv := &service.DataServer{DB: pool}
+	mux := NewMux()
+	require.NoError(t, api.RegisterDataServiceHandlerServer(context.Background(), mux, srv))
+
+	for _, tc := range []struct {
+		principal *auth.Principal
+		code      int
+		want      string
+	}{
+		{nil, http.StatusForbidden, `"code":"CREDENTIAL_REQUIRED"`},
+		{&auth.Principal{UserID: "7", Role: "auditor"}, http.StatusForbidden, `"message":"insufficient role"`},
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/v1/messages/7", strings.NewReader(`{"task_id":"5"}`))
+		if tc.principal != nil {
+			req = req.WithContext(auth.WithPrincipal(req.Context(), *tc.principal))
+		}
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		assert.Equal(t, tc.code, rr.Code)
+		assert.Contains(t, rr.Body.String(), tc.want)
+	}
+}
+
+func TestDataServer_RejectsCertificateIssuedForAnotherAgent(t *testing.T) {
+	pool, err := pgxpool.New(context.Background(), "postgres://guardian@127.0.0.1:1/guardian")
+	require.NoError(t, err)
+	defer pool.Close()
+	srv := &service.DataServer{DB: pool}
+
+	// CA 签发给 agent 2 的证书不能以 agent 3 的身份上传，校验在访问数据库前完成
+	cert := &x509.Certificate{Raw: []byte("agent-2-cert"), Subject: pkix.Name{CommonName: service.AgentCommonName(2)}}
+	ctx := peer.NewContext(context.Background(), &peer.Peer{
+		Addr:     &net.TCPAddr{IP: net.ParseIP("10.0.0.5"), Port: 4242},
+		AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}},
+	})
+	_, err = srv.UploadMessages(ctx, &api.UploadMessagesRequest{AgentId: 3, TaskId: "5"})
+	require.Error(t, err)
+	assert.ErrorIs(t, err, database.ErrCredentialMismatch)
+}
@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"context"
+	"net"
+	"net/http"
+
+	"guardian-backend/internal/auth"
+	"guardian-backend/internal/database"
+	"guardian-backend/pkg/logger"
+)
+
+// auditor 是写审计日志所需的最小接口
+type auditor interface {
+	RecordAudit(ctx context.Context, e database.AuditEntry) error
+}
+
+// recordAudit 补全操作者与来源 IP 后写入审计日志；写入失败只记录错误日志，不影响响应
+func recordAudit(r *http.Request, a auditor, e database.AuditEntry) {
+	if e.Actor == "" {
+		e.Actor = PrincipalFrom(r.Context()).UserID
+	}
+	if e.Actor == "" {
+		e.Actor = "anonymous"
+	}
+	if e.IPAddress == "" {
+		e.IPAddress = clientIP(r)
+	}
+	if e.Outcome == "" {
+		e.Outcome = database.AuditOutcomeSuccess
+	}
+	if err := a.RecordAudit(r.Context(), e); err != nil {
+		logger.From(r.Context()).Error("Failed to record audit log", "error", err, "action", e.Action, "outcome", e.Outcome)
+	}
+}
+
+// serviceContext 返回调用 service 层时使用的 context：补全来源 IP，使 service 写入的审计日志与 recordAudit 一致
+func serviceContext(r *http.Request) context.Context {
+	if auth.ClientIPFrom(r.Context()) != "" {
+		return r.Context()
+	}
+	return auth.WithClientIP(r.Context(), clientIP(r))
+}
+
+// clientIP 返回请求来源 IP（不含端口）
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
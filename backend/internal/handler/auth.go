@@ -1,17 +1,63 @@
 package handler
 
 import (
+    "context"
+    "crypto/rand"
+    "crypto/sha256"
+    "encoding/base64"
+    "encoding/hex"
     "encoding/json"
+    "errors"
     "net/http"
+    "strconv"
     "time"
+
+    "github.com/go-chi/chi/v5"
     "github.com/golang-jwt/jwt/v5"
+    "guardian-backend/internal/auth"
+    "guardian-backend/internal/database"
+    "guardian-backend/internal/ratelimit"
     "guardian-backend/pkg/httpx"
+    "guardian-backend/pkg/logger"
 )
 
+// SessionStore 是会话持久化所需的接口
+type SessionStore interface {
+    auditor
+    CreateSession(ctx context.Context, s database.Session) error
+    RotateSession(ctx context.Context, presentedHash, newHash string, expiresAt time.Time) (database.Session, error)
+    RevokeSession(ctx context.Context, sessionID, reason string) error
+    RevokeUserSessions(ctx context.Context, userID, reason string) (int64, error)
+}
+
+// UserStore 是控制台账户与 MFA 状态持久化所需的接口
+type UserStore interface {
+    GetUserByUsername(ctx context.Context, username string) (database.User, error)
+    GetUserByID(ctx context.Context, id int) (database.User, error)
+    SetPendingMFASecret(ctx context.Context, userID int, secret string) error
+    ConsumeTOTPStep(ctx context.Context, userID int, step int64) (bool, error)
+    ActivateMFA(ctx context.Context, userID int, recoveryCodeHashes []string) error
+    UseRecoveryCode(ctx context.Context, userID int, codeHash string) (bool, error)
+    ResetMFA(ctx context.Context, userID int) error
+    UpsertOIDCUser(ctx context.Context, issuer, subject, username, email, role string) (database.User, error)
+}
+
 type AuthHandler struct {
-	JWTSecret string
-    AdminUsername string
-    AdminPassword string
+	Keys *auth.Keyring
+    // AccessTokenTTL 为访问令牌有效期，应较短；RefreshTokenTTL 为刷新令牌（会话）有效期
+    AccessTokenTTL  time.Duration
+    RefreshTokenTTL time.Duration
+    Sessions        SessionStore
+    Users           UserStore
+    // MFARequiredRoles 中的角色必须完成 TOTP 绑定才能登录
+    MFARequiredRoles []string
+    // OIDC 非空时启用单点登录；PostLoginRedirect 为 SSO 完成后携带令牌跳转的前端地址
+    OIDC              *auth.OIDCProvider
+    PostLoginRedirect string
+    // UsernameLimiter 按用户名限流；Lockout 在连续失败后渐进锁定用户名与来源 IP，锁定写入 SecurityEvents。均可为空
+    UsernameLimiter *ratelimit.Limiter
+    Lockout         *ratelimit.Lockout
+    SecurityEvents  SecurityEventRecorder
 }
 
 type loginRequest struct {
@@ -19,30 +65,247 @@ type loginRequest struct {
 	Password string `json:"password"`
 }
 
+type refreshRequest struct {
+    RefreshToken string `json:"refresh_token"`
+}
+
 type loginResponse struct {
-	Token string `json:"token"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	// RecoveryCodes 仅在首次完成 MFA 绑定时返回一次
+	RecoveryCodes []string `json:"recovery_codes,omitempty"`
+}
+
+// mfaChallengeResponse 表示口令已通过，需以 mfa_token 完成第二步验证
+type mfaChallengeResponse struct {
+	MFARequired           bool   `json:"mfa_required"`
+	MFAEnrollmentRequired bool   `json:"mfa_enrollment_required"`
+	MFAToken              string `json:"mfa_token"`
+	ExpiresIn             int64  `json:"expires_in"`
 }
 
+// dummyPasswordHash 用于用户不存在时仍执行一次 bcrypt 比较，避免通过响应时间枚举用户名
+var dummyPasswordHash, _ = auth.HashPassword("guardian-dummy-password")
+
 func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	var req loginRequest
     if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
         httpx.WriteError(w, r, http.StatusBadRequest, "BAD_REQUEST", "invalid request body")
         return
     }
-    if req.Username != h.AdminUsername || req.Password != h.AdminPassword {
+    if !h.allowLoginAttempt(w, r, req.Username) {
+        return
+    }
+    user, err := h.Users.GetUserByUsername(r.Context(), req.Username)
+    if err != nil && !errors.Is(err, database.ErrUserNotFound) {
+        logger.From(r.Context()).Error("Failed to load user", "error", err)
+        httpx.WriteError(w, r, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to login")
+        return
+    }
+    // 用户不存在或为 SSO 用户（没有本地口令）时仍执行一次 bcrypt 比较
+    hash := user.PasswordHash
+    if err != nil || hash == "" {
+        hash = dummyPasswordHash
+    }
+    if !auth.CheckPassword(hash, req.Password) || hash == dummyPasswordHash {
+        h.loginFailed(r, req.Username, "password")
         httpx.WriteError(w, r, http.StatusUnauthorized, "UNAUTHORIZED", "invalid credentials")
         return
     }
-	claims := jwt.MapClaims{
-		"sub": "1",
-		"role": "admin",
-		"exp": time.Now().Add(24 * time.Hour).Unix(),
-	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenStr, err := token.SignedString([]byte(h.JWTSecret))
+    // 已绑定 MFA 或角色策略要求 MFA 时，口令只是第一步
+    if user.MFAEnabled() || h.mfaRequired(user.Role) {
+        mfaToken, err := h.Keys.Sign(jwt.MapClaims{
+            "sub":       strconv.Itoa(user.ID),
+            "token_use": tokenUseMFA,
+            "exp":       time.Now().Add(mfaTokenTTL).Unix(),
+        })
+        if err != nil {
+            httpx.WriteError(w, r, http.StatusInternalServerError, "TOKEN_ERROR", "failed to sign token")
+            return
+        }
+        httpx.WriteJSON(w, http.StatusOK, mfaChallengeResponse{
+            MFARequired:           true,
+            MFAEnrollmentRequired: !user.MFAEnabled(),
+            MFAToken:              mfaToken,
+            ExpiresIn:             int64(mfaTokenTTL.Seconds()),
+        })
+        return
+    }
+    h.startSession(w, r, user, nil)
+}
+
+// startSession 创建会话并返回访问令牌与刷新令牌
+func (h *AuthHandler) startSession(w http.ResponseWriter, r *http.Request, user database.User, recoveryCodes []string) {
+    resp, err := h.newSession(r, user)
+    if err != nil {
+        logger.From(r.Context()).Error("Failed to create session", "error", err)
+        httpx.WriteError(w, r, http.StatusInternalServerError, "TOKEN_ERROR", "failed to create session")
+        return
+    }
+    h.loginSucceeded(user.Username)
+    resp.RecoveryCodes = recoveryCodes
+    httpx.WriteJSON(w, http.StatusOK, resp)
+}
+
+// newSession 为用户创建会话并签发令牌
+func (h *AuthHandler) newSession(r *http.Request, user database.User) (loginResponse, error) {
+    refreshToken, refreshHash, err := newRefreshToken()
+    if err != nil {
+        return loginResponse{}, err
+    }
+    sess := database.Session{
+        ID:               newSessionID(),
+        UserID:           strconv.Itoa(user.ID),
+        Role:             user.Role,
+        RefreshTokenHash: refreshHash,
+        UserAgent:        r.UserAgent(),
+        IPAddress:        clientIP(r),
+        ExpiresAt:        time.Now().Add(h.refreshTTL()),
+    }
+    if err := h.Sessions.CreateSession(r.Context(), sess); err != nil {
+        return loginResponse{}, err
+    }
+    return h.issueTokens(sess, refreshToken)
+}
+
+func (h *AuthHandler) mfaRequired(role string) bool {
+    for _, r := range h.MFARequiredRoles {
+        if r == role {
+            return true
+        }
+    }
+    return false
+}
+
+// Refresh 以刷新令牌换取新的访问令牌，同时轮换刷新令牌
+func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+    var req refreshRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+        httpx.WriteError(w, r, http.StatusBadRequest, "BAD_REQUEST", "refresh_token is required")
+        return
+    }
+    refreshToken, refreshHash, err := newRefreshToken()
+    if err != nil {
+        httpx.WriteError(w, r, http.StatusInternalServerError, "TOKEN_ERROR", "failed to rotate session")
+        return
+    }
+    sess, err := h.Sessions.RotateSession(r.Context(), hashToken(req.RefreshToken), refreshHash, time.Now().Add(h.refreshTTL()))
+    if err != nil {
+        switch {
+        case errors.Is(err, database.ErrRefreshTokenReused):
+            logger.From(r.Context()).Warn("Refresh token reuse detected, session revoked")
+            recordAudit(r, h.Sessions, database.AuditEntry{Action: "session.refresh", Outcome: database.AuditOutcomeDenied, Detail: map[string]any{"reason": "refresh_reuse"}})
+            httpx.WriteError(w, r, http.StatusUnauthorized, "SESSION_REVOKED", "refresh token reuse detected")
+        case errors.Is(err, database.ErrSessionNotFound), errors.Is(err, database.ErrSessionInactive):
+            httpx.WriteError(w, r, http.StatusUnauthorized, "UNAUTHORIZED", "invalid or expired refresh token")
+        default:
+            logger.From(r.Context()).Error("Failed to rotate session", "error", err)
+            httpx.WriteError(w, r, http.StatusInternalServerError, "TOKEN_ERROR", "failed to rotate session")
+        }
+        return
+    }
+    resp, err := h.issueTokens(sess, refreshToken)
     if err != nil {
         httpx.WriteError(w, r, http.StatusInternalServerError, "TOKEN_ERROR", "failed to sign token")
         return
     }
-    httpx.WriteJSON(w, http.StatusOK, loginResponse{Token: tokenStr})
+    httpx.WriteJSON(w, http.StatusOK, resp)
+}
+
+// Logout 吊销当前访问令牌所属的会话
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+    p := PrincipalFrom(r.Context())
+    if err := h.Sessions.RevokeSession(r.Context(), p.SessionID, database.RevokeReasonLogout); err != nil {
+        logger.From(r.Context()).Error("Failed to revoke session", "error", err)
+        httpx.WriteError(w, r, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to logout")
+        return
+    }
+    recordAudit(r, h.Sessions, database.AuditEntry{Action: "session.logout", TargetType: "session", TargetID: p.SessionID})
+    w.WriteHeader(http.StatusNoContent)
+}
+
+// RevokeUserSessions 管理员吊销指定用户的全部会话，其访问令牌随即失效
+func (h *AuthHandler) RevokeUserSessions(w http.ResponseWriter, r *http.Request) {
+    userID := chi.URLParam(r, "userID")
+    if userID == "" {
+        httpx.WriteError(w, r, http.StatusBadRequest, "BAD_REQUEST", "user id is required")
+        return
+    }
+    n, err := h.Sessions.RevokeUserSessions(r.Context(), userID, database.RevokeReasonAdmin)
+    if err != nil {
+        logger.From(r.Context()).Error("Failed to revoke user sessions", "error", err, "user_id", userID)
+        httpx.WriteError(w, r, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to revoke sessions")
+        return
+    }
+    recordAudit(r, h.Sessions, database.AuditEntry{
+        Action:     "user.sessions.revoke",
+        TargetType: "user",
+        TargetID:   userID,
+        Detail:     map[string]any{"revoked": n},
+    })
+    httpx.WriteJSON(w, http.StatusOK, map[string]any{"revoked": n})
+}
+
+// JWKS 公开 EdDSA 验签公钥，供其他内部服务校验控制台令牌
+func (h *AuthHandler) JWKS(w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Cache-Control", "public, max-age=300")
+    httpx.WriteJSON(w, http.StatusOK, h.Keys.JWKS())
+}
+
+// issueTokens 为会话签发访问令牌，连同刷新令牌组成登录响应
+func (h *AuthHandler) issueTokens(sess database.Session, refreshToken string) (loginResponse, error) {
+    ttl := h.accessTTL()
+	tokenStr, err := h.Keys.Sign(jwt.MapClaims{
+		"sub": sess.UserID,
+		"role": sess.Role,
+		"sid": sess.ID,
+		"token_use": tokenUseAccess,
+		"exp": time.Now().Add(ttl).Unix(),
+	})
+    if err != nil {
+        return loginResponse{}, err
+    }
+    return loginResponse{
+        Token:        tokenStr,
+        RefreshToken: refreshToken,
+        TokenType:    "Bearer",
+        ExpiresIn:    int64(ttl.Seconds()),
+    }, nil
+}
+
+func (h *AuthHandler) accessTTL() time.Duration {
+    if h.AccessTokenTTL <= 0 {
+        return 15 * time.Minute
+    }
+    return h.AccessTokenTTL
+}
+
+func (h *AuthHandler) refreshTTL() time.Duration {
+    if h.RefreshTokenTTL <= 0 {
+        return 7 * 24 * time.Hour
+    }
+    return h.RefreshTokenTTL
+}
+
+// newRefreshToken 生成随机刷新令牌及其哈希；数据库只保存哈希
+func newRefreshToken() (token, hash string, err error) {
+    b := make([]byte, 32)
+    if _, err := rand.Read(b); err != nil {
+        return "", "", err
+    }
+    token = base64.RawURLEncoding.EncodeToString(b)
+    return token, hashToken(token), nil
+}
+
+func hashToken(token string) string {
+    sum := sha256.Sum256([]byte(token))
+    return hex.EncodeToString(sum[:])
+}
+
+func newSessionID() string {
+    b := make([]byte, 16)
+    _, _ = rand.Read(b)
+    return hex.EncodeToString(b)
 }
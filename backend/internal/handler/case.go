@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"guardian-backend/internal/database"
+	"guardian-backend/internal/service"
+	api "guardian-backend/pkg/grpc/api/guardian/pkg/grpc/api"
+	"guardian-backend/pkg/httpx"
+)
+
+// CaseHandler 管理调查案件及其数据密钥；业务逻辑由 service.ConsoleServer 实现
+type CaseHandler struct {
+	DB interface {
+		auditor
+		CreateCase(ctx context.Context, name string) (database.Case, error)
+		ListCases(ctx context.Context) ([]database.Case, error)
+		DestroyCaseKey(ctx context.Context, caseID int64) error
+	}
+}
+
+func (h *CaseHandler) console() *service.ConsoleServer {
+	return &service.ConsoleServer{Cases: h.DB}
+}
+
+type caseDTO struct {
+	ID             int64  `json:"id"`
+	Name           string `json:"name"`
+	DefaultAgentID *int   `json:"default_agent_id,omitempty"`
+	Status         string `json:"status"`
+	CreatedAt      int64  `json:"created_at"`
+	KeyDestroyedAt *int64 `json:"key_destroyed_at,omitempty"`
+}
+
+func toCaseDTO(c *api.Case) caseDTO {
+	dto := caseDTO{ID: c.Id, Name: c.Name, Status: c.Status, CreatedAt: c.CreatedAt.AsTime().UnixMilli()}
+	if c.DefaultAgentId != nil {
+		id := int(*c.DefaultAgentId)
+		dto.DefaultAgentID = &id
+	}
+	if c.KeyDestroyedAt != nil {
+		ms := c.KeyDestroyedAt.AsTime().UnixMilli()
+		dto.KeyDestroyedAt = &ms
+	}
+	return dto
+}
+
+// List 查询全部案件
+func (h *CaseHandler) List(w http.ResponseWriter, r *http.Request) {
+	resp, err := h.console().ListCases(serviceContext(r), &api.ListCasesRequest{})
+	if err != nil {
+		httpx.WriteAppError(w, r, err)
+		return
+	}
+	out := make([]caseDTO, 0, len(resp.Cases))
+	for _, c := range resp.Cases {
+		out = append(out, toCaseDTO(c))
+	}
+	httpx.WriteJSON(w, http.StatusOK, out)
+}
+
+// Create 新建案件
+func (h *CaseHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var payload CreateCasePayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		httpx.WriteError(w, r, http.StatusBadRequest, "BAD_REQUEST", "invalid request body")
+		return
+	}
+	c, err := h.console().CreateCase(serviceContext(r), &api.CreateCaseRequest{Name: payload.Name})
+	if err != nil {
+		httpx.WriteAppError(w, r, err)
+		return
+	}
+	httpx.WriteJSON(w, http.StatusCreated, toCaseDTO(c))
+}
+
+// DestroyKey 销毁案件数据密钥（加密擦除），该案件已采集的数据从此不可恢复
+func (h *CaseHandler) DestroyKey(w http.ResponseWriter, r *http.Request) {
+	caseID, err := strconv.ParseInt(chi.URLParam(r, "caseID"), 10, 64)
+	if err != nil {
+		httpx.WriteError(w, r, http.StatusBadRequest, "BAD_REQUEST", "invalid case id")
+		return
+	}
+	var payload DestroyCaseKeyPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		httpx.WriteError(w, r, http.StatusBadRequest, "BAD_REQUEST", "invalid request body")
+		return
+	}
+	if _, err := h.console().DestroyCaseKey(serviceContext(r), &api.DestroyCaseKeyRequest{CaseId: caseID, Confirm: payload.Confirm, Reason: payload.Reason}); err != nil {
+		httpx.WriteAppError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
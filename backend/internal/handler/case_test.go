@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"guardian-backend/internal/database"
+)
+
+type mockCaseDB struct {
+	mock.Mock
+}
+
+func (m *mockCaseDB) RecordAudit(ctx context.Context, e database.AuditEntry) error {
+	return m.Called(ctx, e).Error(0)
+}
+
+func (m *mockCaseDB) CreateCase(ctx context.Context, name string) (database.Case, error) {
+	args := m.Called(ctx, name)
+	return args.Get(0).(database.Case), args.Error(1)
+}
+
+func (m *mockCaseDB) ListCases(ctx context.Context) ([]database.Case, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]database.Case), args.Error(1)
+}
+
+func (m *mockCaseDB) DestroyCaseKey(ctx context.Context, caseID int64) error {
+	return m.Called(ctx, caseID).Error(0)
+}
+
+func destroyCaseKey(h *CaseHandler, caseID, body string) *httptest.ResponseRecorder {
+	router := chi.NewRouter()
+	router.Delete("/v1/cases/{caseID}/key", h.DestroyKey)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodDelete, "/v1/cases/"+caseID+"/key", strings.NewReader(body)))
+	return rr
+}
+
+func TestCaseHandler_DestroyKey_Audited(t *testing.T) {
+	db := new(mockCaseDB)
+	db.On("DestroyCaseKey", mock.Anything, int64(7)).Return(nil)
+	db.On("RecordAudit", mock.Anything, mock.MatchedBy(func(e database.AuditEntry) bool {
+		return e.Action == "case.key.destroy" && e.TargetID == "7" && e.Detail["reason"] == "court order"
+	})).Return(nil)
+
+	rr := destroyCaseKey(&CaseHandler{DB: db}, "7", `{"confirm":7,"reason":"court order"}`)
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	db.AssertExpectations(t)
+}
+
+func TestCaseHandler_DestroyKey_RequiresConfirmation(t *testing.T) {
+	db := new(mockCaseDB)
+	rr := destroyCaseKey(&CaseHandler{DB: db}, "7", `{"confirm":8,"reason":"typo"}`)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	db.AssertNotCalled(t, "DestroyCaseKey", mock.Anything, mock.Anything)
+}
+
+func TestCaseHandler_DestroyKey_AlreadyDestroyed(t *testing.T) {
+	db := new(mockCaseDB)
+	db.On("DestroyCaseKey", mock.Anything, int64(7)).Return(database.ErrCaseKeyDestroyed)
+	rr := destroyCaseKey(&CaseHandler{DB: db}, "7", `{"confirm":7,"reason":"again"}`)
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	assert.Contains(t, rr.Body.String(), "CASE_KEY_DESTROYED")
+}
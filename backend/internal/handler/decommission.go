@@ -0,0 +1,36 @@
+package handler
+
+import (
+    "encoding/json"
+    "errors"
+    "net/http"
+
+    "guardian-backend/pkg/apperr"
+    api "guardian-backend/pkg/grpc/api/guardian/pkg/grpc/api"
+    "guardian-backend/pkg/httpx"
+)
+
+// Decommission 为 agent 下发卸载任务；agent 确认卸载后服务端将其退役并吊销凭据，
+// 其数据按管理员选择保留（受保留期约束）或清除。
+func (h *TaskHandler) Decommission(w http.ResponseWriter, r *http.Request) {
+    agentID, ok := r.Context().Value(AgentIDKey).(int)
+    if !ok {
+        httpx.WriteAppError(w, r, apperr.Internal(errors.New("agent id missing from request context")))
+        return
+    }
+    var payload DecommissionPayload
+    if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+        httpx.WriteError(w, r, http.StatusBadRequest, "BAD_REQUEST", "invalid request body")
+        return
+    }
+    resp, err := h.console().DecommissionAgent(serviceContext(r), &api.DecommissionAgentRequest{
+        AgentId:         int32(agentID),
+        DataDisposition: payload.DataDisposition,
+        Reason:          payload.Reason,
+    })
+    if err != nil {
+        httpx.WriteAppError(w, r, err)
+        return
+    }
+    httpx.WriteJSON(w, http.StatusAccepted, map[string]any{"status": "decommission scheduled", "task_id": resp.TaskId})
+}
@@ -0,0 +1,135 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"guardian-backend/internal/database"
+	"guardian-backend/pkg/httpx"
+	"guardian-backend/pkg/logger"
+)
+
+// defaultKeepAlive 为未配置时的心跳间隔，须短于常见代理的空闲超时
+const defaultKeepAlive = 25 * time.Second
+
+// EventsHandler 以 Server-Sent Events 向控制台推送 agent、任务与入库状态变化
+type EventsHandler struct {
+	Hub interface {
+		Subscribe() (<-chan database.LiveEvent, func())
+	}
+	// Sessions 用于在连接期间定期确认会话未被吊销，吊销后断开
+	Sessions SessionChecker
+	// TopicRoles 为各主题（agent / task / ingestion）允许接收的角色；未列出的主题不推送
+	TopicRoles map[string][]string
+	KeepAlive  time.Duration
+}
+
+// Stream 推送调用者角色可见的事件；?topics=agent,task 可进一步限定主题。
+// 事件不做持久化，断线重连后客户端应重新拉取列表。
+func (h *EventsHandler) Stream(w http.ResponseWriter, r *http.Request) {
+	principal := PrincipalFrom(r.Context())
+	topics := h.allowedTopics(principal.Role)
+	if v := r.URL.Query().Get("topics"); v != "" {
+		requested := map[string]bool{}
+		for _, t := range strings.Split(v, ",") {
+			requested[strings.TrimSpace(t)] = true
+		}
+		for t := range topics {
+			if !requested[t] {
+				delete(topics, t)
+			}
+		}
+	}
+	if len(topics) == 0 {
+		httpx.WriteError(w, r, http.StatusForbidden, "FORBIDDEN", "no event topics available for this role")
+		return
+	}
+
+	events, cancel := h.Hub.Subscribe()
+	defer cancel()
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	// 关闭 nginx 等反向代理的响应缓冲
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	// 建议客户端断线 5 秒后重连
+	if _, err := fmt.Fprint(w, "retry: 5000\n\n"); err != nil {
+		return
+	}
+	if err := rc.Flush(); err != nil {
+		logger.From(r.Context()).Error("SSE stream cannot be flushed", "error", err)
+		return
+	}
+
+	keepAlive := h.KeepAlive
+	if keepAlive <= 0 {
+		keepAlive = defaultKeepAlive
+	}
+	ticker := time.NewTicker(keepAlive)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case ev, ok := <-events:
+			if !ok {
+				// 消费过慢被断开，由客户端重连
+				return
+			}
+			if !topics[topicOf(ev.Type)] {
+				continue
+			}
+			data, err := json.Marshal(ev)
+			if err != nil {
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.Type, data); err != nil {
+				return
+			}
+			if err := rc.Flush(); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if h.Sessions != nil {
+				active, err := h.Sessions.IsSessionActive(r.Context(), principal.SessionID)
+				if err != nil {
+					logger.From(r.Context()).Error("Failed to check session", "error", err)
+				} else if !active {
+					return
+				}
+			}
+			if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+			if err := rc.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// allowedTopics 返回角色可接收的主题集合
+func (h *EventsHandler) allowedTopics(role string) map[string]bool {
+	topics := map[string]bool{}
+	for topic, roles := range h.TopicRoles {
+		for _, allowed := range roles {
+			if allowed == role {
+				topics[topic] = true
+				break
+			}
+		}
+	}
+	return topics
+}
+
+// topicOf 取事件类型中 "." 之前的部分，如 task.status → task
+func topicOf(eventType string) string {
+	topic, _, _ := strings.Cut(eventType, ".")
+	return topic
+}
@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"guardian-backend/internal/database"
+)
+
+// closedHub 交付预置事件后关闭通道，使 Stream 在事件写完后返回
+type closedHub struct {
+	events []database.LiveEvent
+}
+
+func (h *closedHub) Subscribe() (<-chan database.LiveEvent, func()) {
+	ch := make(chan database.LiveEvent, len(h.events))
+	for _, ev := range h.events {
+		ch <- ev
+	}
+	close(ch)
+	return ch, func() {}
+}
+
+func streamEvents(h *EventsHandler, role, query string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/v1/events"+query, nil)
+	req = req.WithContext(context.WithValue(req.Context(), PrincipalKey, Principal{UserID: "1", Role: role}))
+	rr := httptest.NewRecorder()
+	h.Stream(rr, req)
+	return rr
+}
+
+func TestEventsHandler_FiltersByRole(t *testing.T) {
+	h := &EventsHandler{
+		Hub: &closedHub{events: []database.LiveEvent{
+			{Type: database.LiveAgentStatus, AgentID: 1, Data: map[string]any{"status": "offline"}},
+			{Type: database.LiveIngestionBatch, AgentID: 1, TaskID: 5, Data: map[string]any{"accepted": 3}},
+			{Type: database.LiveTaskStatus, AgentID: 1, TaskID: 5, Data: map[string]any{"status": "completed"}},
+		}},
+		TopicRoles: map[string][]string{
+			"agent":     {"admin", "auditor"},
+			"task":      {"admin", "auditor"},
+			"ingestion": {"admin"},
+		},
+	}
+
+	rr := streamEvents(h, "auditor", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "text/event-stream", rr.Header().Get("Content-Type"))
+	body := rr.Body.String()
+	assert.Contains(t, body, "event: agent.status\ndata: {\"type\":\"agent.status\",\"agent_id\":1,\"data\":{\"status\":\"offline\"}}\n\n")
+	assert.Contains(t, body, "event: task.status\n")
+	assert.NotContains(t, body, "ingestion.batch")
+
+	rr = streamEvents(h, "admin", "?topics=ingestion")
+	assert.Contains(t, rr.Body.String(), "event: ingestion.batch\n")
+	assert.NotContains(t, rr.Body.String(), "agent.status")
+}
+
+func TestEventsHandler_ForbiddenWithoutTopics(t *testing.T) {
+	h := &EventsHandler{Hub: &closedHub{}, TopicRoles: map[string][]string{"ingestion": {"admin"}}}
+	assert.Equal(t, http.StatusForbidden, streamEvents(h, "approver", "").Code)
+	assert.Equal(t, http.StatusForbidden, streamEvents(h, "admin", "?topics=agent").Code)
+}
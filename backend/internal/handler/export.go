@@ -0,0 +1,221 @@
+package handler
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"guardian-backend/internal/database"
+	"guardian-backend/internal/evidence"
+	"guardian-backend/internal/redact"
+	"guardian-backend/pkg/httpx"
+	"guardian-backend/pkg/logger"
+	"guardian-backend/pkg/validator"
+)
+
+// approvalActions 是监管链中视为批准采集的审计操作
+var approvalActions = map[string]bool{
+	"task.create": true,
+}
+
+// ExportHandler 生成签名的案件证据导出包
+type ExportHandler struct {
+	DB interface {
+		auditor
+		GetCase(ctx context.Context, caseID int64) (database.Case, error)
+		ListCaseMessages(ctx context.Context, caseID int64) ([]database.CaseMessage, error)
+		ListCaseTasks(ctx context.Context, caseID int64) ([]database.CaseTask, error)
+		ListCaseAuditTrail(ctx context.Context, caseID int64) ([]database.AuditRecord, error)
+		RecordEvidenceExport(ctx context.Context, e database.EvidenceExport) error
+	}
+	Signer *evidence.Signer
+	// Redactor 在请求 redact=true 时遮盖全部检测到的敏感字段
+	Redactor *redact.Engine
+}
+
+// Create 导出案件消息（JSONL 与 CSV）、监管链记录及签名清单，以 ZIP 返回
+func (h *ExportHandler) Create(w http.ResponseWriter, r *http.Request) {
+	caseID, err := strconv.ParseInt(chi.URLParam(r, "caseID"), 10, 64)
+	if err != nil {
+		httpx.WriteError(w, r, http.StatusBadRequest, "BAD_REQUEST", "invalid case id")
+		return
+	}
+	var payload CreateExportPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		httpx.WriteError(w, r, http.StatusBadRequest, "BAD_REQUEST", "invalid request body")
+		return
+	}
+	if err := validator.ValidateStruct(payload); err != nil {
+		httpx.WriteError(w, r, http.StatusBadRequest, "VALIDATION_FAILED", err.Error())
+		return
+	}
+	ctx := r.Context()
+	c, err := h.DB.GetCase(ctx, caseID)
+	if errors.Is(err, database.ErrCaseNotFound) {
+		httpx.WriteError(w, r, http.StatusNotFound, "NOT_FOUND", "case not found")
+		return
+	}
+	if err != nil {
+		logger.From(r.Context()).Error("Failed to load case", "error", err, "case_id", caseID)
+		httpx.WriteError(w, r, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to export case")
+		return
+	}
+	messages, err := h.DB.ListCaseMessages(ctx, caseID)
+	if errors.Is(err, database.ErrCaseKeyDestroyed) {
+		httpx.WriteError(w, r, http.StatusConflict, "CASE_KEY_DESTROYED", "case key has been destroyed")
+		return
+	}
+	if err != nil {
+		logger.From(r.Context()).Error("Failed to load case messages", "error", err, "case_id", caseID)
+		httpx.WriteError(w, r, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to export case")
+		return
+	}
+	tasks, err := h.DB.ListCaseTasks(ctx, caseID)
+	if err != nil {
+		logger.From(r.Context()).Error("Failed to load case tasks", "error", err, "case_id", caseID)
+		httpx.WriteError(w, r, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to export case")
+		return
+	}
+	trail, err := h.DB.ListCaseAuditTrail(ctx, caseID)
+	if err != nil {
+		logger.From(r.Context()).Error("Failed to load case audit trail", "error", err, "case_id", caseID)
+		httpx.WriteError(w, r, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to export case")
+		return
+	}
+
+	bundle := evidence.Bundle{
+		BundleID:   newBundleID(),
+		CaseID:     c.ID,
+		CaseName:   c.Name,
+		ExportedBy: PrincipalFrom(ctx).UserID,
+		ExportedAt: time.Now(),
+		Reason:     payload.Reason,
+		Redacted:   payload.Redact && h.Redactor != nil,
+		Messages:   make([]evidence.Message, 0, len(messages)),
+		Custody:    buildCustody(tasks, trail),
+	}
+	for _, m := range messages {
+		content := m.Content
+		if bundle.Redacted {
+			// 不按角色放行任何字段
+			content, _ = h.Redactor.Redact("", content)
+		}
+		bundle.Messages = append(bundle.Messages, evidence.Message{
+			ID:             m.ID,
+			AgentID:        m.AgentID,
+			TaskID:         m.TaskID,
+			ConversationID: m.ConversationID,
+			Timestamp:      m.Timestamp,
+			Content:        content,
+		})
+	}
+	// 导出包先写入临时文件再回传，不在内存中保留整个 ZIP
+	tmp, err := os.CreateTemp("", "guardian-export-*.zip")
+	if err != nil {
+		logger.From(r.Context()).Error("Failed to create export file", "error", err, "case_id", caseID)
+		httpx.WriteError(w, r, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to export case")
+		return
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	manifest, manifestHash, err := evidence.Write(tmp, bundle, h.Signer)
+	if err != nil {
+		logger.From(r.Context()).Error("Failed to build evidence bundle", "error", err, "case_id", caseID)
+		httpx.WriteError(w, r, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to export case")
+		return
+	}
+	size, err := tmp.Seek(0, io.SeekEnd)
+	if err == nil {
+		_, err = tmp.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		logger.From(r.Context()).Error("Failed to read export file", "error", err, "case_id", caseID)
+		httpx.WriteError(w, r, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to export case")
+		return
+	}
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="case-%d-%s.zip"`, caseID, bundle.BundleID))
+	w.Header().Set("X-Manifest-SHA256", manifestHash)
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(w, tmp); err != nil {
+		// 导出包未完整交付，不登记导出与审计，监管链中不出现未发生的移交
+		logger.From(r.Context()).Warn("Failed to send evidence bundle", "error", err, "case_id", caseID, "bundle_id", bundle.BundleID)
+		return
+	}
+	// 响应已发出，登记失败只能记录日志；导出包的清单哈希仍可在日志中找到
+	if err := h.DB.RecordEvidenceExport(ctx, database.EvidenceExport{
+		BundleID:       bundle.BundleID,
+		CaseID:         caseID,
+		ExportedBy:     bundle.ExportedBy,
+		Reason:         bundle.Reason,
+		Redacted:       bundle.Redacted,
+		MessageCount:   manifest.MessageCount,
+		ManifestSHA256: manifestHash,
+		SigningKeyID:   manifest.KeyID,
+	}); err != nil {
+		logger.From(r.Context()).Error("Failed to record evidence export", "error", err, "case_id", caseID, "bundle_id", bundle.BundleID, "manifest_sha256", manifestHash)
+	}
+	recordAudit(r, h.DB, database.AuditEntry{
+		Action:     "case.export",
+		TargetType: "case",
+		TargetID:   strconv.FormatInt(caseID, 10),
+		Detail: map[string]any{
+			"bundle_id":       bundle.BundleID,
+			"reason":          bundle.Reason,
+			"redacted":        bundle.Redacted,
+			"messages":        manifest.MessageCount,
+			"manifest_sha256": manifestHash,
+		},
+	})
+}
+
+// buildCustody 把任务与审计日志整理为监管链：批准采集的操作与其余访问（消息查询、查看明文、导出）、处置操作分开列出
+func buildCustody(tasks []database.CaseTask, trail []database.AuditRecord) evidence.Custody {
+	c := evidence.Custody{Tasks: make([]evidence.Task, 0, len(tasks))}
+	for _, t := range tasks {
+		c.Tasks = append(c.Tasks, evidence.Task{
+			ID:         t.ID,
+			AgentID:    t.AgentID,
+			TaskType:   t.TaskType,
+			Status:     t.Status,
+			ScopeStart: t.ScopeStart,
+			ScopeEnd:   t.ScopeEnd,
+			CreatedAt:  t.CreatedAt,
+			UpdatedAt:  t.UpdatedAt,
+		})
+	}
+	for _, a := range trail {
+		e := evidence.Event{
+			Time:       a.CreatedAt,
+			Actor:      a.Actor,
+			Action:     a.Action,
+			TargetType: a.TargetType,
+			TargetID:   a.TargetID,
+			Outcome:    a.Outcome,
+			IPAddress:  a.IPAddress,
+			Detail:     a.Detail,
+		}
+		if approvalActions[a.Action] && a.Outcome == database.AuditOutcomeSuccess {
+			c.Approvals = append(c.Approvals, e)
+		} else {
+			c.Accessors = append(c.Accessors, e)
+		}
+	}
+	return c
+}
+
+func newBundleID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"guardian-backend/internal/database"
+	"guardian-backend/internal/evidence"
+)
+
+type fakeExportDB struct {
+	messages []database.CaseMessage
+	msgErr   error
+	trail    []database.AuditRecord
+	exports  []database.EvidenceExport
+	audits   []database.AuditEntry
+}
+
+func (f *fakeExportDB) RecordAudit(_ context.Context, e database.AuditEntry) error {
+	f.audits = append(f.audits, e)
+	return nil
+}
+
+func (f *fakeExportDB) GetCase(_ context.Context, caseID int64) (database.Case, error) {
+	if caseID != 7 {
+		return database.Case{}, database.ErrCaseNotFound
+	}
+	return database.Case{ID: 7, Name: "case seven", Status: database.CaseStatusOpen}, nil
+}
+
+func (f *fakeExportDB) ListCaseMessages(context.Context, int64) ([]database.CaseMessage, error) {
+	return f.messages, f.msgErr
+}
+
+func (f *fakeExportDB) ListCaseTasks(context.Context, int64) ([]database.CaseTask, error) {
+	return []database.CaseTask{{ID: 3, AgentID: 2, TaskType: database.TaskTypeDumpWechatData, Status: "completed"}}, nil
+}
+
+func (f *fakeExportDB) ListCaseAuditTrail(context.Context, int64) ([]database.AuditRecord, error) {
+	return f.trail, nil
+}
+
+func (f *fakeExportDB) RecordEvidenceExport(_ context.Context, e database.EvidenceExport) error {
+	f.exports = append(f.exports, e)
+	return nil
+}
+
+func postExport(h *ExportHandler, caseID, body string) *httptest.ResponseRecorder {
+	router := chi.NewRouter()
+	router.Post("/v1/cases/{caseID}/exports", h.Create)
+	req := httptest.NewRequest(http.MethodPost, "/v1/cases/"+caseID+"/exports", strings.NewReader(body))
+	req = req.WithContext(context.WithValue(req.Context(), PrincipalKey, Principal{UserID: "1", Role: "admin"}))
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestExportHandler_CreateSignedBundle(t *testing.T) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	signer := &evidence.Signer{KeyID: "export-1", Key: priv}
+	db := &fakeExportDB{
+		messages: []database.CaseMessage{{ID: 1, AgentID: 2, Timestamp: time.Unix(1700000000, 0), Content: "hello"}},
+		trail: []database.AuditRecord{
+			{AuditEntry: database.AuditEntry{Actor: "1", Action: "task.create", TargetType: "task", TargetID: "3", Outcome: database.AuditOutcomeSuccess}},
+			{AuditEntry: database.AuditEntry{Actor: "4", Action: "messages.unmask", TargetType: "agent", TargetID: "2", Outcome: database.AuditOutcomeSuccess}},
+			{AuditEntry: database.AuditEntry{Actor: "5", Action: "messages.read", TargetType: "task", TargetID: "3", Outcome: database.AuditOutcomeSuccess}},
+		},
+	}
+	rr := postExport(&ExportHandler{DB: db, Signer: signer}, "7", `{"reason":"hand-off to legal"}`)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.Equal(t, "application/zip", rr.Header().Get("Content-Type"))
+	assert.Equal(t, strconv.Itoa(rr.Body.Len()), rr.Header().Get("Content-Length"))
+
+	body := rr.Body.Bytes()
+	res, err := evidence.Verify(bytes.NewReader(body), int64(len(body)), signer.PublicKey())
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), res.Manifest.CaseID)
+	assert.Equal(t, 1, res.Manifest.MessageCount)
+
+	require.Len(t, db.exports, 1)
+	assert.Equal(t, rr.Header().Get("X-Manifest-SHA256"), db.exports[0].ManifestSHA256)
+	require.Len(t, db.audits, 1)
+	assert.Equal(t, "case.export", db.audits[0].Action)
+
+	custody := buildCustody(nil, db.trail)
+	assert.Len(t, custody.Approvals, 1)
+	// 查看明文与普通查询都列为访问者
+	require.Len(t, custody.Accessors, 2)
+	assert.Equal(t, "messages.read", custody.Accessors[1].Action)
+	assert.Equal(t, "5", custody.Accessors[1].Actor)
+}
+
+// failingWriter 模拟下载中途断开的客户端
+type failingWriter struct {
+	*httptest.ResponseRecorder
+}
+
+func (w failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("connection reset by peer")
+}
+
+func TestExportHandler_FailedDeliveryIsNotRecorded(t *testing.T) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	db := &fakeExportDB{messages: []database.CaseMessage{{ID: 1, AgentID: 2, Timestamp: time.Unix(1700000000, 0), Content: "hello"}}}
+	h := &ExportHandler{DB: db, Signer: &evidence.Signer{KeyID: "export-1", Key: priv}}
+
+	router := chi.NewRouter()
+	router.Post("/v1/cases/{caseID}/exports", h.Create)
+	req := httptest.NewRequest(http.MethodPost, "/v1/cases/7/exports", strings.NewReader(`{"reason":"hand-off to legal"}`))
+	req = req.WithContext(context.WithValue(req.Context(), PrincipalKey, Principal{UserID: "1", Role: "admin"}))
+	router.ServeHTTP(failingWriter{httptest.NewRecorder()}, req)
+
+	// 导出包未交付，监管链中不能出现这次移交
+	assert.Empty(t, db.exports)
+	assert.Empty(t, db.audits)
+}
+
+func TestExportHandler_Errors(t *testing.T) {
+	_, priv, _ := ed25519.GenerateKey(rand.Reader)
+	h := &ExportHandler{DB: &fakeExportDB{}, Signer: &evidence.Signer{KeyID: "k", Key: priv}}
+
+	assert.Equal(t, http.StatusBadRequest, postExport(h, "7", `{}`).Code)
+	assert.Equal(t, http.StatusNotFound, postExport(h, "8", `{"reason":"x"}`).Code)
+
+	h.DB = &fakeExportDB{msgErr: database.ErrCaseKeyDestroyed}
+	rr := postExport(h, "7", `{"reason":"x"}`)
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	assert.Contains(t, rr.Body.String(), "CASE_KEY_DESTROYED")
+}
@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"guardian-backend/internal/database"
+)
+
+type fakeIntegrityDB struct {
+	agentID int
+	audits  []database.AuditEntry
+}
+
+func (f *fakeIntegrityDB) RecordAudit(_ context.Context, e database.AuditEntry) error {
+	f.audits = append(f.audits, e)
+	return nil
+}
+
+func (f *fakeIntegrityDB) CheckIngestionIntegrity(_ context.Context, agentID int) (database.IntegrityReport, error) {
+	f.agentID = agentID
+	return database.IntegrityReport{
+		Checked:    3,
+		Mismatches: []database.IntegrityMismatch{{BatchID: 12, AgentID: agentID, ReceivedAt: time.UnixMilli(5000), Reason: database.IntegrityContentMismatch}},
+	}, nil
+}
+
+func TestIntegrityHandler_Check(t *testing.T) {
+	db := &fakeIntegrityDB{}
+	h := &IntegrityHandler{DB: db}
+
+	rr := httptest.NewRecorder()
+	h.Check(rr, httptest.NewRequest(http.MethodPost, "/v1/admin/integrity-check?agent_id=4", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"checked":3,"unverifiable":0,"mismatches":[{"batch_id":12,"agent_id":4,"received_at":5000,"reason":"content_hash_mismatch"}]}`, rr.Body.String())
+	assert.Equal(t, 4, db.agentID)
+	if assert.Len(t, db.audits, 1) {
+		assert.Equal(t, "ingestion.integrity_check", db.audits[0].Action)
+		assert.Equal(t, []int64{12}, db.audits[0].Detail["mismatched_batches"])
+	}
+
+	rr = httptest.NewRecorder()
+	h.Check(rr, httptest.NewRequest(http.MethodPost, "/v1/admin/integrity-check?agent_id=x", nil))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
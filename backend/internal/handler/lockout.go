@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"guardian-backend/internal/database"
+	"guardian-backend/pkg/httpx"
+	"guardian-backend/pkg/logger"
+)
+
+// SecurityEventRecorder 用于写入安全事件（如登录锁定）
+type SecurityEventRecorder interface {
+	RecordSecurityEvent(ctx context.Context, e database.SecurityEvent) error
+}
+
+// loginKeys 返回登录失败计数使用的键：按用户名与按来源 IP 分别计数，
+// 前者防止针对单个账户的猜测，后者防止同一来源轮换用户名撞库
+func loginKeys(r *http.Request, username string) []string {
+	return []string{"user:" + strings.ToLower(username), KeyByIP(r)}
+}
+
+// allowLoginAttempt 检查按用户名限流与锁定状态；不允许时已写出 429 响应
+func (h *AuthHandler) allowLoginAttempt(w http.ResponseWriter, r *http.Request, username string) bool {
+	if h.UsernameLimiter != nil {
+		d := h.UsernameLimiter.Allow("user:" + strings.ToLower(username))
+		if !d.Allowed {
+			writeRateLimitHeaders(w, d)
+			httpx.WriteError(w, r, http.StatusTooManyRequests, "RATE_LIMITED", "rate limit exceeded")
+			return false
+		}
+	}
+	if h.Lockout == nil {
+		return true
+	}
+	for _, key := range loginKeys(r, username) {
+		if locked, remaining := h.Lockout.Locked(key); locked {
+			w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(remaining)))
+			httpx.WriteError(w, r, http.StatusTooManyRequests, "LOGIN_LOCKED", "too many failed login attempts, try again later")
+			return false
+		}
+	}
+	return true
+}
+
+// loginFailed 记录一次口令或 MFA 校验失败，触发锁定时写入安全事件
+func (h *AuthHandler) loginFailed(r *http.Request, username, stage string) {
+	if h.Lockout == nil {
+		return
+	}
+	for _, key := range loginKeys(r, username) {
+		locked, d := h.Lockout.Failure(key)
+		if !locked {
+			continue
+		}
+		logger.From(r.Context()).Warn("Login locked after repeated failures", "key", key, "duration", d)
+		if h.SecurityEvents == nil {
+			continue
+		}
+		e := database.SecurityEvent{
+			Type:      database.SecurityEventLoginLockout,
+			Username:  username,
+			IPAddress: clientIP(r),
+			Detail: map[string]any{
+				"key":              key,
+				"stage":            stage,
+				"locked_until":     time.Now().Add(d).UTC().Format(time.RFC3339),
+				"duration_seconds": int(d.Seconds()),
+			},
+		}
+		if err := h.SecurityEvents.RecordSecurityEvent(r.Context(), e); err != nil {
+			logger.From(r.Context()).Error("Failed to record security event", "error", err, "type", e.Type)
+		}
+	}
+}
+
+// loginSucceeded 在完整登录成功后清除该用户名的失败记录；IP 计数不清除，避免攻击者用自有账户重置
+func (h *AuthHandler) loginSucceeded(username string) {
+	if h.Lockout != nil {
+		h.Lockout.Success("user:" + strings.ToLower(username))
+	}
+}
@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"guardian-backend/internal/auth"
+	"guardian-backend/internal/database"
+	"guardian-backend/internal/ratelimit"
+)
+
+type memSecurityEvents []database.SecurityEvent
+
+func (m *memSecurityEvents) RecordSecurityEvent(_ context.Context, e database.SecurityEvent) error {
+	*m = append(*m, e)
+	return nil
+}
+
+func TestLogin_LockoutAfterRepeatedFailures(t *testing.T) {
+	hash, err := auth.HashPassword("s3cret")
+	require.NoError(t, err)
+	users := &memUsers{user: database.User{ID: 3, Username: "bob", PasswordHash: hash, Role: "auditor"}}
+	events := &memSecurityEvents{}
+	h := &AuthHandler{
+		Keys:           testKeyring(t),
+		Sessions:       &memSessionStore{},
+		Users:          users,
+		Lockout:        ratelimit.NewLockout(ratelimit.LockoutPolicy{Threshold: 3, Window: time.Minute, BaseDuration: time.Minute, MaxDuration: time.Hour}, 100),
+		SecurityEvents: events,
+	}
+
+	for i := 0; i < 3; i++ {
+		rr, _ := postJSON(t, h.Login, loginRequest{Username: "bob", Password: "wrong"})
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	}
+	require.NotEmpty(t, *events)
+	assert.Equal(t, database.SecurityEventLoginLockout, (*events)[0].Type)
+	assert.Equal(t, "bob", (*events)[0].Username)
+
+	// 锁定期内即使口令正确也被拒绝，且用户名大小写不能绕过
+	rr, body := postJSON(t, h.Login, loginRequest{Username: "BOB", Password: "s3cret"})
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.Equal(t, "LOGIN_LOCKED", body["code"])
+	assert.Equal(t, "60", rr.Header().Get("Retry-After"))
+}
+
+func TestLogin_UsernameLimiter(t *testing.T) {
+	h := &AuthHandler{
+		Keys:            testKeyring(t),
+		Sessions:        &memSessionStore{},
+		Users:           &memUsers{},
+		UsernameLimiter: ratelimit.NewLimiter(1, 1, 100),
+	}
+	rr, _ := postJSON(t, h.Login, loginRequest{Username: "eve", Password: "x"})
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	rr, _ = postJSON(t, h.Login, loginRequest{Username: "eve", Password: "x"})
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.NotEmpty(t, rr.Header().Get("Retry-After"))
+}
@@ -0,0 +1,286 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"guardian-backend/internal/auth"
+	"guardian-backend/internal/database"
+	"guardian-backend/pkg/httpx"
+	"guardian-backend/pkg/logger"
+)
+
+// 令牌用途：访问令牌与登录第二步使用的 MFA 中间令牌互不通用
+const (
+	tokenUseAccess = auth.TokenUseAccess
+	tokenUseMFA    = "mfa"
+)
+
+// mfaTokenTTL 是口令校验通过后完成 MFA 的时限
+const mfaTokenTTL = 5 * time.Minute
+
+// recoveryCodeCount 是每次绑定生成的恢复码数量
+const recoveryCodeCount = 10
+
+type mfaTokenRequest struct {
+	MFAToken string `json:"mfa_token"`
+}
+
+type mfaVerifyRequest struct {
+	MFAToken     string `json:"mfa_token"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+type mfaActivateRequest struct {
+	Code string `json:"code"`
+}
+
+type mfaEnrollResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+type mfaActivateResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// LoginMFAEnroll 在登录流程中为尚未绑定的用户生成 TOTP 密钥（凭 mfa_token）
+func (h *AuthHandler) LoginMFAEnroll(w http.ResponseWriter, r *http.Request) {
+	var req mfaTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpx.WriteError(w, r, http.StatusBadRequest, "BAD_REQUEST", "invalid request body")
+		return
+	}
+	user, ok := h.userFromMFAToken(w, r, req.MFAToken)
+	if !ok {
+		return
+	}
+	h.enroll(w, r, user)
+}
+
+// LoginMFA 完成登录第二步：校验 TOTP 或恢复码后签发会话令牌。
+// 用户处于待绑定状态时，首个有效验证码同时激活 MFA，并在响应中一次性返回恢复码。
+func (h *AuthHandler) LoginMFA(w http.ResponseWriter, r *http.Request) {
+	var req mfaVerifyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpx.WriteError(w, r, http.StatusBadRequest, "BAD_REQUEST", "invalid request body")
+		return
+	}
+	user, ok := h.userFromMFAToken(w, r, req.MFAToken)
+	if !ok {
+		return
+	}
+	if !h.allowLoginAttempt(w, r, user.Username) {
+		return
+	}
+	actor := strconv.Itoa(user.ID)
+	if !user.MFAEnabled() {
+		if user.MFASecret == "" {
+			httpx.WriteError(w, r, http.StatusConflict, "MFA_ENROLLMENT_REQUIRED", "mfa enrollment has not been started")
+			return
+		}
+		codes, ok := h.activate(w, r, user, req.Code)
+		if !ok {
+			return
+		}
+		h.startSession(w, r, user, codes)
+		return
+	}
+
+	var (
+		valid  bool
+		err    error
+		method = "totp"
+	)
+	if req.RecoveryCode != "" {
+		method = "recovery_code"
+		valid, err = h.Users.UseRecoveryCode(r.Context(), user.ID, hashToken(auth.NormalizeRecoveryCode(req.RecoveryCode)))
+	} else {
+		valid, err = h.verifyTOTP(r.Context(), user, req.Code)
+	}
+	if err != nil {
+		logger.From(r.Context()).Error("Failed to verify mfa", "error", err, "user_id", user.ID)
+		httpx.WriteError(w, r, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to verify mfa")
+		return
+	}
+	if !valid {
+		h.loginFailed(r, user.Username, "mfa")
+		recordAudit(r, h.Sessions, database.AuditEntry{Actor: actor, Action: "login.mfa", TargetType: "user", TargetID: actor, Outcome: database.AuditOutcomeDenied, Detail: map[string]any{"method": method}})
+		httpx.WriteError(w, r, http.StatusUnauthorized, "INVALID_MFA_CODE", "invalid mfa code")
+		return
+	}
+	if method == "recovery_code" {
+		recordAudit(r, h.Sessions, database.AuditEntry{Actor: actor, Action: "login.mfa", TargetType: "user", TargetID: actor, Detail: map[string]any{"method": method}})
+	}
+	h.startSession(w, r, user, nil)
+}
+
+// EnrollMFA 已登录用户自助开始绑定 TOTP
+func (h *AuthHandler) EnrollMFA(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+	h.enroll(w, r, user)
+}
+
+// ActivateMFA 已登录用户提交验证码完成绑定，返回恢复码
+func (h *AuthHandler) ActivateMFA(w http.ResponseWriter, r *http.Request) {
+	var req mfaActivateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpx.WriteError(w, r, http.StatusBadRequest, "BAD_REQUEST", "invalid request body")
+		return
+	}
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+	if user.MFAEnabled() {
+		httpx.WriteError(w, r, http.StatusConflict, "MFA_ALREADY_ENABLED", "mfa is already enabled")
+		return
+	}
+	if user.MFASecret == "" {
+		httpx.WriteError(w, r, http.StatusConflict, "MFA_ENROLLMENT_REQUIRED", "mfa enrollment has not been started")
+		return
+	}
+	codes, ok := h.activate(w, r, user, req.Code)
+	if !ok {
+		return
+	}
+	httpx.WriteJSON(w, http.StatusOK, mfaActivateResponse{RecoveryCodes: codes})
+}
+
+// ResetUserMFA 管理员重置用户的 MFA（例如设备丢失），同时吊销其全部会话
+func (h *AuthHandler) ResetUserMFA(w http.ResponseWriter, r *http.Request) {
+	userIDStr := chi.URLParam(r, "userID")
+	userID, err := strconv.Atoi(userIDStr)
+	if err != nil {
+		httpx.WriteError(w, r, http.StatusBadRequest, "BAD_REQUEST", "invalid user id")
+		return
+	}
+	if err := h.Users.ResetMFA(r.Context(), userID); err != nil {
+		if errors.Is(err, database.ErrUserNotFound) {
+			httpx.WriteError(w, r, http.StatusNotFound, "NOT_FOUND", "user not found")
+			return
+		}
+		logger.From(r.Context()).Error("Failed to reset mfa", "error", err, "user_id", userID)
+		httpx.WriteError(w, r, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to reset mfa")
+		return
+	}
+	revoked, err := h.Sessions.RevokeUserSessions(r.Context(), userIDStr, database.RevokeReasonAdmin)
+	if err != nil {
+		logger.From(r.Context()).Error("Failed to revoke user sessions", "error", err, "user_id", userID)
+	}
+	recordAudit(r, h.Sessions, database.AuditEntry{
+		Action:     "user.mfa.reset",
+		TargetType: "user",
+		TargetID:   userIDStr,
+		Detail:     map[string]any{"sessions_revoked": revoked},
+	})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// enroll 生成新的待确认 TOTP 密钥；已启用 MFA 的用户须先由管理员重置
+func (h *AuthHandler) enroll(w http.ResponseWriter, r *http.Request, user database.User) {
+	if user.MFAEnabled() {
+		httpx.WriteError(w, r, http.StatusConflict, "MFA_ALREADY_ENABLED", "mfa is already enabled")
+		return
+	}
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		httpx.WriteError(w, r, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to generate secret")
+		return
+	}
+	if err := h.Users.SetPendingMFASecret(r.Context(), user.ID, secret); err != nil {
+		logger.From(r.Context()).Error("Failed to save mfa secret", "error", err, "user_id", user.ID)
+		httpx.WriteError(w, r, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to start enrollment")
+		return
+	}
+	httpx.WriteJSON(w, http.StatusOK, mfaEnrollResponse{
+		Secret:          secret,
+		ProvisioningURI: auth.TOTPProvisioningURI(h.Keys.Issuer, user.Username, secret),
+	})
+}
+
+// activate 校验首个验证码并启用 MFA，返回明文恢复码（数据库只保存哈希）
+func (h *AuthHandler) activate(w http.ResponseWriter, r *http.Request, user database.User, code string) ([]string, bool) {
+	actor := strconv.Itoa(user.ID)
+	valid, err := h.verifyTOTP(r.Context(), user, code)
+	if err != nil {
+		logger.From(r.Context()).Error("Failed to verify mfa", "error", err, "user_id", user.ID)
+		httpx.WriteError(w, r, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to verify mfa")
+		return nil, false
+	}
+	if !valid {
+		h.loginFailed(r, user.Username, "mfa")
+		recordAudit(r, h.Sessions, database.AuditEntry{Actor: actor, Action: "user.mfa.enroll", TargetType: "user", TargetID: actor, Outcome: database.AuditOutcomeDenied})
+		httpx.WriteError(w, r, http.StatusUnauthorized, "INVALID_MFA_CODE", "invalid mfa code")
+		return nil, false
+	}
+	codes, err := auth.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		httpx.WriteError(w, r, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to generate recovery codes")
+		return nil, false
+	}
+	hashes := make([]string, len(codes))
+	for i, c := range codes {
+		hashes[i] = hashToken(auth.NormalizeRecoveryCode(c))
+	}
+	if err := h.Users.ActivateMFA(r.Context(), user.ID, hashes); err != nil {
+		logger.From(r.Context()).Error("Failed to activate mfa", "error", err, "user_id", user.ID)
+		httpx.WriteError(w, r, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to activate mfa")
+		return nil, false
+	}
+	recordAudit(r, h.Sessions, database.AuditEntry{Actor: actor, Action: "user.mfa.enroll", TargetType: "user", TargetID: actor})
+	return codes, true
+}
+
+// verifyTOTP 校验验证码并登记其时间步，同一验证码不能被重复使用
+func (h *AuthHandler) verifyTOTP(ctx context.Context, user database.User, code string) (bool, error) {
+	step, ok := auth.ValidateTOTP(user.MFASecret, code, time.Now())
+	if !ok {
+		return false, nil
+	}
+	return h.Users.ConsumeTOTPStep(ctx, user.ID, step)
+}
+
+// userFromMFAToken 校验 MFA 中间令牌并加载对应用户；失败时已写出响应
+func (h *AuthHandler) userFromMFAToken(w http.ResponseWriter, r *http.Request, token string) (database.User, bool) {
+	claims, err := h.Keys.Parse(token)
+	if err != nil || claims["token_use"] != tokenUseMFA {
+		httpx.WriteError(w, r, http.StatusUnauthorized, "UNAUTHORIZED", "invalid or expired mfa token")
+		return database.User{}, false
+	}
+	sub, _ := claims["sub"].(string)
+	return h.loadUser(w, r, sub)
+}
+
+// currentUser 加载当前已认证用户
+func (h *AuthHandler) currentUser(w http.ResponseWriter, r *http.Request) (database.User, bool) {
+	return h.loadUser(w, r, PrincipalFrom(r.Context()).UserID)
+}
+
+func (h *AuthHandler) loadUser(w http.ResponseWriter, r *http.Request, userID string) (database.User, bool) {
+	id, err := strconv.Atoi(userID)
+	if err != nil {
+		httpx.WriteError(w, r, http.StatusUnauthorized, "UNAUTHORIZED", "unknown user")
+		return database.User{}, false
+	}
+	user, err := h.Users.GetUserByID(r.Context(), id)
+	if errors.Is(err, database.ErrUserNotFound) {
+		httpx.WriteError(w, r, http.StatusUnauthorized, "UNAUTHORIZED", "unknown user")
+		return database.User{}, false
+	}
+	if err != nil {
+		logger.From(r.Context()).Error("Failed to load user", "error", err, "user_id", id)
+		httpx.WriteError(w, r, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to load user")
+		return database.User{}, false
+	}
+	return user, true
+}
@@ -0,0 +1,208 @@
+package handler
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"guardian-backend/internal/auth"
+	"guardian-backend/internal/database"
+)
+
+// memUsers 是内存实现的 UserStore，只保存单个用户
+type memUsers struct {
+	user        database.User
+	oidcSubject string
+	lastStep    int64
+	codes       map[string]bool
+}
+
+func (m *memUsers) GetUserByUsername(_ context.Context, username string) (database.User, error) {
+	if username != m.user.Username {
+		return database.User{}, database.ErrUserNotFound
+	}
+	return m.user, nil
+}
+
+func (m *memUsers) GetUserByID(_ context.Context, id int) (database.User, error) {
+	if id != m.user.ID {
+		return database.User{}, database.ErrUserNotFound
+	}
+	return m.user, nil
+}
+
+func (m *memUsers) SetPendingMFASecret(_ context.Context, _ int, secret string) error {
+	m.user.MFASecret, m.lastStep = secret, 0
+	return nil
+}
+
+func (m *memUsers) ConsumeTOTPStep(_ context.Context, _ int, step int64) (bool, error) {
+	if step <= m.lastStep {
+		return false, nil
+	}
+	m.lastStep = step
+	return true, nil
+}
+
+func (m *memUsers) ActivateMFA(_ context.Context, _ int, hashes []string) error {
+	now := time.Now()
+	m.user.MFAEnabledAt = &now
+	m.codes = map[string]bool{}
+	for _, h := range hashes {
+		m.codes[h] = true
+	}
+	return nil
+}
+
+func (m *memUsers) UseRecoveryCode(_ context.Context, _ int, hash string) (bool, error) {
+	if !m.codes[hash] {
+		return false, nil
+	}
+	m.codes[hash] = false
+	return true, nil
+}
+
+func (m *memUsers) ResetMFA(_ context.Context, _ int) error {
+	m.user.MFASecret, m.user.MFAEnabledAt, m.codes = "", nil, nil
+	return nil
+}
+
+func (m *memUsers) UpsertOIDCUser(_ context.Context, _, subject, username, _, role string) (database.User, error) {
+	switch {
+	case m.user.ID == 0:
+		m.user, m.oidcSubject = database.User{ID: 42, Username: username}, subject
+	case m.oidcSubject != subject:
+		return database.User{}, database.ErrUsernameTaken
+	}
+	m.user.Role = role
+	return m.user, nil
+}
+
+// memSessionStore 只记录创建的会话与审计动作
+type memSessionStore struct {
+	created []database.Session
+	audits  []database.AuditEntry
+}
+
+func (s *memSessionStore) RecordAudit(_ context.Context, e database.AuditEntry) error {
+	s.audits = append(s.audits, e)
+	return nil
+}
+
+func (s *memSessionStore) CreateSession(_ context.Context, sess database.Session) error {
+	s.created = append(s.created, sess)
+	return nil
+}
+
+func (s *memSessionStore) RotateSession(context.Context, string, string, time.Time) (database.Session, error) {
+	return database.Session{}, database.ErrSessionNotFound
+}
+
+func (s *memSessionStore) RevokeSession(context.Context, string, string) error { return nil }
+
+func (s *memSessionStore) RevokeUserSessions(context.Context, string, string) (int64, error) {
+	return 0, nil
+}
+
+func postJSON(t *testing.T, h http.HandlerFunc, body any) (*httptest.ResponseRecorder, map[string]any) {
+	t.Helper()
+	raw, err := json.Marshal(body)
+	require.NoError(t, err)
+	rr := httptest.NewRecorder()
+	h(rr, httptest.NewRequest("POST", "/", strings.NewReader(string(raw))))
+	out := map[string]any{}
+	_ = json.Unmarshal(rr.Body.Bytes(), &out)
+	return rr, out
+}
+
+// currentCode 按 RFC 6238 计算密钥当前时间步的验证码
+func currentCode(t *testing.T, secret string, now time.Time) string {
+	t.Helper()
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	require.NoError(t, err)
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(now.Unix()/30))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	return fmt.Sprintf("%06d", (binary.BigEndian.Uint32(sum[offset:offset+4])&0x7fffffff)%1000000)
+}
+
+func TestLogin_MFAEnrollmentAndVerification(t *testing.T) {
+	hash, err := auth.HashPassword("s3cret")
+	require.NoError(t, err)
+	users := &memUsers{user: database.User{ID: 7, Username: "alice", PasswordHash: hash, Role: "admin"}}
+	sessions := &memSessionStore{}
+	h := &AuthHandler{Keys: testKeyring(t), Sessions: sessions, Users: users, MFARequiredRoles: []string{"admin"}}
+
+	rr, _ := postJSON(t, h.Login, loginRequest{Username: "alice", Password: "wrong"})
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+
+	// 口令正确但角色要求 MFA：只返回中间令牌，不创建会话
+	rr, body := postJSON(t, h.Login, loginRequest{Username: "alice", Password: "s3cret"})
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, true, body["mfa_required"])
+	assert.Equal(t, true, body["mfa_enrollment_required"])
+	assert.Empty(t, sessions.created)
+	mfaToken := body["mfa_token"].(string)
+
+	// 中间令牌不能当作访问令牌使用
+	req := httptest.NewRequest("GET", "/v1/agents", nil)
+	req.Header.Set("Authorization", "Bearer "+mfaToken)
+	rr = httptest.NewRecorder()
+	JWTAuth(h.Keys, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+
+	rr, body = postJSON(t, h.LoginMFAEnroll, mfaTokenRequest{MFAToken: mfaToken})
+	require.Equal(t, http.StatusOK, rr.Code)
+	secret := body["secret"].(string)
+	assert.Contains(t, body["provisioning_uri"], "otpauth://totp/")
+
+	code := currentCode(t, secret, time.Now())
+	rr, body = postJSON(t, h.LoginMFA, mfaVerifyRequest{MFAToken: mfaToken, Code: code})
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.NotEmpty(t, body["token"])
+	require.Len(t, body["recovery_codes"], recoveryCodeCount)
+	assert.True(t, users.user.MFAEnabled())
+	require.Len(t, sessions.created, 1)
+	assert.Equal(t, "7", sessions.created[0].UserID)
+
+	// 同一验证码不能重放
+	rr, _ = postJSON(t, h.LoginMFA, mfaVerifyRequest{MFAToken: mfaToken, Code: code})
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	assert.Equal(t, database.AuditOutcomeDenied, sessions.audits[len(sessions.audits)-1].Outcome)
+
+	// 恢复码仅可使用一次
+	recovery := body["recovery_codes"].([]any)[0].(string)
+	rr, _ = postJSON(t, h.LoginMFA, mfaVerifyRequest{MFAToken: mfaToken, RecoveryCode: strings.ToUpper(recovery)})
+	assert.Equal(t, http.StatusOK, rr.Code)
+	rr, _ = postJSON(t, h.LoginMFA, mfaVerifyRequest{MFAToken: mfaToken, RecoveryCode: recovery})
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+}
+
+func TestLogin_WithoutMFARequirementIssuesTokens(t *testing.T) {
+	hash, err := auth.HashPassword("s3cret")
+	require.NoError(t, err)
+	users := &memUsers{user: database.User{ID: 3, Username: "bob", PasswordHash: hash, Role: "auditor"}}
+	sessions := &memSessionStore{}
+	h := &AuthHandler{Keys: testKeyring(t), Sessions: sessions, Users: users, MFARequiredRoles: []string{"admin"}}
+
+	rr, body := postJSON(t, h.Login, loginRequest{Username: "bob", Password: "s3cret"})
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.NotEmpty(t, body["token"])
+	assert.Nil(t, body["mfa_required"])
+	require.Len(t, sessions.created, 1)
+	assert.Equal(t, "auditor", sessions.created[0].Role)
+}
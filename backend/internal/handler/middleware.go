@@ -3,69 +3,108 @@ package handler
 import (
     "context"
     "net/http"
+    "path"
     "strconv"
-    "strings"
     "time"
     "log/slog"
 
     "github.com/go-chi/chi/v5"
-    "github.com/golang-jwt/jwt/v5"
     chimid "github.com/go-chi/chi/v5/middleware"
+    "guardian-backend/internal/auth"
+    "guardian-backend/internal/ratelimit"
+    "guardian-backend/pkg/httpx"
+    "guardian-backend/pkg/logger"
+    "guardian-backend/pkg/tracing"
 )
 
 // 定义一个唯一的key类型，用于在context中存取值，避免冲突
 type contextKey string
 const AgentIDKey contextKey = "agentID"
 const RequestIDKey contextKey = "requestID"
+// PrincipalKey 与 Principal 定义在 auth 包，gRPC 拦截器与 HTTP 中间件共用
+const PrincipalKey = auth.PrincipalKey
 
-// TODO: 统一错误响应结构可通过包装 http.Error，附带 requestID 与 code
+// Principal 是通过 JWT 认证的控制台用户
+type Principal = auth.Principal
+
+// SessionChecker 用于在每次请求时确认令牌所属会话未被吊销
+type SessionChecker = auth.SessionChecker
+
+// PrincipalFrom 从 context 中取出当前用户；未认证时返回零值
+func PrincipalFrom(ctx context.Context) Principal {
+    return auth.PrincipalFrom(ctx)
+}
 
 // AgentCtx 是一个中间件，负责从URL中解析agentID并存入请求的context中
 func AgentCtx(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		agentIDStr := chi.URLParam(r, "agentID")
 		if agentIDStr == "" {
-			http.Error(w, "Agent ID is required", http.StatusBadRequest)
+			httpx.WriteError(w, r, http.StatusBadRequest, "BAD_REQUEST", "agent id is required")
 			return
 		}
 		agentID, err := strconv.Atoi(agentIDStr)
 		if err != nil {
-			http.Error(w, "Invalid Agent ID format", http.StatusBadRequest)
+			httpx.WriteError(w, r, http.StatusBadRequest, "BAD_REQUEST", "invalid agent id")
 			return
 		}
 		// 将解析出的 agentID 存入 context
 		ctx := context.WithValue(r.Context(), AgentIDKey, agentID)
+		logger.Annotate(ctx, "agent_id", agentID)
 		// 将带有新 context 的请求传递给下一个处理器
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 // ...existing code...
 
-func JWTAuth(jwtSecret string) func(next http.Handler) http.Handler {
+// JWTAuth 校验访问令牌；sessions 非空时还要求令牌所属会话仍然有效，从而支持登出与吊销
+func JWTAuth(keys *auth.Keyring, sessions SessionChecker) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			header := r.Header.Get("Authorization")
-			if header == "" || !strings.HasPrefix(header, "Bearer ") {
-				http.Error(w, "missing token", http.StatusUnauthorized)
-				return
-			}
-			tokenStr := header[7:]
-			token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
-				return []byte(jwtSecret), nil
-			})
-			if err != nil || !token.Valid {
-				http.Error(w, "invalid token", http.StatusUnauthorized)
+			principal, err := auth.Authenticate(r.Context(), keys, sessions, r.Header.Get("Authorization"))
+			if err != nil {
+				httpx.WriteAppError(w, r, err)
 				return
 			}
-			next.ServeHTTP(w, r)
+			// 将用户身份与来源 IP 存入 context，供审计与授权使用
+			ctx := auth.WithClientIP(auth.WithPrincipal(r.Context(), principal), clientIP(r))
+			logger.Annotate(ctx, "user_id", principal.UserID, "role", principal.Role)
+			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
 
-// WithRequestTimeout 为请求设置超时
-func WithRequestTimeout(timeout time.Duration) func(http.Handler) http.Handler {
+// RequireRole 仅允许指定角色访问，需在 JWTAuth 之后使用
+func RequireRole(roles ...string) func(http.Handler) http.Handler {
+    allowed := make(map[string]struct{}, len(roles))
+    for _, role := range roles {
+        allowed[role] = struct{}{}
+    }
+    return func(next http.Handler) http.Handler {
+        return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+            if _, ok := allowed[PrincipalFrom(r.Context()).Role]; !ok {
+                httpx.WriteError(w, r, http.StatusForbidden, "FORBIDDEN", "insufficient role")
+                return
+            }
+            next.ServeHTTP(w, r)
+        })
+    }
+}
+
+// WithRequestTimeout 为请求设置超时；exemptPaths 中的长连接或大响应接口（如 SSE、证据导出）不受限制。
+// exemptPaths 按 path.Match 匹配，如 "/v1/cases/*/exports"；http.TimeoutHandler 会缓冲整个响应，豁免的接口直接写出
+func WithRequestTimeout(timeout time.Duration, exemptPaths ...string) func(http.Handler) http.Handler {
     return func(next http.Handler) http.Handler {
-        return http.TimeoutHandler(next, timeout, "request timeout")
+        limited := http.TimeoutHandler(next, timeout, "request timeout")
+        return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+            for _, pattern := range exemptPaths {
+                if ok, _ := path.Match(pattern, r.URL.Path); ok {
+                    next.ServeHTTP(w, r)
+                    return
+                }
+            }
+            limited.ServeHTTP(w, r)
+        })
     }
 }
 
@@ -88,7 +127,7 @@ func CORSWithOrigins(origins []string) func(http.Handler) http.Handler {
                 }
             }
             w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
-            w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+            w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
             if r.Method == http.MethodOptions {
                 w.WriteHeader(http.StatusNoContent)
                 return
@@ -98,44 +137,84 @@ func CORSWithOrigins(origins []string) func(http.Handler) http.Handler {
     }
 }
 
-// TokenBucketLimiter 简易令牌桶限流中间件
-func TokenBucketLimiter(rps int, burst int) func(http.Handler) http.Handler {
-    if rps <= 0 { rps = 1 }
-    if burst < 0 { burst = 0 }
-    ch := make(chan struct{}, burst)
-    ticker := time.NewTicker(time.Second / time.Duration(rps))
-    go func() {
-        for range ticker.C {
-            select { case ch <- struct{}{}: default: }
-        }
-    }()
+// RateLimitKeyFunc 从请求中提取限流键
+type RateLimitKeyFunc func(r *http.Request) string
+
+// KeyByIP 按客户端 IP 限流
+func KeyByIP(r *http.Request) string {
+    return "ip:" + clientIP(r)
+}
+
+// KeyByUser 按已认证用户 ID 限流（需在 JWTAuth 之后使用），未认证时退回按 IP
+func KeyByUser(r *http.Request) string {
+    if id := PrincipalFrom(r.Context()).UserID; id != "" {
+        return "user:" + id
+    }
+    return KeyByIP(r)
+}
+
+// RateLimit 按键限流，每个客户端拥有独立的令牌桶，并返回 RateLimit-* 响应头
+func RateLimit(l *ratelimit.Limiter, key RateLimitKeyFunc) func(http.Handler) http.Handler {
     return func(next http.Handler) http.Handler {
         return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-            select {
-            case <-r.Context().Done():
-                http.Error(w, "canceled", http.StatusRequestTimeout)
+            d := l.Allow(key(r))
+            writeRateLimitHeaders(w, d)
+            if !d.Allowed {
+                httpx.WriteError(w, r, http.StatusTooManyRequests, "RATE_LIMITED", "rate limit exceeded")
                 return
-            case <-ch:
-                next.ServeHTTP(w, r)
-            default:
-                http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
             }
+            next.ServeHTTP(w, r)
         })
     }
 }
 
-// RequestLogger 记录每个请求的开始/结束日志，包含 request_id
+// writeRateLimitHeaders 按 IETF RateLimit 头草案写入配额信息，被拒绝时附带 Retry-After
+func writeRateLimitHeaders(w http.ResponseWriter, d ratelimit.Decision) {
+    w.Header().Set("RateLimit-Limit", strconv.Itoa(d.Limit))
+    w.Header().Set("RateLimit-Remaining", strconv.Itoa(d.Remaining))
+    w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(d.Reset)))
+    if !d.Allowed {
+        w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(d.RetryAfter)))
+    }
+}
+
+// ceilSeconds 向上取整到秒，保证客户端按 Retry-After 重试时已可用
+func ceilSeconds(d time.Duration) int {
+    return int((d + time.Second - 1) / time.Second)
+}
+
+// RequestLogger 为每个请求创建携带 request_id（已采样追踪时还有 trace_id）的请求级 logger（logger.From 取得），
+// 认证与路由中间件再追加 user_id、agent_id；记录请求的开始/结束日志。需挂在 tracing.HTTPMiddleware 之后
 func RequestLogger(next http.Handler) http.Handler {
-    type rw struct { http.ResponseWriter; status int }
     return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
         rid := ""
         if v := r.Context().Value(chimid.RequestIDKey); v != nil {
             if s, ok := v.(string); ok { rid = s }
         }
+        l := slog.Default().With("request_id", rid)
+        if tid := tracing.TraceID(r.Context()); tid != "" {
+            l = l.With("trace_id", tid)
+        }
+        ctx := logger.NewContext(r.Context(), l)
         start := time.Now()
-        wrapper := &rw{ResponseWriter: w, status: http.StatusOK}
-        slog.Info("request start", "request_id", rid, "method", r.Method, "path", r.URL.Path)
-        next.ServeHTTP(wrapper, r)
-        slog.Info("request end", "request_id", rid, "method", r.Method, "path", r.URL.Path, "status", wrapper.status, "duration_ms", time.Since(start).Milliseconds())
+        wrapper := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+        logger.From(ctx).Info("request start", "method", r.Method, "path", r.URL.Path)
+        next.ServeHTTP(wrapper, r.WithContext(ctx))
+        logger.From(ctx).Info("request end", "method", r.Method, "path", r.URL.Path, "status", wrapper.status, "duration_ms", time.Since(start).Milliseconds())
     })
 }
+
+// statusRecorder 记录响应状态码；Unwrap 使 http.ResponseController 能找到底层的 Flush
+type statusRecorder struct {
+    http.ResponseWriter
+    status int
+}
+
+func (w *statusRecorder) WriteHeader(code int) {
+    w.status = code
+    w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusRecorder) Unwrap() http.ResponseWriter {
+    return w.ResponseWriter
+}
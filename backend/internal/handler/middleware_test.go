@@ -0,0 +1,141 @@
+package handler
+
+import (
+    "context"
+    "net/http"
+    "net/http/httptest"
+    "testing"
+    "time"
+
+    "github.com/golang-jwt/jwt/v5"
+    "github.com/stretchr/testify/assert"
+    "guardian-backend/internal/auth"
+    "guardian-backend/internal/ratelimit"
+)
+
+type fakeSessions map[string]bool
+
+func (f fakeSessions) IsSessionActive(_ context.Context, sessionID string) (bool, error) {
+    return f[sessionID], nil
+}
+
+func testKeyring(t *testing.T) *auth.Keyring {
+    t.Helper()
+    kr, err := auth.NewKeyring("guardian", "guardian-console", "k1", []auth.KeyConfig{
+        {ID: "k1", Algorithm: auth.AlgHS256, Secret: "0123456789abcdef0123456789abcdef"},
+    })
+    assert.NoError(t, err)
+    return kr
+}
+
+func TestJWTAuth_SessionRevocation(t *testing.T) {
+    sessions := fakeSessions{"live": true, "revoked": false}
+    var got Principal
+    kr := testKeyring(t)
+    h := JWTAuth(kr, sessions)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        got = PrincipalFrom(r.Context())
+        w.WriteHeader(http.StatusOK)
+    }))
+    exp := time.Now().Add(time.Minute).Unix()
+
+    cases := []struct {
+        name   string
+        claims jwt.MapClaims
+        want   int
+    }{
+        {"active session", jwt.MapClaims{"sub": "1", "role": "admin", "sid": "live", "token_use": "access", "exp": exp}, http.StatusOK},
+        {"revoked session", jwt.MapClaims{"sub": "1", "role": "admin", "sid": "revoked", "token_use": "access", "exp": exp}, http.StatusUnauthorized},
+        {"missing sid", jwt.MapClaims{"sub": "1", "role": "admin", "token_use": "access", "exp": exp}, http.StatusUnauthorized},
+        {"mfa token", jwt.MapClaims{"sub": "1", "sid": "live", "token_use": "mfa", "exp": exp}, http.StatusUnauthorized},
+    }
+    for _, tc := range cases {
+        t.Run(tc.name, func(t *testing.T) {
+            req := httptest.NewRequest("GET", "/v1/agents", nil)
+            token, err := kr.Sign(tc.claims)
+            assert.NoError(t, err)
+            req.Header.Set("Authorization", "Bearer "+token)
+            rr := httptest.NewRecorder()
+            h.ServeHTTP(rr, req)
+            assert.Equal(t, tc.want, rr.Code)
+        })
+    }
+    assert.Equal(t, Principal{UserID: "1", Role: "admin", SessionID: "live"}, got)
+}
+
+func TestJWTAuth_JSONErrors(t *testing.T) {
+    h := JWTAuth(testKeyring(t), nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+    req := httptest.NewRequest("GET", "/v1/agents", nil)
+    rr := httptest.NewRecorder()
+    h.ServeHTTP(rr, req)
+    assert.Equal(t, http.StatusUnauthorized, rr.Code)
+    assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+    assert.JSONEq(t, `{"code":"UNAUTHORIZED","message":"missing token"}`, rr.Body.String())
+}
+
+func TestRequireRole(t *testing.T) {
+    h := RequireRole("admin")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        w.WriteHeader(http.StatusOK)
+    }))
+    for role, want := range map[string]int{"admin": http.StatusOK, "auditor": http.StatusForbidden, "": http.StatusForbidden} {
+        req := httptest.NewRequest("POST", "/v1/admin/users/2/revoke-sessions", nil)
+        req = req.WithContext(context.WithValue(req.Context(), PrincipalKey, Principal{UserID: "1", Role: role}))
+        rr := httptest.NewRecorder()
+        h.ServeHTTP(rr, req)
+        assert.Equal(t, want, rr.Code, "role %q", role)
+    }
+}
+
+func TestRateLimit_PerClientWithHeaders(t *testing.T) {
+    h := RateLimit(ratelimit.NewLimiter(1, 2, 100), KeyByIP)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        w.WriteHeader(http.StatusOK)
+    }))
+    do := func(ip string) *httptest.ResponseRecorder {
+        req := httptest.NewRequest("POST", "/login", nil)
+        req.RemoteAddr = ip + ":1234"
+        rr := httptest.NewRecorder()
+        h.ServeHTTP(rr, req)
+        return rr
+    }
+    rr := do("10.0.0.1")
+    assert.Equal(t, http.StatusOK, rr.Code)
+    assert.Equal(t, "2", rr.Header().Get("RateLimit-Limit"))
+    assert.Equal(t, "1", rr.Header().Get("RateLimit-Remaining"))
+    assert.Equal(t, http.StatusOK, do("10.0.0.1").Code)
+
+    rr = do("10.0.0.1")
+    assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+    assert.Equal(t, "1", rr.Header().Get("Retry-After"))
+    assert.Equal(t, http.StatusOK, do("10.0.0.2").Code, "other clients are unaffected")
+}
+
+func TestWithRequestTimeout_ExemptPaths(t *testing.T) {
+    h := WithRequestTimeout(10*time.Millisecond, "/v1/events")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        time.Sleep(50 * time.Millisecond)
+        // 被豁免的流式接口必须能拿到可 Flush 的 ResponseWriter
+        if r.URL.Path == "/v1/events" {
+            assert.NoError(t, http.NewResponseController(w).Flush())
+        }
+        w.WriteHeader(http.StatusOK)
+    }))
+    rr := httptest.NewRecorder()
+    h.ServeHTTP(rr, httptest.NewRequest("GET", "/v1/events", nil))
+    assert.Equal(t, http.StatusOK, rr.Code)
+
+    rr = httptest.NewRecorder()
+    h.ServeHTTP(rr, httptest.NewRequest("GET", "/v1/agents", nil))
+    assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+}
+
+func TestWithRequestTimeout_ExemptPattern(t *testing.T) {
+    h := WithRequestTimeout(10*time.Millisecond, "/v1/cases/*/exports")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        time.Sleep(50 * time.Millisecond)
+        w.WriteHeader(http.StatusOK)
+    }))
+    rr := httptest.NewRecorder()
+    h.ServeHTTP(rr, httptest.NewRequest("POST", "/v1/cases/7/exports", nil))
+    assert.Equal(t, http.StatusOK, rr.Code)
+
+    rr = httptest.NewRecorder()
+    h.ServeHTTP(rr, httptest.NewRequest("DELETE", "/v1/cases/7/key", nil))
+    assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+}
@@ -0,0 +1,144 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"guardian-backend/internal/auth"
+	"guardian-backend/internal/database"
+	"guardian-backend/pkg/httpx"
+	"guardian-backend/pkg/logger"
+)
+
+const (
+	// oidcStateCookie 保存签名后的 state / nonce / PKCE verifier，浏览器回跳时取回
+	oidcStateCookie = "guardian_oidc"
+	oidcStatePath   = "/login/oidc"
+	oidcStateTTL    = 10 * time.Minute
+	tokenUseOIDC    = "oidc_state"
+)
+
+// OIDCLogin 发起授权码流程（PKCE S256），重定向到 IdP
+func (h *AuthHandler) OIDCLogin(w http.ResponseWriter, r *http.Request) {
+	state, nonce, verifier, err := auth.NewOIDCState()
+	if err != nil {
+		httpx.WriteError(w, r, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to start sso")
+		return
+	}
+	signed, err := h.Keys.Sign(jwt.MapClaims{
+		"state":     state,
+		"nonce":     nonce,
+		"pkce":      verifier,
+		"token_use": tokenUseOIDC,
+		"exp":       time.Now().Add(oidcStateTTL).Unix(),
+	})
+	if err != nil {
+		httpx.WriteError(w, r, http.StatusInternalServerError, "TOKEN_ERROR", "failed to sign state")
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    signed,
+		Path:     oidcStatePath,
+		MaxAge:   int(oidcStateTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   isHTTPS(r),
+		// IdP 回跳是跨站顶级导航，Lax 才会携带 cookie
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, h.OIDC.AuthCodeURL(state, nonce, verifier), http.StatusFound)
+}
+
+// OIDCCallback 处理 IdP 回跳：校验 state，交换授权码，映射角色并即时创建用户后签发会话令牌
+func (h *AuthHandler) OIDCCallback(w http.ResponseWriter, r *http.Request) {
+	// state cookie 只能使用一次
+	http.SetCookie(w, &http.Cookie{Name: oidcStateCookie, Value: "", Path: oidcStatePath, MaxAge: -1, HttpOnly: true, Secure: isHTTPS(r)})
+
+	q := r.URL.Query()
+	if e := q.Get("error"); e != "" {
+		recordAudit(r, h.Sessions, database.AuditEntry{Action: "login.oidc", Outcome: database.AuditOutcomeDenied, Detail: map[string]any{"reason": e}})
+		httpx.WriteError(w, r, http.StatusUnauthorized, "SSO_ERROR", "identity provider returned "+e)
+		return
+	}
+	cookie, err := r.Cookie(oidcStateCookie)
+	if err != nil {
+		httpx.WriteError(w, r, http.StatusBadRequest, "INVALID_STATE", "missing sso state")
+		return
+	}
+	claims, err := h.Keys.Parse(cookie.Value)
+	if err != nil || claims["token_use"] != tokenUseOIDC {
+		httpx.WriteError(w, r, http.StatusBadRequest, "INVALID_STATE", "invalid or expired sso state")
+		return
+	}
+	state, _ := claims["state"].(string)
+	nonce, _ := claims["nonce"].(string)
+	verifier, _ := claims["pkce"].(string)
+	if state == "" || subtle.ConstantTimeCompare([]byte(state), []byte(q.Get("state"))) != 1 {
+		httpx.WriteError(w, r, http.StatusBadRequest, "INVALID_STATE", "sso state mismatch")
+		return
+	}
+
+	identity, err := h.OIDC.Exchange(r.Context(), q.Get("code"), verifier, nonce)
+	if err != nil {
+		logger.From(r.Context()).Warn("OIDC code exchange failed", "error", err)
+		recordAudit(r, h.Sessions, database.AuditEntry{Action: "login.oidc", Outcome: database.AuditOutcomeDenied, Detail: map[string]any{"reason": "exchange_failed"}})
+		httpx.WriteError(w, r, http.StatusUnauthorized, "UNAUTHORIZED", "sso authentication failed")
+		return
+	}
+	role, err := h.OIDC.MapRole(identity.Groups)
+	if err != nil {
+		recordAudit(r, h.Sessions, database.AuditEntry{
+			Actor:      identity.Username,
+			Action:     "login.oidc",
+			TargetType: "user",
+			Outcome:    database.AuditOutcomeDenied,
+			Detail:     map[string]any{"reason": "no_role_mapping", "subject": identity.Subject, "groups": identity.Groups},
+		})
+		httpx.WriteError(w, r, http.StatusForbidden, "NO_ROLE_MAPPING", "your identity provider groups are not mapped to a guardian role")
+		return
+	}
+	user, err := h.Users.UpsertOIDCUser(r.Context(), identity.Issuer, identity.Subject, identity.Username, identity.Email, role)
+	if err != nil {
+		if errors.Is(err, database.ErrUsernameTaken) {
+			httpx.WriteError(w, r, http.StatusConflict, "USERNAME_TAKEN", "username is already used by another account")
+			return
+		}
+		logger.From(r.Context()).Error("Failed to provision sso user", "error", err)
+		httpx.WriteError(w, r, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to provision user")
+		return
+	}
+	resp, err := h.newSession(r, user)
+	if err != nil {
+		logger.From(r.Context()).Error("Failed to create session", "error", err)
+		httpx.WriteError(w, r, http.StatusInternalServerError, "TOKEN_ERROR", "failed to create session")
+		return
+	}
+	userID := strconv.Itoa(user.ID)
+	recordAudit(r, h.Sessions, database.AuditEntry{
+		Actor:      userID,
+		Action:     "login.oidc",
+		TargetType: "user",
+		TargetID:   userID,
+		Detail:     map[string]any{"issuer": identity.Issuer, "subject": identity.Subject, "role": role},
+	})
+	if h.PostLoginRedirect == "" {
+		httpx.WriteJSON(w, http.StatusOK, resp)
+		return
+	}
+	// 令牌放在 URL fragment 中，不会发送到前端服务器或出现在访问日志里
+	fragment := url.Values{
+		"token":         {resp.Token},
+		"refresh_token": {resp.RefreshToken},
+		"expires_in":    {strconv.FormatInt(resp.ExpiresIn, 10)},
+	}
+	http.Redirect(w, r, h.PostLoginRedirect+"#"+fragment.Encode(), http.StatusFound)
+}
+
+func isHTTPS(r *http.Request) bool {
+	return r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https"
+}
@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"guardian-backend/internal/auth"
+	"guardian-backend/internal/auth/oidctest"
+)
+
+const callbackURL = "http://guardian.test/login/oidc/callback"
+
+func newOIDCHandler(t *testing.T, user oidctest.User) (*AuthHandler, *oidctest.Provider, *memUsers, *memSessionStore) {
+	t.Helper()
+	idp, srv, err := oidctest.NewServer("guardian-console", user)
+	require.NoError(t, err)
+	t.Cleanup(srv.Close)
+	provider, err := auth.NewOIDCProvider(context.Background(), auth.OIDCConfig{
+		IssuerURL:   srv.URL,
+		ClientID:    "guardian-console",
+		RedirectURL: callbackURL,
+		GroupRoles: []auth.GroupRole{
+			{Group: "guardian-admins", Role: "admin"},
+			{Group: "guardian-auditors", Role: "auditor"},
+		},
+	})
+	require.NoError(t, err)
+	users, sessions := &memUsers{}, &memSessionStore{}
+	return &AuthHandler{Keys: testKeyring(t), Sessions: sessions, Users: users, OIDC: provider}, idp, users, sessions
+}
+
+// startSSO 发起登录并让模拟 IdP 自动同意，返回回调地址与 state cookie
+func startSSO(t *testing.T, h *AuthHandler) (string, *http.Cookie) {
+	t.Helper()
+	rr := httptest.NewRecorder()
+	h.OIDCLogin(rr, httptest.NewRequest("GET", "/login/oidc", nil))
+	require.Equal(t, http.StatusFound, rr.Code)
+	authURL, err := url.Parse(rr.Header().Get("Location"))
+	require.NoError(t, err)
+	assert.Equal(t, "S256", authURL.Query().Get("code_challenge_method"))
+	assert.NotEmpty(t, authURL.Query().Get("nonce"))
+	cookies := rr.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.True(t, cookies[0].HttpOnly)
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(authURL.String())
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+	return resp.Header.Get("Location"), cookies[0]
+}
+
+func callback(h *AuthHandler, target string, cookie *http.Cookie) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", target, nil)
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	rr := httptest.NewRecorder()
+	h.OIDCCallback(rr, req)
+	return rr
+}
+
+func TestOIDC_LoginProvisionsUserWithMappedRole(t *testing.T) {
+	h, _, users, sessions := newOIDCHandler(t, oidctest.User{Subject: "u-1", PreferredUsername: "carol", Groups: []string{"staff", "guardian-auditors"}})
+	target, cookie := startSSO(t, h)
+
+	rr := callback(h, target, cookie)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var resp loginResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.NotEmpty(t, resp.Token)
+	assert.NotEmpty(t, resp.RefreshToken)
+	assert.Equal(t, "carol", users.user.Username)
+	require.Len(t, sessions.created, 1)
+	assert.Equal(t, "auditor", sessions.created[0].Role)
+
+	// 授权码只能使用一次
+	rr = callback(h, target, cookie)
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+}
+
+func TestOIDC_RejectsStateMismatchAndMissingCookie(t *testing.T) {
+	h, _, _, sessions := newOIDCHandler(t, oidctest.User{Subject: "u-1", PreferredUsername: "carol", Groups: []string{"guardian-auditors"}})
+	target, cookie := startSSO(t, h)
+
+	u, _ := url.Parse(target)
+	q := u.Query()
+	q.Set("state", "forged")
+	u.RawQuery = q.Encode()
+	assert.Equal(t, http.StatusBadRequest, callback(h, u.String(), cookie).Code)
+	assert.Equal(t, http.StatusBadRequest, callback(h, target, nil).Code)
+	assert.Empty(t, sessions.created)
+}
+
+func TestOIDC_UnmappedGroupIsDenied(t *testing.T) {
+	h, _, users, sessions := newOIDCHandler(t, oidctest.User{Subject: "u-2", PreferredUsername: "dave", Groups: []string{"staff"}})
+	target, cookie := startSSO(t, h)
+
+	rr := callback(h, target, cookie)
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	assert.Zero(t, users.user.ID, "user must not be provisioned")
+	assert.Empty(t, sessions.created)
+	require.NotEmpty(t, sessions.audits)
+	assert.Equal(t, "login.oidc", sessions.audits[len(sessions.audits)-1].Action)
+}
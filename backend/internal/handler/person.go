@@ -0,0 +1,185 @@
+package handler
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "net/http"
+    "strconv"
+    "time"
+
+    "github.com/go-chi/chi/v5"
+    "guardian-backend/internal/database"
+    "guardian-backend/pkg/httpx"
+    "guardian-backend/pkg/logger"
+    "guardian-backend/pkg/validator"
+)
+
+// PersonHandler 管理被监测人员及其监测告知/确认记录
+type PersonHandler struct {
+    DB interface {
+        auditor
+        CreateMonitoredPerson(ctx context.Context, fullName, employeeID string) (int, error)
+        ListMonitoredPersons(ctx context.Context) ([]database.MonitoredPerson, error)
+        RecordAcknowledgement(ctx context.Context, ack database.Acknowledgement) (int64, error)
+        LinkAgentToPerson(ctx context.Context, agentID, personID int) error
+    }
+}
+
+// PersonAdminRoles 为可登记被监测人员、告知确认并关联 agent 的角色。这些记录是下发任务的前置条件，
+// 因此不包含可下发任务的 approver，避免同一人自行补齐告知后批准采集
+var PersonAdminRoles = []string{"admin"}
+
+// Routes 注册被监测人员路由：列表对登录用户开放，新建与登记告知限 PersonAdminRoles
+func (h *PersonHandler) Routes(r chi.Router) {
+    r.Get("/v1/monitored-persons", h.List)
+    r.With(RequireRole(PersonAdminRoles...)).Post("/v1/monitored-persons", h.Create)
+    r.With(RequireRole(PersonAdminRoles...)).Post("/v1/monitored-persons/{personID}/acknowledgements", h.RecordAcknowledgement)
+}
+
+// AgentRoutes 在 /v1/agents/{agentID} 路由组内注册 agent 与人员的关联，限 PersonAdminRoles
+func (h *PersonHandler) AgentRoutes(agent chi.Router) {
+    agent.With(RequireRole(PersonAdminRoles...)).Put("/monitored-person", h.LinkAgent)
+}
+
+type ackDTO struct {
+    ID             int64  `json:"id"`
+    NoticeVersion  string `json:"notice_version"`
+    NotifiedAt     int64  `json:"notified_at"`
+    AcknowledgedAt *int64 `json:"acknowledged_at"`
+    RecordedBy     string `json:"recorded_by"`
+}
+
+type personDTO struct {
+    ID         int     `json:"id"`
+    FullName   string  `json:"full_name"`
+    EmployeeID string  `json:"employee_id,omitempty"`
+    LatestAck  *ackDTO `json:"latest_acknowledgement"`
+}
+
+// List 查询被监测人员及最近一条告知记录
+func (h *PersonHandler) List(w http.ResponseWriter, r *http.Request) {
+    list, err := h.DB.ListMonitoredPersons(r.Context())
+    if err != nil {
+        logger.From(r.Context()).Error("Failed to list monitored persons", "error", err)
+        httpx.WriteError(w, r, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to list monitored persons")
+        return
+    }
+    out := make([]personDTO, 0, len(list))
+    for _, p := range list {
+        dto := personDTO{ID: p.ID, FullName: p.FullName, EmployeeID: p.EmployeeID}
+        if a := p.LatestAck; a != nil {
+            dto.LatestAck = &ackDTO{ID: a.ID, NoticeVersion: a.NoticeVersion, NotifiedAt: a.NotifiedAt.UnixMilli(), RecordedBy: a.RecordedBy}
+            if a.AcknowledgedAt != nil {
+                ms := a.AcknowledgedAt.UnixMilli()
+                dto.LatestAck.AcknowledgedAt = &ms
+            }
+        }
+        out = append(out, dto)
+    }
+    httpx.WriteJSON(w, http.StatusOK, out)
+}
+
+// Create 新建被监测人员
+func (h *PersonHandler) Create(w http.ResponseWriter, r *http.Request) {
+    var payload CreatePersonPayload
+    if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+        httpx.WriteError(w, r, http.StatusBadRequest, "BAD_REQUEST", "invalid request body")
+        return
+    }
+    if err := validator.ValidateStruct(payload); err != nil {
+        httpx.WriteError(w, r, http.StatusBadRequest, "VALIDATION_FAILED", err.Error())
+        return
+    }
+    id, err := h.DB.CreateMonitoredPerson(r.Context(), payload.FullName, payload.EmployeeID)
+    if err != nil {
+        logger.From(r.Context()).Error("Failed to create monitored person", "error", err)
+        httpx.WriteError(w, r, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to create monitored person")
+        return
+    }
+    recordAudit(r, h.DB, database.AuditEntry{Action: "person.create", TargetType: "monitored_person", TargetID: strconv.Itoa(id)})
+    httpx.WriteJSON(w, http.StatusCreated, map[string]any{"id": id})
+}
+
+// RecordAcknowledgement 为人员登记一次监测告知及确认日期
+func (h *PersonHandler) RecordAcknowledgement(w http.ResponseWriter, r *http.Request) {
+    personID, err := strconv.Atoi(chi.URLParam(r, "personID"))
+    if err != nil {
+        httpx.WriteError(w, r, http.StatusBadRequest, "BAD_REQUEST", "invalid person id")
+        return
+    }
+    var payload AcknowledgementPayload
+    if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+        httpx.WriteError(w, r, http.StatusBadRequest, "BAD_REQUEST", "invalid request body")
+        return
+    }
+    if err := validator.ValidateStruct(payload); err != nil {
+        httpx.WriteError(w, r, http.StatusBadRequest, "VALIDATION_FAILED", err.Error())
+        return
+    }
+    if payload.AcknowledgedAt != nil && (payload.AcknowledgedAt.Before(payload.NotifiedAt) || payload.AcknowledgedAt.After(time.Now())) {
+        httpx.WriteError(w, r, http.StatusBadRequest, "VALIDATION_FAILED", "acknowledged_at must be between notified_at and now")
+        return
+    }
+    id, err := h.DB.RecordAcknowledgement(r.Context(), database.Acknowledgement{
+        PersonID:       personID,
+        NoticeVersion:  payload.NoticeVersion,
+        NotifiedAt:     payload.NotifiedAt,
+        AcknowledgedAt: payload.AcknowledgedAt,
+        RecordedBy:     PrincipalFrom(r.Context()).UserID,
+    })
+    if err != nil {
+        if errors.Is(err, database.ErrPersonNotFound) {
+            httpx.WriteError(w, r, http.StatusNotFound, "NOT_FOUND", "monitored person not found")
+            return
+        }
+        logger.From(r.Context()).Error("Failed to record acknowledgement", "error", err, "person_id", personID)
+        httpx.WriteError(w, r, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to record acknowledgement")
+        return
+    }
+    recordAudit(r, h.DB, database.AuditEntry{
+        Action:     "person.acknowledgement.record",
+        TargetType: "monitored_person",
+        TargetID:   strconv.Itoa(personID),
+        Detail:     map[string]any{"notice_version": payload.NoticeVersion, "acknowledged": payload.AcknowledgedAt != nil},
+    })
+    httpx.WriteJSON(w, http.StatusCreated, map[string]any{"id": id})
+}
+
+// LinkAgent 将 agent 关联到被监测人员
+func (h *PersonHandler) LinkAgent(w http.ResponseWriter, r *http.Request) {
+    agentID, ok := r.Context().Value(AgentIDKey).(int)
+    if !ok {
+        logger.From(r.Context()).Error("Could not retrieve agentID from context")
+        httpx.WriteError(w, r, http.StatusInternalServerError, "INTERNAL_ERROR", "agent id missing in context")
+        return
+    }
+    var payload LinkPersonPayload
+    if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+        httpx.WriteError(w, r, http.StatusBadRequest, "BAD_REQUEST", "invalid request body")
+        return
+    }
+    if err := validator.ValidateStruct(payload); err != nil {
+        httpx.WriteError(w, r, http.StatusBadRequest, "VALIDATION_FAILED", err.Error())
+        return
+    }
+    if err := h.DB.LinkAgentToPerson(r.Context(), agentID, payload.PersonID); err != nil {
+        switch {
+        case errors.Is(err, database.ErrAgentNotFound):
+            httpx.WriteError(w, r, http.StatusNotFound, "NOT_FOUND", "agent not found")
+        case errors.Is(err, database.ErrPersonNotFound):
+            httpx.WriteError(w, r, http.StatusNotFound, "NOT_FOUND", "monitored person not found")
+        default:
+            logger.From(r.Context()).Error("Failed to link agent to person", "error", err, "agent_id", agentID)
+            httpx.WriteError(w, r, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to link agent")
+        }
+        return
+    }
+    recordAudit(r, h.DB, database.AuditEntry{
+        Action:     "agent.person.link",
+        TargetType: "agent",
+        TargetID:   strconv.Itoa(agentID),
+        Detail:     map[string]any{"person_id": payload.PersonID},
+    })
+    httpx.WriteJSON(w, http.StatusOK, map[string]string{"status": "linked"})
+}
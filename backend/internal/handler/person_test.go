@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"guardian-backend/internal/database"
+)
+
+type mockPersonDB struct {
+	mock.Mock
+}
+
+func (m *mockPersonDB) RecordAudit(ctx context.Context, e database.AuditEntry) error {
+	return m.Called(ctx, e).Error(0)
+}
+
+func (m *mockPersonDB) CreateMonitoredPerson(ctx context.Context, fullName, employeeID string) (int, error) {
+	args := m.Called(ctx, fullName, employeeID)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *mockPersonDB) ListMonitoredPersons(ctx context.Context) ([]database.MonitoredPerson, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]database.MonitoredPerson), args.Error(1)
+}
+
+func (m *mockPersonDB) RecordAcknowledgement(ctx context.Context, ack database.Acknowledgement) (int64, error) {
+	args := m.Called(ctx, ack)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *mockPersonDB) LinkAgentToPerson(ctx context.Context, agentID, personID int) error {
+	return m.Called(ctx, agentID, personID).Error(0)
+}
+
+// personRouter 按 main 中的方式注册被监测人员路由
+func personRouter(h *PersonHandler) http.Handler {
+	router := chi.NewRouter()
+	h.Routes(router)
+	router.Route("/v1/agents/{agentID}", func(agent chi.Router) {
+		agent.Use(AgentCtx)
+		h.AgentRoutes(agent)
+	})
+	return router
+}
+
+func servePerson(h *PersonHandler, role, method, target, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req = req.WithContext(context.WithValue(req.Context(), PrincipalKey, Principal{UserID: "u-" + role, Role: role}))
+	rr := httptest.NewRecorder()
+	personRouter(h).ServeHTTP(rr, req)
+	return rr
+}
+
+func TestPersonHandler_WritesRequireAdmin(t *testing.T) {
+	requests := []struct {
+		method, target, body string
+	}{
+		{http.MethodPost, "/v1/monitored-persons", `{"full_name":"Zhang San"}`},
+		{http.MethodPost, "/v1/monitored-persons/4/acknowledgements", `{"notice_version":"v1","notified_at":"2024-05-01T00:00:00Z"}`},
+		{http.MethodPut, "/v1/agents/3/monitored-person", `{"person_id":4}`},
+	}
+	// approver 可下发任务，不能自行登记告知确认或关联人员以通过下发前的检查
+	for _, role := range []string{"approver", "auditor"} {
+		for _, req := range requests {
+			db := new(mockPersonDB)
+			rr := servePerson(&PersonHandler{DB: db}, role, req.method, req.target, req.body)
+			assert.Equal(t, http.StatusForbidden, rr.Code, "%s %s as %s", req.method, req.target, role)
+			db.AssertNotCalled(t, "CreateMonitoredPerson", mock.Anything, mock.Anything, mock.Anything)
+			db.AssertNotCalled(t, "RecordAcknowledgement", mock.Anything, mock.Anything)
+			db.AssertNotCalled(t, "LinkAgentToPerson", mock.Anything, mock.Anything, mock.Anything)
+		}
+	}
+}
+
+func TestPersonHandler_AdminLinksAgent(t *testing.T) {
+	db := new(mockPersonDB)
+	db.On("LinkAgentToPerson", mock.Anything, 3, 4).Return(nil)
+	db.On("RecordAudit", mock.Anything, mock.Anything).Return(nil)
+
+	rr := servePerson(&PersonHandler{DB: db}, "admin", http.MethodPut, "/v1/agents/3/monitored-person", `{"person_id":4}`)
+	assert.Less(t, rr.Code, 300, rr.Body.String())
+	db.AssertCalled(t, "LinkAgentToPerson", mock.Anything, 3, 4)
+}
+
+func TestPersonHandler_ListOpenToAllRoles(t *testing.T) {
+	db := new(mockPersonDB)
+	db.On("ListMonitoredPersons", mock.Anything).Return([]database.MonitoredPerson{}, nil)
+
+	rr := servePerson(&PersonHandler{DB: db}, "auditor", http.MethodGet, "/v1/monitored-persons", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+}
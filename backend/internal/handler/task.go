@@ -2,42 +2,76 @@ package handler
 
 import (
     "context"
+    "encoding/json"
     "errors"
-    "log/slog"
+    "io"
     "net/http"
     "strconv"
 
+    "github.com/go-chi/chi/v5"
+    "google.golang.org/protobuf/types/known/timestamppb"
     "guardian-backend/internal/database"
+    "guardian-backend/internal/redact"
+    "guardian-backend/internal/service"
+    "guardian-backend/pkg/apperr"
+    api "guardian-backend/pkg/grpc/api/guardian/pkg/grpc/api"
     "guardian-backend/pkg/httpx"
 )
 
+// TaskHandler 提供 /v1 下 agent、任务与消息相关的控制台接口。
+// 业务逻辑由 service.ConsoleServer 实现，这里只负责解析请求并输出 /v1 的 JSON 格式。
 type TaskHandler struct {
     DB interface {
         database.DBOperations
         ListAgents(ctx context.Context) ([]database.AgentInfo, error)
-        ListMessagesByAgent(ctx context.Context, agentID int) ([]database.WechatMessageRecord, error)
+        ListMessagesByAgent(ctx context.Context, agentID int, limit, offset int) ([]database.WechatMessageRecord, error)
+        ListMessagesByTask(ctx context.Context, taskID int64, limit, offset int) ([]database.WechatMessageRecord, error)
+        PurgeTaskMessages(ctx context.Context, taskID int64, reason string) (int64, error)
+        CreateDecommissionTask(ctx context.Context, agentID int, disposition string) (int64, error)
     }
+    // Redactor 在返回消息前按角色脱敏；为 nil 时不脱敏
+    Redactor *redact.Engine
+}
+
+func (h *TaskHandler) console() *service.ConsoleServer {
+    return &service.ConsoleServer{Agents: h.DB, Redactor: h.Redactor}
 }
 
 func (h *TaskHandler) Create(w http.ResponseWriter, r *http.Request) {
 	agentID, ok := r.Context().Value(AgentIDKey).(int)
 	if !ok {
-		slog.Error("Could not retrieve agentID from context")
-        httpx.WriteError(w, r, http.StatusInternalServerError, "INTERNAL_ERROR", "agent id missing in context")
+        httpx.WriteAppError(w, r, apperr.Internal(errors.New("agent id missing from request context")))
+		return
+	}
+	// 请求体可选：为空时创建不限范围的任务
+	var payload CreateTaskPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil && !errors.Is(err, io.EOF) {
+        httpx.WriteError(w, r, http.StatusBadRequest, "BAD_REQUEST", "invalid request body")
 		return
 	}
-	taskType := "DUMP_WECHAT_DATA"
-	err := h.DB.CreateTaskForAgent(r.Context(), agentID, taskType)
+	req := &api.CreateTaskRequest{
+		AgentId:               int32(agentID),
+		ExcludedConversations: payload.ExcludedConversations,
+		CaseId:                payload.CaseID,
+	}
+	if payload.ScopeStart != nil {
+		req.ScopeStart = timestamppb.New(*payload.ScopeStart)
+	}
+	if payload.ScopeEnd != nil {
+		req.ScopeEnd = timestamppb.New(*payload.ScopeEnd)
+	}
+	resp, err := h.console().CreateTask(serviceContext(r), req)
 	if err != nil {
-		if errors.Is(err, database.ErrAgentNotFound) {
-            httpx.WriteError(w, r, http.StatusNotFound, "NOT_FOUND", "agent not found")
-		} else {
-			slog.Error("Failed to create task", "error", err, "agent_id", agentID)
-            httpx.WriteError(w, r, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to create task")
-		}
+		httpx.WriteAppError(w, r, err)
 		return
 	}
-    httpx.WriteJSON(w, http.StatusCreated, map[string]string{"status": "task created"})
+    httpx.WriteJSON(w, http.StatusCreated, map[string]any{"status": "task created", "task_id": resp.TaskId})
+}
+
+type agentDTO struct {
+    ID     int    `json:"id"`
+    Name   string `json:"name"`
+    Status string `json:"status"`
 }
 
 // Agents 列表查询
@@ -46,59 +80,83 @@ func (h *TaskHandler) Agents(w http.ResponseWriter, r *http.Request) {
     q := r.URL.Query()
     page, _ := strconv.Atoi(q.Get("page"))
     size, _ := strconv.Atoi(q.Get("page_size"))
-    if page <= 0 { page = 1 }
-    if size <= 0 || size > 200 { size = 50 }
-
-    list, err := h.DB.ListAgents(r.Context())
+    resp, err := h.console().ListAgents(serviceContext(r), &api.ListAgentsRequest{Page: int32(page), PageSize: int32(size)})
     if err != nil {
-        slog.Error("Failed to list agents", "error", err)
-        httpx.WriteError(w, r, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to list agents")
+        httpx.WriteAppError(w, r, err)
         return
     }
-    type agentDTO struct{ ID int `json:"id"`; Name string `json:"name"` }
-    out := make([]agentDTO, 0, len(list))
-    for _, a := range list {
-        out = append(out, agentDTO{ID: a.ID, Name: a.Hostname})
+    out := make([]agentDTO, 0, len(resp.Agents))
+    for _, a := range resp.Agents {
+        out = append(out, agentDTO{ID: int(a.Id), Name: a.Hostname, Status: a.Status})
     }
-    // 服务器端简单截断
-    start := (page-1)*size
-    if start > len(out) { start = len(out) }
-    end := start + size
-    if end > len(out) { end = len(out) }
-
-    httpx.WriteJSON(w, http.StatusOK, out[start:end])
+    httpx.WriteJSON(w, http.StatusOK, out)
 }
 
 // MessagesByAgent 查询某 Agent 的消息
 func (h *TaskHandler) MessagesByAgent(w http.ResponseWriter, r *http.Request) {
     agentID, ok := r.Context().Value(AgentIDKey).(int)
     if !ok {
-        slog.Error("Could not retrieve agentID from context")
-        http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+        httpx.WriteAppError(w, r, apperr.Internal(errors.New("agent id missing from request context")))
+        return
+    }
+    h.writeMessages(w, r, &api.ListMessagesRequest{AgentId: int32(agentID)})
+}
+
+// MessagesByTask 查询某任务采集的消息
+func (h *TaskHandler) MessagesByTask(w http.ResponseWriter, r *http.Request) {
+    taskID, err := strconv.ParseInt(chi.URLParam(r, "taskID"), 10, 64)
+    if err != nil || taskID <= 0 {
+        httpx.WriteError(w, r, http.StatusBadRequest, "BAD_REQUEST", "invalid task id")
         return
     }
-    // 简单分页：?page=1&page_size=100
+    h.writeMessages(w, r, &api.ListMessagesRequest{TaskId: taskID})
+}
+
+// PurgeTaskData 清除某任务采集的全部消息（例如事后认定任务不当），未结束的任务随之取消
+func (h *TaskHandler) PurgeTaskData(w http.ResponseWriter, r *http.Request) {
+    taskID, err := strconv.ParseInt(chi.URLParam(r, "taskID"), 10, 64)
+    if err != nil {
+        httpx.WriteError(w, r, http.StatusBadRequest, "BAD_REQUEST", "invalid task id")
+        return
+    }
+    var payload PurgeTaskDataPayload
+    if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+        httpx.WriteError(w, r, http.StatusBadRequest, "BAD_REQUEST", "invalid request body")
+        return
+    }
+    resp, err := h.console().PurgeTaskMessages(serviceContext(r), &api.PurgeTaskMessagesRequest{TaskId: taskID, Confirm: payload.Confirm, Reason: payload.Reason})
+    if err != nil {
+        httpx.WriteAppError(w, r, err)
+        return
+    }
+    httpx.WriteJSON(w, http.StatusOK, map[string]any{"task_id": resp.TaskId, "messages_purged": resp.MessagesPurged})
+}
+
+type msgDTO struct {
+    Content   string `json:"content"`
+    Timestamp int64  `json:"timestamp"`
+    TaskID    *int64 `json:"task_id,omitempty"`
+    Redacted  bool   `json:"redacted,omitempty"`
+}
+
+// writeMessages 按查询参数补全请求后查询消息：?page=1&page_size=100&q=...&unmask=true&reason=...
+// 脱敏、检索与查看明文的审计规则见 service.ConsoleServer.ListMessages
+func (h *TaskHandler) writeMessages(w http.ResponseWriter, r *http.Request, req *api.ListMessagesRequest) {
     q := r.URL.Query()
     page, _ := strconv.Atoi(q.Get("page"))
     size, _ := strconv.Atoi(q.Get("page_size"))
-    if page <= 0 { page = 1 }
-    if size <= 0 || size > 500 { size = 100 }
-
-    recs, err := h.DB.ListMessagesByAgent(r.Context(), agentID)
+    req.Page, req.PageSize = int32(page), int32(size)
+    req.Q = q.Get("q")
+    req.Unmask = q.Get("unmask") == "true"
+    req.Reason = q.Get("reason")
+    resp, err := h.console().ListMessages(serviceContext(r), req)
     if err != nil {
-        slog.Error("Failed to list messages", "error", err, "agent_id", agentID)
-        httpx.WriteError(w, r, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to list messages")
+        httpx.WriteAppError(w, r, err)
         return
     }
-    type msgDTO struct{ Content string `json:"content"`; Timestamp int64 `json:"timestamp"` }
-    out := make([]msgDTO, 0, len(recs))
-    for _, m := range recs {
-        out = append(out, msgDTO{Content: m.Content, Timestamp: m.Timestamp.UnixMilli()})
+    out := make([]msgDTO, 0, len(resp.Messages))
+    for _, m := range resp.Messages {
+        out = append(out, msgDTO{Content: m.Content, Timestamp: m.Timestamp.AsTime().UnixMilli(), TaskID: m.TaskId, Redacted: m.Redacted})
     }
-    start := (page-1)*size
-    if start > len(out) { start = len(out) }
-    end := start + size
-    if end > len(out) { end = len(out) }
-
-    httpx.WriteJSON(w, http.StatusOK, out[start:end])
+    httpx.WriteJSON(w, http.StatusOK, out)
 }
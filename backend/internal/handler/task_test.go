@@ -4,11 +4,16 @@ import (
     "context"
     "net/http"
     "net/http/httptest"
+    "strings"
     "testing"
+    "time"
 
     "github.com/go-chi/chi/v5"
     "github.com/stretchr/testify/assert"
     "github.com/stretchr/testify/mock"
+    "guardian-backend/internal/database"
+    "guardian-backend/internal/redact"
+    api "guardian-backend/pkg/grpc/api/guardian/pkg/grpc/api"
 )
 
 // 1. 创建一个数据库的模拟对象
@@ -16,20 +21,58 @@ import (
 	mock.Mock
 }
 // 2. 为模拟对象实现我们定义的接口
-func (m *MockDB) CreateTaskForAgent(ctx context.Context, agentID int, taskType string) error {
-	args := m.Called(ctx, agentID, taskType)
+func (m *MockDB) CreateTaskForAgent(ctx context.Context, agentID int, taskType string, scope database.TaskScope) (int64, error) {
+	args := m.Called(ctx, agentID, taskType, scope)
+	return int64(args.Int(0)), args.Error(1)
+}
+
+func (m *MockDB) SaveMessages(ctx context.Context, batch database.IngestionBatch, messages []*api.ChatMessage) (int64, error) {
+	args := m.Called(ctx, batch, messages)
+	return int64(args.Int(0)), args.Error(1)
+}
+
+func (m *MockDB) RecordAudit(ctx context.Context, e database.AuditEntry) error {
+	args := m.Called(ctx, e)
 	return args.Error(0)
 }
 
+func (m *MockDB) CreateDecommissionTask(ctx context.Context, agentID int, disposition string) (int64, error) {
+	args := m.Called(ctx, agentID, disposition)
+	return int64(args.Int(0)), args.Error(1)
+}
+
+func (m *MockDB) ListAgents(ctx context.Context) ([]database.AgentInfo, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]database.AgentInfo), args.Error(1)
+}
+
+func (m *MockDB) ListMessagesByAgent(ctx context.Context, agentID int, limit, offset int) ([]database.WechatMessageRecord, error) {
+	args := m.Called(ctx, agentID, limit, offset)
+	return args.Get(0).([]database.WechatMessageRecord), args.Error(1)
+}
+
+func (m *MockDB) ListMessagesByTask(ctx context.Context, taskID int64, limit, offset int) ([]database.WechatMessageRecord, error) {
+	args := m.Called(ctx, taskID, limit, offset)
+	return args.Get(0).([]database.WechatMessageRecord), args.Error(1)
+}
+
+func (m *MockDB) PurgeTaskMessages(ctx context.Context, taskID int64, reason string) (int64, error) {
+	args := m.Called(ctx, taskID, reason)
+	return int64(args.Int(0)), args.Error(1)
+}
+
 // 3. 编写测试函数
 func TestTaskHandler_Create_Success(t *testing.T) {
 	mockDB := new(MockDB)
-	mockDB.On("CreateTaskForAgent", mock.Anything, 1, "DUMP_WECHAT_DATA").Return(nil)
+	mockDB.On("CreateTaskForAgent", mock.Anything, 1, "DUMP_WECHAT_DATA", database.TaskScope{}).Return(1, nil)
+	mockDB.On("RecordAudit", mock.Anything, mock.MatchedBy(func(e database.AuditEntry) bool {
+		return e.Action == "task.create" && e.Outcome == database.AuditOutcomeSuccess
+	})).Return(nil)
 	handler := TaskHandler{DB: mockDB}
 	req := httptest.NewRequest("POST", "/v1/agents/1/tasks", nil)
 	rr := httptest.NewRecorder()
 	router := chi.NewRouter()
-	router.Post("/v1/agents/{agentID}/tasks", handler.Create)
+	router.With(AgentCtx).Post("/v1/agents/{agentID}/tasks", handler.Create)
 	router.ServeHTTP(rr, req)
 	assert.Equal(t, http.StatusCreated, rr.Code)
 	mockDB.AssertExpectations(t)
@@ -37,13 +80,234 @@ func TestTaskHandler_Create_Success(t *testing.T) {
 
 func TestTaskHandler_Create_DBError(t *testing.T) {
 	mockDB := new(MockDB)
-	mockDB.On("CreateTaskForAgent", mock.Anything, 2, "DUMP_WECHAT_DATA").Return(assert.AnError)
+	mockDB.On("CreateTaskForAgent", mock.Anything, 2, "DUMP_WECHAT_DATA", database.TaskScope{}).Return(0, assert.AnError)
 	handler := TaskHandler{DB: mockDB}
 	req := httptest.NewRequest("POST", "/v1/agents/2/tasks", nil)
 	rr := httptest.NewRecorder()
 	router := chi.NewRouter()
-	router.Post("/v1/agents/{agentID}/tasks", handler.Create)
+	router.With(AgentCtx).Post("/v1/agents/{agentID}/tasks", handler.Create)
 	router.ServeHTTP(rr, req)
 	assert.Equal(t, http.StatusInternalServerError, rr.Code)
 	mockDB.AssertExpectations(t)
 }
+
+func TestTaskHandler_Create_WithScope(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	mockDB := new(MockDB)
+	mockDB.On("CreateTaskForAgent", mock.Anything, 3, "DUMP_WECHAT_DATA", database.TaskScope{
+		ScopeStart:            &start,
+		ScopeEnd:              &end,
+		ExcludedConversations: []string{"wxid_private"},
+	}).Return(7, nil)
+	mockDB.On("RecordAudit", mock.Anything, mock.Anything).Return(nil)
+	handler := TaskHandler{DB: mockDB}
+	body := `{"scope_start":"2025-01-01T00:00:00Z","scope_end":"2025-02-01T00:00:00Z","excluded_conversations":["wxid_private"]}`
+	req := httptest.NewRequest("POST", "/v1/agents/3/tasks", strings.NewReader(body))
+	rr := httptest.NewRecorder()
+	router := chi.NewRouter()
+	router.With(AgentCtx).Post("/v1/agents/{agentID}/tasks", handler.Create)
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"task_id":7`)
+	mockDB.AssertExpectations(t)
+}
+
+func TestTaskHandler_Create_InvalidScope(t *testing.T) {
+	mockDB := new(MockDB)
+	handler := TaskHandler{DB: mockDB}
+	body := `{"scope_start":"2025-02-01T00:00:00Z","scope_end":"2025-01-01T00:00:00Z"}`
+	req := httptest.NewRequest("POST", "/v1/agents/3/tasks", strings.NewReader(body))
+	rr := httptest.NewRecorder()
+	router := chi.NewRouter()
+	router.With(AgentCtx).Post("/v1/agents/{agentID}/tasks", handler.Create)
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	mockDB.AssertNotCalled(t, "CreateTaskForAgent")
+}
+
+func TestTaskHandler_Create_AcknowledgementRequiredIsAudited(t *testing.T) {
+	mockDB := new(MockDB)
+	mockDB.On("CreateTaskForAgent", mock.Anything, 4, "DUMP_WECHAT_DATA", database.TaskScope{}).Return(0, database.ErrAcknowledgementRequired)
+	mockDB.On("RecordAudit", mock.Anything, mock.MatchedBy(func(e database.AuditEntry) bool {
+		return e.Action == "task.create" && e.Outcome == database.AuditOutcomeDenied &&
+			e.TargetType == "agent" && e.TargetID == "4" && e.Detail["reason"] == "acknowledgement_required"
+	})).Return(nil)
+	handler := TaskHandler{DB: mockDB}
+	req := httptest.NewRequest("POST", "/v1/agents/4/tasks", nil)
+	rr := httptest.NewRecorder()
+	router := chi.NewRouter()
+	router.With(AgentCtx).Post("/v1/agents/{agentID}/tasks", handler.Create)
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	assert.Contains(t, rr.Body.String(), "ACKNOWLEDGEMENT_REQUIRED")
+	mockDB.AssertExpectations(t)
+}
+
+func TestTaskHandler_Decommission(t *testing.T) {
+	mockDB := new(MockDB)
+	mockDB.On("CreateDecommissionTask", mock.Anything, 5, database.DispositionPurge).Return(9, nil)
+	mockDB.On("RecordAudit", mock.Anything, mock.MatchedBy(func(e database.AuditEntry) bool {
+		return e.Action == "agent.decommission.request" && e.Outcome == database.AuditOutcomeSuccess &&
+			e.Detail["data_disposition"] == database.DispositionPurge
+	})).Return(nil)
+	handler := TaskHandler{DB: mockDB}
+	req := httptest.NewRequest("POST", "/v1/agents/5/decommission", strings.NewReader(`{"data_disposition":"purge","reason":"left company"}`))
+	rr := httptest.NewRecorder()
+	router := chi.NewRouter()
+	router.With(AgentCtx).Post("/v1/agents/{agentID}/decommission", handler.Decommission)
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusAccepted, rr.Code)
+	mockDB.AssertExpectations(t)
+}
+
+func TestTaskHandler_Decommission_RequiresDisposition(t *testing.T) {
+	mockDB := new(MockDB)
+	handler := TaskHandler{DB: mockDB}
+	req := httptest.NewRequest("POST", "/v1/agents/5/decommission", strings.NewReader(`{"data_disposition":"keep"}`))
+	rr := httptest.NewRecorder()
+	router := chi.NewRouter()
+	router.With(AgentCtx).Post("/v1/agents/{agentID}/decommission", handler.Decommission)
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	mockDB.AssertNotCalled(t, "CreateDecommissionTask")
+}
+
+func listMessages(h *TaskHandler, role, query string) *httptest.ResponseRecorder {
+	router := chi.NewRouter()
+	router.With(AgentCtx).Get("/v1/agents/{agentID}/messages", h.MessagesByAgent)
+	req := httptest.NewRequest("GET", "/v1/agents/1/messages"+query, nil)
+	req = req.WithContext(context.WithValue(req.Context(), PrincipalKey, Principal{UserID: "9", Role: role}))
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	return rr
+}
+
+func testRedactor() *redact.Engine {
+	phone, _ := redact.Builtin(redact.DetectorPhone)
+	return redact.New([]redact.Detector{phone}, redact.Policy{UnmaskRoles: []string{"admin"}})
+}
+
+func TestTaskHandler_MessagesByAgent_RedactsAndSearchesMaskedText(t *testing.T) {
+	mockDB := new(MockDB)
+	recs := []database.WechatMessageRecord{
+		{Content: "call me at 13800138000", Timestamp: time.UnixMilli(2000)},
+		{Content: "see you", Timestamp: time.UnixMilli(1000)},
+	}
+	// 普通查询在数据库中取本页；检索按批扫描
+	mockDB.On("ListMessagesByAgent", mock.Anything, 1, 100, 0).Return(recs, nil).Once()
+	mockDB.On("ListMessagesByAgent", mock.Anything, 1, 500, 0).Return(recs, nil).Once()
+	// 每次查询都记录访问，包括检索结果为空的查询
+	mockDB.On("RecordAudit", mock.Anything, mock.MatchedBy(func(e database.AuditEntry) bool {
+		return e.Action == "messages.read" && e.TargetType == "agent" && e.TargetID == "1" && e.Detail["messages"] == 2
+	})).Return(nil).Once()
+	mockDB.On("RecordAudit", mock.Anything, mock.MatchedBy(func(e database.AuditEntry) bool {
+		return e.Action == "messages.read" && e.Detail["query"] == "0013" && e.Detail["messages"] == 0
+	})).Return(nil).Once()
+	h := &TaskHandler{DB: mockDB, Redactor: testRedactor()}
+
+	rr := listMessages(h, "auditor", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"content":"call me at 138****8000","timestamp":2000,"redacted":true`)
+	assert.NotContains(t, rr.Body.String(), "13800138000")
+
+	// 检索作用于脱敏后的内容，不能借此确认被遮盖的号码
+	rr = listMessages(h, "auditor", "?q=0013")
+	assert.JSONEq(t, `[]`, rr.Body.String())
+	mockDB.AssertExpectations(t)
+}
+
+func TestTaskHandler_MessagesByAgent_PagesBeyondFirst500(t *testing.T) {
+	mockDB := new(MockDB)
+	older := []database.WechatMessageRecord{{Content: "old note", Timestamp: time.UnixMilli(1000)}}
+	// 第 3 页（每页 500 条）直接以 OFFSET 1000 查询
+	mockDB.On("ListMessagesByAgent", mock.Anything, 1, 500, 1000).Return(older, nil).Once()
+	// 检索逐批扫描，命中出现在第 500 条之后也能返回
+	filler := make([]database.WechatMessageRecord, 500)
+	for i := range filler {
+		filler[i] = database.WechatMessageRecord{Content: "chatter", Timestamp: time.UnixMilli(5000)}
+	}
+	mockDB.On("ListMessagesByAgent", mock.Anything, 1, 500, 0).Return(filler, nil).Once()
+	mockDB.On("ListMessagesByAgent", mock.Anything, 1, 500, 500).Return(older, nil).Once()
+	mockDB.On("RecordAudit", mock.Anything, mock.Anything).Return(nil)
+	h := &TaskHandler{DB: mockDB}
+
+	rr := listMessages(h, "auditor", "?page=3&page_size=500")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `[{"content":"old note","timestamp":1000}]`, rr.Body.String())
+
+	rr = listMessages(h, "auditor", "?q=NOTE")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `[{"content":"old note","timestamp":1000}]`, rr.Body.String())
+	mockDB.AssertExpectations(t)
+}
+
+func TestTaskHandler_MessagesByAgent_Unmask(t *testing.T) {
+	mockDB := new(MockDB)
+	mockDB.On("ListMessagesByAgent", mock.Anything, 1, 100, 0).Return([]database.WechatMessageRecord{
+		{Content: "call me at 13800138000", Timestamp: time.UnixMilli(2000)},
+	}, nil)
+	mockDB.On("RecordAudit", mock.Anything, mock.MatchedBy(func(e database.AuditEntry) bool {
+		return e.Action == "messages.unmask" && e.Outcome == database.AuditOutcomeDenied
+	})).Return(nil).Once()
+	mockDB.On("RecordAudit", mock.Anything, mock.MatchedBy(func(e database.AuditEntry) bool {
+		return e.Action == "messages.unmask" && e.Outcome == database.AuditOutcomeSuccess &&
+			e.Detail["reason"] == "case 12 review" && e.Detail["fields_revealed"] == 1
+	})).Return(nil).Once()
+	h := &TaskHandler{DB: mockDB, Redactor: testRedactor()}
+
+	rr := listMessages(h, "auditor", "?unmask=true&reason=x")
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+
+	rr = listMessages(h, "admin", "?unmask=true")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	rr = listMessages(h, "admin", "?unmask=true&reason=case+12+review")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), "13800138000")
+	mockDB.AssertExpectations(t)
+}
+
+func TestTaskHandler_MessagesByTask(t *testing.T) {
+	mockDB := new(MockDB)
+	taskID := int64(5)
+	mockDB.On("ListMessagesByTask", mock.Anything, taskID, 100, 0).Return([]database.WechatMessageRecord{
+		{Content: "hi", Timestamp: time.UnixMilli(1000), TaskID: &taskID},
+	}, nil)
+	mockDB.On("ListMessagesByTask", mock.Anything, int64(6), 100, 0).Return([]database.WechatMessageRecord(nil), database.ErrTaskNotFound)
+	mockDB.On("RecordAudit", mock.Anything, mock.MatchedBy(func(e database.AuditEntry) bool {
+		return e.Action == "messages.read" && e.TargetType == "task" && e.TargetID == "5"
+	})).Return(nil).Once()
+	router := chi.NewRouter()
+	router.Get("/v1/tasks/{taskID}/messages", (&TaskHandler{DB: mockDB}).MessagesByTask)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/v1/tasks/5/messages", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `[{"content":"hi","timestamp":1000,"task_id":5}]`, rr.Body.String())
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/v1/tasks/6/messages", nil))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	mockDB.AssertExpectations(t)
+}
+
+func TestTaskHandler_PurgeTaskData(t *testing.T) {
+	mockDB := new(MockDB)
+	mockDB.On("PurgeTaskMessages", mock.Anything, int64(5), "scope exceeded approval").Return(42, nil)
+	mockDB.On("RecordAudit", mock.Anything, mock.MatchedBy(func(e database.AuditEntry) bool {
+		return e.Action == "task.data.purge" && e.TargetType == "task" && e.TargetID == "5" && e.Detail["messages_purged"] == int64(42)
+	})).Return(nil)
+	router := chi.NewRouter()
+	router.Delete("/v1/tasks/{taskID}/messages", (&TaskHandler{DB: mockDB}).PurgeTaskData)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("DELETE", "/v1/tasks/5/messages", strings.NewReader(`{"confirm":4,"reason":"x"}`)))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("DELETE", "/v1/tasks/5/messages", strings.NewReader(`{"confirm":5,"reason":"scope exceeded approval"}`)))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"task_id":5,"messages_purged":42}`, rr.Body.String())
+	mockDB.AssertExpectations(t)
+}
@@ -1,5 +1,7 @@
 package handler
 
+import "time"
+
 type UploadMessagesPayload struct {
 	Messages []*ChatMessagePayload `json:"messages" validate:"required,dive"`
 }
@@ -8,3 +10,67 @@ type ChatMessagePayload struct {
 	Content   string `json:"content" validate:"required,max=10000"`
 	Timestamp int64  `json:"timestamp" validate:"required"`
 }
+
+// CreateTaskPayload 是创建任务的可选请求体，用于限定授权的采集范围
+type CreateTaskPayload struct {
+	ScopeStart            *time.Time `json:"scope_start"`
+	ScopeEnd              *time.Time `json:"scope_end"`
+	ExcludedConversations []string   `json:"excluded_conversations"`
+	// CaseID 为任务所属案件，决定采集数据的加密密钥；为空时归入 agent 的默认案件
+	CaseID int64 `json:"case_id"`
+}
+
+// CreatePersonPayload 是新建被监测人员的请求体
+type CreatePersonPayload struct {
+	FullName   string `json:"full_name" validate:"required,max=255"`
+	EmployeeID string `json:"employee_id" validate:"max=128"`
+}
+
+// AcknowledgementPayload 记录一次监测告知；acknowledged_at 为空表示已告知但尚未确认
+type AcknowledgementPayload struct {
+	NoticeVersion  string     `json:"notice_version" validate:"required,max=64"`
+	NotifiedAt     time.Time  `json:"notified_at" validate:"required"`
+	AcknowledgedAt *time.Time `json:"acknowledged_at"`
+}
+
+// LinkPersonPayload 将 agent 关联到被监测人员
+type LinkPersonPayload struct {
+	PersonID int `json:"person_id" validate:"required,gt=0"`
+}
+
+// DecommissionPayload 是退役 agent 的请求体，管理员须明确数据处置方式
+type DecommissionPayload struct {
+	DataDisposition string `json:"data_disposition"`
+	Reason          string `json:"reason"`
+}
+
+// CreateCasePayload 是新建案件的请求体
+type CreateCasePayload struct {
+	Name string `json:"name"`
+}
+
+// DestroyCaseKeyPayload 是销毁案件密钥的请求体；confirm 须与案件 ID 一致，防止误操作
+type DestroyCaseKeyPayload struct {
+	Confirm int64  `json:"confirm"`
+	Reason  string `json:"reason"`
+}
+
+// CreateExportPayload 是导出案件证据包的请求体
+type CreateExportPayload struct {
+	Reason string `json:"reason" validate:"required,max=1000"`
+	// Redact 为 true 时遮盖全部检测到的敏感字段
+	Redact bool `json:"redact"`
+}
+
+// PurgeTaskDataPayload 是清除任务数据的请求体；confirm 须与任务 ID 一致，防止误操作
+type PurgeTaskDataPayload struct {
+	Confirm int64  `json:"confirm"`
+	Reason  string `json:"reason"`
+}
+
+// CreateWebhookPayload 是新建 webhook 订阅的请求体；event_types 为空表示订阅全部事件
+type CreateWebhookPayload struct {
+	URL         string   `json:"url" validate:"required,url,max=2048"`
+	EventTypes  []string `json:"event_types" validate:"omitempty,dive,oneof=task.completed task.failed task.timed_out agent.offline"`
+	Description string   `json:"description" validate:"max=255"`
+}
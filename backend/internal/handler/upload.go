@@ -2,9 +2,10 @@ package handler
 
 import (
     "encoding/json"
-    "log/slog"
     "net/http"
 
+    "guardian-backend/pkg/httpx"
+    "guardian-backend/pkg/logger"
     "guardian-backend/pkg/validator"
 )
 
@@ -12,15 +13,14 @@ import (
 func (h *TaskHandler) UploadMessages(w http.ResponseWriter, r *http.Request) {
 	var payload UploadMessagesPayload
 	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		httpx.WriteError(w, r, http.StatusBadRequest, "BAD_REQUEST", "invalid request body")
 		return
 	}
 	if err := validator.ValidateStruct(payload); err != nil {
-		slog.Warn("Validation failed", "error", err)
-		http.Error(w, "Invalid data: "+err.Error(), http.StatusBadRequest)
+		logger.From(r.Context()).Warn("Validation failed", "error", err)
+		httpx.WriteError(w, r, http.StatusBadRequest, "VALIDATION_FAILED", err.Error())
 		return
 	}
 	// TODO: 业务逻辑处理，如保存消息到数据库
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte(`{"status": "messages uploaded"}`))
+	httpx.WriteJSON(w, http.StatusOK, map[string]string{"status": "messages uploaded"})
 }
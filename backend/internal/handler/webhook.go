@@ -0,0 +1,220 @@
+package handler
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"guardian-backend/internal/database"
+	"guardian-backend/pkg/httpx"
+	"guardian-backend/pkg/logger"
+	"guardian-backend/pkg/validator"
+)
+
+// 投递日志默认与最多返回的条数
+const (
+	defaultDeliveryLimit = 50
+	maxDeliveryLimit     = 200
+)
+
+// WebhookHandler 管理 webhook 订阅并提供投递日志
+type WebhookHandler struct {
+	DB interface {
+		auditor
+		CreateWebhookSubscription(ctx context.Context, s database.WebhookSubscription) (int, error)
+		ListWebhookSubscriptions(ctx context.Context) ([]database.WebhookSubscription, error)
+		DeleteWebhookSubscription(ctx context.Context, id int) error
+		ListWebhookDeliveries(ctx context.Context, subscriptionID, limit int) ([]database.WebhookDelivery, error)
+	}
+}
+
+type webhookDTO struct {
+	ID          int      `json:"id"`
+	URL         string   `json:"url"`
+	EventTypes  []string `json:"event_types"`
+	Description string   `json:"description,omitempty"`
+	CreatedBy   string   `json:"created_by,omitempty"`
+	CreatedAt   int64    `json:"created_at"`
+	// Secret 只在创建时返回一次
+	Secret string `json:"secret,omitempty"`
+}
+
+type webhookAttemptDTO struct {
+	AttemptedAt    int64  `json:"attempted_at"`
+	ResponseStatus int    `json:"response_status,omitempty"`
+	Error          string `json:"error,omitempty"`
+	DurationMS     int64  `json:"duration_ms"`
+}
+
+type webhookDeliveryDTO struct {
+	ID            int64               `json:"id"`
+	EventID       int64               `json:"event_id"`
+	EventType     string              `json:"event_type"`
+	Payload       json.RawMessage     `json:"payload"`
+	Status        string              `json:"status"`
+	Attempts      int                 `json:"attempts"`
+	NextAttemptAt *int64              `json:"next_attempt_at,omitempty"`
+	CreatedAt     int64               `json:"created_at"`
+	CompletedAt   *int64              `json:"completed_at,omitempty"`
+	Log           []webhookAttemptDTO `json:"log"`
+}
+
+func toWebhookDTO(s database.WebhookSubscription) webhookDTO {
+	eventTypes := s.EventTypes
+	if eventTypes == nil {
+		eventTypes = []string{}
+	}
+	return webhookDTO{ID: s.ID, URL: s.URL, EventTypes: eventTypes, Description: s.Description, CreatedBy: s.CreatedBy, CreatedAt: s.CreatedAt.UnixMilli()}
+}
+
+// List 查询全部订阅（不含密钥）
+func (h *WebhookHandler) List(w http.ResponseWriter, r *http.Request) {
+	list, err := h.DB.ListWebhookSubscriptions(r.Context())
+	if err != nil {
+		logger.From(r.Context()).Error("Failed to list webhooks", "error", err)
+		httpx.WriteError(w, r, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to list webhooks")
+		return
+	}
+	out := make([]webhookDTO, 0, len(list))
+	for _, s := range list {
+		out = append(out, toWebhookDTO(s))
+	}
+	httpx.WriteJSON(w, http.StatusOK, out)
+}
+
+// Create 新建订阅；签名密钥由服务端生成，仅在响应中返回一次
+func (h *WebhookHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var payload CreateWebhookPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		httpx.WriteError(w, r, http.StatusBadRequest, "BAD_REQUEST", "invalid request body")
+		return
+	}
+	if err := validator.ValidateStruct(payload); err != nil {
+		httpx.WriteError(w, r, http.StatusBadRequest, "VALIDATION_FAILED", err.Error())
+		return
+	}
+	if u, err := url.Parse(payload.URL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		httpx.WriteError(w, r, http.StatusBadRequest, "VALIDATION_FAILED", "url must be an absolute http(s) url")
+		return
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		httpx.WriteError(w, r, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to generate secret")
+		return
+	}
+	s := database.WebhookSubscription{
+		URL:         payload.URL,
+		Secret:      hex.EncodeToString(secret),
+		EventTypes:  payload.EventTypes,
+		Description: payload.Description,
+		CreatedBy:   PrincipalFrom(r.Context()).UserID,
+	}
+	id, err := h.DB.CreateWebhookSubscription(r.Context(), s)
+	if err != nil {
+		logger.From(r.Context()).Error("Failed to create webhook", "error", err)
+		httpx.WriteError(w, r, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to create webhook")
+		return
+	}
+	s.ID = id
+	recordAudit(r, h.DB, database.AuditEntry{
+		Action:     "webhook.create",
+		TargetType: "webhook",
+		TargetID:   strconv.Itoa(id),
+		Detail:     map[string]any{"url": s.URL, "event_types": toWebhookDTO(s).EventTypes},
+	})
+	dto := toWebhookDTO(s)
+	dto.Secret = s.Secret
+	httpx.WriteJSON(w, http.StatusCreated, dto)
+}
+
+// Delete 删除订阅，未完成的投递随之取消
+func (h *WebhookHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, ok := webhookID(w, r)
+	if !ok {
+		return
+	}
+	if err := h.DB.DeleteWebhookSubscription(r.Context(), id); err != nil {
+		if errors.Is(err, database.ErrWebhookNotFound) {
+			httpx.WriteError(w, r, http.StatusNotFound, "NOT_FOUND", "webhook not found")
+			return
+		}
+		logger.From(r.Context()).Error("Failed to delete webhook", "error", err, "webhook_id", id)
+		httpx.WriteError(w, r, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to delete webhook")
+		return
+	}
+	recordAudit(r, h.DB, database.AuditEntry{Action: "webhook.delete", TargetType: "webhook", TargetID: strconv.Itoa(id)})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Deliveries 返回订阅最近的投递及每次尝试的结果；?limit= 默认 50，最多 200
+func (h *WebhookHandler) Deliveries(w http.ResponseWriter, r *http.Request) {
+	id, ok := webhookID(w, r)
+	if !ok {
+		return
+	}
+	limit := defaultDeliveryLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			httpx.WriteError(w, r, http.StatusBadRequest, "BAD_REQUEST", "invalid limit")
+			return
+		}
+		limit = min(n, maxDeliveryLimit)
+	}
+	list, err := h.DB.ListWebhookDeliveries(r.Context(), id, limit)
+	if err != nil {
+		if errors.Is(err, database.ErrWebhookNotFound) {
+			httpx.WriteError(w, r, http.StatusNotFound, "NOT_FOUND", "webhook not found")
+			return
+		}
+		logger.From(r.Context()).Error("Failed to list webhook deliveries", "error", err, "webhook_id", id)
+		httpx.WriteError(w, r, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to list deliveries")
+		return
+	}
+	out := make([]webhookDeliveryDTO, 0, len(list))
+	for _, d := range list {
+		dto := webhookDeliveryDTO{
+			ID:        d.ID,
+			EventID:   d.EventID,
+			EventType: d.EventType,
+			Payload:   d.Payload,
+			Status:    d.Status,
+			Attempts:  d.Attempts,
+			CreatedAt: d.CreatedAt.UnixMilli(),
+			Log:       make([]webhookAttemptDTO, 0, len(d.Log)),
+		}
+		if d.Status == database.DeliveryPending {
+			ms := d.NextAttemptAt.UnixMilli()
+			dto.NextAttemptAt = &ms
+		}
+		if d.CompletedAt != nil {
+			ms := d.CompletedAt.UnixMilli()
+			dto.CompletedAt = &ms
+		}
+		for _, a := range d.Log {
+			dto.Log = append(dto.Log, webhookAttemptDTO{
+				AttemptedAt:    a.AttemptedAt.UnixMilli(),
+				ResponseStatus: a.ResponseStatus,
+				Error:          a.Error,
+				DurationMS:     a.Duration.Milliseconds(),
+			})
+		}
+		out = append(out, dto)
+	}
+	httpx.WriteJSON(w, http.StatusOK, out)
+}
+
+func webhookID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(chi.URLParam(r, "webhookID"))
+	if err != nil || id <= 0 {
+		httpx.WriteError(w, r, http.StatusBadRequest, "BAD_REQUEST", "invalid webhook id")
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"guardian-backend/internal/database"
+)
+
+type mockWebhookDB struct {
+	mock.Mock
+}
+
+func (m *mockWebhookDB) RecordAudit(ctx context.Context, e database.AuditEntry) error {
+	return m.Called(ctx, e).Error(0)
+}
+
+func (m *mockWebhookDB) CreateWebhookSubscription(ctx context.Context, s database.WebhookSubscription) (int, error) {
+	args := m.Called(ctx, s)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *mockWebhookDB) ListWebhookSubscriptions(ctx context.Context) ([]database.WebhookSubscription, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]database.WebhookSubscription), args.Error(1)
+}
+
+func (m *mockWebhookDB) DeleteWebhookSubscription(ctx context.Context, id int) error {
+	return m.Called(ctx, id).Error(0)
+}
+
+func (m *mockWebhookDB) ListWebhookDeliveries(ctx context.Context, subscriptionID, limit int) ([]database.WebhookDelivery, error) {
+	args := m.Called(ctx, subscriptionID, limit)
+	return args.Get(0).([]database.WebhookDelivery), args.Error(1)
+}
+
+func webhookRouter(h *WebhookHandler) http.Handler {
+	router := chi.NewRouter()
+	router.Post("/v1/admin/webhooks", h.Create)
+	router.Delete("/v1/admin/webhooks/{webhookID}", h.Delete)
+	router.Get("/v1/admin/webhooks/{webhookID}/deliveries", h.Deliveries)
+	return router
+}
+
+func TestWebhookHandler_Create(t *testing.T) {
+	db := new(mockWebhookDB)
+	db.On("CreateWebhookSubscription", mock.Anything, mock.MatchedBy(func(s database.WebhookSubscription) bool {
+		return s.URL == "https://hooks.example.com/guardian" && len(s.Secret) == 64 && len(s.EventTypes) == 1
+	})).Return(3, nil)
+	db.On("RecordAudit", mock.Anything, mock.MatchedBy(func(e database.AuditEntry) bool {
+		return e.Action == "webhook.create" && e.TargetID == "3"
+	})).Return(nil)
+
+	rr := httptest.NewRecorder()
+	body := `{"url":"https://hooks.example.com/guardian","event_types":["task.failed"]}`
+	webhookRouter(&WebhookHandler{DB: db}).ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/v1/admin/webhooks", strings.NewReader(body)))
+	require.Equal(t, http.StatusCreated, rr.Code)
+	var resp webhookDTO
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.Equal(t, 3, resp.ID)
+	assert.Len(t, resp.Secret, 64)
+	db.AssertExpectations(t)
+}
+
+func TestWebhookHandler_Create_RejectsInvalid(t *testing.T) {
+	for _, body := range []string{
+		`{"url":"ftp://hooks.example.com/x"}`,
+		`{"url":"not a url"}`,
+		`{"url":"https://hooks.example.com/x","event_types":["task.unknown"]}`,
+	} {
+		rr := httptest.NewRecorder()
+		webhookRouter(&WebhookHandler{DB: new(mockWebhookDB)}).ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/v1/admin/webhooks", strings.NewReader(body)))
+		assert.Equal(t, http.StatusBadRequest, rr.Code, body)
+	}
+}
+
+func TestWebhookHandler_Delete_NotFound(t *testing.T) {
+	db := new(mockWebhookDB)
+	db.On("DeleteWebhookSubscription", mock.Anything, 9).Return(database.ErrWebhookNotFound)
+	rr := httptest.NewRecorder()
+	webhookRouter(&WebhookHandler{DB: db}).ServeHTTP(rr, httptest.NewRequest(http.MethodDelete, "/v1/admin/webhooks/9", nil))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+func TestWebhookHandler_Deliveries(t *testing.T) {
+	at := time.UnixMilli(1700000000000)
+	db := new(mockWebhookDB)
+	db.On("ListWebhookDeliveries", mock.Anything, 3, maxDeliveryLimit).Return([]database.WebhookDelivery{{
+		ID: 11, EventID: 42, EventType: database.EventTaskFailed, Payload: json.RawMessage(`{"task_id":5}`),
+		Status: database.DeliveryPending, Attempts: 1, NextAttemptAt: at.Add(10 * time.Second), CreatedAt: at,
+		Log: []database.WebhookAttempt{{AttemptedAt: at, ResponseStatus: 502, Error: "unexpected status 502", Duration: 120 * time.Millisecond}},
+	}}, nil)
+
+	rr := httptest.NewRecorder()
+	webhookRouter(&WebhookHandler{DB: db}).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/admin/webhooks/3/deliveries?limit=1000", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `[{
+		"id":11,"event_id":42,"event_type":"task.failed","payload":{"task_id":5},"status":"pending","attempts":1,
+		"next_attempt_at":1700000010000,"created_at":1700000000000,
+		"log":[{"attempted_at":1700000000000,"response_status":502,"error":"unexpected status 502","duration_ms":120}]
+	}]`, rr.Body.String())
+}
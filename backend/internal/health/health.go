@@ -0,0 +1,225 @@
+// Package health 汇总就绪检查（数据库连通性、迁移版本）与后台任务的运行状态，
+// 以 HTTP（/healthz、/readyz）与标准 grpc.health.v1 服务两种形式对外提供。
+// grpc.health.v1 同时注册在 mTLS 的 gRPC 端口与管理端口（明文 h2c）上，后者供没有客户端证书的负载均衡探测。
+//
+// 只有依赖检查决定是否就绪；后台任务（数据清理、超时判定、webhook 投递等）的状态仅用于报告，
+// 任务失败不会使实例被摘除流量。
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// staleFactor 为周期任务超过多少个周期未运行即报告为 stale
+const staleFactor = 3
+
+// 检查与任务状态
+const (
+	StatusOK      = "ok"
+	StatusFailing = "failing"
+	StatusPending = "pending"
+	StatusStale   = "stale"
+)
+
+// CheckFunc 检查一项依赖，返回 nil 表示可用
+type CheckFunc func(ctx context.Context) error
+
+type check struct {
+	name string
+	fn   CheckFunc
+}
+
+// Checker 持有依赖检查与后台任务，可并发使用
+type Checker struct {
+	// Timeout 为单次检查的超时，默认 3 秒
+	Timeout time.Duration
+
+	mu     sync.Mutex
+	checks []check
+	jobs   []*Job
+	now    func() time.Time
+}
+
+// New 返回空的 Checker
+func New() *Checker {
+	return &Checker{Timeout: 3 * time.Second, now: time.Now}
+}
+
+// AddCheck 注册依赖检查；任一检查失败时不就绪
+func (c *Checker) AddCheck(name string, fn CheckFunc) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checks = append(c.checks, check{name: name, fn: fn})
+}
+
+// Job 注册后台任务并返回其状态记录；interval 为运行周期，只运行一次的任务传 0
+func (c *Checker) Job(name string, interval time.Duration) *Job {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	j := &Job{name: name, interval: interval, started: c.now(), now: c.now}
+	c.jobs = append(c.jobs, j)
+	return j
+}
+
+// Job 记录后台任务最近一次运行的结果
+type Job struct {
+	name     string
+	interval time.Duration
+	started  time.Time
+	now      func() time.Time
+
+	mu      sync.Mutex
+	lastRun time.Time
+	lastErr error
+}
+
+// Record 记录一次运行的结果
+func (j *Job) Record(err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.lastRun = j.now()
+	j.lastErr = err
+}
+
+// CheckResult 为一项依赖检查的结果
+type CheckResult struct {
+	Status     string `json:"status"`
+	Error      string `json:"error,omitempty"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+// JobResult 为后台任务的状态：pending（尚未完成过一次）、ok、failing（最近一次失败）
+// 或 stale（周期任务超过 3 个周期未运行）
+type JobResult struct {
+	Status    string     `json:"status"`
+	LastRun   *time.Time `json:"last_run,omitempty"`
+	LastError string     `json:"last_error,omitempty"`
+}
+
+// Report 为一次就绪检查的完整结果
+type Report struct {
+	Ready  bool                   `json:"ready"`
+	Checks map[string]CheckResult `json:"checks"`
+	Jobs   map[string]JobResult   `json:"jobs,omitempty"`
+}
+
+func (j *Job) result(now time.Time) JobResult {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	last := j.lastRun
+	if j.interval > 0 && now.Sub(last) > staleFactor*j.interval && now.Sub(j.started) > staleFactor*j.interval {
+		r := JobResult{Status: StatusStale}
+		if !last.IsZero() {
+			r.LastRun = &last
+		}
+		return r
+	}
+	if last.IsZero() {
+		return JobResult{Status: StatusPending}
+	}
+	if j.lastErr != nil {
+		return JobResult{Status: StatusFailing, LastRun: &last, LastError: j.lastErr.Error()}
+	}
+	return JobResult{Status: StatusOK, LastRun: &last}
+}
+
+// Check 依次运行全部依赖检查并汇总后台任务状态
+func (c *Checker) Check(ctx context.Context) Report {
+	c.mu.Lock()
+	checks := append([]check(nil), c.checks...)
+	jobs := append([]*Job(nil), c.jobs...)
+	c.mu.Unlock()
+
+	report := Report{Ready: true, Checks: make(map[string]CheckResult, len(checks))}
+	for _, ch := range checks {
+		cctx, cancel := context.WithTimeout(ctx, c.Timeout)
+		start := c.now()
+		err := ch.fn(cctx)
+		cancel()
+		res := CheckResult{Status: StatusOK, DurationMS: c.now().Sub(start).Milliseconds()}
+		if err != nil {
+			report.Ready = false
+			res.Status, res.Error = StatusFailing, err.Error()
+		}
+		report.Checks[ch.name] = res
+	}
+	if len(jobs) > 0 {
+		now := c.now()
+		report.Jobs = make(map[string]JobResult, len(jobs))
+		for _, j := range jobs {
+			report.Jobs[j.name] = j.result(now)
+		}
+	}
+	return report
+}
+
+// LiveHandler 为存活探针：进程能处理请求即返回 200，不检查依赖
+func LiveHandler(w http.ResponseWriter, _ *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte("ok"))
+}
+
+// ReadyHandler 为就绪探针：输出 Report，未就绪时状态码为 503
+func (c *Checker) ReadyHandler(w http.ResponseWriter, r *http.Request) {
+	report := c.Check(r.Context())
+	status := http.StatusOK
+	if !report.Ready {
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(report)
+}
+
+// StatusSetter 为 grpc health.Server 中本包用到的方法
+type StatusSetter interface {
+	SetServingStatus(service string, status healthpb.HealthCheckResponse_ServingStatus)
+}
+
+// Watch 每隔 every 运行一次检查，并把结果同步到 gRPC 健康服务的整体状态（""）与 services 中的各服务；
+// 首次检查完成前为 NOT_SERVING。ctx 结束时返回
+func (c *Checker) Watch(ctx context.Context, hs StatusSetter, every time.Duration, services ...string) {
+	services = append([]string{""}, services...)
+	set := func(s healthpb.HealthCheckResponse_ServingStatus) {
+		for _, name := range services {
+			hs.SetServingStatus(name, s)
+		}
+	}
+	set(healthpb.HealthCheckResponse_NOT_SERVING)
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for {
+		if c.Check(ctx).Ready {
+			set(healthpb.HealthCheckResponse_SERVING)
+		} else {
+			set(healthpb.HealthCheckResponse_NOT_SERVING)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// WithGRPC 让 h 所在的明文监听同时以 h2c 提供 grpcSrv：Content-Type 为 application/grpc 的 HTTP/2 请求
+// 交给 grpcSrv，其余请求交给 h
+func WithGRPC(h http.Handler, grpcSrv *grpc.Server) http.Handler {
+	return h2c.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+			grpcSrv.ServeHTTP(w, r)
+			return
+		}
+		h.ServeHTTP(w, r)
+	}), &http2.Server{})
+}
@@ -0,0 +1,161 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestReadyHandler_FailingCheckReturns503(t *testing.T) {
+	c := New()
+	c.AddCheck("database", func(context.Context) error { return nil })
+	c.AddCheck("schema", func(context.Context) error { return errors.New("schema version 14 is behind required 15") })
+
+	rr := httptest.NewRecorder()
+	c.ReadyHandler(rr, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	var report Report
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &report))
+	assert.False(t, report.Ready)
+	assert.Equal(t, StatusOK, report.Checks["database"].Status)
+	assert.Equal(t, StatusFailing, report.Checks["schema"].Status)
+	assert.Contains(t, report.Checks["schema"].Error, "behind required")
+}
+
+func TestCheck_TimesOutSlowDependency(t *testing.T) {
+	c := New()
+	c.Timeout = 10 * time.Millisecond
+	c.AddCheck("database", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	report := c.Check(context.Background())
+	assert.False(t, report.Ready)
+	assert.Equal(t, context.DeadlineExceeded.Error(), report.Checks["database"].Error)
+}
+
+func TestJobs_ReportedWithoutAffectingReadiness(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := New()
+	c.now = func() time.Time { return now }
+	purge := c.Job("retention_purge", time.Minute)
+	webhooks := c.Job("webhooks", time.Minute)
+	legacy := c.Job("legacy_encryption", 0)
+	monitor := c.Job("monitoring", time.Minute)
+
+	report := c.Check(context.Background())
+	assert.True(t, report.Ready)
+	assert.Equal(t, StatusPending, report.Jobs["retention_purge"].Status)
+
+	purge.Record(nil)
+	webhooks.Record(errors.New("claim deliveries: conn refused"))
+	legacy.Record(nil)
+	now = now.Add(2 * time.Minute)
+	monitor.Record(nil)
+	now = now.Add(2 * time.Minute)
+
+	report = c.Check(context.Background())
+	assert.True(t, report.Ready)
+	// 4 分钟未运行，超过 3 个周期
+	assert.Equal(t, StatusStale, report.Jobs["retention_purge"].Status)
+	assert.Equal(t, StatusStale, report.Jobs["webhooks"].Status)
+	// 一次性任务完成后不会过期
+	assert.Equal(t, StatusOK, report.Jobs["legacy_encryption"].Status)
+	assert.Equal(t, StatusOK, report.Jobs["monitoring"].Status)
+
+	webhooks.Record(errors.New("claim deliveries: conn refused"))
+	report = c.Check(context.Background())
+	assert.Equal(t, JobResult{Status: StatusFailing, LastRun: &now, LastError: "claim deliveries: conn refused"}, report.Jobs["webhooks"])
+}
+
+type recordingSetter struct {
+	mu     sync.Mutex
+	status map[string]healthpb.HealthCheckResponse_ServingStatus
+}
+
+func (r *recordingSetter) SetServingStatus(service string, s healthpb.HealthCheckResponse_ServingStatus) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.status[service] = s
+}
+
+func (r *recordingSetter) get(service string) healthpb.HealthCheckResponse_ServingStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.status[service]
+}
+
+func TestWatch_FollowsChecks(t *testing.T) {
+	var mu sync.Mutex
+	dbErr := errors.New("connection refused")
+	c := New()
+	c.AddCheck("database", func(context.Context) error {
+		mu.Lock()
+		defer mu.Unlock()
+		return dbErr
+	})
+	hs := &recordingSetter{status: map[string]healthpb.HealthCheckResponse_ServingStatus{}}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		c.Watch(ctx, hs, 5*time.Millisecond, "guardian.DataService")
+		close(done)
+	}()
+
+	assert.Eventually(t, func() bool {
+		return hs.get("") == healthpb.HealthCheckResponse_NOT_SERVING
+	}, time.Second, time.Millisecond)
+
+	mu.Lock()
+	dbErr = nil
+	mu.Unlock()
+	assert.Eventually(t, func() bool {
+		return hs.get("") == healthpb.HealthCheckResponse_SERVING &&
+			hs.get("guardian.DataService") == healthpb.HealthCheckResponse_SERVING
+	}, time.Second, time.Millisecond)
+
+	cancel()
+	<-done
+}
+
+func TestWithGRPC_ServesHealthWithoutClientCert(t *testing.T) {
+	hs := grpchealth.NewServer()
+	hs.SetServingStatus("guardian.DataService", healthpb.HealthCheckResponse_NOT_SERVING)
+	grpcSrv := grpc.NewServer()
+	healthpb.RegisterHealthServer(grpcSrv, hs)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", LiveHandler)
+	srv := httptest.NewServer(WithGRPC(mux, grpcSrv))
+	defer srv.Close()
+
+	// 普通 HTTP 请求仍由 mux 处理
+	resp, err := http.Get(srv.URL + "/healthz")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	conn, err := grpc.NewClient(strings.TrimPrefix(srv.URL, "http://"), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+	client := healthpb.NewHealthClient(conn)
+	res, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, res.Status)
+	res, err = client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "guardian.DataService"})
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, res.Status)
+}
@@ -0,0 +1,85 @@
+package ingest
+
+import (
+	"time"
+
+	"guardian-backend/internal/database"
+	api "guardian-backend/pkg/grpc/api/guardian/pkg/grpc/api"
+)
+
+// 丢弃原因，写入 ingestion_discards.reason
+const (
+	ReasonNoActiveTask         = "no_active_task"
+	ReasonOutsideTimeWindow    = "outside_time_window"
+	ReasonExcludedConversation = "excluded_conversation"
+	ReasonMissingTimestamp     = "missing_timestamp"
+	// ReasonTaskNotRunning 表示上传注明的任务不是 agent 当前执行中的任务，整批拒收
+	ReasonTaskNotRunning = "task_not_running"
+)
+
+// Filter 是入库前的范围过滤阶段：按任务授权时间窗与排除会话筛掉越界消息
+type Filter struct {
+	// ExcludedConversations 是全局配置的排除会话，对所有任务生效
+	ExcludedConversations []string
+}
+
+// Result 是一次过滤的结果；被丢弃的消息只保留按原因聚合的计数
+type Result struct {
+	Kept      []*api.ChatMessage
+	Discarded map[string]int
+}
+
+// DiscardedTotal 返回被丢弃的消息总数
+func (r Result) DiscardedTotal() int {
+	n := 0
+	for _, c := range r.Discarded {
+		n += c
+	}
+	return n
+}
+
+// Apply 根据任务范围过滤消息；scope 为 nil 表示 agent 没有执行中的授权任务，全部丢弃
+func (f *Filter) Apply(scope *database.TaskScope, messages []*api.ChatMessage) Result {
+	res := Result{Discarded: map[string]int{}}
+	if scope == nil {
+		if len(messages) > 0 {
+			res.Discarded[ReasonNoActiveTask] = len(messages)
+		}
+		return res
+	}
+	excluded := make(map[string]struct{}, len(f.ExcludedConversations)+len(scope.ExcludedConversations))
+	for _, c := range f.ExcludedConversations {
+		excluded[c] = struct{}{}
+	}
+	for _, c := range scope.ExcludedConversations {
+		excluded[c] = struct{}{}
+	}
+	res.Kept = make([]*api.ChatMessage, 0, len(messages))
+	for _, m := range messages {
+		if m == nil || m.Timestamp == nil {
+			res.Discarded[ReasonMissingTimestamp]++
+			continue
+		}
+		if _, ok := excluded[m.ConversationId]; ok && m.ConversationId != "" {
+			res.Discarded[ReasonExcludedConversation]++
+			continue
+		}
+		if !inWindow(m.Timestamp.AsTime(), scope.ScopeStart, scope.ScopeEnd) {
+			res.Discarded[ReasonOutsideTimeWindow]++
+			continue
+		}
+		res.Kept = append(res.Kept, m)
+	}
+	return res
+}
+
+// inWindow 判断时间是否落在 [start, end] 内；边界为空表示不限
+func inWindow(t time.Time, start, end *time.Time) bool {
+	if start != nil && t.Before(*start) {
+		return false
+	}
+	if end != nil && t.After(*end) {
+		return false
+	}
+	return true
+}
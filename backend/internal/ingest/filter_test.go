@@ -0,0 +1,58 @@
+package ingest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"guardian-backend/internal/database"
+	api "guardian-backend/pkg/grpc/api/guardian/pkg/grpc/api"
+)
+
+func msg(conv string, t time.Time) *api.ChatMessage {
+	return &api.ChatMessage{Content: "x", ConversationId: conv, Timestamp: timestamppb.New(t)}
+}
+
+func TestFilter_NoActiveTaskDiscardsAll(t *testing.T) {
+	f := &Filter{}
+	res := f.Apply(nil, []*api.ChatMessage{msg("a", time.Now()), msg("b", time.Now())})
+	assert.Empty(t, res.Kept)
+	assert.Equal(t, map[string]int{ReasonNoActiveTask: 2}, res.Discarded)
+}
+
+func TestFilter_WindowAndExclusions(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC)
+	f := &Filter{ExcludedConversations: []string{"wxid_global"}}
+	scope := &database.TaskScope{
+		TaskID:                1,
+		ScopeStart:            &start,
+		ScopeEnd:              &end,
+		ExcludedConversations: []string{"wxid_task"},
+	}
+	in := []*api.ChatMessage{
+		msg("wxid_ok", start.Add(time.Hour)),
+		msg("wxid_ok", start.Add(-time.Hour)),
+		msg("wxid_ok", end.Add(time.Hour)),
+		msg("wxid_global", start.Add(time.Hour)),
+		msg("wxid_task", start.Add(time.Hour)),
+		{Content: "no time", ConversationId: "wxid_ok"},
+	}
+	res := f.Apply(scope, in)
+	assert.Equal(t, []*api.ChatMessage{in[0]}, res.Kept)
+	assert.Equal(t, map[string]int{
+		ReasonOutsideTimeWindow:    2,
+		ReasonExcludedConversation: 2,
+		ReasonMissingTimestamp:     1,
+	}, res.Discarded)
+	assert.Equal(t, 5, res.DiscardedTotal())
+}
+
+func TestFilter_OpenWindowKeepsEverything(t *testing.T) {
+	f := &Filter{}
+	in := []*api.ChatMessage{msg("", time.Unix(0, 0)), msg("wxid_a", time.Now())}
+	res := f.Apply(&database.TaskScope{TaskID: 2}, in)
+	assert.Equal(t, in, res.Kept)
+	assert.Zero(t, res.DiscardedTotal())
+}
@@ -0,0 +1,78 @@
+// Package integrity 计算入库批次的完整性哈希。
+//
+// 每个上传批次有两个哈希：RequestHash 是收到请求时对完整请求（含被过滤掉的消息）的确定性编码求得，
+// 作为回执返回给 agent；ContentDigest 只覆盖实际入库的消息，可在事后从数据库解密重算，
+// 用于发现入库后被篡改、删除或插入的消息。
+package integrity
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"hash"
+	"time"
+
+	api "guardian-backend/pkg/grpc/api/guardian/pkg/grpc/api"
+	"google.golang.org/protobuf/proto"
+)
+
+// RequestHash 返回上传请求确定性 protobuf 编码的 SHA-256
+func RequestHash(req *api.UploadMessagesRequest) (string, error) {
+	b, err := proto.MarshalOptions{Deterministic: true}.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// ContentDigest 按入库顺序累积消息的规范编码。
+// 时间戳截断到微秒并转为 UTC，与数据库 TIMESTAMPTZ 的精度一致，使重算结果稳定。
+type ContentDigest struct {
+	h     hash.Hash
+	count int
+}
+
+// NewContentDigest 创建空摘要
+func NewContentDigest() *ContentDigest {
+	return &ContentDigest{h: sha256.New()}
+}
+
+// Add 追加一条消息
+func (d *ContentDigest) Add(conversationID string, ts time.Time, content string) {
+	var buf [8]byte
+	writeField := func(s string) {
+		binary.BigEndian.PutUint64(buf[:], uint64(len(s)))
+		d.h.Write(buf[:])
+		d.h.Write([]byte(s))
+	}
+	writeField(conversationID)
+	binary.BigEndian.PutUint64(buf[:], uint64(ts.UTC().Truncate(time.Microsecond).UnixMicro()))
+	d.h.Write(buf[:])
+	writeField(content)
+	d.count++
+}
+
+// Count 返回已追加的消息数
+func (d *ContentDigest) Count() int {
+	return d.count
+}
+
+// Sum 返回十六进制摘要；包含消息条数，空批次也有确定的值
+func (d *ContentDigest) Sum() string {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], uint64(d.count))
+	h := sha256.New()
+	h.Write(buf[:])
+	h.Write(d.h.Sum(nil))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// ContentHash 计算一组待入库消息的内容哈希
+func ContentHash(messages []*api.ChatMessage) string {
+	d := NewContentDigest()
+	for _, m := range messages {
+		d.Add(m.ConversationId, m.Timestamp.AsTime(), m.Content)
+	}
+	return d.Sum()
+}
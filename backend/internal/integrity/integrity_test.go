@@ -0,0 +1,49 @@
+package integrity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	api "guardian-backend/pkg/grpc/api/guardian/pkg/grpc/api"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestContentHash_MatchesRecomputationFromStoredRows(t *testing.T) {
+	ts := time.Date(2025, 1, 2, 3, 4, 5, 123456789, time.FixedZone("CST", 8*3600))
+	msgs := []*api.ChatMessage{
+		{Content: "hello", Timestamp: timestamppb.New(ts), ConversationId: "wxid_a"},
+		{Content: "world", Timestamp: timestamppb.New(ts.Add(time.Second))},
+	}
+	// 数据库只保留到微秒、以 UTC 读出
+	d := NewContentDigest()
+	d.Add("wxid_a", ts.UTC().Truncate(time.Microsecond), "hello")
+	d.Add("", ts.Add(time.Second).UTC().Truncate(time.Microsecond), "world")
+	assert.Equal(t, ContentHash(msgs), d.Sum())
+	assert.Equal(t, 2, d.Count())
+}
+
+func TestContentHash_DetectsChanges(t *testing.T) {
+	ts := timestamppb.New(time.Unix(1700000000, 0))
+	base := ContentHash([]*api.ChatMessage{{Content: "ab", Timestamp: ts}, {Content: "c", Timestamp: ts}})
+	for name, msgs := range map[string][]*api.ChatMessage{
+		"content":  {{Content: "ab", Timestamp: ts}, {Content: "C", Timestamp: ts}},
+		"boundary": {{Content: "a", Timestamp: ts}, {Content: "bc", Timestamp: ts}},
+		"order":    {{Content: "c", Timestamp: ts}, {Content: "ab", Timestamp: ts}},
+		"deleted":  {{Content: "ab", Timestamp: ts}},
+	} {
+		assert.NotEqual(t, base, ContentHash(msgs), name)
+	}
+	assert.NotEqual(t, ContentHash(nil), "")
+}
+
+func TestRequestHash_Deterministic(t *testing.T) {
+	req := &api.UploadMessagesRequest{AgentId: 1, Messages: []*api.ChatMessage{{Content: "x"}}}
+	a, err := RequestHash(req)
+	require.NoError(t, err)
+	b, err := RequestHash(&api.UploadMessagesRequest{AgentId: 1, Messages: []*api.ChatMessage{{Content: "x"}}})
+	require.NoError(t, err)
+	assert.Equal(t, a, b)
+	assert.Len(t, a, 64)
+}
@@ -0,0 +1,49 @@
+package interceptor
+
+import (
+    "context"
+    "net"
+    "strings"
+
+    "google.golang.org/grpc"
+    "google.golang.org/grpc/metadata"
+    "google.golang.org/grpc/peer"
+    "guardian-backend/internal/auth"
+    "guardian-backend/pkg/logger"
+)
+
+// ConsoleAuth 返回控制台用户认证拦截器：仅作用于以 methodPrefix 开头的方法（如 /guardian.ConsoleService/），
+// 与 HTTP JWTAuth 一样校验 authorization 元数据中的 Bearer 访问令牌及其会话，
+// 通过后把用户身份与来源 IP 写入 context，供授权与审计使用
+func ConsoleAuth(keys *auth.Keyring, sessions auth.SessionChecker, methodPrefix string) grpc.UnaryServerInterceptor {
+    return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+        if !strings.HasPrefix(info.FullMethod, methodPrefix) {
+            return handler(ctx, req)
+        }
+        var authorization string
+        if md, ok := metadata.FromIncomingContext(ctx); ok {
+            if v := md.Get("authorization"); len(v) > 0 {
+                authorization = v[0]
+            }
+        }
+        principal, err := auth.Authenticate(ctx, keys, sessions, authorization)
+        if err != nil {
+            return nil, err
+        }
+        logger.Annotate(ctx, "user_id", principal.UserID, "role", principal.Role)
+        return handler(auth.WithClientIP(auth.WithPrincipal(ctx, principal), peerIP(ctx)), req)
+    }
+}
+
+// peerIP 返回对端 IP（不含端口）
+func peerIP(ctx context.Context) string {
+    p, ok := peer.FromContext(ctx)
+    if !ok || p.Addr == nil {
+        return ""
+    }
+    host, _, err := net.SplitHostPort(p.Addr.String())
+    if err != nil {
+        return p.Addr.String()
+    }
+    return host
+}
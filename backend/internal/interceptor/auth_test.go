@@ -0,0 +1,69 @@
+package interceptor
+
+import (
+    "context"
+    "net"
+    "testing"
+    "time"
+
+    "github.com/golang-jwt/jwt/v5"
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/require"
+    "google.golang.org/grpc"
+    "google.golang.org/grpc/codes"
+    "google.golang.org/grpc/metadata"
+    "google.golang.org/grpc/peer"
+    "google.golang.org/grpc/status"
+    "guardian-backend/internal/auth"
+)
+
+type fakeSessions map[string]bool
+
+func (f fakeSessions) IsSessionActive(_ context.Context, sessionID string) (bool, error) {
+    return f[sessionID], nil
+}
+
+func TestConsoleAuth(t *testing.T) {
+    kr, err := auth.NewKeyring("guardian", "guardian-console", "k1", []auth.KeyConfig{
+        {ID: "k1", Algorithm: auth.AlgHS256, Secret: "0123456789abcdef0123456789abcdef"},
+    })
+    require.NoError(t, err)
+    sign := func(sid string) string {
+        token, err := kr.Sign(jwt.MapClaims{"sub": "7", "role": "auditor", "sid": sid, "token_use": auth.TokenUseAccess, "exp": time.Now().Add(time.Minute).Unix()})
+        require.NoError(t, err)
+        return "Bearer " + token
+    }
+    ic := ConsoleAuth(kr, fakeSessions{"live": true}, "/guardian.ConsoleService/")
+    console := &grpc.UnaryServerInfo{FullMethod: "/guardian.ConsoleService/ListAgents"}
+
+    var got auth.Principal
+    var gotIP string
+    handler := func(ctx context.Context, req any) (any, error) {
+        got, gotIP = auth.PrincipalFrom(ctx), auth.ClientIPFrom(ctx)
+        return "ok", nil
+    }
+    call := func(info *grpc.UnaryServerInfo, authorization string) error {
+        ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.5"), Port: 4242}})
+        if authorization != "" {
+            ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", authorization))
+        }
+        // 经过恢复拦截器，与服务端链路一致地把领域错误转换为状态
+        _, err := unaryRecovery(ctx, nil, info, func(ctx context.Context, req any) (any, error) {
+            return ic(ctx, req, info, handler)
+        })
+        return err
+    }
+
+    require.NoError(t, call(console, sign("live")))
+    assert.Equal(t, auth.Principal{UserID: "7", Role: "auditor", SessionID: "live"}, got)
+    assert.Equal(t, "10.0.0.5", gotIP)
+
+    assert.Equal(t, codes.Unauthenticated, status.Code(call(console, "")))
+    assert.Equal(t, codes.Unauthenticated, status.Code(call(console, "Bearer garbage")))
+    err = call(console, sign("revoked"))
+    assert.Equal(t, codes.Unauthenticated, status.Code(err))
+    assert.Equal(t, "session revoked", status.Convert(err).Message())
+
+    // agent 服务使用 mTLS 证书认证，不要求访问令牌
+    assert.NoError(t, call(testInfo, ""))
+}
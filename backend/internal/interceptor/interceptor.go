@@ -0,0 +1,294 @@
+// Package interceptor 提供 gRPC 服务端拦截器链：请求 ID、结构化日志、指标、panic 恢复与截止时间上限。
+package interceptor
+
+import (
+    "context"
+    "crypto/rand"
+    "crypto/sha256"
+    "encoding/hex"
+    "errors"
+    "log/slog"
+    "runtime/debug"
+    "strings"
+    "time"
+
+    "google.golang.org/grpc"
+    "google.golang.org/grpc/codes"
+    "google.golang.org/grpc/credentials"
+    "google.golang.org/grpc/metadata"
+    "google.golang.org/grpc/peer"
+    "google.golang.org/grpc/status"
+    "guardian-backend/pkg/apperr"
+    "guardian-backend/pkg/logger"
+    m "guardian-backend/pkg/metrics"
+    "guardian-backend/pkg/tracing"
+)
+
+// RequestIDMetadataKey 为请求 ID 的 gRPC 元数据键；客户端未携带时由服务端生成，并在响应头中返回
+const RequestIDMetadataKey = "x-request-id"
+
+// TraceIDMetadataKey 为响应头中 trace ID 的 gRPC 元数据键，便于按错误响应查找对应的链路
+const TraceIDMetadataKey = "x-trace-id"
+
+// healthMethodPrefix 为 grpc.health.v1 健康服务的方法前缀
+const healthMethodPrefix = "/grpc.health.v1.Health/"
+
+// maxRequestIDLength 限制客户端传入的请求 ID 长度，超出或含不可见字符时重新生成
+const maxRequestIDLength = 128
+
+type requestIDKey struct{}
+
+// RequestIDFromContext 返回当前 RPC 的请求 ID
+func RequestIDFromContext(ctx context.Context) string {
+    id, _ := ctx.Value(requestIDKey{}).(string)
+    return id
+}
+
+// DeadlinePolicy 为服务端允许的最长处理时间；客户端未设置截止时间或设置得更长时按此截断
+type DeadlinePolicy struct {
+    Default time.Duration
+    // Methods 按完整方法名（如 /guardian.DataService/UploadMessages）覆盖默认值
+    Methods map[string]time.Duration
+}
+
+func (p DeadlinePolicy) limit(fullMethod string) time.Duration {
+    if d, ok := p.Methods[fullMethod]; ok {
+        return d
+    }
+    return p.Default
+}
+
+// ServerOptions 返回 gRPC 服务端拦截器链，自外向内依次为：请求 ID、日志、指标、panic 恢复与错误码映射、截止时间，
+// 最后是 unary 中按顺序追加的一元拦截器（如 ConsoleAuth）。
+// 日志与指标位于恢复之外，因此能记录 panic 转换后的状态码。
+func ServerOptions(deadlines DeadlinePolicy, unary ...grpc.UnaryServerInterceptor) []grpc.ServerOption {
+    return []grpc.ServerOption{
+        grpc.ChainUnaryInterceptor(append([]grpc.UnaryServerInterceptor{
+            unaryRequestID,
+            unaryLogging,
+            m.UnaryServerMetrics,
+            unaryRecovery,
+            unaryDeadline(deadlines),
+        }, unary...)...),
+        grpc.ChainStreamInterceptor(
+            streamRequestID,
+            streamLogging,
+            m.StreamServerMetrics,
+            streamRecovery,
+            streamDeadline(deadlines),
+        ),
+    }
+}
+
+// wrappedStream 用于替换流的 context
+type wrappedStream struct {
+    grpc.ServerStream
+    ctx context.Context
+}
+
+func (s *wrappedStream) Context() context.Context { return s.ctx }
+
+// withRequestID 取客户端传入的请求 ID 或生成新 ID，写入 context 并设置响应头；
+// 同时创建携带 request_id（及 trace_id）的请求级 logger，供后续拦截器与服务实现通过 logger.From 使用
+func withRequestID(ctx context.Context) context.Context {
+    id := ""
+    if md, ok := metadata.FromIncomingContext(ctx); ok {
+        if v := md.Get(RequestIDMetadataKey); len(v) > 0 && validRequestID(v[0]) {
+            id = v[0]
+        }
+    }
+    if id == "" {
+        b := make([]byte, 16)
+        _, _ = rand.Read(b)
+        id = hex.EncodeToString(b)
+    }
+    header := metadata.Pairs(RequestIDMetadataKey, id)
+    l := slog.Default().With("request_id", id)
+    if tid := tracing.TraceID(ctx); tid != "" {
+        header.Set(TraceIDMetadataKey, tid)
+        l = l.With("trace_id", tid)
+    }
+    _ = grpc.SetHeader(ctx, header)
+    ctx = logger.NewContext(ctx, l)
+    return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+func validRequestID(id string) bool {
+    if id == "" || len(id) > maxRequestIDLength {
+        return false
+    }
+    for _, c := range id {
+        if c < 0x21 || c > 0x7e {
+            return false
+        }
+    }
+    return true
+}
+
+func unaryRequestID(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+    return handler(withRequestID(ctx), req)
+}
+
+func streamRequestID(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+    return handler(srv, &wrappedStream{ServerStream: ss, ctx: withRequestID(ss.Context())})
+}
+
+// PeerCertFingerprint 返回 mTLS 客户端证书的 SHA-256 指纹（hex）；非 TLS 连接返回空串
+func PeerCertFingerprint(ctx context.Context) string {
+    p, ok := peer.FromContext(ctx)
+    if !ok || p.AuthInfo == nil {
+        return ""
+    }
+    info, ok := p.AuthInfo.(credentials.TLSInfo)
+    if !ok || len(info.State.PeerCertificates) == 0 {
+        return ""
+    }
+    sum := sha256.Sum256(info.State.PeerCertificates[0].Raw)
+    return hex.EncodeToString(sum[:])
+}
+
+// PeerCertCommonName 返回 mTLS 客户端证书的 Subject CN；非 TLS 连接返回空串
+func PeerCertCommonName(ctx context.Context) string {
+    p, ok := peer.FromContext(ctx)
+    if !ok || p.AuthInfo == nil {
+        return ""
+    }
+    info, ok := p.AuthInfo.(credentials.TLSInfo)
+    if !ok || len(info.State.PeerCertificates) == 0 {
+        return ""
+    }
+    return info.State.PeerCertificates[0].Subject.CommonName
+}
+
+// agentIDGetter 由携带 agent_id 的请求消息实现
+type agentIDGetter interface {
+    GetAgentId() int32
+}
+
+// annotateCaller 为请求级 logger 追加调用方身份：声明的 agent_id、客户端证书指纹前缀与来源地址
+func annotateCaller(ctx context.Context, req any) {
+    var attrs []any
+    if g, ok := req.(agentIDGetter); ok {
+        attrs = append(attrs, "agent_id", g.GetAgentId())
+    }
+    if fp := PeerCertFingerprint(ctx); fp != "" {
+        attrs = append(attrs, "cert_sha256", fp[:16])
+    }
+    if p, ok := peer.FromContext(ctx); ok {
+        attrs = append(attrs, "peer", p.Addr.String())
+    }
+    if len(attrs) > 0 {
+        logger.Annotate(ctx, attrs...)
+    }
+}
+
+// logRPC 按状态码分级记录一次 RPC；请求 ID 与调用方身份由请求级 logger 携带
+func logRPC(ctx context.Context, fullMethod string, err error, elapsed time.Duration) {
+    code := status.Code(err)
+    l := logger.From(ctx)
+    attrs := []any{
+        "method", fullMethod,
+        "code", code.String(),
+        "duration_ms", elapsed.Milliseconds(),
+    }
+    switch {
+    case code == codes.OK && strings.HasPrefix(fullMethod, healthMethodPrefix):
+        // 负载均衡与 agent 的健康探测很频繁，成功时只在 debug 级别记录
+        l.Debug("gRPC request", attrs...)
+    case code == codes.OK:
+        l.Info("gRPC request", attrs...)
+    case code == codes.Internal, code == codes.Unknown, code == codes.DataLoss, code == codes.Unavailable:
+        l.Error("gRPC request", append(attrs, "error", status.Convert(err).Message())...)
+    default:
+        l.Warn("gRPC request", append(attrs, "error", status.Convert(err).Message())...)
+    }
+}
+
+func unaryLogging(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+    annotateCaller(ctx, req)
+    start := time.Now()
+    resp, err := handler(ctx, req)
+    logRPC(ctx, info.FullMethod, err, time.Since(start))
+    return resp, err
+}
+
+func streamLogging(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+    annotateCaller(ss.Context(), nil)
+    start := time.Now()
+    err := handler(srv, ss)
+    logRPC(ss.Context(), info.FullMethod, err, time.Since(start))
+    return err
+}
+
+// toStatus 把处理函数返回的错误映射为 gRPC 状态：apperr 错误按分类转换（包括被包装的），
+// 上下文超时与取消保留对应状态码，其余视为内部错误；内部错误细节只记日志，不返回给客户端
+func toStatus(ctx context.Context, fullMethod string, err error) error {
+    if err == nil {
+        return nil
+    }
+    var appErr *apperr.Error
+    if !errors.As(err, &appErr) {
+        // 处理函数直接返回的 status 错误原样透传
+        if s, ok := err.(interface{ GRPCStatus() *status.Status }); ok {
+            return s.GRPCStatus().Err()
+        }
+        appErr = apperr.From(err)
+    }
+    if appErr.Kind == apperr.KindInternal {
+        logger.From(ctx).Error("gRPC handler failed", "method", fullMethod, "error", err)
+    }
+    return appErr.GRPCStatus().Err()
+}
+
+// recovered 把 panic 记入日志与指标并转换为 Internal，进程继续服务其他请求
+func recovered(ctx context.Context, fullMethod string, p any) error {
+    m.GRPCPanic(fullMethod)
+    logger.From(ctx).Error("gRPC handler panic", "method", fullMethod, "panic", p, "stack", string(debug.Stack()))
+    return status.Error(codes.Internal, "internal error")
+}
+
+func unaryRecovery(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+    defer func() {
+        if p := recover(); p != nil {
+            resp, err = nil, recovered(ctx, info.FullMethod, p)
+        }
+    }()
+    resp, err = handler(ctx, req)
+    return resp, toStatus(ctx, info.FullMethod, err)
+}
+
+func streamRecovery(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+    defer func() {
+        if p := recover(); p != nil {
+            err = recovered(ss.Context(), info.FullMethod, p)
+        }
+    }()
+    return toStatus(ss.Context(), info.FullMethod, handler(srv, ss))
+}
+
+// withDeadline 在客户端未设置截止时间或设置得比上限更晚时收紧为上限
+func withDeadline(ctx context.Context, limit time.Duration) (context.Context, context.CancelFunc) {
+    if limit <= 0 {
+        return ctx, func() {}
+    }
+    if d, ok := ctx.Deadline(); ok && time.Until(d) <= limit {
+        return ctx, func() {}
+    }
+    return context.WithTimeout(ctx, limit)
+}
+
+func unaryDeadline(p DeadlinePolicy) grpc.UnaryServerInterceptor {
+    return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+        ctx, cancel := withDeadline(ctx, p.limit(info.FullMethod))
+        defer cancel()
+        return handler(ctx, req)
+    }
+}
+
+func streamDeadline(p DeadlinePolicy) grpc.StreamServerInterceptor {
+    return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+        ctx, cancel := withDeadline(ss.Context(), p.limit(info.FullMethod))
+        defer cancel()
+        return handler(srv, &wrappedStream{ServerStream: ss, ctx: ctx})
+    }
+}
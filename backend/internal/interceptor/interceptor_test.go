@@ -0,0 +1,120 @@
+package interceptor
+
+import (
+    "bytes"
+    "context"
+    "errors"
+    "fmt"
+    "testing"
+    "time"
+
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/require"
+    "google.golang.org/grpc"
+    "google.golang.org/grpc/codes"
+    "google.golang.org/grpc/metadata"
+    "google.golang.org/grpc/status"
+    api "guardian-backend/pkg/grpc/api/guardian/pkg/grpc/api"
+    "guardian-backend/pkg/apperr"
+    "guardian-backend/pkg/logger"
+)
+
+var testInfo = &grpc.UnaryServerInfo{FullMethod: "/guardian.DataService/UploadMessages"}
+
+func TestUnaryRecovery_PanicBecomesInternal(t *testing.T) {
+    resp, err := unaryRecovery(context.Background(), nil, testInfo, func(ctx context.Context, req any) (any, error) {
+        var m map[string]int
+        m["boom"]++ // nil map 写入引发 panic
+        return "unreachable", nil
+    })
+    assert.Nil(t, resp)
+    assert.Equal(t, codes.Internal, status.Code(err))
+    assert.Equal(t, "internal error", status.Convert(err).Message())
+}
+
+func TestUnaryRecovery_RedactsPanicValue(t *testing.T) {
+    var buf bytes.Buffer
+    l, err := logger.New(&buf, logger.Options{})
+    require.NoError(t, err)
+    ctx := logger.NewContext(context.Background(), l)
+    _, err = unaryRecovery(ctx, nil, testInfo, func(ctx context.Context, req any) (any, error) {
+        panic(&api.ChatMessage{Content: "hello from wxid_1", ConversationId: "wxid_2"})
+    })
+    assert.Equal(t, codes.Internal, status.Code(err))
+    assert.Contains(t, buf.String(), "gRPC handler panic")
+    assert.Contains(t, buf.String(), "wxid_2")
+    assert.NotContains(t, buf.String(), "hello from wxid_1")
+}
+
+func TestUnaryRecovery_MapsUntypedErrors(t *testing.T) {
+    for err, want := range map[error]codes.Code{
+        errors.New("db exploded"):                       codes.Internal,
+        context.DeadlineExceeded:                        codes.DeadlineExceeded,
+        context.Canceled:                                codes.Canceled,
+        status.Error(codes.NotFound, "agent not found"): codes.NotFound,
+    } {
+        _, got := unaryRecovery(context.Background(), nil, testInfo, func(ctx context.Context, req any) (any, error) {
+            return nil, err
+        })
+        assert.Equal(t, want, status.Code(got), err.Error())
+    }
+    // 内部错误细节不返回给客户端
+    _, got := unaryRecovery(context.Background(), nil, testInfo, func(ctx context.Context, req any) (any, error) {
+        return nil, errors.New("password=hunter2")
+    })
+    assert.NotContains(t, got.Error(), "hunter2")
+
+    // 被包装的领域错误按分类转换，包装层的内部信息不外泄
+    _, got = unaryRecovery(context.Background(), nil, testInfo, func(ctx context.Context, req any) (any, error) {
+        return nil, fmt.Errorf("select from tasks where secret=1: %w", apperr.New(apperr.KindNotFound, "", "task not found"))
+    })
+    assert.Equal(t, codes.NotFound, status.Code(got))
+    assert.Equal(t, "task not found", status.Convert(got).Message())
+}
+
+func TestUnaryRequestID(t *testing.T) {
+    var seen string
+    handler := func(ctx context.Context, req any) (any, error) {
+        seen = RequestIDFromContext(ctx)
+        return nil, nil
+    }
+
+    ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(RequestIDMetadataKey, "req-123"))
+    _, _ = unaryRequestID(ctx, nil, testInfo, handler)
+    assert.Equal(t, "req-123", seen)
+
+    // 缺失或不合法的请求 ID 由服务端生成
+    ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs(RequestIDMetadataKey, "bad id\n"))
+    _, _ = unaryRequestID(ctx, nil, testInfo, handler)
+    assert.Len(t, seen, 32)
+    _, _ = unaryRequestID(context.Background(), nil, testInfo, handler)
+    assert.Len(t, seen, 32)
+}
+
+func TestUnaryDeadline(t *testing.T) {
+    policy := DeadlinePolicy{Default: time.Second, Methods: map[string]time.Duration{testInfo.FullMethod: time.Minute}}
+    remaining := func(ctx context.Context, info *grpc.UnaryServerInfo) time.Duration {
+        var left time.Duration
+        _, err := unaryDeadline(policy)(ctx, nil, info, func(ctx context.Context, req any) (any, error) {
+            d, ok := ctx.Deadline()
+            require.True(t, ok)
+            left = time.Until(d)
+            return nil, nil
+        })
+        require.NoError(t, err)
+        return left
+    }
+
+    // 未设置截止时间：按方法上限
+    assert.InDelta(t, time.Minute, remaining(context.Background(), testInfo), float64(time.Second))
+    // 其他方法使用默认上限
+    assert.LessOrEqual(t, remaining(context.Background(), &grpc.UnaryServerInfo{FullMethod: "/guardian.AgentService/Heartbeat"}), time.Second)
+    // 客户端设置更长的截止时间：被收紧
+    ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+    defer cancel()
+    assert.LessOrEqual(t, remaining(ctx, testInfo), time.Minute)
+    // 客户端设置更短的截止时间：保持不变
+    ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+    defer cancel()
+    assert.LessOrEqual(t, remaining(ctx, testInfo), 100*time.Millisecond)
+}
@@ -0,0 +1,18 @@
+// Package kms 定义主密钥服务接口。主密钥只用于包装/解包各案件的数据密钥（信封加密），
+// 从不直接加密业务数据，也不离开 KMS 实现。
+package kms
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrUnknownMasterKey 表示包装数据密钥所用的主密钥不在当前 KMS 中
+var ErrUnknownMasterKey = errors.New("unknown master key")
+
+// KMS 是主密钥服务。Wrap 使用当前主密钥包装数据密钥并返回其标识，
+// Unwrap 按标识选择主密钥解包，从而支持主密钥轮换。
+type KMS interface {
+	Wrap(ctx context.Context, dataKey []byte) (wrapped []byte, masterKeyID string, err error)
+	Unwrap(ctx context.Context, wrapped []byte, masterKeyID string) ([]byte, error)
+}
@@ -0,0 +1,103 @@
+package kms
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// MasterKeyConfig 是本地主密钥文件配置；文件内容为 32 字节原始密钥或其 base64 编码
+type MasterKeyConfig struct {
+	ID   string
+	File string
+}
+
+// LocalKMS 使用本地文件中的 AES-256 主密钥包装数据密钥，适用于单机部署与开发环境。
+// 生产环境应以云 KMS / HSM 实现 KMS 接口替换。
+type LocalKMS struct {
+	current string
+	keys    map[string]cipher.AEAD
+}
+
+// NewLocalKMS 加载主密钥；currentID 用于包装新的数据密钥，其余密钥仅用于解包（轮换期间保留）
+func NewLocalKMS(currentID string, configs []MasterKeyConfig) (*LocalKMS, error) {
+	k := &LocalKMS{current: currentID, keys: map[string]cipher.AEAD{}}
+	for _, c := range configs {
+		if c.ID == "" {
+			return nil, errors.New("master key id is required")
+		}
+		if _, dup := k.keys[c.ID]; dup {
+			return nil, fmt.Errorf("duplicate master key id %q", c.ID)
+		}
+		raw, err := os.ReadFile(c.File)
+		if err != nil {
+			return nil, fmt.Errorf("master key %q: %w", c.ID, err)
+		}
+		key, err := decodeKey(raw)
+		if err != nil {
+			return nil, fmt.Errorf("master key %q: %w", c.ID, err)
+		}
+		aead, err := newGCM(key)
+		if err != nil {
+			return nil, err
+		}
+		k.keys[c.ID] = aead
+	}
+	if _, ok := k.keys[currentID]; !ok {
+		return nil, fmt.Errorf("current master key %q not configured", currentID)
+	}
+	return k, nil
+}
+
+func decodeKey(raw []byte) ([]byte, error) {
+	if len(raw) == 32 {
+		return raw, nil
+	}
+	b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(raw)))
+	if err != nil || len(b) != 32 {
+		return nil, errors.New("master key must be 32 bytes (raw or base64)")
+	}
+	return b, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// aad 把包装结果绑定到主密钥标识，防止被替换为其他主密钥的包装结果
+func aad(masterKeyID string) []byte {
+	return []byte("guardian-dek:" + masterKeyID)
+}
+
+// Wrap 返回 nonce || AES-GCM(dataKey)
+func (k *LocalKMS) Wrap(_ context.Context, dataKey []byte) ([]byte, string, error) {
+	aead := k.keys[k.current]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, "", err
+	}
+	return aead.Seal(nonce, nonce, dataKey, aad(k.current)), k.current, nil
+}
+
+// Unwrap 解包数据密钥
+func (k *LocalKMS) Unwrap(_ context.Context, wrapped []byte, masterKeyID string) ([]byte, error) {
+	aead, ok := k.keys[masterKeyID]
+	if !ok {
+		return nil, ErrUnknownMasterKey
+	}
+	if len(wrapped) < aead.NonceSize() {
+		return nil, errors.New("wrapped key too short")
+	}
+	nonce, ct := wrapped[:aead.NonceSize()], wrapped[aead.NonceSize():]
+	return aead.Open(nil, nonce, ct, aad(masterKeyID))
+}
@@ -0,0 +1,55 @@
+package kms
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeMasterKey(t *testing.T, name string) string {
+	t.Helper()
+	key := make([]byte, 32)
+	_, err := rand.Read(key)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(base64.StdEncoding.EncodeToString(key)+"\n"), 0o600))
+	return path
+}
+
+func TestLocalKMS_WrapUnwrapAndRotation(t *testing.T) {
+	ctx := context.Background()
+	oldFile := writeMasterKey(t, "old.key")
+	old, err := NewLocalKMS("m1", []MasterKeyConfig{{ID: "m1", File: oldFile}})
+	require.NoError(t, err)
+	dek := []byte("0123456789abcdef0123456789abcdef")
+	wrapped, id, err := old.Wrap(ctx, dek)
+	require.NoError(t, err)
+	assert.Equal(t, "m1", id)
+	assert.NotContains(t, string(wrapped), string(dek))
+
+	rotated, err := NewLocalKMS("m2", []MasterKeyConfig{{ID: "m1", File: oldFile}, {ID: "m2", File: writeMasterKey(t, "new.key")}})
+	require.NoError(t, err)
+	got, err := rotated.Unwrap(ctx, wrapped, "m1")
+	require.NoError(t, err)
+	assert.Equal(t, dek, got)
+
+	_, err = rotated.Unwrap(ctx, wrapped, "m2")
+	assert.Error(t, err, "wrapped key is bound to its master key id")
+	_, err = rotated.Unwrap(ctx, wrapped, "missing")
+	assert.ErrorIs(t, err, ErrUnknownMasterKey)
+}
+
+func TestNewLocalKMS_Validation(t *testing.T) {
+	short := filepath.Join(t.TempDir(), "short.key")
+	require.NoError(t, os.WriteFile(short, []byte("too-short"), 0o600))
+	_, err := NewLocalKMS("m1", []MasterKeyConfig{{ID: "m1", File: short}})
+	assert.Error(t, err)
+	_, err = NewLocalKMS("m2", []MasterKeyConfig{{ID: "m1", File: writeMasterKey(t, "a.key")}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,127 @@
+// Package live 把数据库通过 NOTIFY 发出的状态变化分发给本实例的控制台连接。
+// 每个实例各自 LISTEN，因此任一副本上发生的变化都会推送到所有副本的订阅者。
+package live
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"guardian-backend/internal/database"
+)
+
+// subscriberBuffer 为每个订阅者的缓冲事件数；消费过慢导致缓冲写满时断开该订阅者，由客户端重连
+const subscriberBuffer = 64
+
+// Hub 在进程内把事件广播给全部订阅者
+type Hub struct {
+	mu     sync.Mutex
+	subs   map[chan database.LiveEvent]struct{}
+	closed bool
+}
+
+// NewHub 创建空的 Hub
+func NewHub() *Hub {
+	return &Hub{subs: map[chan database.LiveEvent]struct{}{}}
+}
+
+// Subscribe 注册订阅者，返回事件通道与取消函数；通道被关闭表示订阅已被断开。
+// Hub 关闭后返回已关闭的通道
+func (h *Hub) Subscribe() (<-chan database.LiveEvent, func()) {
+	ch := make(chan database.LiveEvent, subscriberBuffer)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		close(ch)
+		return ch, func() {}
+	}
+	h.subs[ch] = struct{}{}
+	return ch, func() { h.remove(ch) }
+}
+
+// Close 断开全部订阅者并拒绝新的订阅；服务退出时调用，使推送连接结束、客户端重连到其他实例
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for ch := range h.subs {
+		delete(h.subs, ch)
+		close(ch)
+	}
+}
+
+// Publish 非阻塞地投递事件；缓冲已满的订阅者被断开
+func (h *Hub) Publish(ev database.LiveEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subs {
+		select {
+		case ch <- ev:
+		default:
+			delete(h.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+// Subscribers 返回当前订阅者数量
+func (h *Hub) Subscribers() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subs)
+}
+
+func (h *Hub) remove(ch chan database.LiveEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.subs[ch]; ok {
+		delete(h.subs, ch)
+		close(ch)
+	}
+}
+
+// Listen 以独立连接 LISTEN 实时事件通道并发布到 hub，连接断开后按退避重连，直到 ctx 结束。
+// 重连期间发生的事件会丢失，控制台应在重连后重新拉取列表。
+func Listen(ctx context.Context, connConfig *pgx.ConnConfig, hub *Hub) {
+	backoff := time.Second
+	for {
+		err := listenOnce(ctx, connConfig, hub, func() { backoff = time.Second })
+		if ctx.Err() != nil {
+			return
+		}
+		slog.Warn("live event listener disconnected", "error", err, "retry_in", backoff)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, 30*time.Second)
+	}
+}
+
+func listenOnce(ctx context.Context, connConfig *pgx.ConnConfig, hub *Hub, connected func()) error {
+	conn, err := pgx.ConnectConfig(ctx, connConfig)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{database.LiveChannel}.Sanitize()); err != nil {
+		return err
+	}
+	connected()
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		var ev database.LiveEvent
+		if err := json.Unmarshal([]byte(n.Payload), &ev); err != nil {
+			slog.Warn("invalid live event payload", "error", err)
+			continue
+		}
+		hub.Publish(ev)
+	}
+}
@@ -0,0 +1,57 @@
+package live
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"guardian-backend/internal/database"
+)
+
+func TestHub_PublishFansOut(t *testing.T) {
+	hub := NewHub()
+	a, cancelA := hub.Subscribe()
+	b, cancelB := hub.Subscribe()
+	defer cancelB()
+
+	ev := database.LiveEvent{Type: database.LiveAgentStatus, AgentID: 1, Data: map[string]any{"status": "offline"}}
+	hub.Publish(ev)
+	assert.Equal(t, ev, <-a)
+	assert.Equal(t, ev, <-b)
+
+	cancelA()
+	_, open := <-a
+	assert.False(t, open)
+	assert.Equal(t, 1, hub.Subscribers())
+	cancelA() // 重复取消无副作用
+}
+
+func TestHub_DropsSlowSubscriber(t *testing.T) {
+	hub := NewHub()
+	slow, cancel := hub.Subscribe()
+	defer cancel()
+	for i := 0; i <= subscriberBuffer; i++ {
+		hub.Publish(database.LiveEvent{Type: database.LiveTaskStatus, TaskID: int64(i)})
+	}
+	assert.Equal(t, 0, hub.Subscribers())
+	received := 0
+	for range slow {
+		received++
+	}
+	assert.Equal(t, subscriberBuffer, received)
+}
+
+func TestHub_CloseDisconnectsSubscribers(t *testing.T) {
+	hub := NewHub()
+	sub, cancel := hub.Subscribe()
+	hub.Close()
+	_, open := <-sub
+	assert.False(t, open)
+	cancel() // 关闭后取消无副作用
+
+	late, cancelLate := hub.Subscribe()
+	defer cancelLate()
+	_, open = <-late
+	assert.False(t, open, "subscriptions after Close are already closed")
+	assert.Equal(t, 0, hub.Subscribers())
+	hub.Publish(database.LiveEvent{Type: database.LiveAgentStatus})
+}
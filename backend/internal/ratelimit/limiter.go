@@ -0,0 +1,75 @@
+// Package ratelimit 提供按键（IP、用户名、用户 ID）区分的令牌桶限流与登录失败锁定。
+// 状态保存在进程内存中并按 LRU 淘汰，多实例部署时各实例独立计数。
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Decision 是一次限流判定的结果，用于填充 RateLimit-* 与 Retry-After 响应头
+type Decision struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset 为令牌桶回满所需时间；RetryAfter 为被拒绝时下一个令牌可用的等待时间
+	Reset      time.Duration
+	RetryAfter time.Duration
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Limiter 是按键区分的令牌桶限流器。令牌按时间差惰性补充，不需要后台 goroutine。
+type Limiter struct {
+	rate  float64 // 每秒补充的令牌数
+	burst int
+
+	mu      sync.Mutex
+	buckets *lru[*bucket]
+	now     func() time.Time
+}
+
+// NewLimiter 创建限流器：每个键每秒 rps 个令牌、桶容量 burst，最多跟踪 maxKeys 个键
+func NewLimiter(rps float64, burst, maxKeys int) *Limiter {
+	if rps <= 0 {
+		rps = 1
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return &Limiter{rate: rps, burst: burst, buckets: newLRU[*bucket](maxKeys), now: time.Now}
+}
+
+// Allow 为 key 消耗一个令牌
+func (l *Limiter) Allow(key string) Decision {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	b, ok := l.buckets.get(key)
+	if !ok {
+		b = &bucket{tokens: float64(l.burst), last: now}
+		l.buckets.put(key, b)
+	}
+	b.tokens = math.Min(float64(l.burst), b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	d := Decision{Limit: l.burst}
+	if b.tokens >= 1 {
+		b.tokens--
+		d.Allowed = true
+	} else {
+		d.RetryAfter = l.seconds(1 - b.tokens)
+	}
+	d.Remaining = int(b.tokens)
+	d.Reset = l.seconds(float64(l.burst) - b.tokens)
+	return d
+}
+
+// seconds 返回补充 n 个令牌所需的时间
+func (l *Limiter) seconds(n float64) time.Duration {
+	return time.Duration(n / l.rate * float64(time.Second))
+}
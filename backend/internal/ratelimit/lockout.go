@@ -0,0 +1,108 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// LockoutPolicy 描述渐进式锁定策略：窗口内连续失败 Threshold 次即锁定，
+// 锁定时长从 BaseDuration 起每次翻倍，最长 MaxDuration。
+type LockoutPolicy struct {
+	Threshold    int
+	Window       time.Duration
+	BaseDuration time.Duration
+	MaxDuration  time.Duration
+}
+
+type lockState struct {
+	failures    int
+	lockouts    int
+	lastFailure time.Time
+	lockedUntil time.Time
+}
+
+// Lockout 跟踪按键（如用户名、IP）的登录失败次数
+type Lockout struct {
+	policy LockoutPolicy
+
+	mu     sync.Mutex
+	states *lru[*lockState]
+	now    func() time.Time
+}
+
+// NewLockout 创建锁定跟踪器，最多跟踪 maxKeys 个键。
+// 处于锁定期的键不会被淘汰，否则攻击者可用大量新键把被锁定的键挤出缓存从而解除锁定；
+// 锁定期内的键超过 maxKeys 时缓存暂时超出上限，锁定到期后恢复。
+func NewLockout(p LockoutPolicy, maxKeys int) *Lockout {
+	if p.Threshold <= 0 {
+		p.Threshold = 5
+	}
+	if p.Window <= 0 {
+		p.Window = 15 * time.Minute
+	}
+	if p.BaseDuration <= 0 {
+		p.BaseDuration = time.Minute
+	}
+	if p.MaxDuration < p.BaseDuration {
+		p.MaxDuration = time.Hour
+	}
+	l := &Lockout{policy: p, states: newLRU[*lockState](maxKeys), now: time.Now}
+	l.states.pinned = func(s *lockState) bool { return s.lockedUntil.After(l.now()) }
+	return l
+}
+
+// Locked 返回 key 是否处于锁定期以及剩余时长
+func (l *Lockout) Locked(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	s, ok := l.states.get(key)
+	if !ok {
+		return false, 0
+	}
+	if remaining := s.lockedUntil.Sub(l.now()); remaining > 0 {
+		return true, remaining
+	}
+	return false, 0
+}
+
+// Failure 记录一次失败；本次失败触发锁定时返回 true 与锁定时长
+func (l *Lockout) Failure(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	s, ok := l.states.get(key)
+	if !ok {
+		s = &lockState{}
+		l.states.put(key, s)
+	}
+	// 超出窗口未再失败则重新计数；长时间无失败后锁定级别也随之回落
+	if idle := now.Sub(s.lastFailure); idle > l.policy.Window {
+		s.failures = 0
+		if idle > l.policy.Window+l.policy.MaxDuration {
+			s.lockouts = 0
+		}
+	}
+	s.lastFailure = now
+	s.failures++
+	if s.failures < l.policy.Threshold {
+		return false, 0
+	}
+	s.failures = 0
+	s.lockouts++
+	d := l.policy.BaseDuration
+	for i := 1; i < s.lockouts && d < l.policy.MaxDuration; i++ {
+		d *= 2
+	}
+	if d > l.policy.MaxDuration {
+		d = l.policy.MaxDuration
+	}
+	s.lockedUntil = now.Add(d)
+	return true, d
+}
+
+// Success 清除 key 的失败记录
+func (l *Lockout) Success(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.states.remove(key)
+}
@@ -0,0 +1,67 @@
+package ratelimit
+
+import "container/list"
+
+// lru 是容量固定的最近最少使用缓存，超出容量时淘汰最久未访问的键，保证内存有界。
+// 非并发安全，由调用方加锁。
+type lru[V any] struct {
+	max   int
+	order *list.List
+	items map[string]*list.Element
+	// pinned 非 nil 时，返回 true 的条目不参与淘汰；其余条目都被固定时缓存暂时超出容量
+	pinned func(V) bool
+}
+
+type lruEntry[V any] struct {
+	key   string
+	value V
+}
+
+func newLRU[V any](max int) *lru[V] {
+	if max <= 0 {
+		max = 10000
+	}
+	return &lru[V]{max: max, order: list.New(), items: make(map[string]*list.Element)}
+}
+
+// get 返回键对应的值并将其标记为最近使用
+func (c *lru[V]) get(key string) (V, bool) {
+	if e, ok := c.items[key]; ok {
+		c.order.MoveToFront(e)
+		return e.Value.(*lruEntry[V]).value, true
+	}
+	var zero V
+	return zero, false
+}
+
+// put 写入键值，必要时淘汰最久未使用且未被固定的键
+func (c *lru[V]) put(key string, value V) {
+	if e, ok := c.items[key]; ok {
+		e.Value.(*lruEntry[V]).value = value
+		c.order.MoveToFront(e)
+		return
+	}
+	front := c.order.PushFront(&lruEntry[V]{key: key, value: value})
+	c.items[key] = front
+	// 新写入的键本身不参与淘汰，否则其余键全部被固定时新键写入即被丢弃
+	for e := c.order.Back(); e != front && c.order.Len() > c.max; {
+		prev := e.Prev()
+		entry := e.Value.(*lruEntry[V])
+		if c.pinned == nil || !c.pinned(entry.value) {
+			c.order.Remove(e)
+			delete(c.items, entry.key)
+		}
+		e = prev
+	}
+}
+
+func (c *lru[V]) remove(key string) {
+	if e, ok := c.items[key]; ok {
+		c.order.Remove(e)
+		delete(c.items, key)
+	}
+}
+
+func (c *lru[V]) len() int {
+	return c.order.Len()
+}
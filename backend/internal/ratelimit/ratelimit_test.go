@@ -0,0 +1,140 @@
+package ratelimit
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func TestLimiter_KeysAreIndependent(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(1_700_000_000, 0)}
+	l := NewLimiter(1, 2, 100)
+	l.now = clock.now
+
+	assert.True(t, l.Allow("ip:a").Allowed)
+	assert.True(t, l.Allow("ip:a").Allowed)
+	d := l.Allow("ip:a")
+	require.False(t, d.Allowed)
+	assert.Equal(t, 0, d.Remaining)
+	assert.Equal(t, time.Second, d.RetryAfter)
+	assert.Equal(t, 2, d.Limit)
+
+	// 一个客户端耗尽配额不影响其他客户端
+	assert.True(t, l.Allow("ip:b").Allowed)
+
+	clock.advance(time.Second)
+	assert.True(t, l.Allow("ip:a").Allowed)
+}
+
+func TestLimiter_BoundedMemory(t *testing.T) {
+	l := NewLimiter(1, 1, 3)
+	for i := 0; i < 10; i++ {
+		l.Allow(fmt.Sprintf("k%d", i))
+	}
+	assert.Equal(t, 3, l.buckets.len())
+}
+
+func TestLockout_ProgressiveDuration(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(1_700_000_000, 0)}
+	l := NewLockout(LockoutPolicy{Threshold: 3, Window: time.Minute, BaseDuration: time.Minute, MaxDuration: 3 * time.Minute}, 100)
+	l.now = clock.now
+
+	fail := func() (bool, time.Duration) {
+		var locked bool
+		var d time.Duration
+		for i := 0; i < 3; i++ {
+			locked, d = l.Failure("user:alice")
+		}
+		return locked, d
+	}
+
+	locked, d := fail()
+	require.True(t, locked)
+	assert.Equal(t, time.Minute, d)
+	locked, remaining := l.Locked("user:alice")
+	assert.True(t, locked)
+	assert.Equal(t, time.Minute, remaining)
+
+	clock.advance(time.Minute)
+	locked, _ = l.Locked("user:alice")
+	assert.False(t, locked)
+
+	_, d = fail()
+	assert.Equal(t, 2*time.Minute, d)
+	clock.advance(2 * time.Minute)
+	_, d = fail()
+	assert.Equal(t, 3*time.Minute, d, "capped at MaxDuration")
+
+	l.Success("user:alice")
+	locked, _ = l.Locked("user:alice")
+	assert.False(t, locked)
+}
+
+func TestLockout_WindowResetsFailures(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(1_700_000_000, 0)}
+	l := NewLockout(LockoutPolicy{Threshold: 2, Window: time.Minute, BaseDuration: time.Minute, MaxDuration: time.Hour}, 100)
+	l.now = clock.now
+
+	locked, _ := l.Failure("ip:1.2.3.4")
+	assert.False(t, locked)
+	clock.advance(2 * time.Minute)
+	locked, _ = l.Failure("ip:1.2.3.4")
+	assert.False(t, locked, "failure outside window starts a new count")
+	locked, _ = l.Failure("ip:1.2.3.4")
+	assert.True(t, locked)
+}
+
+func TestLockout_EvictionKeepsActiveLockout(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(1_700_000_000, 0)}
+	l := NewLockout(LockoutPolicy{Threshold: 1, Window: time.Minute, BaseDuration: time.Minute, MaxDuration: time.Hour}, 3)
+	l.now = clock.now
+
+	locked, _ := l.Failure("user:alice")
+	require.True(t, locked)
+	// 大量新键不能把处于锁定期的键挤出缓存
+	for i := 0; i < 10; i++ {
+		l.states.put(fmt.Sprintf("ip:%d", i), &lockState{lastFailure: clock.t})
+	}
+	assert.Equal(t, 3, l.states.len())
+	locked, remaining := l.Locked("user:alice")
+	assert.True(t, locked, "eviction must not unlock an active lockout")
+	assert.Equal(t, time.Minute, remaining)
+
+	// 锁定到期后按正常的最近最少使用顺序淘汰
+	clock.advance(time.Minute)
+	for i := 0; i < 3; i++ {
+		l.states.put(fmt.Sprintf("ip:new%d", i), &lockState{lastFailure: clock.t})
+	}
+	_, ok := l.states.get("user:alice")
+	assert.False(t, ok)
+	assert.Equal(t, 3, l.states.len())
+}
+
+func TestLockout_NewKeyLocksWhenCacheFullOfLockouts(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(1_700_000_000, 0)}
+	l := NewLockout(LockoutPolicy{Threshold: 2, Window: time.Minute, BaseDuration: time.Minute, MaxDuration: time.Hour}, 3)
+	l.now = clock.now
+
+	for i := 0; i < 3; i++ {
+		key := fmt.Sprintf("user:%d", i)
+		l.Failure(key)
+		locked, _ := l.Failure(key)
+		require.True(t, locked)
+	}
+	// 缓存已满且全部处于锁定期，新键仍须被跟踪并能被锁定
+	locked, _ := l.Failure("user:mallory")
+	assert.False(t, locked)
+	locked, _ = l.Failure("user:mallory")
+	assert.True(t, locked, "a new key must accumulate failures even when every other key is locked")
+	locked, _ = l.Locked("user:mallory")
+	assert.True(t, locked)
+	assert.Equal(t, 4, l.states.len(), "the cache grows past max while every other key is locked")
+}
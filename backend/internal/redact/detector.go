@@ -0,0 +1,112 @@
+package redact
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// 内置检测器名称
+const (
+	DetectorPhone    = "phone"
+	DetectorIDCard   = "id_card"
+	DetectorBankCard = "bank_card"
+)
+
+// Detector 识别一类敏感字段：正则给出候选，Validate（可选）做校验位等二次确认，
+// 脱敏时保留前 KeepPrefix 位与后 KeepSuffix 位字母数字
+type Detector struct {
+	Name       string
+	Pattern    *regexp.Regexp
+	Validate   func(match string) bool
+	KeepPrefix int
+	KeepSuffix int
+}
+
+var builtins = map[string]Detector{
+	// 中国大陆手机号，允许 +86 前缀及空格 / 短横线分组
+	DetectorPhone: {
+		Name:       DetectorPhone,
+		Pattern:    regexp.MustCompile(`(?:\+?86[ -]?)?1[3-9][0-9](?:[ -]?[0-9]{4}){2}`),
+		KeepPrefix: 3,
+		KeepSuffix: 4,
+	},
+	// 18 位居民身份证号，校验 GB 11643 校验码与出生日期
+	DetectorIDCard: {
+		Name:       DetectorIDCard,
+		Pattern:    regexp.MustCompile(`[1-9][0-9]{16}[0-9Xx]`),
+		Validate:   validIDCard,
+		KeepPrefix: 1,
+		KeepSuffix: 1,
+	},
+	// 16–19 位银行卡号，允许四位一组书写，须通过 Luhn 校验
+	DetectorBankCard: {
+		Name:       DetectorBankCard,
+		Pattern:    regexp.MustCompile(`[0-9]{4}(?:[ -]?[0-9]{4}){3}(?:[ -]?[0-9]{1,3})?`),
+		Validate:   validBankCard,
+		KeepSuffix: 4,
+	},
+}
+
+// Builtin 返回内置检测器
+func Builtin(name string) (Detector, bool) {
+	d, ok := builtins[name]
+	return d, ok
+}
+
+// Custom 以正则构造自定义检测器，命中内容全部脱敏
+func Custom(name, pattern string) (Detector, error) {
+	if name == "" {
+		return Detector{}, fmt.Errorf("custom detector name is required")
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return Detector{}, fmt.Errorf("detector %q: %w", name, err)
+	}
+	return Detector{Name: name, Pattern: re}, nil
+}
+
+var idCardWeights = [17]int{7, 9, 10, 5, 8, 4, 2, 1, 6, 3, 7, 9, 10, 5, 8, 4, 2}
+
+const idCardCheckChars = "10X98765432"
+
+func validIDCard(s string) bool {
+	s = strings.ToUpper(s)
+	if len(s) != 18 {
+		return false
+	}
+	sum := 0
+	for i := 0; i < 17; i++ {
+		sum += int(s[i]-'0') * idCardWeights[i]
+	}
+	if idCardCheckChars[sum%11] != s[17] {
+		return false
+	}
+	month := int(s[10]-'0')*10 + int(s[11]-'0')
+	day := int(s[12]-'0')*10 + int(s[13]-'0')
+	return month >= 1 && month <= 12 && day >= 1 && day <= 31
+}
+
+func validBankCard(s string) bool {
+	digits := make([]int, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] >= '0' && s[i] <= '9' {
+			digits = append(digits, int(s[i]-'0'))
+		}
+	}
+	if len(digits) < 16 || len(digits) > 19 {
+		return false
+	}
+	sum := 0
+	for i := range digits {
+		d := digits[len(digits)-1-i]
+		if i%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return sum%10 == 0
+}
@@ -0,0 +1,163 @@
+// Package redact 在消息返回前按角色对手机号、身份证号、银行卡号等敏感字段脱敏。
+package redact
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Finding 是一处命中的敏感字段，Start/End 为字节偏移
+type Finding struct {
+	Detector   string
+	Start, End int
+}
+
+// Policy 是按角色的脱敏策略
+type Policy struct {
+	// Clear 列出各角色可直接看到明文的检测器；未列出的角色全部脱敏
+	Clear map[string][]string
+	// UnmaskRoles 可提交（经审计的）取消脱敏请求的角色
+	UnmaskRoles []string
+}
+
+// Engine 按顺序运行检测器；多个检测器命中重叠区域时，排在前面的优先
+type Engine struct {
+	detectors []Detector
+	// exact[i] 为 detectors[i].Pattern 的全串匹配形式，用于在候选未通过校验时尝试更短的前缀
+	exact  []*regexp.Regexp
+	clear  map[string]map[string]bool
+	unmask map[string]bool
+}
+
+// New 创建脱敏引擎
+func New(detectors []Detector, policy Policy) *Engine {
+	e := &Engine{detectors: detectors, clear: map[string]map[string]bool{}, unmask: map[string]bool{}}
+	for _, d := range detectors {
+		e.exact = append(e.exact, regexp.MustCompile(`^(?:`+d.Pattern.String()+`)$`))
+	}
+	for role, names := range policy.Clear {
+		set := make(map[string]bool, len(names))
+		for _, n := range names {
+			set[n] = true
+		}
+		e.clear[role] = set
+	}
+	for _, role := range policy.UnmaskRoles {
+		e.unmask[role] = true
+	}
+	return e
+}
+
+// CanUnmask 判断角色是否可以请求查看明文
+func (e *Engine) CanUnmask(role string) bool {
+	return e.unmask[role]
+}
+
+// Find 返回文本中全部敏感字段，按位置排序且互不重叠
+func (e *Engine) Find(text string) []Finding {
+	var found []Finding
+	taken := func(start, end int) bool {
+		for _, f := range found {
+			if start < f.End && f.Start < end {
+				return true
+			}
+		}
+		return false
+	}
+	for i, d := range e.detectors {
+		for _, loc := range d.Pattern.FindAllStringIndex(text, -1) {
+			start := loc[0]
+			if start > 0 && isAlnum(text[start-1]) {
+				continue
+			}
+			// 可选的尾部分组是贪婪的，会把紧随其后的数字（如“卡号 6222… 100元”中的 100）并入候选；
+			// 整段未通过校验时依次尝试仍符合模式的更短前缀
+			for end := loc[1]; end > start; end-- {
+				if end < loc[1] && !e.exact[i].MatchString(text[start:end]) {
+					continue
+				}
+				if !isBoundary(text, start, end) || taken(start, end) {
+					continue
+				}
+				if d.Validate != nil && !d.Validate(text[start:end]) {
+					continue
+				}
+				found = append(found, Finding{Detector: d.Name, Start: start, End: end})
+				break
+			}
+		}
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].Start < found[j].Start })
+	return found
+}
+
+// Redact 按角色策略脱敏，返回脱敏后的文本与被遮盖的字段数
+func (e *Engine) Redact(role, text string) (string, int) {
+	findings := e.Find(text)
+	if len(findings) == 0 {
+		return text, 0
+	}
+	var b strings.Builder
+	b.Grow(len(text))
+	prev, masked := 0, 0
+	for _, f := range findings {
+		if e.clear[role][f.Detector] {
+			continue
+		}
+		b.WriteString(text[prev:f.Start])
+		b.WriteString(mask(text[f.Start:f.End], e.detector(f.Detector)))
+		prev = f.End
+		masked++
+	}
+	b.WriteString(text[prev:])
+	return b.String(), masked
+}
+
+func (e *Engine) detector(name string) Detector {
+	for _, d := range e.detectors {
+		if d.Name == name {
+			return d
+		}
+	}
+	return Detector{}
+}
+
+// mask 把字母数字替换为 *，保留分隔符及首尾指定位数
+func mask(s string, d Detector) string {
+	runes := []rune(s)
+	n := 0
+	for _, r := range runes {
+		if maskable(r) {
+			n++
+		}
+	}
+	pos := 0
+	for i, r := range runes {
+		if !maskable(r) {
+			continue
+		}
+		if pos >= d.KeepPrefix && pos < n-d.KeepSuffix {
+			runes[i] = '*'
+		}
+		pos++
+	}
+	return string(runes)
+}
+
+func maskable(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// isBoundary 要求命中两侧不紧邻数字或字母，避免在更长的数字串中截取片段
+func isBoundary(text string, start, end int) bool {
+	if start > 0 && isAlnum(text[start-1]) {
+		return false
+	}
+	return end >= len(text) || !isAlnum(text[end])
+}
+
+func isAlnum(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
@@ -0,0 +1,88 @@
+package redact
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testEngine(t *testing.T, policy Policy) *Engine {
+	t.Helper()
+	var ds []Detector
+	for _, name := range []string{DetectorIDCard, DetectorBankCard, DetectorPhone} {
+		d, ok := Builtin(name)
+		require.True(t, ok)
+		ds = append(ds, d)
+	}
+	return New(ds, policy)
+}
+
+func TestValidIDCard(t *testing.T) {
+	assert.True(t, validIDCard("11010519491231002X"))
+	assert.True(t, validIDCard("11010519491231002x"))
+	assert.False(t, validIDCard("110105194912310021"), "wrong check digit")
+	assert.False(t, validIDCard("110105194913310029"), "invalid month")
+}
+
+func TestValidBankCard(t *testing.T) {
+	assert.True(t, validBankCard("4111111111111111"))
+	assert.True(t, validBankCard("4111 1111 1111 1111"))
+	assert.False(t, validBankCard("4111111111111112"))
+	assert.False(t, validBankCard("411111111111"), "too short")
+}
+
+func TestRedact_MasksByDetector(t *testing.T) {
+	e := testEngine(t, Policy{})
+	out, n := e.Redact("auditor", "电话 138-0013-8000，身份证11010519491231002X，卡号 4111 1111 1111 1111。")
+	assert.Equal(t, 3, n)
+	assert.Equal(t, "电话 138-****-8000，身份证1****************X，卡号 **** **** **** 1111。", out)
+}
+
+func TestRedact_BankCardFollowedByDigits(t *testing.T) {
+	e := testEngine(t, Policy{})
+	// 可选尾部分组会吞入后面的金额，整段不通过 Luhn 时仍须识别出 16 位卡号
+	out, n := e.Redact("auditor", "卡号 6222021234567894 100元")
+	assert.Equal(t, 1, n)
+	assert.Equal(t, "卡号 ************7894 100元", out)
+
+	out, n = e.Redact("auditor", "卡号 4111 1111 1111 1111 23 笔")
+	assert.Equal(t, 1, n)
+	assert.Equal(t, "卡号 **** **** **** 1111 23 笔", out)
+}
+
+func TestRedact_IgnoresInvalidAndEmbeddedNumbers(t *testing.T) {
+	e := testEngine(t, Policy{})
+	for _, s := range []string{
+		"订单号 110105194912310021", // 校验码错误
+		"流水 4111111111111112",    // Luhn 不通过
+		"编号 9913800138000123",    // 手机号嵌在更长的数字串中
+		"ref A13800138000",       // 前面紧邻字母
+	} {
+		out, n := e.Redact("auditor", s)
+		assert.Zero(t, n, s)
+		assert.Equal(t, s, out)
+	}
+}
+
+func TestRedact_RolePolicy(t *testing.T) {
+	e := testEngine(t, Policy{Clear: map[string][]string{"investigator": {DetectorPhone}}, UnmaskRoles: []string{"admin"}})
+	out, n := e.Redact("investigator", "13800138000 / 4111111111111111")
+	assert.Equal(t, 1, n)
+	assert.Equal(t, "13800138000 / ************1111", out)
+
+	assert.True(t, e.CanUnmask("admin"))
+	assert.False(t, e.CanUnmask("investigator"))
+}
+
+func TestCustomDetector(t *testing.T) {
+	d, err := Custom("email", `[a-z0-9._%+-]+@[a-z0-9.-]+\.[a-z]{2,}`)
+	require.NoError(t, err)
+	e := New([]Detector{d}, Policy{})
+	out, n := e.Redact("auditor", "mail li.lei@example.com now")
+	assert.Equal(t, 1, n)
+	assert.Equal(t, "mail **.***@*******.*** now", out)
+
+	_, err = Custom("bad", "(")
+	assert.Error(t, err)
+}
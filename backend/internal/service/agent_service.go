@@ -2,21 +2,97 @@ package service
 
 import (
     "context"
-    "log/slog"
+    "errors"
+    "strconv"
+    "time"
 
     "github.com/jackc/pgx/v5/pgxpool"
+    "guardian-backend/internal/database"
+    "guardian-backend/pkg/apperr"
     api "guardian-backend/pkg/grpc/api/guardian/pkg/grpc/api"
+    "guardian-backend/pkg/logger"
 )
 
 type AgentServer struct {
 	api.UnimplementedAgentServiceServer
 	DB *pgxpool.Pool
+	// RetentionPeriod 为选择 retain 处置的退役 agent 数据保留期限
+	RetentionPeriod time.Duration
 }
 
 // RegisterAgent 在当前 proto 中不存在，移除实现以避免未定义类型错误
 
 func (s *AgentServer) Heartbeat(ctx context.Context, req *api.HeartbeatRequest) (*api.HeartbeatResponse, error) {
-	slog.Info("Heartbeat received", "agent_id", req.AgentId)
-	// TODO: 未来可加入任务分发逻辑
-	return &api.HeartbeatResponse{TaskId: ""}, nil
+	logger.From(ctx).Info("Heartbeat received")
+	db := &database.DB{Pool: s.DB}
+	if err := authorizeAgent(ctx, db, int(req.AgentId)); err != nil {
+		return nil, err
+	}
+	if err := db.TouchAgent(ctx, int(req.AgentId)); err != nil {
+		logger.From(ctx).Error("Failed to record heartbeat", "error", err)
+	}
+	// 下发最早的待执行任务；任务进入 sent 状态后，其授权范围即作为上传过滤依据
+	taskID, taskType, err := db.GetAndDispatchPendingTaskForAgent(ctx, int(req.AgentId))
+	if err != nil {
+		logger.From(ctx).Error("Failed to dispatch task", "error", err)
+		return nil, apperr.Internal(err)
+	}
+	if taskID == 0 {
+		return &api.HeartbeatResponse{TaskId: ""}, nil
+	}
+	logger.From(ctx).Info("Task dispatched", "task_id", taskID, "task_type", taskType)
+	return &api.HeartbeatResponse{
+		TaskId:   strconv.FormatInt(taskID, 10),
+		TaskType: api.TaskType(api.TaskType_value[taskType]),
+	}, nil
+}
+
+// ReportTaskResult 记录任务执行结果；卸载任务完成即视为 agent 确认退役
+func (s *AgentServer) ReportTaskResult(ctx context.Context, req *api.ReportTaskResultRequest) (*api.ReportTaskResultResponse, error) {
+	db := &database.DB{Pool: s.DB}
+	agentID := int(req.AgentId)
+	if err := authorizeAgent(ctx, db, agentID); err != nil {
+		return nil, err
+	}
+	taskID, err := strconv.ParseInt(req.TaskId, 10, 64)
+	if err != nil {
+		return nil, apperr.New(apperr.KindInvalidArgument, "", "invalid task_id")
+	}
+	var taskStatus string
+	switch req.Status {
+	case api.TaskResultStatus_TASK_RESULT_COMPLETED:
+		taskStatus = database.TaskStatusCompleted
+	case api.TaskResultStatus_TASK_RESULT_FAILED:
+		taskStatus = database.TaskStatusFailed
+	default:
+		return nil, apperr.New(apperr.KindInvalidArgument, "", "task result status is required")
+	}
+	c, err := db.CompleteTask(ctx, agentID, taskID, taskStatus, req.Message, s.RetentionPeriod)
+	if err != nil {
+		if errors.Is(err, database.ErrTaskNotFound) {
+			return nil, apperr.From(err)
+		}
+		logger.From(ctx).Error("Failed to complete task", "error", err, "task_id", taskID)
+		return nil, apperr.Internal(err)
+	}
+	logger.From(ctx).Info("Task result recorded", "task_id", taskID, "task_type", c.TaskType, "status", taskStatus)
+	if c.Retired {
+		logger.From(ctx).Info("Agent retired", "data_disposition", c.Disposition, "purged_messages", c.PurgedMessages)
+		if err := db.RecordAudit(ctx, database.AuditEntry{
+			Actor:      "agent:" + strconv.Itoa(agentID),
+			Action:     "agent.retire",
+			TargetType: "agent",
+			TargetID:   strconv.Itoa(agentID),
+			Outcome:    database.AuditOutcomeSuccess,
+			Detail: map[string]any{
+				"task_id":          taskID,
+				"data_disposition": c.Disposition,
+				"purged_messages":  c.PurgedMessages,
+				"credentials":      "revoked",
+			},
+		}); err != nil {
+			logger.From(ctx).Error("Failed to record audit log", "error", err, "action", "agent.retire")
+		}
+	}
+	return &api.ReportTaskResultResponse{Success: true}, nil
 }
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"slices"
+
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	"guardian-backend/internal/auth"
+	"guardian-backend/pkg/apperr"
+	api "guardian-backend/pkg/grpc/api/guardian/pkg/grpc/api"
+)
+
+// AuthorizeConsole 按角色限制 ConsoleService 的方法，gRPC 与 gRPC-Gateway 注册的都是它返回的实现。
+// 调用者须已由 gRPC 认证拦截器或 HTTP JWTAuth 认证；/v1 路由仍由 handler.RequireRole 授权，规则与此一致。
+func AuthorizeConsole(next api.ConsoleServiceServer) api.ConsoleServiceServer {
+	return &authorizedConsole{next: next}
+}
+
+type authorizedConsole struct {
+	api.UnimplementedConsoleServiceServer
+	next api.ConsoleServiceServer
+}
+
+// requireRole 要求调用者已认证；roles 非空时还要求角色在其中
+func requireRole(ctx context.Context, roles ...string) error {
+	p := auth.PrincipalFrom(ctx)
+	if p.UserID == "" {
+		return apperr.New(apperr.KindUnauthenticated, "", "missing token")
+	}
+	if len(roles) > 0 && !slices.Contains(roles, p.Role) {
+		return apperr.New(apperr.KindPermissionDenied, "", "insufficient role")
+	}
+	return nil
+}
+
+func (a *authorizedConsole) ListAgents(ctx context.Context, req *api.ListAgentsRequest) (*api.ListAgentsResponse, error) {
+	if err := requireRole(ctx); err != nil {
+		return nil, err
+	}
+	return a.next.ListAgents(ctx, req)
+}
+
+func (a *authorizedConsole) CreateTask(ctx context.Context, req *api.CreateTaskRequest) (*api.CreateTaskResponse, error) {
+	if err := requireRole(ctx, "admin", "approver"); err != nil {
+		return nil, err
+	}
+	return a.next.CreateTask(ctx, req)
+}
+
+func (a *authorizedConsole) DecommissionAgent(ctx context.Context, req *api.DecommissionAgentRequest) (*api.DecommissionAgentResponse, error) {
+	if err := requireRole(ctx, "admin"); err != nil {
+		return nil, err
+	}
+	return a.next.DecommissionAgent(ctx, req)
+}
+
+func (a *authorizedConsole) ListMessages(ctx context.Context, req *api.ListMessagesRequest) (*api.ListMessagesResponse, error) {
+	if err := requireRole(ctx); err != nil {
+		return nil, err
+	}
+	return a.next.ListMessages(ctx, req)
+}
+
+func (a *authorizedConsole) PurgeTaskMessages(ctx context.Context, req *api.PurgeTaskMessagesRequest) (*api.PurgeTaskMessagesResponse, error) {
+	if err := requireRole(ctx, "admin"); err != nil {
+		return nil, err
+	}
+	return a.next.PurgeTaskMessages(ctx, req)
+}
+
+func (a *authorizedConsole) ListCases(ctx context.Context, req *api.ListCasesRequest) (*api.ListCasesResponse, error) {
+	if err := requireRole(ctx); err != nil {
+		return nil, err
+	}
+	return a.next.ListCases(ctx, req)
+}
+
+func (a *authorizedConsole) CreateCase(ctx context.Context, req *api.CreateCaseRequest) (*api.Case, error) {
+	if err := requireRole(ctx); err != nil {
+		return nil, err
+	}
+	return a.next.CreateCase(ctx, req)
+}
+
+func (a *authorizedConsole) DestroyCaseKey(ctx context.Context, req *api.DestroyCaseKeyRequest) (*emptypb.Empty, error) {
+	if err := requireRole(ctx, "admin"); err != nil {
+		return nil, err
+	}
+	return a.next.DestroyCaseKey(ctx, req)
+}
+
+func (a *authorizedConsole) ListAuditLogs(ctx context.Context, req *api.ListAuditLogsRequest) (*api.ListAuditLogsResponse, error) {
+	if err := requireRole(ctx, "admin", "auditor"); err != nil {
+		return nil, err
+	}
+	return a.next.ListAuditLogs(ctx, req)
+}
@@ -0,0 +1,462 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"guardian-backend/internal/auth"
+	"guardian-backend/internal/database"
+	"guardian-backend/internal/redact"
+	"guardian-backend/pkg/apperr"
+	api "guardian-backend/pkg/grpc/api/guardian/pkg/grpc/api"
+	"guardian-backend/pkg/logger"
+)
+
+// auditRecorder 是写审计日志所需的最小接口
+type auditRecorder interface {
+	RecordAudit(ctx context.Context, e database.AuditEntry) error
+}
+
+// AgentStore 是 agent、任务与消息相关方法所需的存储接口
+type AgentStore interface {
+	auditRecorder
+	ListAgents(ctx context.Context) ([]database.AgentInfo, error)
+	CreateTaskForAgent(ctx context.Context, agentID int, taskType string, scope database.TaskScope) (int64, error)
+	CreateDecommissionTask(ctx context.Context, agentID int, disposition string) (int64, error)
+	ListMessagesByAgent(ctx context.Context, agentID int, limit, offset int) ([]database.WechatMessageRecord, error)
+	ListMessagesByTask(ctx context.Context, taskID int64, limit, offset int) ([]database.WechatMessageRecord, error)
+	PurgeTaskMessages(ctx context.Context, taskID int64, reason string) (int64, error)
+}
+
+// CaseStore 是案件相关方法所需的存储接口
+type CaseStore interface {
+	auditRecorder
+	CreateCase(ctx context.Context, name string) (database.Case, error)
+	ListCases(ctx context.Context) ([]database.Case, error)
+	DestroyCaseKey(ctx context.Context, caseID int64) error
+}
+
+// AuditLogStore 是审计日志查询所需的存储接口
+type AuditLogStore interface {
+	ListAuditLogs(ctx context.Context, f database.AuditFilter) ([]database.AuditRecord, error)
+}
+
+// ConsoleServer 实现 ConsoleService。gRPC、gRPC-Gateway（/v2）与 /v1 控制台路由共用这一份实现：
+// 参数校验、分页、脱敏与审计都在这里完成，调用者身份与来源 IP 取自 context（auth.WithPrincipal / auth.WithClientIP）。
+// 角色授权由 AuthorizeConsole 负责。
+type ConsoleServer struct {
+	api.UnimplementedConsoleServiceServer
+	Agents AgentStore
+	Cases  CaseStore
+	Audit  AuditLogStore
+	// Redactor 在返回消息前按角色脱敏；为 nil 时不脱敏
+	Redactor *redact.Engine
+}
+
+// invalid 返回参数校验失败错误，错误码与 HTTP 接口的 VALIDATION_FAILED 一致
+func invalid(message string) error {
+	return apperr.New(apperr.KindInvalidArgument, "VALIDATION_FAILED", message)
+}
+
+// recordAudit 补全操作者与来源 IP 后写入审计日志；写入失败只记录错误日志，不影响响应
+func recordAudit(ctx context.Context, a auditRecorder, e database.AuditEntry) {
+	if e.Actor == "" {
+		e.Actor = auth.PrincipalFrom(ctx).UserID
+	}
+	if e.Actor == "" {
+		e.Actor = "anonymous"
+	}
+	if e.IPAddress == "" {
+		e.IPAddress = auth.ClientIPFrom(ctx)
+	}
+	if e.Outcome == "" {
+		e.Outcome = database.AuditOutcomeSuccess
+	}
+	if err := a.RecordAudit(ctx, e); err != nil {
+		logger.From(ctx).Error("Failed to record audit log", "error", err, "action", e.Action, "outcome", e.Outcome)
+	}
+}
+
+// paginate 返回第 page 页（从 1 开始）的切片范围；page 或 size 越界时使用默认值
+func paginate(total int, page, size, defaultSize, maxSize int32) (start, end int) {
+	if page <= 0 {
+		page = 1
+	}
+	if size <= 0 || size > maxSize {
+		size = defaultSize
+	}
+	start = min(int(page-1)*int(size), total)
+	end = min(start+int(size), total)
+	return start, end
+}
+
+// ListAgents 分页列出 agent
+func (s *ConsoleServer) ListAgents(ctx context.Context, req *api.ListAgentsRequest) (*api.ListAgentsResponse, error) {
+	list, err := s.Agents.ListAgents(ctx)
+	if err != nil {
+		return nil, apperr.From(err)
+	}
+	start, end := paginate(len(list), req.GetPage(), req.GetPageSize(), 50, 200)
+	resp := &api.ListAgentsResponse{Agents: make([]*api.Agent, 0, end-start)}
+	for _, a := range list[start:end] {
+		resp.Agents = append(resp.Agents, &api.Agent{Id: int32(a.ID), Hostname: a.Hostname, Status: a.Status})
+	}
+	return resp, nil
+}
+
+// CreateTask 为 agent 下发采集任务；未取得被监测人员告知确认时拒绝并留痕
+func (s *ConsoleServer) CreateTask(ctx context.Context, req *api.CreateTaskRequest) (*api.CreateTaskResponse, error) {
+	scope := database.TaskScope{ExcludedConversations: req.GetExcludedConversations(), CaseID: req.GetCaseId()}
+	for _, c := range scope.ExcludedConversations {
+		if c == "" || len(c) > 255 {
+			return nil, invalid("excluded_conversations entries must be 1-255 characters")
+		}
+	}
+	if scope.CaseID < 0 {
+		return nil, invalid("case_id must not be negative")
+	}
+	if req.ScopeStart != nil {
+		t := req.ScopeStart.AsTime()
+		scope.ScopeStart = &t
+	}
+	if req.ScopeEnd != nil {
+		t := req.ScopeEnd.AsTime()
+		scope.ScopeEnd = &t
+	}
+	if scope.ScopeStart != nil && scope.ScopeEnd != nil && !scope.ScopeEnd.After(*scope.ScopeStart) {
+		return nil, invalid("scope_end must be after scope_start")
+	}
+	agentID := int(req.GetAgentId())
+	taskType := database.TaskTypeDumpWechatData
+	taskID, err := s.Agents.CreateTaskForAgent(ctx, agentID, taskType, scope)
+	if err != nil {
+		if errors.Is(err, database.ErrAcknowledgementRequired) {
+			recordAudit(ctx, s.Agents, database.AuditEntry{
+				Action:     "task.create",
+				TargetType: "agent",
+				TargetID:   strconv.Itoa(agentID),
+				Outcome:    database.AuditOutcomeDenied,
+				Detail:     map[string]any{"reason": "acknowledgement_required", "task_type": taskType},
+			})
+		}
+		return nil, apperr.From(err)
+	}
+	recordAudit(ctx, s.Agents, database.AuditEntry{
+		Action:     "task.create",
+		TargetType: "task",
+		TargetID:   strconv.FormatInt(taskID, 10),
+		Detail:     map[string]any{"agent_id": agentID, "task_type": taskType},
+	})
+	return &api.CreateTaskResponse{TaskId: taskID}, nil
+}
+
+// DecommissionAgent 为 agent 下发卸载任务；重复或对已退役 agent 的请求被拒绝并留痕
+func (s *ConsoleServer) DecommissionAgent(ctx context.Context, req *api.DecommissionAgentRequest) (*api.DecommissionAgentResponse, error) {
+	if req.GetDataDisposition() != "retain" && req.GetDataDisposition() != "purge" {
+		return nil, invalid("data_disposition must be retain or purge")
+	}
+	if len(req.GetReason()) > 1000 {
+		return nil, invalid("reason must be at most 1000 characters")
+	}
+	agentID := int(req.GetAgentId())
+	entry := database.AuditEntry{
+		Action:     "agent.decommission.request",
+		TargetType: "agent",
+		TargetID:   strconv.Itoa(agentID),
+		Detail:     map[string]any{"data_disposition": req.GetDataDisposition(), "reason": req.GetReason()},
+	}
+	taskID, err := s.Agents.CreateDecommissionTask(ctx, agentID, req.GetDataDisposition())
+	if err != nil {
+		switch {
+		case errors.Is(err, database.ErrAgentRetired):
+			entry.Outcome = database.AuditOutcomeDenied
+			entry.Detail["denied_reason"] = "agent_retired"
+			recordAudit(ctx, s.Agents, entry)
+		case errors.Is(err, database.ErrDecommissionInProgress):
+			entry.Outcome = database.AuditOutcomeDenied
+			entry.Detail["denied_reason"] = "decommission_in_progress"
+			recordAudit(ctx, s.Agents, entry)
+		}
+		return nil, apperr.From(err)
+	}
+	entry.Detail["task_id"] = taskID
+	recordAudit(ctx, s.Agents, entry)
+	return &api.DecommissionAgentResponse{TaskId: taskID}, nil
+}
+
+// messageScanBatch 为检索消息时每次从数据库读取的条数
+const messageScanBatch = 500
+
+// ListMessages 按 agent 或任务查询消息。
+// 内容按调用者角色脱敏；q 在脱敏后的内容中检索，避免借检索探测被遮盖的字段。
+// 有权限的角色可带 unmask 与 reason 查看明文；每次查询都写入审计日志（messages.read 或 messages.unmask），
+// 证据导出的监管链据此列出访问者。
+func (s *ConsoleServer) ListMessages(ctx context.Context, req *api.ListMessagesRequest) (*api.ListMessagesResponse, error) {
+	var (
+		targetType string
+		target     string
+		load       func(ctx context.Context, limit, offset int) ([]database.WechatMessageRecord, error)
+	)
+	switch {
+	case req.GetAgentId() != 0 && req.GetTaskId() != 0:
+		return nil, invalid("only one of agent_id and task_id may be set")
+	case req.GetAgentId() != 0:
+		targetType, target = "agent", strconv.Itoa(int(req.GetAgentId()))
+		load = func(ctx context.Context, limit, offset int) ([]database.WechatMessageRecord, error) {
+			return s.Agents.ListMessagesByAgent(ctx, int(req.GetAgentId()), limit, offset)
+		}
+	case req.GetTaskId() != 0:
+		targetType, target = "task", strconv.FormatInt(req.GetTaskId(), 10)
+		load = func(ctx context.Context, limit, offset int) ([]database.WechatMessageRecord, error) {
+			return s.Agents.ListMessagesByTask(ctx, req.GetTaskId(), limit, offset)
+		}
+	default:
+		return nil, invalid("agent_id or task_id is required")
+	}
+	search := strings.ToLower(strings.TrimSpace(req.GetQ()))
+	role := auth.PrincipalFrom(ctx).Role
+
+	unmask := req.GetUnmask() && s.Redactor != nil
+	reason := strings.TrimSpace(req.GetReason())
+	if unmask {
+		if !s.Redactor.CanUnmask(role) {
+			recordAudit(ctx, s.Agents, database.AuditEntry{Action: "messages.unmask", TargetType: targetType, TargetID: target, Outcome: database.AuditOutcomeDenied})
+			return nil, apperr.New(apperr.KindPermissionDenied, "", "role may not unmask messages")
+		}
+		if reason == "" {
+			return nil, invalid("reason is required to unmask messages")
+		}
+	}
+
+	toMessage := func(m database.WechatMessageRecord) *api.StoredMessage {
+		msg := &api.StoredMessage{Content: m.Content, Timestamp: timestamppb.New(m.Timestamp), TaskId: m.TaskID}
+		if s.Redactor != nil && !unmask {
+			var n int
+			msg.Content, n = s.Redactor.Redact(role, m.Content)
+			msg.Redacted = n > 0
+		}
+		return msg
+	}
+	page, size := max(int(req.GetPage()), 1), int(req.GetPageSize())
+	if size <= 0 || size > 500 {
+		size = 100
+	}
+	var out []*api.StoredMessage
+	if search == "" {
+		recs, err := load(ctx, size, (page-1)*size)
+		if err != nil {
+			return nil, apperr.From(err)
+		}
+		out = make([]*api.StoredMessage, 0, len(recs))
+		for _, m := range recs {
+			out = append(out, toMessage(m))
+		}
+	} else {
+		// 内容加密存储且检索作用于脱敏后的文本，无法在 SQL 中过滤：
+		// 按批从新到旧扫描，跳过前几页的命中，凑满本页或扫完全部消息为止
+		skip := (page - 1) * size
+		out = make([]*api.StoredMessage, 0, size)
+	scan:
+		for offset := 0; ; offset += messageScanBatch {
+			recs, err := load(ctx, messageScanBatch, offset)
+			if err != nil {
+				return nil, apperr.From(err)
+			}
+			for _, m := range recs {
+				msg := toMessage(m)
+				if !strings.Contains(strings.ToLower(msg.Content), search) {
+					continue
+				}
+				if skip > 0 {
+					skip--
+					continue
+				}
+				if out = append(out, msg); len(out) == size {
+					break scan
+				}
+			}
+			if len(recs) < messageScanBatch {
+				break
+			}
+		}
+	}
+
+	if unmask {
+		revealed := 0
+		for _, m := range out {
+			revealed += len(s.Redactor.Find(m.Content))
+		}
+		recordAudit(ctx, s.Agents, database.AuditEntry{
+			Action:     "messages.unmask",
+			TargetType: targetType,
+			TargetID:   target,
+			Detail:     map[string]any{"reason": reason, "query": search, "page": page, "messages": len(out), "fields_revealed": revealed},
+		})
+	} else {
+		recordAudit(ctx, s.Agents, database.AuditEntry{
+			Action:     "messages.read",
+			TargetType: targetType,
+			TargetID:   target,
+			Detail:     map[string]any{"query": search, "page": page, "messages": len(out)},
+		})
+	}
+	return &api.ListMessagesResponse{Messages: out}, nil
+}
+
+// PurgeTaskMessages 清除某任务采集的全部消息（例如事后认定任务不当），未结束的任务随之取消
+func (s *ConsoleServer) PurgeTaskMessages(ctx context.Context, req *api.PurgeTaskMessagesRequest) (*api.PurgeTaskMessagesResponse, error) {
+	reason := req.GetReason()
+	if reason == "" || len(reason) > 1000 {
+		return nil, invalid("reason must be 1-1000 characters")
+	}
+	if req.GetConfirm() != req.GetTaskId() {
+		return nil, invalid("confirm must equal the task id")
+	}
+	purged, err := s.Agents.PurgeTaskMessages(ctx, req.GetTaskId(), reason)
+	if err != nil {
+		return nil, apperr.From(err)
+	}
+	recordAudit(ctx, s.Agents, database.AuditEntry{
+		Action:     "task.data.purge",
+		TargetType: "task",
+		TargetID:   strconv.FormatInt(req.GetTaskId(), 10),
+		Detail:     map[string]any{"reason": reason, "messages_purged": purged},
+	})
+	return &api.PurgeTaskMessagesResponse{TaskId: req.GetTaskId(), MessagesPurged: purged}, nil
+}
+
+// ListCases 列出全部案件
+func (s *ConsoleServer) ListCases(ctx context.Context, _ *api.ListCasesRequest) (*api.ListCasesResponse, error) {
+	list, err := s.Cases.ListCases(ctx)
+	if err != nil {
+		return nil, apperr.From(err)
+	}
+	resp := &api.ListCasesResponse{Cases: make([]*api.Case, 0, len(list))}
+	for _, c := range list {
+		resp.Cases = append(resp.Cases, toCase(c))
+	}
+	return resp, nil
+}
+
+// CreateCase 新建案件
+func (s *ConsoleServer) CreateCase(ctx context.Context, req *api.CreateCaseRequest) (*api.Case, error) {
+	name := req.GetName()
+	if name == "" || len(name) > 255 {
+		return nil, invalid("name must be 1-255 characters")
+	}
+	c, err := s.Cases.CreateCase(ctx, name)
+	if err != nil {
+		return nil, apperr.From(err)
+	}
+	recordAudit(ctx, s.Cases, database.AuditEntry{Action: "case.create", TargetType: "case", TargetID: strconv.FormatInt(c.ID, 10)})
+	return toCase(c), nil
+}
+
+// DestroyCaseKey 销毁案件数据密钥（加密擦除），该案件已采集的数据从此不可恢复
+func (s *ConsoleServer) DestroyCaseKey(ctx context.Context, req *api.DestroyCaseKeyRequest) (*emptypb.Empty, error) {
+	reason := req.GetReason()
+	if reason == "" || len(reason) > 1000 {
+		return nil, invalid("reason must be 1-1000 characters")
+	}
+	if req.GetConfirm() != req.GetCaseId() {
+		return nil, invalid("confirm must equal the case id")
+	}
+	if err := s.Cases.DestroyCaseKey(ctx, req.GetCaseId()); err != nil {
+		return nil, apperr.From(err)
+	}
+	recordAudit(ctx, s.Cases, database.AuditEntry{
+		Action:     "case.key.destroy",
+		TargetType: "case",
+		TargetID:   strconv.FormatInt(req.GetCaseId(), 10),
+		Detail:     map[string]any{"reason": reason},
+	})
+	return &emptypb.Empty{}, nil
+}
+
+// ListAuditLogs 由新到旧查询审计日志。page_token 为上一页最后一条记录的 id，
+// 因此翻页期间新写入的日志不会造成重复或遗漏
+func (s *ConsoleServer) ListAuditLogs(ctx context.Context, req *api.ListAuditLogsRequest) (*api.ListAuditLogsResponse, error) {
+	f := database.AuditFilter{
+		Actor:      req.GetActor(),
+		Action:     req.GetAction(),
+		TargetType: req.GetTargetType(),
+		TargetID:   req.GetTargetId(),
+		Limit:      int(req.GetPageSize()),
+	}
+	if f.Limit <= 0 || f.Limit > 500 {
+		f.Limit = 100
+	}
+	if req.GetPageToken() != "" {
+		id, err := strconv.ParseInt(req.GetPageToken(), 10, 64)
+		if err != nil || id <= 0 {
+			return nil, invalid("invalid page_token")
+		}
+		f.BeforeID = id
+	}
+	for _, ts := range []struct {
+		src *timestamppb.Timestamp
+		dst **time.Time
+	}{{req.Since, &f.Since}, {req.Until, &f.Until}} {
+		if ts.src == nil {
+			continue
+		}
+		if err := ts.src.CheckValid(); err != nil {
+			return nil, invalid("invalid time range")
+		}
+		t := ts.src.AsTime()
+		*ts.dst = &t
+	}
+	// 多取一条用于判断是否还有下一页
+	limit := f.Limit
+	f.Limit++
+	recs, err := s.Audit.ListAuditLogs(ctx, f)
+	if err != nil {
+		return nil, apperr.From(err)
+	}
+	resp := &api.ListAuditLogsResponse{}
+	if len(recs) > limit {
+		recs = recs[:limit]
+		resp.NextPageToken = strconv.FormatInt(recs[limit-1].ID, 10)
+	}
+	resp.Entries = make([]*api.AuditLog, 0, len(recs))
+	for _, rec := range recs {
+		entry := &api.AuditLog{
+			Id:         rec.ID,
+			Actor:      rec.Actor,
+			Action:     rec.Action,
+			TargetType: rec.TargetType,
+			TargetId:   rec.TargetID,
+			Outcome:    rec.Outcome,
+			IpAddress:  rec.IPAddress,
+			CreatedAt:  timestamppb.New(rec.CreatedAt),
+		}
+		if len(rec.Detail) > 0 {
+			detail, err := structpb.NewStruct(rec.Detail)
+			if err != nil {
+				return nil, apperr.Internal(err)
+			}
+			entry.Detail = detail
+		}
+		resp.Entries = append(resp.Entries, entry)
+	}
+	return resp, nil
+}
+
+func toCase(c database.Case) *api.Case {
+	out := &api.Case{Id: c.ID, Name: c.Name, Status: c.Status, CreatedAt: timestamppb.New(c.CreatedAt)}
+	if c.DefaultAgentID != nil {
+		id := int32(*c.DefaultAgentID)
+		out.DefaultAgentId = &id
+	}
+	if c.KeyDestroyedAt != nil {
+		out.KeyDestroyedAt = timestamppb.New(*c.KeyDestroyedAt)
+	}
+	return out
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+    "context"
+    "errors"
+    "strconv"
+
+    "guardian-backend/internal/auth"
+    "guardian-backend/internal/database"
+    "guardian-backend/internal/interceptor"
+    "guardian-backend/pkg/apperr"
+    "guardian-backend/pkg/logger"
+)
+
+// authorizeAgent 要求请求出示 agent 客户端证书且证书 CN 为该 agent 的身份（agent-<id>），
+// 并拒绝已退役或凭据已吊销的 agent；返回的 apperr 错误由 gRPC 转换为对应状态码
+func authorizeAgent(ctx context.Context, db *database.DB, agentID int) error {
+    fingerprint := interceptor.PeerCertFingerprint(ctx)
+    if fingerprint != "" && interceptor.PeerCertCommonName(ctx) != AgentCommonName(agentID) {
+        return agentAuthError(ctx, database.ErrCredentialMismatch)
+    }
+    return agentAuthError(ctx, db.AuthorizeAgentCredential(ctx, agentID, fingerprint))
+}
+
+// AgentCommonName 返回 agent 客户端证书应有的 Subject CN；签发证书时须与 agent_id 对应
+func AgentCommonName(agentID int) string {
+    return "agent-" + strconv.Itoa(agentID)
+}
+
+// authorizeUploader 确认上传者身份：经 mTLS 的请求按 agent 证书校验，返回空字符串；
+// 经 gRPC-Gateway 的请求没有客户端证书，须为已认证的 admin，返回其用户 ID 以便登记在批次上
+func authorizeUploader(ctx context.Context, db *database.DB, agentID int) (string, error) {
+    if interceptor.PeerCertFingerprint(ctx) != "" {
+        return "", authorizeAgent(ctx, db, agentID)
+    }
+    p := auth.PrincipalFrom(ctx)
+    if p.UserID == "" {
+        return "", apperr.From(database.ErrCredentialRequired)
+    }
+    if p.Role != "admin" {
+        return "", apperr.New(apperr.KindPermissionDenied, "", "insufficient role")
+    }
+    return p.UserID, agentAuthError(ctx, db.CheckAgentActive(ctx, agentID))
+}
+
+func agentAuthError(ctx context.Context, err error) error {
+    switch {
+    case err == nil:
+        return nil
+    case errors.Is(err, database.ErrAgentNotFound):
+        return apperr.From(err)
+    case errors.Is(err, database.ErrAgentRetired):
+        // 对 agent 而言退役即无权访问，而非控制台语义下的状态冲突
+        return apperr.Wrap(err, apperr.KindPermissionDenied, "AGENT_RETIRED", "agent is retired")
+    case errors.Is(err, database.ErrCredentialRequired):
+        logger.From(ctx).Warn("Rejected agent request without client certificate")
+        return apperr.From(err)
+    case errors.Is(err, database.ErrCredentialRevoked):
+        logger.From(ctx).Warn("Rejected revoked agent credential")
+        return apperr.From(err)
+    case errors.Is(err, database.ErrCredentialMismatch):
+        logger.From(ctx).Warn("Rejected agent credential bound to another agent")
+        return apperr.From(err)
+    case errors.Is(err, database.ErrCredentialAlreadyBound):
+        logger.From(ctx).Warn("Rejected new certificate for agent with an active credential")
+        return apperr.From(err)
+    default:
+        logger.From(ctx).Error("Failed to authorize agent", "error", err)
+        return apperr.Internal(err)
+    }
+}
@@ -2,29 +2,185 @@ package service
 
 import (
     "context"
-    "log/slog"
+    "errors"
+    "strconv"
 
     api "guardian-backend/pkg/grpc/api/guardian/pkg/grpc/api"
     "github.com/jackc/pgx/v5/pgxpool"
+    "guardian-backend/internal/auth"
     "guardian-backend/internal/database"
+    "guardian-backend/internal/envelope"
+    "guardian-backend/internal/ingest"
+    "guardian-backend/internal/integrity"
+    "guardian-backend/pkg/apperr"
+    "guardian-backend/pkg/logger"
+    "guardian-backend/pkg/metrics"
+    "go.opentelemetry.io/otel"
+    "go.opentelemetry.io/otel/attribute"
+    "go.opentelemetry.io/otel/trace"
 )
 
+// 仅用于指标的拒收原因；范围过滤的原因见 ingest.Reason*
+const (
+    reasonInvalidRequest   = "invalid_request"
+    reasonCaseKeyDestroyed = "case_key_destroyed"
+)
+
+// tracer 为上传处理中的各阶段（请求哈希、范围过滤）创建 span；加密与写库的 span 由 database 包创建
+var tracer = otel.Tracer("guardian-backend/internal/service")
+
 type DataServer struct {
 	api.UnimplementedDataServiceServer
     DB *pgxpool.Pool
+    // Filter 为入库前的范围过滤阶段；为 nil 时使用不含全局排除会话的默认过滤
+    Filter *ingest.Filter
+    // Cipher 用于按案件加密消息内容
+    Cipher *envelope.Cipher
 }
 
 func (s *DataServer) UploadMessages(ctx context.Context, req *api.UploadMessagesRequest) (*api.UploadMessagesResponse, error) {
     if s.DB == nil {
-		slog.Error("DB pool is nil")
-		return &api.UploadMessagesResponse{Success: false}, nil
+		logger.From(ctx).Error("DB pool is nil")
+		return nil, apperr.New(apperr.KindUnavailable, "", "")
 	}
-    // TODO: 这里应通过数据库接口层保存消息；当前以日志代替，避免直接在 *pgxpool.Pool 上调用未定义方法
-    err := (&database.DB{Pool: s.DB}).SaveMessages(ctx, int(req.AgentId), req.Messages)
-	if err != nil {
-		slog.Error("Failed to save messages", "error", err, "agent_id", req.AgentId)
-		return &api.UploadMessagesResponse{Success: false}, err
+    db := &database.DB{Pool: s.DB, Cipher: s.Cipher}
+    agentID := int(req.AgentId)
+    uploadedBy, err := authorizeUploader(ctx, db, agentID)
+    if err != nil {
+        return nil, err
+    }
+
+    // 收到即计算请求哈希，作为回执返回给 agent
+    _, span := tracer.Start(ctx, "ingest.request_hash")
+    requestHash, err := integrity.RequestHash(req)
+    span.End()
+    if err != nil {
+        metrics.IngestBatch(metrics.BatchRejected, len(req.Messages), 0, map[string]int{reasonInvalidRequest: len(req.Messages)})
+        return nil, apperr.New(apperr.KindInvalidArgument, "", "invalid request")
+    }
+
+    // 重放的批次（agent 重试或重复上传同一请求）直接返回原回执，不再入库；任务结束后的重试同样适用
+    if resp, err := duplicateReceipt(ctx, db, agentID, len(req.Messages), requestHash); resp != nil || err != nil {
+        return resp, err
+    }
+
+    // 过滤越界消息：只保留落在当前授权任务范围内的消息
+    scope, err := db.GetActiveTaskScope(ctx, agentID)
+    if err != nil {
+		logger.From(ctx).Error("Failed to load task scope", "error", err)
+		return nil, apperr.Internal(err)
 	}
-	slog.Info("Saved messages", "count", len(req.Messages), "agent_id", req.AgentId)
-	return &api.UploadMessagesResponse{Success: true}, nil
+    // 消息必须注明采集任务，且该任务须为 agent 当前执行中的任务
+    taskID, err := strconv.ParseInt(req.TaskId, 10, 64)
+    if err != nil || taskID <= 0 {
+        metrics.IngestBatch(metrics.BatchRejected, len(req.Messages), 0, map[string]int{reasonInvalidRequest: len(req.Messages)})
+        return nil, apperr.New(apperr.KindInvalidArgument, "", "task_id is required")
+    }
+    if scope == nil || scope.TaskID != taskID {
+        counts := map[string]int{ingest.ReasonTaskNotRunning: len(req.Messages)}
+        metrics.IngestBatch(metrics.BatchRejected, len(req.Messages), 0, counts)
+        if err := db.RecordIngestionDiscards(ctx, agentID, 0, counts); err != nil {
+            logger.From(ctx).Error("Failed to record ingestion discards", "error", err)
+        }
+        logger.From(ctx).Warn("Rejected upload for task that is not running", "task_id", taskID, "count", len(req.Messages))
+        return nil, apperr.New(apperr.KindFailedPrecondition, "TASK_NOT_RUNNING", "task is not running").WithDetail("task_id", req.TaskId)
+    }
+    filter := s.Filter
+    if filter == nil {
+        filter = &ingest.Filter{}
+    }
+    _, span = tracer.Start(ctx, "ingest.filter", trace.WithAttributes(attribute.Int("message_count", len(req.Messages))))
+    res := filter.Apply(scope, req.Messages)
+    batch := database.IngestionBatch{
+        AgentID:       agentID,
+        ReceivedCount: len(req.Messages),
+        RequestSHA256: requestHash,
+        ContentSHA256: integrity.ContentHash(res.Kept),
+        TaskID:        scope.TaskID,
+        CaseID:        scope.CaseID,
+        UploadedBy:    uploadedBy,
+    }
+    span.SetAttributes(attribute.Int("accepted_count", len(res.Kept)), attribute.Int("discarded_count", res.DiscardedTotal()))
+    span.End()
+    if discarded := res.DiscardedTotal(); discarded > 0 {
+        // 仅记录聚合计数，被丢弃的消息内容不落库也不写日志
+        if err := db.RecordIngestionDiscards(ctx, agentID, batch.TaskID, res.Discarded); err != nil {
+            logger.From(ctx).Error("Failed to record ingestion discards", "error", err)
+        }
+        logger.From(ctx).Warn("Discarded out-of-scope messages", "count", discarded, "task_id", batch.TaskID)
+    }
+
+    // 每个批次都登记回执，包括消息全部被过滤的批次
+    batchID, err := db.SaveMessages(ctx, batch, res.Kept)
+    if errors.Is(err, database.ErrCaseKeyDestroyed) {
+        rejected := map[string]int{reasonCaseKeyDestroyed: len(res.Kept)}
+        for reason, n := range res.Discarded {
+            rejected[reason] += n
+        }
+        metrics.IngestBatch(metrics.BatchRejected, len(req.Messages), 0, rejected)
+        logger.From(ctx).Warn("Rejected upload for case with destroyed key", "case_id", batch.CaseID)
+        return nil, apperr.From(err)
+    }
+    if errors.Is(err, database.ErrDuplicateBatch) {
+        // 并发重试：另一请求已先登记了同一批次
+        resp, err := duplicateReceipt(ctx, db, agentID, len(req.Messages), requestHash)
+        if resp == nil && err == nil {
+            err = apperr.Internal(errors.New("duplicate batch not found"))
+        }
+        return resp, err
+    }
+    if err != nil {
+        metrics.IngestBatch(metrics.BatchFailed, len(req.Messages), 0, nil)
+        logger.From(ctx).Error("Failed to save messages", "error", err)
+        return nil, apperr.Internal(err)
+    }
+    metrics.IngestBatch(metrics.BatchSaved, len(req.Messages), len(res.Kept), res.Discarded)
+	logger.From(ctx).Info("Saved messages", "count", len(res.Kept), "batch_id", batchID)
+    if uploadedBy != "" {
+        // 经 REST 上传的消息并非 agent 采集，逐批审计
+        if err := db.RecordAudit(ctx, database.AuditEntry{
+            Actor:      uploadedBy,
+            Action:     "messages.upload",
+            TargetType: "agent",
+            TargetID:   strconv.Itoa(agentID),
+            Outcome:    database.AuditOutcomeSuccess,
+            IPAddress:  auth.ClientIPFrom(ctx),
+            Detail: map[string]any{
+                "batch_id":       batchID,
+                "task_id":        batch.TaskID,
+                "accepted_count": len(res.Kept),
+                "request_sha256": requestHash,
+            },
+        }); err != nil {
+            logger.From(ctx).Error("Failed to record audit log", "error", err, "action", "messages.upload")
+        }
+    }
+	return &api.UploadMessagesResponse{
+        Success:        true,
+        AcceptedCount:  int32(len(res.Kept)),
+        DiscardedCount: int32(res.DiscardedTotal()),
+        BatchId:        batchID,
+        RequestSha256:  requestHash,
+    }, nil
+}
+
+// duplicateReceipt 在该 agent 已登记过相同请求哈希的批次时返回原回执并计入去重指标；未登记时返回 nil
+func duplicateReceipt(ctx context.Context, db *database.DB, agentID, received int, requestHash string) (*api.UploadMessagesResponse, error) {
+    receipt, err := db.FindBatchByRequest(ctx, agentID, requestHash)
+    if err != nil {
+        logger.From(ctx).Error("Failed to look up batch receipt", "error", err)
+        return nil, apperr.Internal(err)
+    }
+    if receipt == nil {
+        return nil, nil
+    }
+    metrics.IngestBatch(metrics.BatchDuplicate, received, 0, nil)
+    logger.From(ctx).Info("Ignored replayed batch", "batch_id", receipt.BatchID, "count", received)
+    return &api.UploadMessagesResponse{
+        Success:        true,
+        AcceptedCount:  int32(receipt.AcceptedCount),
+        DiscardedCount: int32(receipt.ReceivedCount - receipt.AcceptedCount),
+        BatchId:        receipt.BatchID,
+        RequestSha256:  requestHash,
+    }, nil
 }
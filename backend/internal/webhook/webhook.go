@@ -0,0 +1,235 @@
+// Package webhook 把 event_outbox 中的事件投递到订阅方。
+// 请求体以订阅密钥做 HMAC-SHA256 签名，失败时按指数退避重试，每次尝试都记入投递日志。
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"guardian-backend/internal/database"
+)
+
+// 投递请求头
+const (
+	HeaderEvent     = "X-Guardian-Event"
+	HeaderDelivery  = "X-Guardian-Delivery"
+	HeaderTimestamp = "X-Guardian-Timestamp"
+	// HeaderSignature 为 "sha256=" 加 HMAC-SHA256(secret, timestamp + "." + body) 的十六进制
+	HeaderSignature = "X-Guardian-Signature"
+)
+
+// Sign 计算签名头的值；时间戳参与签名，接收方可据此拒绝重放
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify 校验签名头，供接收方参考实现与测试使用
+func Verify(secret string, timestamp int64, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
+
+// Event 是投递请求体；同一事件重试时 id 不变，接收方应据此去重
+type Event struct {
+	ID        int64           `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// Store 是投递器所需的数据库操作
+type Store interface {
+	FanOutEvents(ctx context.Context, limit int) (int64, error)
+	ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]database.WebhookDelivery, error)
+	RecordWebhookAttempt(ctx context.Context, deliveryID int64, a database.WebhookAttempt, status string, nextAttemptAt time.Time) error
+}
+
+// Dispatcher 周期性扇出新事件并投递到期的记录；多实例可同时运行
+type Dispatcher struct {
+	Store  Store
+	Client *http.Client
+	// MaxAttempts 为单个投递的最大尝试次数，用尽后置为 failed
+	MaxAttempts int
+	// BaseBackoff 为首次重试的等待时间，之后每次翻倍，最长 MaxBackoff
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	// BatchSize 为每轮扇出的事件数与投递数上限
+	BatchSize int
+	// Now 便于测试替换时钟
+	Now func() time.Time
+	// OnRun 非空时在每轮结束后以该轮的结果调用，用于就绪检查报告投递器状态
+	OnRun func(err error)
+}
+
+// 未配置时的默认值
+const (
+	defaultMaxAttempts = 8
+	defaultBaseBackoff = 10 * time.Second
+	defaultMaxBackoff  = time.Hour
+	defaultBatchSize   = 100
+	defaultTimeout     = 10 * time.Second
+	// maxResponseLog 为记录到投递日志中的响应体前缀长度
+	maxResponseLog = 512
+)
+
+// Run 每隔 interval 执行一轮，直到 ctx 结束
+func (d *Dispatcher) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		err := d.RunOnce(ctx)
+		if err != nil {
+			slog.Error("webhook dispatch failed", "error", err)
+		}
+		if d.OnRun != nil {
+			d.OnRun(err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce 扇出新事件后投递至多 BatchSize 条到期记录，返回第一个数据库错误；单次投递失败只记入日志。
+// 投递逐条进行，因此每次只领取一条：租期从领取时起算、只覆盖这一次请求，
+// 不会因同批前面的请求耗时而在投递途中过期，被其他实例重复投递。
+func (d *Dispatcher) RunOnce(ctx context.Context) error {
+	if _, err := d.Store.FanOutEvents(ctx, d.batchSize()); err != nil {
+		return fmt.Errorf("fan out events: %w", err)
+	}
+	for range d.batchSize() {
+		claimed, err := d.Store.ClaimWebhookDeliveries(ctx, 1, d.lease())
+		if err != nil {
+			return fmt.Errorf("claim deliveries: %w", err)
+		}
+		if len(claimed) == 0 {
+			return nil
+		}
+		dl := claimed[0]
+		attempt := d.deliver(ctx, dl)
+		status, next := d.outcome(dl, attempt)
+		if err := d.Store.RecordWebhookAttempt(ctx, dl.ID, attempt, status, next); err != nil {
+			return fmt.Errorf("record delivery attempt: %w", err)
+		}
+		if status == database.DeliveryFailed {
+			slog.Warn("webhook delivery gave up", "delivery_id", dl.ID, "subscription_id", dl.SubscriptionID, "event_type", dl.EventType, "attempts", dl.Attempts)
+		}
+	}
+	return nil
+}
+
+// deliver 发送一次签名请求；2xx 视为成功
+func (d *Dispatcher) deliver(ctx context.Context, dl database.WebhookDelivery) database.WebhookAttempt {
+	now := d.now()
+	attempt := database.WebhookAttempt{AttemptedAt: now}
+	body, err := json.Marshal(Event{ID: dl.EventID, Type: dl.EventType, CreatedAt: dl.EventCreatedAt.UTC(), Data: dl.Payload})
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, dl.URL, bytes.NewReader(body))
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	ts := now.Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Guardian-Webhook/1")
+	req.Header.Set(HeaderEvent, dl.EventType)
+	req.Header.Set(HeaderDelivery, strconv.FormatInt(dl.ID, 10))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(ts, 10))
+	req.Header.Set(HeaderSignature, Sign(dl.Secret, ts, body))
+
+	start := time.Now()
+	resp, err := d.client().Do(req)
+	attempt.Duration = time.Since(start)
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	defer resp.Body.Close()
+	attempt.ResponseStatus = resp.StatusCode
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseLog))
+		attempt.Error = fmt.Sprintf("unexpected status %d: %s", resp.StatusCode, bytes.TrimSpace(snippet))
+	}
+	return attempt
+}
+
+// outcome 根据本次结果决定投递状态与下次重试时间
+func (d *Dispatcher) outcome(dl database.WebhookDelivery, a database.WebhookAttempt) (string, time.Time) {
+	if a.Error == "" {
+		return database.DeliverySucceeded, a.AttemptedAt
+	}
+	if dl.Attempts >= d.maxAttempts() {
+		return database.DeliveryFailed, a.AttemptedAt
+	}
+	return database.DeliveryPending, a.AttemptedAt.Add(d.Backoff(dl.Attempts))
+}
+
+// Backoff 返回第 attempt 次失败后的等待时间
+func (d *Dispatcher) Backoff(attempt int) time.Duration {
+	base, max := d.BaseBackoff, d.MaxBackoff
+	if base <= 0 {
+		base = defaultBaseBackoff
+	}
+	if max <= 0 {
+		max = defaultMaxBackoff
+	}
+	wait := base
+	for i := 1; i < attempt && wait < max; i++ {
+		wait *= 2
+	}
+	if wait > max {
+		wait = max
+	}
+	return wait
+}
+
+// lease 为单条投递的租期，须长于单次请求超时，避免请求尚未结束就被其他实例重新领取
+func (d *Dispatcher) lease() time.Duration {
+	return 2*d.client().Timeout + time.Minute
+}
+
+func (d *Dispatcher) client() *http.Client {
+	if d.Client == nil {
+		d.Client = &http.Client{Timeout: defaultTimeout}
+	}
+	return d.Client
+}
+
+func (d *Dispatcher) maxAttempts() int {
+	if d.MaxAttempts <= 0 {
+		return defaultMaxAttempts
+	}
+	return d.MaxAttempts
+}
+
+func (d *Dispatcher) batchSize() int {
+	if d.BatchSize <= 0 {
+		return defaultBatchSize
+	}
+	return d.BatchSize
+}
+
+func (d *Dispatcher) now() time.Time {
+	if d.Now != nil {
+		return d.Now()
+	}
+	return time.Now()
+}
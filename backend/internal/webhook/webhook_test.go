@@ -0,0 +1,156 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"guardian-backend/internal/database"
+)
+
+type recordedAttempt struct {
+	deliveryID int64
+	attempt    database.WebhookAttempt
+	status     string
+	next       time.Time
+}
+
+type fakeStore struct {
+	due      []database.WebhookDelivery
+	fanOuts  int
+	claims   []int
+	recorded []recordedAttempt
+}
+
+func (f *fakeStore) FanOutEvents(ctx context.Context, limit int) (int64, error) {
+	f.fanOuts++
+	return 0, nil
+}
+
+func (f *fakeStore) ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]database.WebhookDelivery, error) {
+	f.claims = append(f.claims, limit)
+	n := min(limit, len(f.due))
+	claimed := f.due[:n]
+	f.due = f.due[n:]
+	return claimed, nil
+}
+
+func (f *fakeStore) RecordWebhookAttempt(ctx context.Context, deliveryID int64, a database.WebhookAttempt, status string, next time.Time) error {
+	f.recorded = append(f.recorded, recordedAttempt{deliveryID, a, status, next})
+	return nil
+}
+
+func TestSignVerify(t *testing.T) {
+	body := []byte(`{"id":1}`)
+	sig := Sign("s3cret", 1700000000, body)
+	assert.True(t, Verify("s3cret", 1700000000, body, sig))
+	assert.False(t, Verify("other", 1700000000, body, sig))
+	assert.False(t, Verify("s3cret", 1700000001, body, sig))
+	assert.False(t, Verify("s3cret", 1700000000, []byte(`{"id":2}`), sig))
+}
+
+func TestDispatcher_DeliversSignedEvent(t *testing.T) {
+	var got *http.Request
+	var gotBody []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		gotBody, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	now := time.Unix(1700000000, 0)
+	store := &fakeStore{due: []database.WebhookDelivery{{
+		ID: 7, SubscriptionID: 1, EventID: 42, EventType: database.EventTaskCompleted,
+		Payload: json.RawMessage(`{"task_id":5}`), EventCreatedAt: now.Add(-time.Minute),
+		URL: srv.URL, Secret: "s3cret", Attempts: 1,
+	}}}
+	d := &Dispatcher{Store: store, Now: func() time.Time { return now }}
+	require.NoError(t, d.RunOnce(context.Background()))
+
+	assert.Equal(t, 1, store.fanOuts)
+	require.Len(t, store.recorded, 1)
+	assert.Equal(t, database.DeliverySucceeded, store.recorded[0].status)
+	assert.Equal(t, http.StatusNoContent, store.recorded[0].attempt.ResponseStatus)
+
+	require.NotNil(t, got)
+	assert.Equal(t, database.EventTaskCompleted, got.Header.Get(HeaderEvent))
+	assert.Equal(t, "7", got.Header.Get(HeaderDelivery))
+	ts, err := strconv.ParseInt(got.Header.Get(HeaderTimestamp), 10, 64)
+	require.NoError(t, err)
+	assert.True(t, Verify("s3cret", ts, gotBody, got.Header.Get(HeaderSignature)))
+	var ev Event
+	require.NoError(t, json.Unmarshal(gotBody, &ev))
+	assert.Equal(t, int64(42), ev.ID)
+	assert.JSONEq(t, `{"task_id":5}`, string(ev.Data))
+}
+
+func TestDispatcher_RetriesWithBackoffThenGivesUp(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	now := time.Unix(1700000000, 0)
+	d := &Dispatcher{MaxAttempts: 3, BaseBackoff: time.Second, Now: func() time.Time { return now }}
+	delivery := database.WebhookDelivery{ID: 1, EventID: 1, EventType: database.EventAgentOffline, Payload: json.RawMessage(`{}`), URL: srv.URL, Secret: "x"}
+
+	for attempt, want := range map[int]string{1: database.DeliveryPending, 2: database.DeliveryPending, 3: database.DeliveryFailed} {
+		store := &fakeStore{}
+		d.Store = store
+		delivery.Attempts = attempt
+		store.due = []database.WebhookDelivery{delivery}
+		require.NoError(t, d.RunOnce(context.Background()))
+		require.Len(t, store.recorded, 1)
+		rec := store.recorded[0]
+		assert.Equal(t, want, rec.status, "attempt %d", attempt)
+		assert.Equal(t, http.StatusServiceUnavailable, rec.attempt.ResponseStatus)
+		assert.Contains(t, rec.attempt.Error, "unavailable")
+		if want == database.DeliveryPending {
+			assert.Equal(t, now.Add(d.Backoff(attempt)), rec.next)
+		}
+	}
+}
+
+func TestDispatcher_Backoff(t *testing.T) {
+	d := &Dispatcher{BaseBackoff: 10 * time.Second, MaxBackoff: time.Minute}
+	assert.Equal(t, 10*time.Second, d.Backoff(1))
+	assert.Equal(t, 20*time.Second, d.Backoff(2))
+	assert.Equal(t, 40*time.Second, d.Backoff(3))
+	assert.Equal(t, time.Minute, d.Backoff(4))
+	assert.Equal(t, time.Minute, d.Backoff(20))
+}
+
+func TestDispatcher_ClaimsOneDeliveryAtATime(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	store := &fakeStore{}
+	for id := int64(1); id <= 3; id++ {
+		store.due = append(store.due, database.WebhookDelivery{ID: id, EventID: id, EventType: database.EventTaskCompleted, Payload: json.RawMessage(`{}`), URL: srv.URL, Secret: "x", Attempts: 1})
+	}
+	d := &Dispatcher{Store: store, BatchSize: 2}
+
+	// 每条投递单独领取，租期只需覆盖一次请求；每轮至多投递 BatchSize 条
+	require.NoError(t, d.RunOnce(context.Background()))
+	assert.Equal(t, []int{1, 1}, store.claims)
+	require.Len(t, store.recorded, 2)
+	assert.Equal(t, int64(1), store.recorded[0].deliveryID)
+	assert.Equal(t, int64(2), store.recorded[1].deliveryID)
+
+	// 没有到期记录时本轮立即结束
+	store.claims = nil
+	require.NoError(t, d.RunOnce(context.Background()))
+	assert.Equal(t, []int{1, 1}, store.claims)
+	require.Len(t, store.recorded, 3)
+	assert.Equal(t, int64(3), store.recorded[2].deliveryID)
+}
@@ -0,0 +1,196 @@
+// Package apperr 定义跨 HTTP 与 gRPC 的统一错误模型。
+//
+// 领域错误携带分类（Kind）、稳定的机器可读错误码与可以安全返回给客户端的消息；
+// 底层原因（如 SQL 错误）只用于服务端日志，不会出现在任何响应中。
+// HTTP 侧由 httpx.WriteAppError 输出 ErrorResponse，gRPC 侧通过 GRPCStatus 自动转换为带 ErrorInfo 详情的状态。
+package apperr
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Domain 为 gRPC ErrorInfo 详情中的错误域
+const Domain = "guardian"
+
+// Kind 是错误分类，决定 HTTP 状态码与 gRPC 状态码
+type Kind uint8
+
+const (
+	KindInternal Kind = iota
+	KindInvalidArgument
+	KindUnauthenticated
+	KindPermissionDenied
+	KindNotFound
+	KindAlreadyExists
+	// KindFailedPrecondition 表示资源当前状态不允许该操作，如密钥已销毁、agent 已退役
+	KindFailedPrecondition
+	KindRateLimited
+	KindUnavailable
+	KindDeadlineExceeded
+	KindCanceled
+	KindUnimplemented
+)
+
+type kindInfo struct {
+	httpStatus int
+	grpcCode   codes.Code
+	code       string
+	message    string
+}
+
+// statusClientClosedRequest 是客户端在响应前断开时使用的非标准状态码（同 nginx）
+const statusClientClosedRequest = 499
+
+var kinds = map[Kind]kindInfo{
+	KindInternal:           {http.StatusInternalServerError, codes.Internal, "INTERNAL_ERROR", "internal error"},
+	KindInvalidArgument:    {http.StatusBadRequest, codes.InvalidArgument, "BAD_REQUEST", "invalid request"},
+	KindUnauthenticated:    {http.StatusUnauthorized, codes.Unauthenticated, "UNAUTHORIZED", "unauthenticated"},
+	KindPermissionDenied:   {http.StatusForbidden, codes.PermissionDenied, "FORBIDDEN", "permission denied"},
+	KindNotFound:           {http.StatusNotFound, codes.NotFound, "NOT_FOUND", "not found"},
+	KindAlreadyExists:      {http.StatusConflict, codes.AlreadyExists, "ALREADY_EXISTS", "already exists"},
+	KindFailedPrecondition: {http.StatusConflict, codes.FailedPrecondition, "FAILED_PRECONDITION", "failed precondition"},
+	KindRateLimited:        {http.StatusTooManyRequests, codes.ResourceExhausted, "RATE_LIMITED", "rate limit exceeded"},
+	KindUnavailable:        {http.StatusServiceUnavailable, codes.Unavailable, "UNAVAILABLE", "service unavailable"},
+	KindDeadlineExceeded:   {http.StatusGatewayTimeout, codes.DeadlineExceeded, "TIMEOUT", "deadline exceeded"},
+	KindCanceled:           {statusClientClosedRequest, codes.Canceled, "CANCELED", "request canceled"},
+	KindUnimplemented:      {http.StatusNotImplemented, codes.Unimplemented, "UNIMPLEMENTED", "not implemented"},
+}
+
+// Error 是带分类的领域错误
+type Error struct {
+	Kind Kind
+	// Code 为稳定的机器可读错误码，如 CASE_KEY_DESTROYED；为空时使用分类的默认码
+	Code string
+	// Message 会原样返回给客户端，不得包含内部细节
+	Message string
+	// Details 为附加的键值信息，HTTP 响应中为 details，gRPC 中为 ErrorInfo.metadata
+	Details map[string]string
+	cause   error
+}
+
+// New 创建领域错误
+func New(kind Kind, code, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message}
+}
+
+// Wrap 创建携带底层原因的领域错误；原因只出现在 Error() 与服务端日志中
+func Wrap(err error, kind Kind, code, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message, cause: err}
+}
+
+// Internal 把未预期的错误包装为内部错误，客户端只会看到 "internal error"
+func Internal(err error) *Error {
+	return Wrap(err, KindInternal, "", "")
+}
+
+func (e *Error) Error() string {
+	if e.cause != nil {
+		return e.PublicMessage() + ": " + e.cause.Error()
+	}
+	return e.PublicMessage()
+}
+
+func (e *Error) Unwrap() error { return e.cause }
+
+// WithDetail 返回附加了一项详情的副本，哨兵错误本身不被修改
+func (e *Error) WithDetail(key, value string) *Error {
+	c := *e
+	c.Details = make(map[string]string, len(e.Details)+1)
+	for k, v := range e.Details {
+		c.Details[k] = v
+	}
+	c.Details[key] = value
+	return &c
+}
+
+// PublicCode 返回对外的错误码
+func (e *Error) PublicCode() string {
+	if e.Code != "" {
+		return e.Code
+	}
+	return kinds[e.Kind].code
+}
+
+// PublicMessage 返回对外的错误消息
+func (e *Error) PublicMessage() string {
+	if e.Message != "" {
+		return e.Message
+	}
+	return kinds[e.Kind].message
+}
+
+// HTTPStatus 返回对应的 HTTP 状态码
+func (e *Error) HTTPStatus() int {
+	return kinds[e.Kind].httpStatus
+}
+
+// GRPCStatus 返回对应的 gRPC 状态，附带 ErrorInfo 详情；grpc-go 据此转换处理函数返回的错误
+func (e *Error) GRPCStatus() *status.Status {
+	st := status.New(kinds[e.Kind].grpcCode, e.PublicMessage())
+	withInfo, err := st.WithDetails(&errdetails.ErrorInfo{Reason: e.PublicCode(), Domain: Domain, Metadata: e.Details})
+	if err != nil {
+		return st
+	}
+	return withInfo
+}
+
+// From 把任意错误归一为领域错误：已分类的错误原样返回，gRPC 状态错误按状态码归类，
+// 上下文超时与取消分别归类，其余一律视为内部错误
+func From(err error) *Error {
+	if err == nil {
+		return nil
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	if s, ok := err.(interface{ GRPCStatus() *status.Status }); ok {
+		return FromStatus(s.GRPCStatus())
+	}
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return Wrap(err, KindDeadlineExceeded, "", "")
+	case errors.Is(err, context.Canceled):
+		return Wrap(err, KindCanceled, "", "")
+	}
+	return Internal(err)
+}
+
+// FromStatus 把 gRPC 状态还原为领域错误，错误码与详情取自 ErrorInfo；
+// 用于 gRPC-Gateway 等以状态传递错误的场景。内部错误不保留状态中的消息
+func FromStatus(st *status.Status) *Error {
+	kind := KindInternal
+	for k, info := range kinds {
+		if info.grpcCode == st.Code() {
+			kind = k
+			break
+		}
+	}
+	e := &Error{Kind: kind, cause: st.Err()}
+	if kind == KindInternal {
+		return e
+	}
+	e.Message = st.Message()
+	for _, d := range st.Details() {
+		if info, ok := d.(*errdetails.ErrorInfo); ok && info.Domain == Domain {
+			e.Code = info.Reason
+			e.Details = info.Metadata
+			break
+		}
+	}
+	return e
+}
+
+// KindOf 返回错误的分类
+func KindOf(err error) Kind {
+	if err == nil {
+		return KindInternal
+	}
+	return From(err).Kind
+}
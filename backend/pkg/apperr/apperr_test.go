@@ -0,0 +1,95 @@
+package apperr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestFrom(t *testing.T) {
+	sentinel := New(KindFailedPrecondition, "CASE_KEY_DESTROYED", "case key destroyed")
+	assert.Same(t, sentinel, From(fmt.Errorf("save messages: %w", sentinel)))
+	assert.Equal(t, KindDeadlineExceeded, KindOf(fmt.Errorf("query: %w", context.DeadlineExceeded)))
+	assert.Equal(t, KindCanceled, KindOf(context.Canceled))
+	assert.Nil(t, From(nil))
+
+	// 未分类错误视为内部错误，原因只保留在 Error() 中
+	e := From(errors.New(`ERROR: relation "agents" does not exist (SQLSTATE 42P01)`))
+	assert.Equal(t, KindInternal, e.Kind)
+	assert.Equal(t, http.StatusInternalServerError, e.HTTPStatus())
+	assert.Equal(t, "INTERNAL_ERROR", e.PublicCode())
+	assert.Equal(t, "internal error", e.PublicMessage())
+	assert.Contains(t, e.Error(), "SQLSTATE")
+}
+
+func TestGRPCStatus(t *testing.T) {
+	sentinel := New(KindFailedPrecondition, "TASK_NOT_RUNNING", "task is not running")
+	err := sentinel.WithDetail("task_id", "42")
+	assert.Nil(t, sentinel.Details, "WithDetail must not modify the sentinel")
+
+	st := status.Convert(err)
+	assert.Equal(t, codes.FailedPrecondition, st.Code())
+	assert.Equal(t, "task is not running", st.Message())
+	require.Len(t, st.Details(), 1)
+	info, ok := st.Details()[0].(*errdetails.ErrorInfo)
+	require.True(t, ok)
+	assert.Equal(t, "TASK_NOT_RUNNING", info.Reason)
+	assert.Equal(t, Domain, info.Domain)
+	assert.Equal(t, map[string]string{"task_id": "42"}, info.Metadata)
+
+	internal := status.Convert(Internal(errors.New("password=hunter2")))
+	assert.Equal(t, codes.Internal, internal.Code())
+	assert.Equal(t, "internal error", internal.Message())
+}
+
+func TestKindMapping(t *testing.T) {
+	for kind, want := range map[Kind]struct {
+		http int
+		grpc codes.Code
+	}{
+		KindInvalidArgument:    {http.StatusBadRequest, codes.InvalidArgument},
+		KindUnauthenticated:    {http.StatusUnauthorized, codes.Unauthenticated},
+		KindPermissionDenied:   {http.StatusForbidden, codes.PermissionDenied},
+		KindNotFound:           {http.StatusNotFound, codes.NotFound},
+		KindAlreadyExists:      {http.StatusConflict, codes.AlreadyExists},
+		KindFailedPrecondition: {http.StatusConflict, codes.FailedPrecondition},
+		KindRateLimited:        {http.StatusTooManyRequests, codes.ResourceExhausted},
+		KindUnavailable:        {http.StatusServiceUnavailable, codes.Unavailable},
+		KindUnimplemented:      {http.StatusNotImplemented, codes.Unimplemented},
+	} {
+		e := New(kind, "", "")
+		assert.Equal(t, want.http, e.HTTPStatus(), e.PublicCode())
+		assert.Equal(t, want.grpc, status.Code(e), e.PublicCode())
+	}
+}
+
+func TestFromStatus(t *testing.T) {
+	// 经 gRPC 传递后还原，错误码与详情保持不变
+	orig := New(KindFailedPrecondition, "TASK_NOT_RUNNING", "task is not running").WithDetail("task_id", "42")
+	e := From(orig.GRPCStatus().Err())
+	assert.Equal(t, KindFailedPrecondition, e.Kind)
+	assert.Equal(t, "TASK_NOT_RUNNING", e.PublicCode())
+	assert.Equal(t, "task is not running", e.PublicMessage())
+	assert.Equal(t, map[string]string{"task_id": "42"}, e.Details)
+
+	// 不带 ErrorInfo 的状态使用分类默认码
+	e = From(status.Error(codes.InvalidArgument, "invalid character in request body"))
+	assert.Equal(t, http.StatusBadRequest, e.HTTPStatus())
+	assert.Equal(t, "BAD_REQUEST", e.PublicCode())
+	assert.Equal(t, "invalid character in request body", e.PublicMessage())
+
+	// 内部错误与未知状态码不透传消息
+	for _, c := range []codes.Code{codes.Internal, codes.Unknown, codes.DataLoss} {
+		e = From(status.Error(c, "pq: relation does not exist"))
+		assert.Equal(t, KindInternal, e.Kind)
+		assert.Equal(t, "internal error", e.PublicMessage())
+	}
+}
@@ -0,0 +1,134 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// tokenResponse 是 /login、/login/mfa 与 /token/refresh 签发令牌时的响应
+type tokenResponse struct {
+	Token         string   `json:"token"`
+	RefreshToken  string   `json:"refresh_token"`
+	ExpiresIn     int64    `json:"expires_in"`
+	RecoveryCodes []string `json:"recovery_codes,omitempty"`
+
+	// 以下字段仅出现在需要二次验证的 /login 响应中
+	MFARequired           bool   `json:"mfa_required"`
+	MFAEnrollmentRequired bool   `json:"mfa_enrollment_required"`
+	MFAToken              string `json:"mfa_token"`
+}
+
+func (r tokenResponse) tokens() Tokens {
+	return Tokens{AccessToken: r.Token, RefreshToken: r.RefreshToken, ExpiresAt: time.Now().Add(time.Duration(r.ExpiresIn) * time.Second)}
+}
+
+// LoginResult 是口令登录的结果。MFARequired 为 true 时尚未取得令牌，
+// 须以 MFAToken 调用 LoginMFA（未绑定时先调用 EnrollMFA）完成第二步
+type LoginResult struct {
+	MFARequired           bool
+	MFAEnrollmentRequired bool
+	MFAToken              string
+}
+
+// MFAEnrollment 是待绑定的 TOTP 密钥
+type MFAEnrollment struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+// Login 以用户名与口令登录；无需二次验证时直接保存令牌
+func (c *Client) Login(ctx context.Context, username, password string) (LoginResult, error) {
+	var resp tokenResponse
+	in := map[string]string{"username": username, "password": password}
+	if err := c.doJSON(ctx, request{method: http.MethodPost, path: "/login", anonymous: true}, in, &resp); err != nil {
+		return LoginResult{}, err
+	}
+	if resp.MFARequired {
+		return LoginResult{MFARequired: true, MFAEnrollmentRequired: resp.MFAEnrollmentRequired, MFAToken: resp.MFAToken}, nil
+	}
+	c.setTokens(resp.tokens())
+	return LoginResult{}, nil
+}
+
+// EnrollMFA 为待绑定用户生成 TOTP 密钥；以其生成的首个验证码调用 LoginMFA 即完成绑定
+func (c *Client) EnrollMFA(ctx context.Context, mfaToken string) (MFAEnrollment, error) {
+	var out MFAEnrollment
+	err := c.doJSON(ctx, request{method: http.MethodPost, path: "/login/mfa/enroll", anonymous: true}, map[string]string{"mfa_token": mfaToken}, &out)
+	return out, err
+}
+
+// LoginMFA 以 TOTP 验证码完成登录并保存令牌；首次绑定时返回一次性的恢复码
+func (c *Client) LoginMFA(ctx context.Context, mfaToken, code string) ([]string, error) {
+	return c.loginMFA(ctx, map[string]string{"mfa_token": mfaToken, "code": code})
+}
+
+// LoginRecoveryCode 以恢复码完成登录并保存令牌
+func (c *Client) LoginRecoveryCode(ctx context.Context, mfaToken, recoveryCode string) error {
+	_, err := c.loginMFA(ctx, map[string]string{"mfa_token": mfaToken, "recovery_code": recoveryCode})
+	return err
+}
+
+func (c *Client) loginMFA(ctx context.Context, in map[string]string) ([]string, error) {
+	var resp tokenResponse
+	if err := c.doJSON(ctx, request{method: http.MethodPost, path: "/login/mfa", anonymous: true}, in, &resp); err != nil {
+		return nil, err
+	}
+	c.setTokens(resp.tokens())
+	return resp.RecoveryCodes, nil
+}
+
+// Refresh 用刷新令牌换取新令牌；刷新令牌每次使用后轮换
+func (c *Client) Refresh(ctx context.Context) error {
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+	return c.refresh(ctx)
+}
+
+// refreshIfCurrent 在访问令牌仍为 stale 时刷新；并发请求中已有其他请求完成刷新时直接返回
+func (c *Client) refreshIfCurrent(ctx context.Context, stale string) error {
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+	if c.Tokens().AccessToken != stale {
+		return nil
+	}
+	return c.refresh(ctx)
+}
+
+func (c *Client) refresh(ctx context.Context) error {
+	refresh := c.Tokens().RefreshToken
+	if refresh == "" {
+		return errors.New("no refresh token")
+	}
+	var resp tokenResponse
+	if err := c.doJSON(ctx, request{method: http.MethodPost, path: "/token/refresh", anonymous: true}, map[string]string{"refresh_token": refresh}, &resp); err != nil {
+		return err
+	}
+	c.setTokens(resp.tokens())
+	return nil
+}
+
+// Logout 吊销当前会话并清除本地令牌
+func (c *Client) Logout(ctx context.Context) error {
+	if err := c.doJSON(ctx, request{method: http.MethodPost, path: "/logout"}, nil, nil); err != nil {
+		return err
+	}
+	c.setTokens(Tokens{})
+	return nil
+}
+
+// RevokeUserSessions 吊销指定用户的全部会话（仅 admin），返回吊销的会话数
+func (c *Client) RevokeUserSessions(ctx context.Context, userID string) (int, error) {
+	var out struct {
+		Revoked int `json:"revoked"`
+	}
+	err := c.doJSON(ctx, request{method: http.MethodPost, path: "/v1/admin/users/" + url.PathEscape(userID) + "/revoke-sessions"}, nil, &out)
+	return out.Revoked, err
+}
+
+// ResetUserMFA 重置指定用户的 MFA 并吊销其会话（仅 admin）
+func (c *Client) ResetUserMFA(ctx context.Context, userID string) error {
+	return c.doJSON(ctx, request{method: http.MethodPost, path: "/v1/admin/users/" + url.PathEscape(userID) + "/mfa/reset"}, nil, nil)
+}
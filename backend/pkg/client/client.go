@@ -0,0 +1,205 @@
+// Package client 是 Guardian 控制台 API 的 Go SDK。
+//
+// 控制台接口（agent、任务、消息、案件、审计日志）调用 /v2 下由 ConsoleService 转码的 HTTP 接口，
+// 请求与响应直接使用 guardian.proto 生成的类型，编解码方式与服务端 gRPC-Gateway 一致；
+// 登录、会话与导出等非 proto 接口使用本包定义的类型。
+//
+// 客户端持有访问令牌与刷新令牌：访问令牌过期（401）时自动用刷新令牌换取新令牌并重试一次，
+// 新令牌通过 WithTokenHook 通知调用方持久化。
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Tokens 是一次登录或刷新得到的令牌
+type Tokens struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+// Error 是服务端返回的错误响应
+type Error struct {
+	StatusCode int
+	Code       string            `json:"code"`
+	Message    string            `json:"message"`
+	Details    map[string]string `json:"details,omitempty"`
+	RequestID  string            `json:"requestId,omitempty"`
+	TraceID    string            `json:"traceId,omitempty"`
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("%s: %s (HTTP %d)", e.Code, e.Message, e.StatusCode)
+	if e.RequestID != "" {
+		msg += " request_id=" + e.RequestID
+	}
+	if e.TraceID != "" {
+		msg += " trace_id=" + e.TraceID
+	}
+	return msg
+}
+
+// ErrorCode 返回服务端错误码，如 NOT_FOUND；err 不是服务端错误时返回空串
+func ErrorCode(err error) string {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return ""
+}
+
+// Client 是控制台 API 客户端，可并发使用
+type Client struct {
+	baseURL string
+	http    *http.Client
+	onToken func(Tokens)
+
+	mu     sync.Mutex
+	tokens Tokens
+	// refreshMu 串行化刷新：刷新令牌只能使用一次，并发刷新会被服务端视为令牌重用而吊销会话
+	refreshMu sync.Mutex
+}
+
+// Option 配置 Client
+type Option func(*Client)
+
+// WithHTTPClient 使用自定义 http.Client（如自定义 TLS 或超时）
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.http = hc }
+}
+
+// WithTokens 使用已有令牌，如上次登录后保存的令牌
+func WithTokens(t Tokens) Option {
+	return func(c *Client) { c.tokens = t }
+}
+
+// WithTokenHook 在登录或刷新得到新令牌后调用 fn，供调用方持久化
+func WithTokenHook(fn func(Tokens)) Option {
+	return func(c *Client) { c.onToken = fn }
+}
+
+// New 创建客户端；baseURL 为服务端 HTTP 地址，如 https://guardian.example.com
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid base url %q", baseURL)
+	}
+	c := &Client{baseURL: strings.TrimRight(baseURL, "/"), http: &http.Client{Timeout: 60 * time.Second}}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+// Tokens 返回当前令牌
+func (c *Client) Tokens() Tokens {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.tokens
+}
+
+func (c *Client) setTokens(t Tokens) {
+	c.mu.Lock()
+	c.tokens = t
+	c.mu.Unlock()
+	if c.onToken != nil {
+		c.onToken(t)
+	}
+}
+
+// request 描述一次 HTTP 调用；body 已编码，便于刷新令牌后重放
+type request struct {
+	method string
+	path   string
+	query  url.Values
+	body   []byte
+	// anonymous 为 true 时不携带访问令牌，也不在 401 时刷新（登录与刷新本身）
+	anonymous bool
+}
+
+// do 发送请求并返回成功（2xx）的响应，调用方负责关闭 Body；非 2xx 响应转换为 *Error
+func (c *Client) do(ctx context.Context, req request) (*http.Response, error) {
+	sent := c.Tokens().AccessToken
+	resp, err := c.send(ctx, req, sent)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized && !req.anonymous && c.Tokens().RefreshToken != "" {
+		resp.Body.Close()
+		if err := c.refreshIfCurrent(ctx, sent); err != nil {
+			return nil, err
+		}
+		if resp, err = c.send(ctx, req, c.Tokens().AccessToken); err != nil {
+			return nil, err
+		}
+	}
+	if resp.StatusCode/100 != 2 {
+		defer resp.Body.Close()
+		return nil, decodeError(resp)
+	}
+	return resp, nil
+}
+
+func (c *Client) send(ctx context.Context, req request, accessToken string) (*http.Response, error) {
+	target := c.baseURL + req.path
+	if len(req.query) > 0 {
+		target += "?" + req.query.Encode()
+	}
+	var body io.Reader
+	if req.body != nil {
+		body = bytes.NewReader(req.body)
+	}
+	hr, err := http.NewRequestWithContext(ctx, req.method, target, body)
+	if err != nil {
+		return nil, err
+	}
+	if req.body != nil {
+		hr.Header.Set("Content-Type", "application/json")
+	}
+	hr.Header.Set("Accept", "application/json")
+	if !req.anonymous && accessToken != "" {
+		hr.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+	return c.http.Do(hr)
+}
+
+func decodeError(resp *http.Response) error {
+	e := &Error{StatusCode: resp.StatusCode}
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err := json.Unmarshal(raw, e); err != nil || e.Code == "" {
+		e.Code = http.StatusText(resp.StatusCode)
+		e.Message = strings.TrimSpace(string(raw))
+	}
+	return e
+}
+
+// doJSON 以 JSON 发送 in（为 nil 时无请求体），并把响应解码到 out（为 nil 时丢弃响应体）
+func (c *Client) doJSON(ctx context.Context, req request, in, out any) error {
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		req.body = b
+	}
+	resp, err := c.do(ctx, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,164 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	api "guardian-backend/pkg/grpc/api/guardian/pkg/grpc/api"
+)
+
+// fakeServer 模拟登录、刷新与一个控制台接口：只接受最新签发的访问令牌，刷新令牌只能使用一次
+type fakeServer struct {
+	mu        sync.Mutex
+	access    string
+	refresh   string
+	refreshes atomic.Int32
+}
+
+func (f *fakeServer) issue(w http.ResponseWriter, n int32) {
+	f.mu.Lock()
+	f.access, f.refresh = fmt.Sprintf("access-%d", n), fmt.Sprintf("refresh-%d", n)
+	body := map[string]any{"token": f.access, "refresh_token": f.refresh, "expires_in": 900}
+	f.mu.Unlock()
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func (f *fakeServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /login", func(w http.ResponseWriter, r *http.Request) {
+		var in map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&in)
+		if in["password"] != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"code":"UNAUTHORIZED","message":"invalid credentials","requestId":"req-1"}`))
+			return
+		}
+		f.issue(w, 0)
+	})
+	mux.HandleFunc("POST /token/refresh", func(w http.ResponseWriter, r *http.Request) {
+		var in map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&in)
+		f.mu.Lock()
+		ok := in["refresh_token"] == f.refresh
+		f.mu.Unlock()
+		if !ok {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"code":"SESSION_REVOKED","message":"refresh token reuse detected"}`))
+			return
+		}
+		f.issue(w, f.refreshes.Add(1))
+	})
+	mux.HandleFunc("GET /v2/cases", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		ok := r.Header.Get("Authorization") == "Bearer "+f.access
+		f.mu.Unlock()
+		if !ok {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"code":"UNAUTHORIZED","message":"invalid token"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"cases":[{"id":"7","name":"audit","status":"open","created_at":"2024-01-01T00:00:00Z","future_field":1}]}`))
+	})
+	return mux
+}
+
+func TestClient_LoginAndList(t *testing.T) {
+	fs := &fakeServer{}
+	srv := httptest.NewServer(fs.handler())
+	defer srv.Close()
+	var saved []Tokens
+	c, err := New(srv.URL, WithTokenHook(func(t Tokens) { saved = append(saved, t) }))
+	require.NoError(t, err)
+
+	_, err = c.Login(context.Background(), "admin", "wrong")
+	assert.Equal(t, "UNAUTHORIZED", ErrorCode(err))
+	assert.Contains(t, err.Error(), "request_id=req-1")
+
+	res, err := c.Login(context.Background(), "admin", "secret")
+	require.NoError(t, err)
+	assert.False(t, res.MFARequired)
+	require.Len(t, saved, 1)
+	assert.Equal(t, "access-0", saved[0].AccessToken)
+
+	cases, err := c.ListCases(context.Background())
+	require.NoError(t, err)
+	require.Len(t, cases.Cases, 1)
+	assert.Equal(t, int64(7), cases.Cases[0].Id)
+	assert.Equal(t, "audit", cases.Cases[0].Name)
+}
+
+func TestClient_RefreshesExpiredTokenOnce(t *testing.T) {
+	fs := &fakeServer{}
+	srv := httptest.NewServer(fs.handler())
+	defer srv.Close()
+	c, err := New(srv.URL)
+	require.NoError(t, err)
+	_, err = c.Login(context.Background(), "admin", "secret")
+	require.NoError(t, err)
+
+	// 服务端令牌轮换（如访问令牌过期），并发请求只能刷新一次，否则第二次刷新会被视为令牌重用
+	fs.mu.Lock()
+	fs.access = "expired"
+	fs.mu.Unlock()
+	var wg sync.WaitGroup
+	errs := make([]error, 8)
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs[i] = c.ListCases(context.Background())
+		}()
+	}
+	wg.Wait()
+	for _, err := range errs {
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, int32(1), fs.refreshes.Load())
+	assert.Equal(t, "access-1", c.Tokens().AccessToken)
+}
+
+func TestClient_SendsProtoJSON(t *testing.T) {
+	var got map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v2/agents/3/tasks", r.URL.Path)
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		_, _ = w.Write([]byte(`{"task_id":"11"}`))
+	}))
+	defer srv.Close()
+	c, err := New(srv.URL, WithTokens(Tokens{AccessToken: "t"}))
+	require.NoError(t, err)
+	resp, err := c.CreateTask(context.Background(), &api.CreateTaskRequest{AgentId: 3, CaseId: 5, ExcludedConversations: []string{"wxid_a"}})
+	require.NoError(t, err)
+	assert.Equal(t, int64(11), resp.TaskId)
+	// 字段使用 proto 原名，与服务端转码一致
+	assert.Equal(t, "5", got["case_id"])
+	assert.Equal(t, []any{"wxid_a"}, got["excluded_conversations"])
+}
+
+func TestClient_ExportCase(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/cases/7/exports", r.URL.Path)
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", `attachment; filename="case-7-abc.zip"`)
+		w.Header().Set("X-Manifest-SHA256", "deadbeef")
+		_, _ = w.Write([]byte("PK-zip-bytes"))
+	}))
+	defer srv.Close()
+	c, err := New(srv.URL, WithTokens(Tokens{AccessToken: "t"}))
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	exp, err := c.ExportCase(context.Background(), 7, "legal hold", false, &buf)
+	require.NoError(t, err)
+	assert.Equal(t, Export{Filename: "case-7-abc.zip", ManifestSHA256: "deadbeef", Size: 12}, exp)
+	assert.Equal(t, "PK-zip-bytes", buf.String())
+}
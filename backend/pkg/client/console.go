@@ -0,0 +1,158 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	api "guardian-backend/pkg/grpc/api/guardian/pkg/grpc/api"
+)
+
+// 与服务端 gRPC-Gateway 的 JSON 编解码方式一致：字段使用 proto 原名，忽略未知字段以兼容新版服务端
+var (
+	marshalOptions   = protojson.MarshalOptions{UseProtoNames: true}
+	unmarshalOptions = protojson.UnmarshalOptions{DiscardUnknown: true}
+)
+
+// call 调用一个 ConsoleService 转码接口；in 为 nil 时不发送请求体
+func (c *Client) call(ctx context.Context, req request, in, out proto.Message) error {
+	if in != nil {
+		b, err := marshalOptions.Marshal(in)
+		if err != nil {
+			return err
+		}
+		req.body = b
+	}
+	resp, err := c.do(ctx, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	return unmarshalOptions.Unmarshal(raw, out)
+}
+
+func setInt(q url.Values, key string, v int64) {
+	if v != 0 {
+		q.Set(key, strconv.FormatInt(v, 10))
+	}
+}
+
+func setString(q url.Values, key, v string) {
+	if v != "" {
+		q.Set(key, v)
+	}
+}
+
+// ListAgents 分页列出 agent
+func (c *Client) ListAgents(ctx context.Context, req *api.ListAgentsRequest) (*api.ListAgentsResponse, error) {
+	q := url.Values{}
+	setInt(q, "page", int64(req.GetPage()))
+	setInt(q, "page_size", int64(req.GetPageSize()))
+	out := &api.ListAgentsResponse{}
+	return out, c.call(ctx, request{method: http.MethodGet, path: "/v2/agents", query: q}, nil, out)
+}
+
+// CreateTask 为 agent 下发采集任务。任务以调用者名义创建，即为对该范围采集的批准，记入审计日志与案件监管链
+func (c *Client) CreateTask(ctx context.Context, req *api.CreateTaskRequest) (*api.CreateTaskResponse, error) {
+	out := &api.CreateTaskResponse{}
+	path := "/v2/agents/" + strconv.Itoa(int(req.GetAgentId())) + "/tasks"
+	return out, c.call(ctx, request{method: http.MethodPost, path: path}, req, out)
+}
+
+// DecommissionAgent 为 agent 下发卸载任务
+func (c *Client) DecommissionAgent(ctx context.Context, req *api.DecommissionAgentRequest) (*api.DecommissionAgentResponse, error) {
+	out := &api.DecommissionAgentResponse{}
+	path := "/v2/agents/" + strconv.Itoa(int(req.GetAgentId())) + "/decommission"
+	return out, c.call(ctx, request{method: http.MethodPost, path: path}, req, out)
+}
+
+// ListMessages 按 agent（AgentId）或任务（TaskId）查询消息
+func (c *Client) ListMessages(ctx context.Context, req *api.ListMessagesRequest) (*api.ListMessagesResponse, error) {
+	path := "/v2/agents/" + strconv.Itoa(int(req.GetAgentId())) + "/messages"
+	if req.GetTaskId() != 0 {
+		path = "/v2/tasks/" + strconv.FormatInt(req.GetTaskId(), 10) + "/messages"
+	}
+	q := url.Values{}
+	setInt(q, "page", int64(req.GetPage()))
+	setInt(q, "page_size", int64(req.GetPageSize()))
+	setString(q, "q", req.GetQ())
+	if req.GetUnmask() {
+		q.Set("unmask", "true")
+	}
+	setString(q, "reason", req.GetReason())
+	out := &api.ListMessagesResponse{}
+	return out, c.call(ctx, request{method: http.MethodGet, path: path, query: q}, nil, out)
+}
+
+// PurgeTaskMessages 清除某任务采集的全部消息（仅 admin）；Confirm 须与 TaskId 一致
+func (c *Client) PurgeTaskMessages(ctx context.Context, req *api.PurgeTaskMessagesRequest) (*api.PurgeTaskMessagesResponse, error) {
+	out := &api.PurgeTaskMessagesResponse{}
+	path := "/v2/tasks/" + strconv.FormatInt(req.GetTaskId(), 10) + "/messages"
+	return out, c.call(ctx, request{method: http.MethodDelete, path: path}, req, out)
+}
+
+// ListCases 列出全部案件
+func (c *Client) ListCases(ctx context.Context) (*api.ListCasesResponse, error) {
+	out := &api.ListCasesResponse{}
+	return out, c.call(ctx, request{method: http.MethodGet, path: "/v2/cases"}, nil, out)
+}
+
+// CreateCase 新建案件
+func (c *Client) CreateCase(ctx context.Context, name string) (*api.Case, error) {
+	out := &api.Case{}
+	return out, c.call(ctx, request{method: http.MethodPost, path: "/v2/cases"}, &api.CreateCaseRequest{Name: name}, out)
+}
+
+// DestroyCaseKey 销毁案件数据密钥（仅 admin），该案件已采集的数据从此不可恢复；Confirm 须与 CaseId 一致
+func (c *Client) DestroyCaseKey(ctx context.Context, req *api.DestroyCaseKeyRequest) error {
+	path := "/v2/cases/" + strconv.FormatInt(req.GetCaseId(), 10) + "/key"
+	return c.call(ctx, request{method: http.MethodDelete, path: path}, req, &emptypb.Empty{})
+}
+
+// ListAuditLogs 由新到旧查询一页审计日志（仅 admin 与 auditor）；下一页以响应的 NextPageToken 作为 PageToken
+func (c *Client) ListAuditLogs(ctx context.Context, req *api.ListAuditLogsRequest) (*api.ListAuditLogsResponse, error) {
+	q := url.Values{}
+	setString(q, "actor", req.GetActor())
+	setString(q, "action", req.GetAction())
+	setString(q, "target_type", req.GetTargetType())
+	setString(q, "target_id", req.GetTargetId())
+	if req.Since != nil {
+		q.Set("since", req.Since.AsTime().Format(time.RFC3339Nano))
+	}
+	if req.Until != nil {
+		q.Set("until", req.Until.AsTime().Format(time.RFC3339Nano))
+	}
+	setInt(q, "page_size", int64(req.GetPageSize()))
+	setString(q, "page_token", req.GetPageToken())
+	out := &api.ListAuditLogsResponse{}
+	return out, c.call(ctx, request{method: http.MethodGet, path: "/v2/audit-logs", query: q}, nil, out)
+}
+
+// AllAuditLogs 逐页查询符合条件的全部审计日志，每页调用一次 fn；fn 返回错误时停止
+func (c *Client) AllAuditLogs(ctx context.Context, req *api.ListAuditLogsRequest, fn func([]*api.AuditLog) error) error {
+	page := proto.Clone(req).(*api.ListAuditLogsRequest)
+	for {
+		resp, err := c.ListAuditLogs(ctx, page)
+		if err != nil {
+			return err
+		}
+		if err := fn(resp.Entries); err != nil {
+			return err
+		}
+		if resp.NextPageToken == "" {
+			return nil
+		}
+		page.PageToken = resp.NextPageToken
+	}
+}
@@ -0,0 +1,38 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+)
+
+// Export 是一次证据导出的响应信息
+type Export struct {
+	// Filename 为服务端建议的文件名，如 case-7-<bundle>.zip
+	Filename string
+	// ManifestSHA256 为清单哈希，与服务端 evidence_exports 中的登记一致
+	ManifestSHA256 string
+	Size           int64
+}
+
+// ExportCase 导出案件证据包（仅 admin）并把 ZIP 写入 w；校验见 internal/evidence 与 guardianctl audit verify-chain
+func (c *Client) ExportCase(ctx context.Context, caseID int64, reason string, redact bool, w io.Writer) (Export, error) {
+	body, err := json.Marshal(map[string]any{"reason": reason, "redact": redact})
+	if err != nil {
+		return Export{}, err
+	}
+	resp, err := c.do(ctx, request{method: http.MethodPost, path: "/v1/cases/" + strconv.FormatInt(caseID, 10) + "/exports", body: body})
+	if err != nil {
+		return Export{}, err
+	}
+	defer resp.Body.Close()
+	out := Export{ManifestSHA256: resp.Header.Get("X-Manifest-SHA256")}
+	if _, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition")); err == nil {
+		out.Filename = params["filename"]
+	}
+	out.Size, err = io.Copy(w, resp.Body)
+	return out, err
+}
@@ -0,0 +1,6 @@
+// Package api 仅用于承载代码生成指令，生成的代码位于 guardian/pkg/grpc/api 子目录。
+//
+// 修改 proto 后在本目录执行 go generate（需安装 protoc、protoc-gen-go、protoc-gen-go-grpc 与 protoc-gen-grpc-gateway）。
+package api
+
+//go:generate protoc -I ../../../api/proto -I ../../../third_party --go_out=. --go-grpc_out=. --grpc-gateway_out=. --grpc-gateway_opt=allow_delete_body=true guardian.proto
@@ -7,8 +7,12 @@
 package api
 
 import (
+	_ "github.com/grpc-ecosystem/grpc-gateway/v2/protoc-gen-openapiv2/options"
+	_ "google.golang.org/genproto/googleapis/api/annotations"
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+	structpb "google.golang.org/protobuf/types/known/structpb"
 	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
 	reflect "reflect"
 	sync "sync"
@@ -22,12 +26,113 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+type TaskType int32
+
+const (
+	TaskType_NONE             TaskType = 0
+	TaskType_DUMP_WECHAT_DATA TaskType = 1
+	// 卸载 agent 并清理本地数据
+	TaskType_UNINSTALL_AGENT TaskType = 2
+)
+
+// Enum value maps for TaskType.
+var (
+	TaskType_name = map[int32]string{
+		0: "NONE",
+		1: "DUMP_WECHAT_DATA",
+		2: "UNINSTALL_AGENT",
+	}
+	TaskType_value = map[string]int32{
+		"NONE":             0,
+		"DUMP_WECHAT_DATA": 1,
+		"UNINSTALL_AGENT":  2,
+	}
+)
+
+func (x TaskType) Enum() *TaskType {
+	p := new(TaskType)
+	*p = x
+	return p
+}
+
+func (x TaskType) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (TaskType) Descriptor() protoreflect.EnumDescriptor {
+	return file_guardian_proto_enumTypes[0].Descriptor()
+}
+
+func (TaskType) Type() protoreflect.EnumType {
+	return &file_guardian_proto_enumTypes[0]
+}
+
+func (x TaskType) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use TaskType.Descriptor instead.
+func (TaskType) EnumDescriptor() ([]byte, []int) {
+	return file_guardian_proto_rawDescGZIP(), []int{0}
+}
+
+type TaskResultStatus int32
+
+const (
+	TaskResultStatus_TASK_RESULT_UNSPECIFIED TaskResultStatus = 0
+	TaskResultStatus_TASK_RESULT_COMPLETED   TaskResultStatus = 1
+	TaskResultStatus_TASK_RESULT_FAILED      TaskResultStatus = 2
+)
+
+// Enum value maps for TaskResultStatus.
+var (
+	TaskResultStatus_name = map[int32]string{
+		0: "TASK_RESULT_UNSPECIFIED",
+		1: "TASK_RESULT_COMPLETED",
+		2: "TASK_RESULT_FAILED",
+	}
+	TaskResultStatus_value = map[string]int32{
+		"TASK_RESULT_UNSPECIFIED": 0,
+		"TASK_RESULT_COMPLETED":   1,
+		"TASK_RESULT_FAILED":      2,
+	}
+)
+
+func (x TaskResultStatus) Enum() *TaskResultStatus {
+	p := new(TaskResultStatus)
+	*p = x
+	return p
+}
+
+func (x TaskResultStatus) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (TaskResultStatus) Descriptor() protoreflect.EnumDescriptor {
+	return file_guardian_proto_enumTypes[1].Descriptor()
+}
+
+func (TaskResultStatus) Type() protoreflect.EnumType {
+	return &file_guardian_proto_enumTypes[1]
+}
+
+func (x TaskResultStatus) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use TaskResultStatus.Descriptor instead.
+func (TaskResultStatus) EnumDescriptor() ([]byte, []int) {
+	return file_guardian_proto_rawDescGZIP(), []int{1}
+}
+
 type ChatMessage struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Content       string                 `protobuf:"bytes,1,opt,name=content,proto3" json:"content,omitempty"`
-	Timestamp     *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Content   string                 `protobuf:"bytes,1,opt,name=content,proto3" json:"content,omitempty"`
+	Timestamp *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	// 会话标识（联系人或群组 wxid），用于服务端范围过滤
+	ConversationId string `protobuf:"bytes,3,opt,name=conversation_id,json=conversationId,proto3" json:"conversation_id,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
 }
 
 func (x *ChatMessage) Reset() {
@@ -74,10 +179,19 @@ func (x *ChatMessage) GetTimestamp() *timestamppb.Timestamp {
 	return nil
 }
 
+func (x *ChatMessage) GetConversationId() string {
+	if x != nil {
+		return x.ConversationId
+	}
+	return ""
+}
+
 type UploadMessagesRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	AgentId       int32                  `protobuf:"varint,1,opt,name=agent_id,json=agentId,proto3" json:"agent_id,omitempty"`
-	Messages      []*ChatMessage         `protobuf:"bytes,2,rep,name=messages,proto3" json:"messages,omitempty"`
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	AgentId  int32                  `protobuf:"varint,1,opt,name=agent_id,json=agentId,proto3" json:"agent_id,omitempty"`
+	Messages []*ChatMessage         `protobuf:"bytes,2,rep,name=messages,proto3" json:"messages,omitempty"`
+	// 采集这批消息的任务（心跳下发的 task_id），须为 agent 当前执行中的任务
+	TaskId        string `protobuf:"bytes,3,opt,name=task_id,json=taskId,proto3" json:"task_id,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -126,9 +240,23 @@ func (x *UploadMessagesRequest) GetMessages() []*ChatMessage {
 	return nil
 }
 
+func (x *UploadMessagesRequest) GetTaskId() string {
+	if x != nil {
+		return x.TaskId
+	}
+	return ""
+}
+
 type UploadMessagesResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Success bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	// 实际入库条数
+	AcceptedCount int32 `protobuf:"varint,2,opt,name=accepted_count,json=acceptedCount,proto3" json:"accepted_count,omitempty"`
+	// 因超出授权范围被丢弃的条数
+	DiscardedCount int32 `protobuf:"varint,3,opt,name=discarded_count,json=discardedCount,proto3" json:"discarded_count,omitempty"`
+	// 入库回执：批次 ID 与收到的请求的 SHA-256（确定性 protobuf 编码）
+	BatchId       int64  `protobuf:"varint,4,opt,name=batch_id,json=batchId,proto3" json:"batch_id,omitempty"`
+	RequestSha256 string `protobuf:"bytes,5,opt,name=request_sha256,json=requestSha256,proto3" json:"request_sha256,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -170,6 +298,34 @@ func (x *UploadMessagesResponse) GetSuccess() bool {
 	return false
 }
 
+func (x *UploadMessagesResponse) GetAcceptedCount() int32 {
+	if x != nil {
+		return x.AcceptedCount
+	}
+	return 0
+}
+
+func (x *UploadMessagesResponse) GetDiscardedCount() int32 {
+	if x != nil {
+		return x.DiscardedCount
+	}
+	return 0
+}
+
+func (x *UploadMessagesResponse) GetBatchId() int64 {
+	if x != nil {
+		return x.BatchId
+	}
+	return 0
+}
+
+func (x *UploadMessagesResponse) GetRequestSha256() string {
+	if x != nil {
+		return x.RequestSha256
+	}
+	return ""
+}
+
 type HeartbeatRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	AgentId       int32                  `protobuf:"varint,1,opt,name=agent_id,json=agentId,proto3" json:"agent_id,omitempty"`
@@ -222,17 +378,131 @@ func (x *HeartbeatRequest) GetHostname() string {
 	return ""
 }
 
+type ReportTaskResultRequest struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	AgentId int32                  `protobuf:"varint,1,opt,name=agent_id,json=agentId,proto3" json:"agent_id,omitempty"`
+	TaskId  string                 `protobuf:"bytes,2,opt,name=task_id,json=taskId,proto3" json:"task_id,omitempty"`
+	Status  TaskResultStatus       `protobuf:"varint,3,opt,name=status,proto3,enum=guardian.TaskResultStatus" json:"status,omitempty"`
+	// 失败原因或补充说明
+	Message       string `protobuf:"bytes,4,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReportTaskResultRequest) Reset() {
+	*x = ReportTaskResultRequest{}
+	mi := &file_guardian_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReportTaskResultRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReportTaskResultRequest) ProtoMessage() {}
+
+func (x *ReportTaskResultRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_guardian_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReportTaskResultRequest.ProtoReflect.Descriptor instead.
+func (*ReportTaskResultRequest) Descriptor() ([]byte, []int) {
+	return file_guardian_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ReportTaskResultRequest) GetAgentId() int32 {
+	if x != nil {
+		return x.AgentId
+	}
+	return 0
+}
+
+func (x *ReportTaskResultRequest) GetTaskId() string {
+	if x != nil {
+		return x.TaskId
+	}
+	return ""
+}
+
+func (x *ReportTaskResultRequest) GetStatus() TaskResultStatus {
+	if x != nil {
+		return x.Status
+	}
+	return TaskResultStatus_TASK_RESULT_UNSPECIFIED
+}
+
+func (x *ReportTaskResultRequest) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+type ReportTaskResultResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReportTaskResultResponse) Reset() {
+	*x = ReportTaskResultResponse{}
+	mi := &file_guardian_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReportTaskResultResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReportTaskResultResponse) ProtoMessage() {}
+
+func (x *ReportTaskResultResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_guardian_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReportTaskResultResponse.ProtoReflect.Descriptor instead.
+func (*ReportTaskResultResponse) Descriptor() ([]byte, []int) {
+	return file_guardian_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ReportTaskResultResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
 type HeartbeatResponse struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
 	// 未来可以加入任务指令
-	TaskId        string `protobuf:"bytes,1,opt,name=task_id,json=taskId,proto3" json:"task_id,omitempty"`
+	TaskId        string   `protobuf:"bytes,1,opt,name=task_id,json=taskId,proto3" json:"task_id,omitempty"`
+	TaskType      TaskType `protobuf:"varint,2,opt,name=task_type,json=taskType,proto3,enum=guardian.TaskType" json:"task_type,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *HeartbeatResponse) Reset() {
 	*x = HeartbeatResponse{}
-	mi := &file_guardian_proto_msgTypes[4]
+	mi := &file_guardian_proto_msgTypes[6]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -244,7 +514,7 @@ func (x *HeartbeatResponse) String() string {
 func (*HeartbeatResponse) ProtoMessage() {}
 
 func (x *HeartbeatResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_guardian_proto_msgTypes[4]
+	mi := &file_guardian_proto_msgTypes[6]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -257,7 +527,7 @@ func (x *HeartbeatResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use HeartbeatResponse.ProtoReflect.Descriptor instead.
 func (*HeartbeatResponse) Descriptor() ([]byte, []int) {
-	return file_guardian_proto_rawDescGZIP(), []int{4}
+	return file_guardian_proto_rawDescGZIP(), []int{6}
 }
 
 func (x *HeartbeatResponse) GetTaskId() string {
@@ -267,28 +537,1498 @@ func (x *HeartbeatResponse) GetTaskId() string {
 	return ""
 }
 
-var File_guardian_proto protoreflect.FileDescriptor
+func (x *HeartbeatResponse) GetTaskType() TaskType {
+	if x != nil {
+		return x.TaskType
+	}
+	return TaskType_NONE
+}
 
-const file_guardian_proto_rawDesc = "" +
-	"\n" +
-	"\x0eguardian.proto\x12\bguardian\x1a\x1fgoogle/protobuf/timestamp.proto\"a\n" +
-	"\vChatMessage\x12\x18\n" +
-	"\acontent\x18\x01 \x01(\tR\acontent\x128\n" +
-	"\ttimestamp\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\ttimestamp\"e\n" +
-	"\x15UploadMessagesRequest\x12\x19\n" +
-	"\bagent_id\x18\x01 \x01(\x05R\aagentId\x121\n" +
-	"\bmessages\x18\x02 \x03(\v2\x15.guardian.ChatMessageR\bmessages\"2\n" +
-	"\x16UploadMessagesResponse\x12\x18\n" +
-	"\asuccess\x18\x01 \x01(\bR\asuccess\"I\n" +
-	"\x10HeartbeatRequest\x12\x19\n" +
-	"\bagent_id\x18\x01 \x01(\x05R\aagentId\x12\x1a\n" +
-	"\bhostname\x18\x02 \x01(\tR\bhostname\",\n" +
-	"\x11HeartbeatResponse\x12\x17\n" +
-	"\atask_id\x18\x01 \x01(\tR\x06taskId2T\n" +
-	"\fAgentService\x12D\n" +
-	"\tHeartbeat\x12\x1a.guardian.HeartbeatRequest\x1a\x1b.guardian.HeartbeatResponse2b\n" +
-	"\vDataService\x12S\n" +
-	"\x0eUploadMessages\x12\x1f.guardian.UploadMessagesRequest\x1a .guardian.UploadMessagesResponseB\x17Z\x15guardian/pkg/grpc/apib\x06proto3"
+type Agent struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int32                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Hostname      string                 `protobuf:"bytes,2,opt,name=hostname,proto3" json:"hostname,omitempty"`
+	Status        string                 `protobuf:"bytes,3,opt,name=status,proto3" json:"status,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Agent) Reset() {
+	*x = Agent{}
+	mi := &file_guardian_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Agent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Agent) ProtoMessage() {}
+
+func (x *Agent) ProtoReflect() protoreflect.Message {
+	mi := &file_guardian_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Agent.ProtoReflect.Descriptor instead.
+func (*Agent) Descriptor() ([]byte, []int) {
+	return file_guardian_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *Agent) GetId() int32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Agent) GetHostname() string {
+	if x != nil {
+		return x.Hostname
+	}
+	return ""
+}
+
+func (x *Agent) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+type ListAgentsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// 页码从 1 开始；page_size 默认 50，最大 200
+	Page          int32 `protobuf:"varint,1,opt,name=page,proto3" json:"page,omitempty"`
+	PageSize      int32 `protobuf:"varint,2,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListAgentsRequest) Reset() {
+	*x = ListAgentsRequest{}
+	mi := &file_guardian_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListAgentsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListAgentsRequest) ProtoMessage() {}
+
+func (x *ListAgentsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_guardian_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListAgentsRequest.ProtoReflect.Descriptor instead.
+func (*ListAgentsRequest) Descriptor() ([]byte, []int) {
+	return file_guardian_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *ListAgentsRequest) GetPage() int32 {
+	if x != nil {
+		return x.Page
+	}
+	return 0
+}
+
+func (x *ListAgentsRequest) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+type ListAgentsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Agents        []*Agent               `protobuf:"bytes,1,rep,name=agents,proto3" json:"agents,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListAgentsResponse) Reset() {
+	*x = ListAgentsResponse{}
+	mi := &file_guardian_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListAgentsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListAgentsResponse) ProtoMessage() {}
+
+func (x *ListAgentsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_guardian_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListAgentsResponse.ProtoReflect.Descriptor instead.
+func (*ListAgentsResponse) Descriptor() ([]byte, []int) {
+	return file_guardian_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *ListAgentsResponse) GetAgents() []*Agent {
+	if x != nil {
+		return x.Agents
+	}
+	return nil
+}
+
+type CreateTaskRequest struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	AgentId int32                  `protobuf:"varint,1,opt,name=agent_id,json=agentId,proto3" json:"agent_id,omitempty"`
+	// 授权的采集时间范围，均可为空
+	ScopeStart *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=scope_start,json=scopeStart,proto3" json:"scope_start,omitempty"`
+	ScopeEnd   *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=scope_end,json=scopeEnd,proto3" json:"scope_end,omitempty"`
+	// 不得采集的会话（联系人或群组 wxid）
+	ExcludedConversations []string `protobuf:"bytes,4,rep,name=excluded_conversations,json=excludedConversations,proto3" json:"excluded_conversations,omitempty"`
+	// 任务所属案件，决定采集数据的加密密钥；为 0 时归入 agent 的默认案件
+	CaseId        int64 `protobuf:"varint,5,opt,name=case_id,json=caseId,proto3" json:"case_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateTaskRequest) Reset() {
+	*x = CreateTaskRequest{}
+	mi := &file_guardian_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateTaskRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateTaskRequest) ProtoMessage() {}
+
+func (x *CreateTaskRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_guardian_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateTaskRequest.ProtoReflect.Descriptor instead.
+func (*CreateTaskRequest) Descriptor() ([]byte, []int) {
+	return file_guardian_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *CreateTaskRequest) GetAgentId() int32 {
+	if x != nil {
+		return x.AgentId
+	}
+	return 0
+}
+
+func (x *CreateTaskRequest) GetScopeStart() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ScopeStart
+	}
+	return nil
+}
+
+func (x *CreateTaskRequest) GetScopeEnd() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ScopeEnd
+	}
+	return nil
+}
+
+func (x *CreateTaskRequest) GetExcludedConversations() []string {
+	if x != nil {
+		return x.ExcludedConversations
+	}
+	return nil
+}
+
+func (x *CreateTaskRequest) GetCaseId() int64 {
+	if x != nil {
+		return x.CaseId
+	}
+	return 0
+}
+
+type CreateTaskResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TaskId        int64                  `protobuf:"varint,1,opt,name=task_id,json=taskId,proto3" json:"task_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateTaskResponse) Reset() {
+	*x = CreateTaskResponse{}
+	mi := &file_guardian_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateTaskResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateTaskResponse) ProtoMessage() {}
+
+func (x *CreateTaskResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_guardian_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateTaskResponse.ProtoReflect.Descriptor instead.
+func (*CreateTaskResponse) Descriptor() ([]byte, []int) {
+	return file_guardian_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *CreateTaskResponse) GetTaskId() int64 {
+	if x != nil {
+		return x.TaskId
+	}
+	return 0
+}
+
+type DecommissionAgentRequest struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	AgentId int32                  `protobuf:"varint,1,opt,name=agent_id,json=agentId,proto3" json:"agent_id,omitempty"`
+	// 数据处置方式：retain（受保留期约束）或 purge
+	DataDisposition string `protobuf:"bytes,2,opt,name=data_disposition,json=dataDisposition,proto3" json:"data_disposition,omitempty"`
+	Reason          string `protobuf:"bytes,3,opt,name=reason,proto3" json:"reason,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *DecommissionAgentRequest) Reset() {
+	*x = DecommissionAgentRequest{}
+	mi := &file_guardian_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DecommissionAgentRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DecommissionAgentRequest) ProtoMessage() {}
+
+func (x *DecommissionAgentRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_guardian_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DecommissionAgentRequest.ProtoReflect.Descriptor instead.
+func (*DecommissionAgentRequest) Descriptor() ([]byte, []int) {
+	return file_guardian_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *DecommissionAgentRequest) GetAgentId() int32 {
+	if x != nil {
+		return x.AgentId
+	}
+	return 0
+}
+
+func (x *DecommissionAgentRequest) GetDataDisposition() string {
+	if x != nil {
+		return x.DataDisposition
+	}
+	return ""
+}
+
+func (x *DecommissionAgentRequest) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+type DecommissionAgentResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// 下发的卸载任务
+	TaskId        int64 `protobuf:"varint,1,opt,name=task_id,json=taskId,proto3" json:"task_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DecommissionAgentResponse) Reset() {
+	*x = DecommissionAgentResponse{}
+	mi := &file_guardian_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DecommissionAgentResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DecommissionAgentResponse) ProtoMessage() {}
+
+func (x *DecommissionAgentResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_guardian_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DecommissionAgentResponse.ProtoReflect.Descriptor instead.
+func (*DecommissionAgentResponse) Descriptor() ([]byte, []int) {
+	return file_guardian_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *DecommissionAgentResponse) GetTaskId() int64 {
+	if x != nil {
+		return x.TaskId
+	}
+	return 0
+}
+
+// StoredMessage 是已入库的消息
+type StoredMessage struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Content   string                 `protobuf:"bytes,1,opt,name=content,proto3" json:"content,omitempty"`
+	Timestamp *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	TaskId    *int64                 `protobuf:"varint,3,opt,name=task_id,json=taskId,proto3,oneof" json:"task_id,omitempty"`
+	// 内容中有字段被脱敏
+	Redacted      bool `protobuf:"varint,4,opt,name=redacted,proto3" json:"redacted,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StoredMessage) Reset() {
+	*x = StoredMessage{}
+	mi := &file_guardian_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StoredMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StoredMessage) ProtoMessage() {}
+
+func (x *StoredMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_guardian_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StoredMessage.ProtoReflect.Descriptor instead.
+func (*StoredMessage) Descriptor() ([]byte, []int) {
+	return file_guardian_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *StoredMessage) GetContent() string {
+	if x != nil {
+		return x.Content
+	}
+	return ""
+}
+
+func (x *StoredMessage) GetTimestamp() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Timestamp
+	}
+	return nil
+}
+
+func (x *StoredMessage) GetTaskId() int64 {
+	if x != nil && x.TaskId != nil {
+		return *x.TaskId
+	}
+	return 0
+}
+
+func (x *StoredMessage) GetRedacted() bool {
+	if x != nil {
+		return x.Redacted
+	}
+	return false
+}
+
+type ListMessagesRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// agent_id 与 task_id 须且只能指定一个
+	AgentId int32 `protobuf:"varint,1,opt,name=agent_id,json=agentId,proto3" json:"agent_id,omitempty"`
+	TaskId  int64 `protobuf:"varint,2,opt,name=task_id,json=taskId,proto3" json:"task_id,omitempty"`
+	// 页码从 1 开始；page_size 默认 100，最大 500
+	Page     int32 `protobuf:"varint,3,opt,name=page,proto3" json:"page,omitempty"`
+	PageSize int32 `protobuf:"varint,4,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	// 在脱敏后的内容中检索（不区分大小写）
+	Q string `protobuf:"bytes,5,opt,name=q,proto3" json:"q,omitempty"`
+	// 查看明文，须具备相应角色并填写 reason
+	Unmask        bool   `protobuf:"varint,6,opt,name=unmask,proto3" json:"unmask,omitempty"`
+	Reason        string `protobuf:"bytes,7,opt,name=reason,proto3" json:"reason,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListMessagesRequest) Reset() {
+	*x = ListMessagesRequest{}
+	mi := &file_guardian_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListMessagesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListMessagesRequest) ProtoMessage() {}
+
+func (x *ListMessagesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_guardian_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListMessagesRequest.ProtoReflect.Descriptor instead.
+func (*ListMessagesRequest) Descriptor() ([]byte, []int) {
+	return file_guardian_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *ListMessagesRequest) GetAgentId() int32 {
+	if x != nil {
+		return x.AgentId
+	}
+	return 0
+}
+
+func (x *ListMessagesRequest) GetTaskId() int64 {
+	if x != nil {
+		return x.TaskId
+	}
+	return 0
+}
+
+func (x *ListMessagesRequest) GetPage() int32 {
+	if x != nil {
+		return x.Page
+	}
+	return 0
+}
+
+func (x *ListMessagesRequest) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+func (x *ListMessagesRequest) GetQ() string {
+	if x != nil {
+		return x.Q
+	}
+	return ""
+}
+
+func (x *ListMessagesRequest) GetUnmask() bool {
+	if x != nil {
+		return x.Unmask
+	}
+	return false
+}
+
+func (x *ListMessagesRequest) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+type ListMessagesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Messages      []*StoredMessage       `protobuf:"bytes,1,rep,name=messages,proto3" json:"messages,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListMessagesResponse) Reset() {
+	*x = ListMessagesResponse{}
+	mi := &file_guardian_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListMessagesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListMessagesResponse) ProtoMessage() {}
+
+func (x *ListMessagesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_guardian_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListMessagesResponse.ProtoReflect.Descriptor instead.
+func (*ListMessagesResponse) Descriptor() ([]byte, []int) {
+	return file_guardian_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *ListMessagesResponse) GetMessages() []*StoredMessage {
+	if x != nil {
+		return x.Messages
+	}
+	return nil
+}
+
+type PurgeTaskMessagesRequest struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	TaskId int64                  `protobuf:"varint,1,opt,name=task_id,json=taskId,proto3" json:"task_id,omitempty"`
+	// 须与 task_id 一致，防止误操作
+	Confirm       int64  `protobuf:"varint,2,opt,name=confirm,proto3" json:"confirm,omitempty"`
+	Reason        string `protobuf:"bytes,3,opt,name=reason,proto3" json:"reason,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PurgeTaskMessagesRequest) Reset() {
+	*x = PurgeTaskMessagesRequest{}
+	mi := &file_guardian_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PurgeTaskMessagesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PurgeTaskMessagesRequest) ProtoMessage() {}
+
+func (x *PurgeTaskMessagesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_guardian_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PurgeTaskMessagesRequest.ProtoReflect.Descriptor instead.
+func (*PurgeTaskMessagesRequest) Descriptor() ([]byte, []int) {
+	return file_guardian_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *PurgeTaskMessagesRequest) GetTaskId() int64 {
+	if x != nil {
+		return x.TaskId
+	}
+	return 0
+}
+
+func (x *PurgeTaskMessagesRequest) GetConfirm() int64 {
+	if x != nil {
+		return x.Confirm
+	}
+	return 0
+}
+
+func (x *PurgeTaskMessagesRequest) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+type PurgeTaskMessagesResponse struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	TaskId         int64                  `protobuf:"varint,1,opt,name=task_id,json=taskId,proto3" json:"task_id,omitempty"`
+	MessagesPurged int64                  `protobuf:"varint,2,opt,name=messages_purged,json=messagesPurged,proto3" json:"messages_purged,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *PurgeTaskMessagesResponse) Reset() {
+	*x = PurgeTaskMessagesResponse{}
+	mi := &file_guardian_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PurgeTaskMessagesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PurgeTaskMessagesResponse) ProtoMessage() {}
+
+func (x *PurgeTaskMessagesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_guardian_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PurgeTaskMessagesResponse.ProtoReflect.Descriptor instead.
+func (*PurgeTaskMessagesResponse) Descriptor() ([]byte, []int) {
+	return file_guardian_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *PurgeTaskMessagesResponse) GetTaskId() int64 {
+	if x != nil {
+		return x.TaskId
+	}
+	return 0
+}
+
+func (x *PurgeTaskMessagesResponse) GetMessagesPurged() int64 {
+	if x != nil {
+		return x.MessagesPurged
+	}
+	return 0
+}
+
+type Case struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Id             int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name           string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	DefaultAgentId *int32                 `protobuf:"varint,3,opt,name=default_agent_id,json=defaultAgentId,proto3,oneof" json:"default_agent_id,omitempty"`
+	Status         string                 `protobuf:"bytes,4,opt,name=status,proto3" json:"status,omitempty"`
+	CreatedAt      *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	// 数据密钥销毁时间，未销毁时为空
+	KeyDestroyedAt *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=key_destroyed_at,json=keyDestroyedAt,proto3" json:"key_destroyed_at,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *Case) Reset() {
+	*x = Case{}
+	mi := &file_guardian_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Case) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Case) ProtoMessage() {}
+
+func (x *Case) ProtoReflect() protoreflect.Message {
+	mi := &file_guardian_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Case.ProtoReflect.Descriptor instead.
+func (*Case) Descriptor() ([]byte, []int) {
+	return file_guardian_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *Case) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Case) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Case) GetDefaultAgentId() int32 {
+	if x != nil && x.DefaultAgentId != nil {
+		return *x.DefaultAgentId
+	}
+	return 0
+}
+
+func (x *Case) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *Case) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *Case) GetKeyDestroyedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.KeyDestroyedAt
+	}
+	return nil
+}
+
+type ListCasesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListCasesRequest) Reset() {
+	*x = ListCasesRequest{}
+	mi := &file_guardian_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListCasesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListCasesRequest) ProtoMessage() {}
+
+func (x *ListCasesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_guardian_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListCasesRequest.ProtoReflect.Descriptor instead.
+func (*ListCasesRequest) Descriptor() ([]byte, []int) {
+	return file_guardian_proto_rawDescGZIP(), []int{20}
+}
+
+type ListCasesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Cases         []*Case                `protobuf:"bytes,1,rep,name=cases,proto3" json:"cases,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListCasesResponse) Reset() {
+	*x = ListCasesResponse{}
+	mi := &file_guardian_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListCasesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListCasesResponse) ProtoMessage() {}
+
+func (x *ListCasesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_guardian_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListCasesResponse.ProtoReflect.Descriptor instead.
+func (*ListCasesResponse) Descriptor() ([]byte, []int) {
+	return file_guardian_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *ListCasesResponse) GetCases() []*Case {
+	if x != nil {
+		return x.Cases
+	}
+	return nil
+}
+
+type CreateCaseRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateCaseRequest) Reset() {
+	*x = CreateCaseRequest{}
+	mi := &file_guardian_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateCaseRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateCaseRequest) ProtoMessage() {}
+
+func (x *CreateCaseRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_guardian_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateCaseRequest.ProtoReflect.Descriptor instead.
+func (*CreateCaseRequest) Descriptor() ([]byte, []int) {
+	return file_guardian_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *CreateCaseRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+type DestroyCaseKeyRequest struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	CaseId int64                  `protobuf:"varint,1,opt,name=case_id,json=caseId,proto3" json:"case_id,omitempty"`
+	// 须与 case_id 一致，防止误操作
+	Confirm       int64  `protobuf:"varint,2,opt,name=confirm,proto3" json:"confirm,omitempty"`
+	Reason        string `protobuf:"bytes,3,opt,name=reason,proto3" json:"reason,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DestroyCaseKeyRequest) Reset() {
+	*x = DestroyCaseKeyRequest{}
+	mi := &file_guardian_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DestroyCaseKeyRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DestroyCaseKeyRequest) ProtoMessage() {}
+
+func (x *DestroyCaseKeyRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_guardian_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DestroyCaseKeyRequest.ProtoReflect.Descriptor instead.
+func (*DestroyCaseKeyRequest) Descriptor() ([]byte, []int) {
+	return file_guardian_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *DestroyCaseKeyRequest) GetCaseId() int64 {
+	if x != nil {
+		return x.CaseId
+	}
+	return 0
+}
+
+func (x *DestroyCaseKeyRequest) GetConfirm() int64 {
+	if x != nil {
+		return x.Confirm
+	}
+	return 0
+}
+
+func (x *DestroyCaseKeyRequest) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+type AuditLog struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	Id         int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Actor      string                 `protobuf:"bytes,2,opt,name=actor,proto3" json:"actor,omitempty"`
+	Action     string                 `protobuf:"bytes,3,opt,name=action,proto3" json:"action,omitempty"`
+	TargetType string                 `protobuf:"bytes,4,opt,name=target_type,json=targetType,proto3" json:"target_type,omitempty"`
+	TargetId   string                 `protobuf:"bytes,5,opt,name=target_id,json=targetId,proto3" json:"target_id,omitempty"`
+	// success 或 denied
+	Outcome       string                 `protobuf:"bytes,6,opt,name=outcome,proto3" json:"outcome,omitempty"`
+	Detail        *structpb.Struct       `protobuf:"bytes,7,opt,name=detail,proto3" json:"detail,omitempty"`
+	IpAddress     string                 `protobuf:"bytes,8,opt,name=ip_address,json=ipAddress,proto3" json:"ip_address,omitempty"`
+	CreatedAt     *timestamppb.Timestamp `protobuf:"bytes,9,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AuditLog) Reset() {
+	*x = AuditLog{}
+	mi := &file_guardian_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AuditLog) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AuditLog) ProtoMessage() {}
+
+func (x *AuditLog) ProtoReflect() protoreflect.Message {
+	mi := &file_guardian_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AuditLog.ProtoReflect.Descriptor instead.
+func (*AuditLog) Descriptor() ([]byte, []int) {
+	return file_guardian_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *AuditLog) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *AuditLog) GetActor() string {
+	if x != nil {
+		return x.Actor
+	}
+	return ""
+}
+
+func (x *AuditLog) GetAction() string {
+	if x != nil {
+		return x.Action
+	}
+	return ""
+}
+
+func (x *AuditLog) GetTargetType() string {
+	if x != nil {
+		return x.TargetType
+	}
+	return ""
+}
+
+func (x *AuditLog) GetTargetId() string {
+	if x != nil {
+		return x.TargetId
+	}
+	return ""
+}
+
+func (x *AuditLog) GetOutcome() string {
+	if x != nil {
+		return x.Outcome
+	}
+	return ""
+}
+
+func (x *AuditLog) GetDetail() *structpb.Struct {
+	if x != nil {
+		return x.Detail
+	}
+	return nil
+}
+
+func (x *AuditLog) GetIpAddress() string {
+	if x != nil {
+		return x.IpAddress
+	}
+	return ""
+}
+
+func (x *AuditLog) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+type ListAuditLogsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// 以下过滤条件为空时不参与过滤
+	Actor      string `protobuf:"bytes,1,opt,name=actor,proto3" json:"actor,omitempty"`
+	Action     string `protobuf:"bytes,2,opt,name=action,proto3" json:"action,omitempty"`
+	TargetType string `protobuf:"bytes,3,opt,name=target_type,json=targetType,proto3" json:"target_type,omitempty"`
+	TargetId   string `protobuf:"bytes,4,opt,name=target_id,json=targetId,proto3" json:"target_id,omitempty"`
+	// 时间范围 [since, until)
+	Since *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=since,proto3" json:"since,omitempty"`
+	Until *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=until,proto3" json:"until,omitempty"`
+	// 默认 100，最大 500
+	PageSize int32 `protobuf:"varint,7,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	// 上一页响应中的 next_page_token
+	PageToken     string `protobuf:"bytes,8,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListAuditLogsRequest) Reset() {
+	*x = ListAuditLogsRequest{}
+	mi := &file_guardian_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListAuditLogsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListAuditLogsRequest) ProtoMessage() {}
+
+func (x *ListAuditLogsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_guardian_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListAuditLogsRequest.ProtoReflect.Descriptor instead.
+func (*ListAuditLogsRequest) Descriptor() ([]byte, []int) {
+	return file_guardian_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *ListAuditLogsRequest) GetActor() string {
+	if x != nil {
+		return x.Actor
+	}
+	return ""
+}
+
+func (x *ListAuditLogsRequest) GetAction() string {
+	if x != nil {
+		return x.Action
+	}
+	return ""
+}
+
+func (x *ListAuditLogsRequest) GetTargetType() string {
+	if x != nil {
+		return x.TargetType
+	}
+	return ""
+}
+
+func (x *ListAuditLogsRequest) GetTargetId() string {
+	if x != nil {
+		return x.TargetId
+	}
+	return ""
+}
+
+func (x *ListAuditLogsRequest) GetSince() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Since
+	}
+	return nil
+}
+
+func (x *ListAuditLogsRequest) GetUntil() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Until
+	}
+	return nil
+}
+
+func (x *ListAuditLogsRequest) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+func (x *ListAuditLogsRequest) GetPageToken() string {
+	if x != nil {
+		return x.PageToken
+	}
+	return ""
+}
+
+type ListAuditLogsResponse struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Entries []*AuditLog            `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries,omitempty"`
+	// 为空表示没有更多记录
+	NextPageToken string `protobuf:"bytes,2,opt,name=next_page_token,json=nextPageToken,proto3" json:"next_page_token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListAuditLogsResponse) Reset() {
+	*x = ListAuditLogsResponse{}
+	mi := &file_guardian_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListAuditLogsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListAuditLogsResponse) ProtoMessage() {}
+
+func (x *ListAuditLogsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_guardian_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListAuditLogsResponse.ProtoReflect.Descriptor instead.
+func (*ListAuditLogsResponse) Descriptor() ([]byte, []int) {
+	return file_guardian_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *ListAuditLogsResponse) GetEntries() []*AuditLog {
+	if x != nil {
+		return x.Entries
+	}
+	return nil
+}
+
+func (x *ListAuditLogsResponse) GetNextPageToken() string {
+	if x != nil {
+		return x.NextPageToken
+	}
+	return ""
+}
+
+// ErrorResponse 是 HTTP 接口（含 gRPC-Gateway 转码接口）的错误响应体，仅用于生成 OpenAPI 文档
+type ErrorResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// 机器可读错误码，如 NOT_FOUND、CASE_KEY_DESTROYED
+	Code    string            `protobuf:"bytes,1,opt,name=code,proto3" json:"code,omitempty"`
+	Message string            `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Details map[string]string `protobuf:"bytes,3,rep,name=details,proto3" json:"details,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	// 请求 ID，用于关联服务端日志（字段名与其他 HTTP 响应一致）
+	RequestId     string `protobuf:"bytes,4,opt,name=requestId,proto3" json:"requestId,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ErrorResponse) Reset() {
+	*x = ErrorResponse{}
+	mi := &file_guardian_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ErrorResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ErrorResponse) ProtoMessage() {}
+
+func (x *ErrorResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_guardian_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ErrorResponse.ProtoReflect.Descriptor instead.
+func (*ErrorResponse) Descriptor() ([]byte, []int) {
+	return file_guardian_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *ErrorResponse) GetCode() string {
+	if x != nil {
+		return x.Code
+	}
+	return ""
+}
+
+func (x *ErrorResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *ErrorResponse) GetDetails() map[string]string {
+	if x != nil {
+		return x.Details
+	}
+	return nil
+}
+
+func (x *ErrorResponse) GetRequestId() string {
+	if x != nil {
+		return x.RequestId
+	}
+	return ""
+}
+
+var File_guardian_proto protoreflect.FileDescriptor
+
+const file_guardian_proto_rawDesc = "" +
+	"\n" +
+	"\x0eguardian.proto\x12\bguardian\x1a\x1bgoogle/protobuf/empty.proto\x1a\x1cgoogle/protobuf/struct.proto\x1a\x1fgoogle/protobuf/timestamp.proto\x1a\x1cgoogle/api/annotations.proto\x1a.protoc-gen-openapiv2/options/annotations.proto\"\x8a\x01\n" +
+	"\vChatMessage\x12\x18\n" +
+	"\acontent\x18\x01 \x01(\tR\acontent\x128\n" +
+	"\ttimestamp\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\ttimestamp\x12'\n" +
+	"\x0fconversation_id\x18\x03 \x01(\tR\x0econversationId\"~\n" +
+	"\x15UploadMessagesRequest\x12\x19\n" +
+	"\bagent_id\x18\x01 \x01(\x05R\aagentId\x121\n" +
+	"\bmessages\x18\x02 \x03(\v2\x15.guardian.ChatMessageR\bmessages\x12\x17\n" +
+	"\atask_id\x18\x03 \x01(\tR\x06taskId\"\xc4\x01\n" +
+	"\x16UploadMessagesResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12%\n" +
+	"\x0eaccepted_count\x18\x02 \x01(\x05R\racceptedCount\x12'\n" +
+	"\x0fdiscarded_count\x18\x03 \x01(\x05R\x0ediscardedCount\x12\x19\n" +
+	"\bbatch_id\x18\x04 \x01(\x03R\abatchId\x12%\n" +
+	"\x0erequest_sha256\x18\x05 \x01(\tR\rrequestSha256\"I\n" +
+	"\x10HeartbeatRequest\x12\x19\n" +
+	"\bagent_id\x18\x01 \x01(\x05R\aagentId\x12\x1a\n" +
+	"\bhostname\x18\x02 \x01(\tR\bhostname\"\x9b\x01\n" +
+	"\x17ReportTaskResultRequest\x12\x19\n" +
+	"\bagent_id\x18\x01 \x01(\x05R\aagentId\x12\x17\n" +
+	"\atask_id\x18\x02 \x01(\tR\x06taskId\x122\n" +
+	"\x06status\x18\x03 \x01(\x0e2\x1a.guardian.TaskResultStatusR\x06status\x12\x18\n" +
+	"\amessage\x18\x04 \x01(\tR\amessage\"4\n" +
+	"\x18ReportTaskResultResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\"]\n" +
+	"\x11HeartbeatResponse\x12\x17\n" +
+	"\atask_id\x18\x01 \x01(\tR\x06taskId\x12/\n" +
+	"\ttask_type\x18\x02 \x01(\x0e2\x12.guardian.TaskTypeR\btaskType\"K\n" +
+	"\x05Agent\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x05R\x02id\x12\x1a\n" +
+	"\bhostname\x18\x02 \x01(\tR\bhostname\x12\x16\n" +
+	"\x06status\x18\x03 \x01(\tR\x06status\"D\n" +
+	"\x11ListAgentsRequest\x12\x12\n" +
+	"\x04page\x18\x01 \x01(\x05R\x04page\x12\x1b\n" +
+	"\tpage_size\x18\x02 \x01(\x05R\bpageSize\"=\n" +
+	"\x12ListAgentsResponse\x12'\n" +
+	"\x06agents\x18\x01 \x03(\v2\x0f.guardian.AgentR\x06agents\"\xf4\x01\n" +
+	"\x11CreateTaskRequest\x12\x19\n" +
+	"\bagent_id\x18\x01 \x01(\x05R\aagentId\x12;\n" +
+	"\vscope_start\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"scopeStart\x127\n" +
+	"\tscope_end\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\bscopeEnd\x125\n" +
+	"\x16excluded_conversations\x18\x04 \x03(\tR\x15excludedConversations\x12\x17\n" +
+	"\acase_id\x18\x05 \x01(\x03R\x06caseId\"-\n" +
+	"\x12CreateTaskResponse\x12\x17\n" +
+	"\atask_id\x18\x01 \x01(\x03R\x06taskId\"x\n" +
+	"\x18DecommissionAgentRequest\x12\x19\n" +
+	"\bagent_id\x18\x01 \x01(\x05R\aagentId\x12)\n" +
+	"\x10data_disposition\x18\x02 \x01(\tR\x0fdataDisposition\x12\x16\n" +
+	"\x06reason\x18\x03 \x01(\tR\x06reason\"4\n" +
+	"\x19DecommissionAgentResponse\x12\x17\n" +
+	"\atask_id\x18\x01 \x01(\x03R\x06taskId\"\xa9\x01\n" +
+	"\rStoredMessage\x12\x18\n" +
+	"\acontent\x18\x01 \x01(\tR\acontent\x128\n" +
+	"\ttimestamp\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\ttimestamp\x12\x1c\n" +
+	"\atask_id\x18\x03 \x01(\x03H\x00R\x06taskId\x88\x01\x01\x12\x1a\n" +
+	"\bredacted\x18\x04 \x01(\bR\bredactedB\n" +
+	"\n" +
+	"\b_task_id\"\xb8\x01\n" +
+	"\x13ListMessagesRequest\x12\x19\n" +
+	"\bagent_id\x18\x01 \x01(\x05R\aagentId\x12\x17\n" +
+	"\atask_id\x18\x02 \x01(\x03R\x06taskId\x12\x12\n" +
+	"\x04page\x18\x03 \x01(\x05R\x04page\x12\x1b\n" +
+	"\tpage_size\x18\x04 \x01(\x05R\bpageSize\x12\f\n" +
+	"\x01q\x18\x05 \x01(\tR\x01q\x12\x16\n" +
+	"\x06unmask\x18\x06 \x01(\bR\x06unmask\x12\x16\n" +
+	"\x06reason\x18\a \x01(\tR\x06reason\"K\n" +
+	"\x14ListMessagesResponse\x123\n" +
+	"\bmessages\x18\x01 \x03(\v2\x17.guardian.StoredMessageR\bmessages\"e\n" +
+	"\x18PurgeTaskMessagesRequest\x12\x17\n" +
+	"\atask_id\x18\x01 \x01(\x03R\x06taskId\x12\x18\n" +
+	"\aconfirm\x18\x02 \x01(\x03R\aconfirm\x12\x16\n" +
+	"\x06reason\x18\x03 \x01(\tR\x06reason\"]\n" +
+	"\x19PurgeTaskMessagesResponse\x12\x17\n" +
+	"\atask_id\x18\x01 \x01(\x03R\x06taskId\x12'\n" +
+	"\x0fmessages_purged\x18\x02 \x01(\x03R\x0emessagesPurged\"\x87\x02\n" +
+	"\x04Case\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12-\n" +
+	"\x10default_agent_id\x18\x03 \x01(\x05H\x00R\x0edefaultAgentId\x88\x01\x01\x12\x16\n" +
+	"\x06status\x18\x04 \x01(\tR\x06status\x129\n" +
+	"\n" +
+	"created_at\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x12D\n" +
+	"\x10key_destroyed_at\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\x0ekeyDestroyedAtB\x13\n" +
+	"\x11_default_agent_id\"\x12\n" +
+	"\x10ListCasesRequest\"9\n" +
+	"\x11ListCasesResponse\x12$\n" +
+	"\x05cases\x18\x01 \x03(\v2\x0e.guardian.CaseR\x05cases\"'\n" +
+	"\x11CreateCaseRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\"b\n" +
+	"\x15DestroyCaseKeyRequest\x12\x17\n" +
+	"\acase_id\x18\x01 \x01(\x03R\x06caseId\x12\x18\n" +
+	"\aconfirm\x18\x02 \x01(\x03R\aconfirm\x12\x16\n" +
+	"\x06reason\x18\x03 \x01(\tR\x06reason\"\xab\x02\n" +
+	"\bAuditLog\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x14\n" +
+	"\x05actor\x18\x02 \x01(\tR\x05actor\x12\x16\n" +
+	"\x06action\x18\x03 \x01(\tR\x06action\x12\x1f\n" +
+	"\vtarget_type\x18\x04 \x01(\tR\n" +
+	"targetType\x12\x1b\n" +
+	"\ttarget_id\x18\x05 \x01(\tR\btargetId\x12\x18\n" +
+	"\aoutcome\x18\x06 \x01(\tR\aoutcome\x12/\n" +
+	"\x06detail\x18\a \x01(\v2\x17.google.protobuf.StructR\x06detail\x12\x1d\n" +
+	"\n" +
+	"ip_address\x18\b \x01(\tR\tipAddress\x129\n" +
+	"\n" +
+	"created_at\x18\t \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\"\xa2\x02\n" +
+	"\x14ListAuditLogsRequest\x12\x14\n" +
+	"\x05actor\x18\x01 \x01(\tR\x05actor\x12\x16\n" +
+	"\x06action\x18\x02 \x01(\tR\x06action\x12\x1f\n" +
+	"\vtarget_type\x18\x03 \x01(\tR\n" +
+	"targetType\x12\x1b\n" +
+	"\ttarget_id\x18\x04 \x01(\tR\btargetId\x120\n" +
+	"\x05since\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\x05since\x120\n" +
+	"\x05until\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\x05until\x12\x1b\n" +
+	"\tpage_size\x18\a \x01(\x05R\bpageSize\x12\x1d\n" +
+	"\n" +
+	"page_token\x18\b \x01(\tR\tpageToken\"m\n" +
+	"\x15ListAuditLogsResponse\x12,\n" +
+	"\aentries\x18\x01 \x03(\v2\x12.guardian.AuditLogR\aentries\x12&\n" +
+	"\x0fnext_page_token\x18\x02 \x01(\tR\rnextPageToken\"\xd7\x01\n" +
+	"\rErrorResponse\x12\x12\n" +
+	"\x04code\x18\x01 \x01(\tR\x04code\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\x12>\n" +
+	"\adetails\x18\x03 \x03(\v2$.guardian.ErrorResponse.DetailsEntryR\adetails\x12\x1c\n" +
+	"\trequestId\x18\x04 \x01(\tR\trequestId\x1a:\n" +
+	"\fDetailsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01*?\n" +
+	"\bTaskType\x12\b\n" +
+	"\x04NONE\x10\x00\x12\x14\n" +
+	"\x10DUMP_WECHAT_DATA\x10\x01\x12\x13\n" +
+	"\x0fUNINSTALL_AGENT\x10\x02*b\n" +
+	"\x10TaskResultStatus\x12\x1b\n" +
+	"\x17TASK_RESULT_UNSPECIFIED\x10\x00\x12\x19\n" +
+	"\x15TASK_RESULT_COMPLETED\x10\x01\x12\x16\n" +
+	"\x12TASK_RESULT_FAILED\x10\x022\xaf\x01\n" +
+	"\fAgentService\x12D\n" +
+	"\tHeartbeat\x12\x1a.guardian.HeartbeatRequest\x1a\x1b.guardian.HeartbeatResponse\x12Y\n" +
+	"\x10ReportTaskResult\x12!.guardian.ReportTaskResultRequest\x1a\".guardian.ReportTaskResultResponse2\x86\x01\n" +
+	"\vDataService\x12w\n" +
+	"\x0eUploadMessages\x12\x1f.guardian.UploadMessagesRequest\x1a .guardian.UploadMessagesResponse\"\"\x82\xd3\xe4\x93\x02\x1c:\x01*\"\x17/v1/messages/{agent_id}2\x8f\b\n" +
+	"\x0eConsoleService\x12[\n" +
+	"\n" +
+	"ListAgents\x12\x1b.guardian.ListAgentsRequest\x1a\x1c.guardian.ListAgentsResponse\"\x12\x82\xd3\xe4\x93\x02\f\x12\n" +
+	"/v2/agents\x12o\n" +
+	"\n" +
+	"CreateTask\x12\x1b.guardian.CreateTaskRequest\x1a\x1c.guardian.CreateTaskResponse\"&\x82\xd3\xe4\x93\x02 :\x01*\"\x1b/v2/agents/{agent_id}/tasks\x12\x8b\x01\n" +
+	"\x11DecommissionAgent\x12\".guardian.DecommissionAgentRequest\x1a#.guardian.DecommissionAgentResponse\"-\x82\xd3\xe4\x93\x02':\x01*\"\"/v2/agents/{agent_id}/decommission\x12\x95\x01\n" +
+	"\fListMessages\x12\x1d.guardian.ListMessagesRequest\x1a\x1e.guardian.ListMessagesResponse\"F\x82\xd3\xe4\x93\x02@Z\x1e\x12\x1c/v2/tasks/{task_id}/messages\x12\x1e/v2/agents/{agent_id}/messages\x12\x85\x01\n" +
+	"\x11PurgeTaskMessages\x12\".guardian.PurgeTaskMessagesRequest\x1a#.guardian.PurgeTaskMessagesResponse\"'\x82\xd3\xe4\x93\x02!:\x01**\x1c/v2/tasks/{task_id}/messages\x12W\n" +
+	"\tListCases\x12\x1a.guardian.ListCasesRequest\x1a\x1b.guardian.ListCasesResponse\"\x11\x82\xd3\xe4\x93\x02\v\x12\t/v2/cases\x12O\n" +
+	"\n" +
+	"CreateCase\x12\x1b.guardian.CreateCaseRequest\x1a\x0e.guardian.Case\"\x14\x82\xd3\xe4\x93\x02\x0e:\x01*\"\t/v2/cases\x12m\n" +
+	"\x0eDestroyCaseKey\x12\x1f.guardian.DestroyCaseKeyRequest\x1a\x16.google.protobuf.Empty\"\"\x82\xd3\xe4\x93\x02\x1c:\x01**\x17/v2/cases/{case_id}/key\x12h\n" +
+	"\rListAuditLogs\x12\x1e.guardian.ListAuditLogsRequest\x1a\x1f.guardian.ListAuditLogsResponse\"\x16\x82\xd3\xe4\x93\x02\x10\x12\x0e/v2/audit-logsB\xa7\x01\x92A\x8c\x01\x12\x13\n" +
+	"\fGuardian API2\x031.0R6\n" +
+	"\adefault\x12+\n" +
+	"\f错误响应\x12\x1b\n" +
+	"\x19\x1a\x17.guardian.ErrorResponseZ/\n" +
+	"-\n" +
+	"\x06bearer\x12#\b\x02\x12\x0eBearer <token>\x1a\rAuthorization \x02b\f\n" +
+	"\n" +
+	"\n" +
+	"\x06bearer\x12\x00Z\x15guardian/pkg/grpc/apib\x06proto3"
 
 var (
 	file_guardian_proto_rawDescOnce sync.Once
@@ -302,27 +2042,92 @@ func file_guardian_proto_rawDescGZIP() []byte {
 	return file_guardian_proto_rawDescData
 }
 
-var file_guardian_proto_msgTypes = make([]protoimpl.MessageInfo, 5)
+var file_guardian_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
+var file_guardian_proto_msgTypes = make([]protoimpl.MessageInfo, 29)
 var file_guardian_proto_goTypes = []any{
-	(*ChatMessage)(nil),            // 0: guardian.ChatMessage
-	(*UploadMessagesRequest)(nil),  // 1: guardian.UploadMessagesRequest
-	(*UploadMessagesResponse)(nil), // 2: guardian.UploadMessagesResponse
-	(*HeartbeatRequest)(nil),       // 3: guardian.HeartbeatRequest
-	(*HeartbeatResponse)(nil),      // 4: guardian.HeartbeatResponse
-	(*timestamppb.Timestamp)(nil),  // 5: google.protobuf.Timestamp
+	(TaskType)(0),                     // 0: guardian.TaskType
+	(TaskResultStatus)(0),             // 1: guardian.TaskResultStatus
+	(*ChatMessage)(nil),               // 2: guardian.ChatMessage
+	(*UploadMessagesRequest)(nil),     // 3: guardian.UploadMessagesRequest
+	(*UploadMessagesResponse)(nil),    // 4: guardian.UploadMessagesResponse
+	(*HeartbeatRequest)(nil),          // 5: guardian.HeartbeatRequest
+	(*ReportTaskResultRequest)(nil),   // 6: guardian.ReportTaskResultRequest
+	(*ReportTaskResultResponse)(nil),  // 7: guardian.ReportTaskResultResponse
+	(*HeartbeatResponse)(nil),         // 8: guardian.HeartbeatResponse
+	(*Agent)(nil),                     // 9: guardian.Agent
+	(*ListAgentsRequest)(nil),         // 10: guardian.ListAgentsRequest
+	(*ListAgentsResponse)(nil),        // 11: guardian.ListAgentsResponse
+	(*CreateTaskRequest)(nil),         // 12: guardian.CreateTaskRequest
+	(*CreateTaskResponse)(nil),        // 13: guardian.CreateTaskResponse
+	(*DecommissionAgentRequest)(nil),  // 14: guardian.DecommissionAgentRequest
+	(*DecommissionAgentResponse)(nil), // 15: guardian.DecommissionAgentResponse
+	(*StoredMessage)(nil),             // 16: guardian.StoredMessage
+	(*ListMessagesRequest)(nil),       // 17: guardian.ListMessagesRequest
+	(*ListMessagesResponse)(nil),      // 18: guardian.ListMessagesResponse
+	(*PurgeTaskMessagesRequest)(nil),  // 19: guardian.PurgeTaskMessagesRequest
+	(*PurgeTaskMessagesResponse)(nil), // 20: guardian.PurgeTaskMessagesResponse
+	(*Case)(nil),                      // 21: guardian.Case
+	(*ListCasesRequest)(nil),          // 22: guardian.ListCasesRequest
+	(*ListCasesResponse)(nil),         // 23: guardian.ListCasesResponse
+	(*CreateCaseRequest)(nil),         // 24: guardian.CreateCaseRequest
+	(*DestroyCaseKeyRequest)(nil),     // 25: guardian.DestroyCaseKeyRequest
+	(*AuditLog)(nil),                  // 26: guardian.AuditLog
+	(*ListAuditLogsRequest)(nil),      // 27: guardian.ListAuditLogsRequest
+	(*ListAuditLogsResponse)(nil),     // 28: guardian.ListAuditLogsResponse
+	(*ErrorResponse)(nil),             // 29: guardian.ErrorResponse
+	nil,                               // 30: guardian.ErrorResponse.DetailsEntry
+	(*timestamppb.Timestamp)(nil),     // 31: google.protobuf.Timestamp
+	(*structpb.Struct)(nil),           // 32: google.protobuf.Struct
+	(*emptypb.Empty)(nil),             // 33: google.protobuf.Empty
 }
 var file_guardian_proto_depIdxs = []int32{
-	5, // 0: guardian.ChatMessage.timestamp:type_name -> google.protobuf.Timestamp
-	0, // 1: guardian.UploadMessagesRequest.messages:type_name -> guardian.ChatMessage
-	3, // 2: guardian.AgentService.Heartbeat:input_type -> guardian.HeartbeatRequest
-	1, // 3: guardian.DataService.UploadMessages:input_type -> guardian.UploadMessagesRequest
-	4, // 4: guardian.AgentService.Heartbeat:output_type -> guardian.HeartbeatResponse
-	2, // 5: guardian.DataService.UploadMessages:output_type -> guardian.UploadMessagesResponse
-	4, // [4:6] is the sub-list for method output_type
-	2, // [2:4] is the sub-list for method input_type
-	2, // [2:2] is the sub-list for extension type_name
-	2, // [2:2] is the sub-list for extension extendee
-	0, // [0:2] is the sub-list for field type_name
+	31, // 0: guardian.ChatMessage.timestamp:type_name -> google.protobuf.Timestamp
+	2,  // 1: guardian.UploadMessagesRequest.messages:type_name -> guardian.ChatMessage
+	1,  // 2: guardian.ReportTaskResultRequest.status:type_name -> guardian.TaskResultStatus
+	0,  // 3: guardian.HeartbeatResponse.task_type:type_name -> guardian.TaskType
+	9,  // 4: guardian.ListAgentsResponse.agents:type_name -> guardian.Agent
+	31, // 5: guardian.CreateTaskRequest.scope_start:type_name -> google.protobuf.Timestamp
+	31, // 6: guardian.CreateTaskRequest.scope_end:type_name -> google.protobuf.Timestamp
+	31, // 7: guardian.StoredMessage.timestamp:type_name -> google.protobuf.Timestamp
+	16, // 8: guardian.ListMessagesResponse.messages:type_name -> guardian.StoredMessage
+	31, // 9: guardian.Case.created_at:type_name -> google.protobuf.Timestamp
+	31, // 10: guardian.Case.key_destroyed_at:type_name -> google.protobuf.Timestamp
+	21, // 11: guardian.ListCasesResponse.cases:type_name -> guardian.Case
+	32, // 12: guardian.AuditLog.detail:type_name -> google.protobuf.Struct
+	31, // 13: guardian.AuditLog.created_at:type_name -> google.protobuf.Timestamp
+	31, // 14: guardian.ListAuditLogsRequest.since:type_name -> google.protobuf.Timestamp
+	31, // 15: guardian.ListAuditLogsRequest.until:type_name -> google.protobuf.Timestamp
+	26, // 16: guardian.ListAuditLogsResponse.entries:type_name -> guardian.AuditLog
+	30, // 17: guardian.ErrorResponse.details:type_name -> guardian.ErrorResponse.DetailsEntry
+	5,  // 18: guardian.AgentService.Heartbeat:input_type -> guardian.HeartbeatRequest
+	6,  // 19: guardian.AgentService.ReportTaskResult:input_type -> guardian.ReportTaskResultRequest
+	3,  // 20: guardian.DataService.UploadMessages:input_type -> guardian.UploadMessagesRequest
+	10, // 21: guardian.ConsoleService.ListAgents:input_type -> guardian.ListAgentsRequest
+	12, // 22: guardian.ConsoleService.CreateTask:input_type -> guardian.CreateTaskRequest
+	14, // 23: guardian.ConsoleService.DecommissionAgent:input_type -> guardian.DecommissionAgentRequest
+	17, // 24: guardian.ConsoleService.ListMessages:input_type -> guardian.ListMessagesRequest
+	19, // 25: guardian.ConsoleService.PurgeTaskMessages:input_type -> guardian.PurgeTaskMessagesRequest
+	22, // 26: guardian.ConsoleService.ListCases:input_type -> guardian.ListCasesRequest
+	24, // 27: guardian.ConsoleService.CreateCase:input_type -> guardian.CreateCaseRequest
+	25, // 28: guardian.ConsoleService.DestroyCaseKey:input_type -> guardian.DestroyCaseKeyRequest
+	27, // 29: guardian.ConsoleService.ListAuditLogs:input_type -> guardian.ListAuditLogsRequest
+	8,  // 30: guardian.AgentService.Heartbeat:output_type -> guardian.HeartbeatResponse
+	7,  // 31: guardian.AgentService.ReportTaskResult:output_type -> guardian.ReportTaskResultResponse
+	4,  // 32: guardian.DataService.UploadMessages:output_type -> guardian.UploadMessagesResponse
+	11, // 33: guardian.ConsoleService.ListAgents:output_type -> guardian.ListAgentsResponse
+	13, // 34: guardian.ConsoleService.CreateTask:output_type -> guardian.CreateTaskResponse
+	15, // 35: guardian.ConsoleService.DecommissionAgent:output_type -> guardian.DecommissionAgentResponse
+	18, // 36: guardian.ConsoleService.ListMessages:output_type -> guardian.ListMessagesResponse
+	20, // 37: guardian.ConsoleService.PurgeTaskMessages:output_type -> guardian.PurgeTaskMessagesResponse
+	23, // 38: guardian.ConsoleService.ListCases:output_type -> guardian.ListCasesResponse
+	21, // 39: guardian.ConsoleService.CreateCase:output_type -> guardian.Case
+	33, // 40: guardian.ConsoleService.DestroyCaseKey:output_type -> google.protobuf.Empty
+	28, // 41: guardian.ConsoleService.ListAuditLogs:output_type -> guardian.ListAuditLogsResponse
+	30, // [30:42] is the sub-list for method output_type
+	18, // [18:30] is the sub-list for method input_type
+	18, // [18:18] is the sub-list for extension type_name
+	18, // [18:18] is the sub-list for extension extendee
+	0,  // [0:18] is the sub-list for field type_name
 }
 
 func init() { file_guardian_proto_init() }
@@ -330,18 +2135,21 @@ func file_guardian_proto_init() {
 	if File_guardian_proto != nil {
 		return
 	}
+	file_guardian_proto_msgTypes[14].OneofWrappers = []any{}
+	file_guardian_proto_msgTypes[19].OneofWrappers = []any{}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_guardian_proto_rawDesc), len(file_guardian_proto_rawDesc)),
-			NumEnums:      0,
-			NumMessages:   5,
+			NumEnums:      2,
+			NumMessages:   29,
 			NumExtensions: 0,
-			NumServices:   2,
+			NumServices:   3,
 		},
 		GoTypes:           file_guardian_proto_goTypes,
 		DependencyIndexes: file_guardian_proto_depIdxs,
+		EnumInfos:         file_guardian_proto_enumTypes,
 		MessageInfos:      file_guardian_proto_msgTypes,
 	}.Build()
 	File_guardian_proto = out.File
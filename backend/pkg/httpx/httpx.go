@@ -5,12 +5,18 @@ import (
     "net/http"
 
     chimid "github.com/go-chi/chi/v5/middleware"
+    "guardian-backend/pkg/apperr"
+    "guardian-backend/pkg/logger"
+    "guardian-backend/pkg/tracing"
 )
 
 type ErrorResponse struct {
     Code      string `json:"code"`
     Message   string `json:"message"`
+    Details   map[string]string `json:"details,omitempty"`
     RequestID string `json:"requestId,omitempty"`
+    // TraceID 为本次请求的 trace ID，未启用追踪且调用方未携带 traceparent 时为空
+    TraceID   string `json:"traceId,omitempty"`
 }
 
 func requestIDFrom(r *http.Request) string {
@@ -26,6 +32,13 @@ func requestIDFrom(r *http.Request) string {
     return ""
 }
 
+func traceIDFrom(r *http.Request) string {
+    if r == nil {
+        return ""
+    }
+    return tracing.TraceID(r.Context())
+}
+
 func WriteJSON(w http.ResponseWriter, status int, v any) {
     w.Header().Set("Content-Type", "application/json")
     w.WriteHeader(status)
@@ -33,8 +46,18 @@ func WriteJSON(w http.ResponseWriter, status int, v any) {
 }
 
 func WriteError(w http.ResponseWriter, r *http.Request, status int, code, message string) {
-    resp := ErrorResponse{Code: code, Message: message, RequestID: requestIDFrom(r)}
+    resp := ErrorResponse{Code: code, Message: message, RequestID: requestIDFrom(r), TraceID: traceIDFrom(r)}
     WriteJSON(w, status, resp)
 }
 
 
+
+// WriteAppError 按 apperr 分类输出错误响应；内部错误只记录日志，响应中仅含通用消息
+func WriteAppError(w http.ResponseWriter, r *http.Request, err error) {
+    e := apperr.From(err)
+    requestID := requestIDFrom(r)
+    if e.Kind == apperr.KindInternal {
+        logger.From(r.Context()).Error("Internal error", "error", err)
+    }
+    WriteJSON(w, e.HTTPStatus(), ErrorResponse{Code: e.PublicCode(), Message: e.PublicMessage(), Details: e.Details, RequestID: requestID, TraceID: traceIDFrom(r)})
+}
@@ -1,12 +1,88 @@
+// Package logger 配置全局 slog logger，并提供请求级 logger。
+//
+// 所有输出都经过 RedactingHandler：消息内容、口令、令牌等敏感字段按键名整体替换，
+// 字符串与错误中出现的 Bearer 令牌、JWT 也会被遮盖。
 package logger
 
 import (
+	"context"
+	"fmt"
+	"io"
 	"log/slog"
-	"os"
+	"strings"
+	"sync"
 )
 
-// Setup 初始化全局 JSON 格式 logger
-func Setup() {
-	log := slog.New(slog.NewJSONHandler(os.Stdout, nil))
-	slog.SetDefault(log)
+// Options 是日志配置
+type Options struct {
+	// Level 为 debug / info / warn / error，默认 info
+	Level string
+	// Format 为 json 或 text，默认 json
+	Format string
+	// AddSource 为 true 时附带源码位置
+	AddSource bool
+}
+
+// New 按配置创建写入 w 的 logger
+func New(w io.Writer, opts Options) (*slog.Logger, error) {
+	var level slog.Level
+	if opts.Level != "" {
+		if err := level.UnmarshalText([]byte(opts.Level)); err != nil {
+			return nil, fmt.Errorf("invalid log level %q", opts.Level)
+		}
+	}
+	ho := &slog.HandlerOptions{Level: level, AddSource: opts.AddSource}
+	var h slog.Handler
+	switch strings.ToLower(opts.Format) {
+	case "", "json":
+		h = slog.NewJSONHandler(w, ho)
+	case "text":
+		h = slog.NewTextHandler(w, ho)
+	default:
+		return nil, fmt.Errorf("invalid log format %q", opts.Format)
+	}
+	return slog.New(NewRedactingHandler(h)), nil
+}
+
+// Setup 按配置创建 logger 并设为全局默认；标准库 log 包的输出同样经过该 logger
+func Setup(w io.Writer, opts Options) (*slog.Logger, error) {
+	l, err := New(w, opts)
+	if err != nil {
+		return nil, err
+	}
+	slog.SetDefault(l)
+	return l, nil
+}
+
+type contextKey struct{}
+
+// scope 是请求级 logger；Annotate 就地追加字段，使外层中间件的结束日志也能带上内层解析出的身份
+type scope struct {
+	mu sync.Mutex
+	l  *slog.Logger
+}
+
+// NewContext 返回携带请求级 logger l 的 context
+func NewContext(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, &scope{l: l})
+}
+
+// From 返回 ctx 中的请求级 logger；没有时返回全局默认 logger
+func From(ctx context.Context) *slog.Logger {
+	if s, ok := ctx.Value(contextKey{}).(*scope); ok {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return s.l
+	}
+	return slog.Default()
+}
+
+// Annotate 为 ctx 中的请求级 logger 追加字段（如认证后的 user_id），对同一请求此后的全部日志生效；
+// ctx 中没有请求级 logger 时不做任何事
+func Annotate(ctx context.Context, args ...any) {
+	if s, ok := ctx.Value(contextKey{}).(*scope); ok {
+		s.mu.Lock()
+		s.l = s.l.With(args...)
+		s.mu.Unlock()
+	}
 }
@@ -0,0 +1,152 @@
+package logger
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+// Redacted 是被遮盖字段的替换值
+const Redacted = "[REDACTED]"
+
+// sensitiveKeys 为按键名整体遮盖的字段（忽略大小写与 _ -）；消息内容、请求体一律不记录
+var sensitiveKeys = map[string]bool{
+	"content":       true,
+	"body":          true,
+	"authorization": true,
+	"cookie":        true,
+	"setcookie":     true,
+	"otp":           true,
+	"totp":          true,
+	"recoverycode":  true,
+	"recoverycodes": true,
+}
+
+// sensitiveSuffixes 覆盖 admin_password、refresh_token、client_secret 等复合键名
+var sensitiveSuffixes = []string{"password", "passwd", "token", "secret", "privatekey", "apikey", "content"}
+
+var (
+	bearerPattern = regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9\-._~+/]+=*`)
+	jwtPattern    = regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`)
+)
+
+// RedactingHandler 在交给下游 handler 之前遮盖敏感字段：
+// 敏感键名的值整体替换为 Redacted，其余字符串、错误与日志消息中的 Bearer 令牌与 JWT 被替换；
+// 结构体等复合值按字段逐层处理
+type RedactingHandler struct {
+	next slog.Handler
+}
+
+// NewRedactingHandler 包装 next
+func NewRedactingHandler(next slog.Handler) *RedactingHandler {
+	return &RedactingHandler{next: next}
+}
+
+func (h *RedactingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *RedactingHandler) Handle(ctx context.Context, r slog.Record) error {
+	out := slog.NewRecord(r.Time, r.Level, scrub(r.Message), r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		out.AddAttrs(redactAttr(a))
+		return true
+	})
+	return h.next.Handle(ctx, out)
+}
+
+func (h *RedactingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		redacted[i] = redactAttr(a)
+	}
+	return &RedactingHandler{next: h.next.WithAttrs(redacted)}
+}
+
+func (h *RedactingHandler) WithGroup(name string) slog.Handler {
+	return &RedactingHandler{next: h.next.WithGroup(name)}
+}
+
+func isSensitiveKey(key string) bool {
+	k := strings.ToLower(strings.NewReplacer("_", "", "-", "").Replace(key))
+	if sensitiveKeys[k] {
+		return true
+	}
+	for _, s := range sensitiveSuffixes {
+		if strings.HasSuffix(k, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func redactAttr(a slog.Attr) slog.Attr {
+	if isSensitiveKey(a.Key) {
+		return slog.String(a.Key, Redacted)
+	}
+	v := a.Value.Resolve()
+	switch v.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, scrub(v.String()))
+	case slog.KindGroup:
+		group := v.Group()
+		redacted := make([]slog.Attr, len(group))
+		for i, g := range group {
+			redacted[i] = redactAttr(g)
+		}
+		return slog.Attr{Key: a.Key, Value: slog.GroupValue(redacted...)}
+	case slog.KindAny:
+		// 错误信息可能拼入了请求头或上游响应，按字符串处理
+		if err, ok := v.Any().(error); ok {
+			return slog.String(a.Key, scrub(err.Error()))
+		}
+		// 结构体、map、protobuf 消息等可能带有消息内容或凭据字段，不能原样交给下游
+		return slog.Any(a.Key, redactAny(v.Any()))
+	}
+	return slog.Attr{Key: a.Key, Value: v}
+}
+
+// redactAny 把任意值经 JSON 转为 map、切片与基本类型后逐层遮盖：敏感键名（字段名或 json 标签）
+// 的值整体替换，字符串中的令牌被替换。无法序列化的值无从逐字段检查，整体替换为 Redacted
+func redactAny(x any) any {
+	b, err := json.Marshal(x)
+	if err != nil {
+		return Redacted
+	}
+	var generic any
+	if err := json.Unmarshal(b, &generic); err != nil {
+		return Redacted
+	}
+	return redactJSON(generic)
+}
+
+func redactJSON(x any) any {
+	switch t := x.(type) {
+	case map[string]any:
+		for k, v := range t {
+			if isSensitiveKey(k) {
+				t[k] = Redacted
+			} else {
+				t[k] = redactJSON(v)
+			}
+		}
+	case []any:
+		for i, v := range t {
+			t[i] = redactJSON(v)
+		}
+	case string:
+		return scrub(t)
+	}
+	return x
+}
+
+// scrub 替换 s 中的 Bearer 令牌与 JWT
+func scrub(s string) string {
+	if !strings.Contains(s, "eyJ") && !strings.Contains(strings.ToLower(s), "bearer") {
+		return s
+	}
+	s = bearerPattern.ReplaceAllString(s, "Bearer "+Redacted)
+	return jwtPattern.ReplaceAllString(s, Redacted)
+}
@@ -0,0 +1,10 @@
+package metrics
+
+import _ "embed"
+
+// GrafanaDashboard is a Grafana dashboard (JSON model) built on the metrics in this package.
+// It selects its Prometheus data source through the "datasource" template variable, so it can be
+// imported as-is or provisioned from a file.
+//
+//go:embed dashboards/guardian.json
+var GrafanaDashboard []byte
@@ -0,0 +1,83 @@
+package metrics
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	metricRef = regexp.MustCompile(`guardian_[a-z_]+`)
+	fqName    = regexp.MustCompile(`fqName: "([^"]+)"`)
+)
+
+// registeredNames returns the series names exposed by this package, including histogram suffixes.
+func registeredNames() map[string]bool {
+	collectors := []prometheus.Collector{
+		httpRequestsTotal, httpRequestDuration,
+		grpcRequestsTotal, grpcRequestDuration, grpcPanicsTotal,
+		ingestBatchesTotal, ingestMessagesAccepted, ingestMessagesDeduplicated, ingestMessagesRejected, ingestBatchSize,
+		copyFromDuration, copyFromRows,
+		&StateCollector{}, &PoolCollector{},
+	}
+	ch := make(chan *prometheus.Desc, 64)
+	go func() {
+		for _, c := range collectors {
+			c.Describe(ch)
+		}
+		close(ch)
+	}()
+	names := map[string]bool{}
+	for d := range ch {
+		name := fqName.FindStringSubmatch(d.String())[1]
+		names[name] = true
+		for _, suffix := range []string{"_bucket", "_sum", "_count"} {
+			names[name+suffix] = true
+		}
+	}
+	return names
+}
+
+func TestGrafanaDashboard_UsesRegisteredMetrics(t *testing.T) {
+	var dashboard struct {
+		UID    string `json:"uid"`
+		Panels []struct {
+			Title   string `json:"title"`
+			Targets []struct {
+				Expr string `json:"expr"`
+			} `json:"targets"`
+		} `json:"panels"`
+	}
+	require.NoError(t, json.Unmarshal(GrafanaDashboard, &dashboard))
+	assert.Equal(t, "guardian-backend", dashboard.UID)
+
+	names := registeredNames()
+	used := map[string]bool{}
+	for _, p := range dashboard.Panels {
+		for _, target := range p.Targets {
+			refs := metricRef.FindAllString(target.Expr, -1)
+			assert.NotEmpty(t, refs, "panel %q", p.Title)
+			for _, ref := range refs {
+				assert.True(t, names[ref], "panel %q references unknown metric %s", p.Title, ref)
+				used[strings.TrimSuffix(ref, "_bucket")] = true
+			}
+		}
+	}
+	for _, want := range []string{
+		"guardian_ingest_messages_accepted_total",
+		"guardian_ingest_messages_rejected_total",
+		"guardian_ingest_messages_deduplicated_total",
+		"guardian_ingest_batch_size_messages",
+		"guardian_db_copy_from_duration_seconds",
+		"guardian_tasks",
+		"guardian_agents",
+		"guardian_db_pool_acquired_connections",
+	} {
+		assert.True(t, used[want], "dashboard should chart %s", want)
+	}
+}
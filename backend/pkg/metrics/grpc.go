@@ -0,0 +1,77 @@
+package metrics
+
+import (
+    "context"
+    "strings"
+    "time"
+
+    "github.com/prometheus/client_golang/prometheus"
+    "github.com/prometheus/client_golang/prometheus/promauto"
+    "google.golang.org/grpc"
+    "google.golang.org/grpc/status"
+)
+
+var (
+    grpcRequestsTotal = promauto.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "guardian_grpc_requests_total",
+            Help: "Total number of gRPC requests handled by the server",
+        },
+        []string{"service", "method", "code"},
+    )
+
+    grpcRequestDuration = promauto.NewHistogramVec(
+        prometheus.HistogramOpts{
+            Name:    "guardian_grpc_request_duration_seconds",
+            Help:    "Duration of gRPC requests in seconds",
+            Buckets: prometheus.DefBuckets,
+        },
+        []string{"service", "method", "code"},
+    )
+
+    grpcPanicsTotal = promauto.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "guardian_grpc_panics_total",
+            Help: "Total number of panics recovered in gRPC handlers",
+        },
+        []string{"service", "method"},
+    )
+)
+
+// UnaryServerMetrics records request counts and latency per service, method and status code.
+func UnaryServerMetrics(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+    start := time.Now()
+    resp, err := handler(ctx, req)
+    observeRPC(info.FullMethod, err, time.Since(start))
+    return resp, err
+}
+
+// StreamServerMetrics is the streaming counterpart of UnaryServerMetrics; latency covers the whole stream.
+func StreamServerMetrics(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+    start := time.Now()
+    err := handler(srv, ss)
+    observeRPC(info.FullMethod, err, time.Since(start))
+    return err
+}
+
+// GRPCPanic counts a panic recovered while handling fullMethod.
+func GRPCPanic(fullMethod string) {
+    service, method := SplitMethod(fullMethod)
+    grpcPanicsTotal.WithLabelValues(service, method).Inc()
+}
+
+func observeRPC(fullMethod string, err error, elapsed time.Duration) {
+    service, method := SplitMethod(fullMethod)
+    code := status.Code(err).String()
+    grpcRequestsTotal.WithLabelValues(service, method, code).Inc()
+    grpcRequestDuration.WithLabelValues(service, method, code).Observe(elapsed.Seconds())
+}
+
+// SplitMethod splits "/package.Service/Method" into its service and method parts.
+func SplitMethod(fullMethod string) (string, string) {
+    service, method, ok := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+    if !ok {
+        return "unknown", fullMethod
+    }
+    return service, method
+}
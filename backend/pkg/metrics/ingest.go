@@ -0,0 +1,100 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Batch outcomes for guardian_ingest_batches_total.
+const (
+	BatchSaved     = "saved"
+	BatchRejected  = "rejected"
+	BatchFailed    = "failed"
+	BatchDuplicate = "duplicate"
+)
+
+var (
+	ingestBatchesTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "guardian_ingest_batches_total",
+			Help: "Upload batches by outcome: saved, rejected (precondition failed, nothing stored), failed (internal error) or duplicate (replay of a stored batch, nothing stored)",
+		},
+		[]string{"outcome"},
+	)
+
+	ingestMessagesAccepted = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "guardian_ingest_messages_accepted_total",
+			Help: "Messages stored after scope filtering",
+		},
+	)
+
+	ingestMessagesDeduplicated = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "guardian_ingest_messages_deduplicated_total",
+			Help: "Messages in replayed batches whose request_sha256 was already stored for the agent; the original receipt is returned and nothing is stored",
+		},
+	)
+
+	ingestMessagesRejected = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "guardian_ingest_messages_rejected_total",
+			Help: "Messages received but not stored, by reason",
+		},
+		[]string{"reason"},
+	)
+
+	ingestBatchSize = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "guardian_ingest_batch_size_messages",
+			Help:    "Messages per upload batch: received before filtering and accepted after filtering",
+			Buckets: prometheus.ExponentialBuckets(1, 4, 8), // 1 .. 16384
+		},
+		[]string{"stage"},
+	)
+
+	copyFromDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "guardian_db_copy_from_duration_seconds",
+			Help:    "Latency of COPY FROM statements by table",
+			Buckets: prometheus.ExponentialBuckets(0.001, 2, 14), // 1ms .. ~8s
+		},
+		[]string{"table"},
+	)
+
+	copyFromRows = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "guardian_db_copy_from_rows_total",
+			Help: "Rows written by COPY FROM statements by table",
+		},
+		[]string{"table"},
+	)
+)
+
+// IngestBatch records one upload batch: how many messages arrived, how many were stored,
+// and how many were dropped per reason. outcome is one of BatchSaved, BatchRejected, BatchFailed
+// or BatchDuplicate; for a duplicate every received message counts as deduplicated.
+func IngestBatch(outcome string, received, accepted int, rejected map[string]int) {
+	ingestBatchesTotal.WithLabelValues(outcome).Inc()
+	ingestBatchSize.WithLabelValues("received").Observe(float64(received))
+	switch outcome {
+	case BatchSaved:
+		ingestBatchSize.WithLabelValues("accepted").Observe(float64(accepted))
+		ingestMessagesAccepted.Add(float64(accepted))
+	case BatchDuplicate:
+		ingestMessagesDeduplicated.Add(float64(received))
+	}
+	for reason, n := range rejected {
+		if n > 0 {
+			ingestMessagesRejected.WithLabelValues(reason).Add(float64(n))
+		}
+	}
+}
+
+// CopyFrom records the latency and row count of a successful COPY FROM into table.
+func CopyFrom(table string, rows int64, elapsed time.Duration) {
+	copyFromDuration.WithLabelValues(table).Observe(elapsed.Seconds())
+	copyFromRows.WithLabelValues(table).Add(float64(rows))
+}
@@ -0,0 +1,31 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIngestBatch(t *testing.T) {
+	accepted := testutil.ToFloat64(ingestMessagesAccepted)
+	outside := testutil.ToFloat64(ingestMessagesRejected.WithLabelValues("outside_time_window"))
+	notRunning := testutil.ToFloat64(ingestMessagesRejected.WithLabelValues("task_not_running"))
+	saved := testutil.ToFloat64(ingestBatchesTotal.WithLabelValues(BatchSaved))
+	rejected := testutil.ToFloat64(ingestBatchesTotal.WithLabelValues(BatchRejected))
+	deduplicated := testutil.ToFloat64(ingestMessagesDeduplicated)
+
+	IngestBatch(BatchSaved, 10, 7, map[string]int{"outside_time_window": 3, "missing_timestamp": 0})
+	IngestBatch(BatchRejected, 4, 0, map[string]int{"task_not_running": 4})
+	IngestBatch(BatchDuplicate, 10, 0, nil)
+
+	assert.Equal(t, accepted+7, testutil.ToFloat64(ingestMessagesAccepted))
+	assert.Equal(t, outside+3, testutil.ToFloat64(ingestMessagesRejected.WithLabelValues("outside_time_window")))
+	assert.Equal(t, notRunning+4, testutil.ToFloat64(ingestMessagesRejected.WithLabelValues("task_not_running")))
+	assert.Equal(t, saved+1, testutil.ToFloat64(ingestBatchesTotal.WithLabelValues(BatchSaved)))
+	assert.Equal(t, rejected+1, testutil.ToFloat64(ingestBatchesTotal.WithLabelValues(BatchRejected)))
+	// A replayed batch stores nothing: its messages count as deduplicated, not accepted.
+	assert.Equal(t, deduplicated+10, testutil.ToFloat64(ingestMessagesDeduplicated))
+	// Reasons with a zero count do not create a series.
+	assert.Equal(t, 2, testutil.CollectAndCount(ingestMessagesRejected, "guardian_ingest_messages_rejected_total"))
+}
@@ -2,6 +2,7 @@ package metrics
 
 import (
     "net/http"
+    "strconv"
     "time"
 
     "github.com/go-chi/chi/v5"
@@ -39,6 +40,11 @@ func (w *responseWriter) WriteHeader(code int) {
     w.ResponseWriter.WriteHeader(code)
 }
 
+// Unwrap lets http.ResponseController reach the underlying Flusher (used by SSE).
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+    return w.ResponseWriter
+}
+
 // HTTPMetrics returns a middleware that records basic HTTP metrics.
 func HTTPMetrics(next http.Handler) http.Handler {
     return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -59,7 +65,7 @@ func HTTPMetrics(next http.Handler) http.Handler {
         labels := prometheus.Labels{
             "method": r.Method,
             "route":  pattern,
-            "status": http.StatusText(rw.status),
+            "status": strconv.Itoa(rw.status),
         }
         httpRequestsTotal.With(labels).Inc()
         httpRequestDuration.With(labels).Observe(time.Since(start).Seconds())
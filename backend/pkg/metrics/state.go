@@ -0,0 +1,95 @@
+package metrics
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// stateQueryTimeout bounds the database queries run on each scrape.
+const stateQueryTimeout = 5 * time.Second
+
+// StateSource reports current counts of persisted entities grouped by status.
+type StateSource interface {
+	TaskStatusCounts(ctx context.Context) (map[string]int, error)
+	AgentStatusCounts(ctx context.Context) (map[string]int, error)
+}
+
+var (
+	tasksDesc = prometheus.NewDesc("guardian_tasks",
+		"Tasks by current status", []string{"status"}, nil)
+	agentsDesc = prometheus.NewDesc("guardian_agents",
+		"Agents by current status (online, offline, decommissioning, retired)", []string{"status"}, nil)
+	stateUpDesc = prometheus.NewDesc("guardian_state_query_success",
+		"Whether the last scrape of task and agent counts succeeded (1) or failed (0)", []string{"source"}, nil)
+)
+
+// StateCollector queries task and agent counts at scrape time, so the gauges always match the
+// database regardless of which replica changed a status. A failed query is reported through
+// guardian_state_query_success instead of failing the whole scrape.
+type StateCollector struct {
+	Source StateSource
+}
+
+func (c *StateCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- tasksDesc
+	ch <- agentsDesc
+	ch <- stateUpDesc
+}
+
+func (c *StateCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), stateQueryTimeout)
+	defer cancel()
+	c.collect(ctx, ch, "tasks", tasksDesc, c.Source.TaskStatusCounts)
+	c.collect(ctx, ch, "agents", agentsDesc, c.Source.AgentStatusCounts)
+}
+
+func (c *StateCollector) collect(ctx context.Context, ch chan<- prometheus.Metric, source string, desc *prometheus.Desc, query func(context.Context) (map[string]int, error)) {
+	counts, err := query(ctx)
+	if err != nil {
+		slog.Warn("failed to collect state metrics", "source", source, "error", err)
+		ch <- prometheus.MustNewConstMetric(stateUpDesc, prometheus.GaugeValue, 0, source)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(stateUpDesc, prometheus.GaugeValue, 1, source)
+	for status, n := range counts {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64(n), status)
+	}
+}
+
+var (
+	poolAcquiredDesc      = prometheus.NewDesc("guardian_db_pool_acquired_connections", "Connections currently checked out of the pool", nil, nil)
+	poolIdleDesc          = prometheus.NewDesc("guardian_db_pool_idle_connections", "Idle connections in the pool", nil, nil)
+	poolTotalDesc         = prometheus.NewDesc("guardian_db_pool_total_connections", "Open connections, including ones being established", nil, nil)
+	poolMaxDesc           = prometheus.NewDesc("guardian_db_pool_max_connections", "Maximum size of the pool", nil, nil)
+	poolAcquiresDesc      = prometheus.NewDesc("guardian_db_pool_acquires_total", "Successful connection acquisitions", nil, nil)
+	poolEmptyAcquiresDesc = prometheus.NewDesc("guardian_db_pool_empty_acquires_total", "Acquisitions that had to wait because the pool was empty", nil, nil)
+	poolCanceledDesc      = prometheus.NewDesc("guardian_db_pool_canceled_acquires_total", "Acquisitions canceled by their context", nil, nil)
+	poolAcquireWaitDesc   = prometheus.NewDesc("guardian_db_pool_acquire_duration_seconds_total", "Cumulative time spent acquiring connections", nil, nil)
+)
+
+// PoolCollector exports pgxpool statistics.
+type PoolCollector struct {
+	Pool *pgxpool.Pool
+}
+
+func (c *PoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{poolAcquiredDesc, poolIdleDesc, poolTotalDesc, poolMaxDesc, poolAcquiresDesc, poolEmptyAcquiresDesc, poolCanceledDesc, poolAcquireWaitDesc} {
+		ch <- d
+	}
+}
+
+func (c *PoolCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.Pool.Stat()
+	ch <- prometheus.MustNewConstMetric(poolAcquiredDesc, prometheus.GaugeValue, float64(s.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(poolIdleDesc, prometheus.GaugeValue, float64(s.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(poolTotalDesc, prometheus.GaugeValue, float64(s.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(poolMaxDesc, prometheus.GaugeValue, float64(s.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(poolAcquiresDesc, prometheus.CounterValue, float64(s.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(poolEmptyAcquiresDesc, prometheus.CounterValue, float64(s.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(poolCanceledDesc, prometheus.CounterValue, float64(s.CanceledAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(poolAcquireWaitDesc, prometheus.CounterValue, s.AcquireDuration().Seconds())
+}
@@ -0,0 +1,133 @@
+// Package tracing 配置 OpenTelemetry 链路追踪，并提供 HTTP 中间件与 gRPC 服务端选项。
+//
+// 未启用导出时仍安装 W3C Trace Context 传播器：调用方传入的 traceparent 会继续传递，
+// 日志与错误响应中的 trace_id 与调用方一致，只是本服务不上报 span。
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+)
+
+// Options 是链路追踪配置
+type Options struct {
+	// Exporter 为 otlp、stdout（写到标准错误，便于与 JSON 日志分开；console 为其别名）或 none（默认，不上报）
+	Exporter string
+	// Endpoint 为 OTLP/gRPC 接收端地址（host:port）；为空时使用 OTEL_EXPORTER_OTLP_ENDPOINT 或 localhost:4317
+	Endpoint string
+	// Insecure 为 true 时以明文连接 OTLP 接收端
+	Insecure bool
+	// SampleRatio 为无上游采样决策时的采样比例（0-1）；上游已采样的请求始终跟随
+	SampleRatio float64
+	ServiceName string
+}
+
+// Setup 按配置安装全局 TracerProvider 与传播器；返回的 shutdown 在退出前刷新未上报的 span
+func Setup(ctx context.Context, opts Options) (shutdown func(context.Context) error, err error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	noop := func(context.Context) error { return nil }
+
+	var exporter sdktrace.SpanExporter
+	switch strings.ToLower(opts.Exporter) {
+	case "", "none":
+		return noop, nil
+	case "stdout", "console":
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stderr))
+	case "otlp":
+		var eopts []otlptracegrpc.Option
+		if opts.Endpoint != "" {
+			eopts = append(eopts, otlptracegrpc.WithEndpoint(opts.Endpoint))
+		}
+		if opts.Insecure {
+			eopts = append(eopts, otlptracegrpc.WithInsecure())
+		}
+		exporter, err = otlptracegrpc.New(ctx, eopts...)
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", opts.Exporter)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	name := opts.ServiceName
+	if name == "" {
+		name = "guardian-backend"
+	}
+	res, err := resource.New(ctx,
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+		resource.WithHost(),
+		resource.WithAttributes(semconv.ServiceName(name)),
+	)
+	if err != nil && !errors.Is(err, resource.ErrPartialResource) {
+		return nil, err
+	}
+	ratio := opts.SampleRatio
+	if ratio <= 0 || ratio > 1 {
+		ratio = 1
+	}
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+	)
+	otel.SetTracerProvider(tp)
+	return tp.Shutdown, nil
+}
+
+// TraceID 返回 ctx 中 span 的 trace ID（hex）；没有有效的 span 时返回空串
+func TraceID(ctx context.Context) string {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return ""
+	}
+	return sc.TraceID().String()
+}
+
+// HTTPMiddleware 为每个请求创建服务端 span，并在路由完成后以 chi 路由模式命名（如 POST /v1/agents/{agentID}/tasks）；
+// skip 中的路径（如 /metrics、/healthz）不创建 span
+func HTTPMiddleware(skip ...string) func(http.Handler) http.Handler {
+	skipped := make(map[string]bool, len(skip))
+	for _, p := range skip {
+		skipped[p] = true
+	}
+	return func(next http.Handler) http.Handler {
+		named := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r)
+			if rc := chi.RouteContext(r.Context()); rc != nil {
+				if pattern := rc.RoutePattern(); pattern != "" {
+					span := trace.SpanFromContext(r.Context())
+					span.SetName(r.Method + " " + pattern)
+					span.SetAttributes(semconv.HTTPRoute(pattern))
+				}
+			}
+		})
+		return otelhttp.NewHandler(named, "HTTP",
+			otelhttp.WithFilter(func(r *http.Request) bool { return !skipped[r.URL.Path] }),
+			otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string { return r.Method }),
+		)
+	}
+}
+
+// GRPCServerOption 返回为每个 RPC 创建服务端 span 的 stats handler；它先于拦截器执行，
+// 拦截器与服务实现中的日志因此能带上 trace_id
+func GRPCServerOption() grpc.ServerOption {
+	return grpc.StatsHandler(otelgrpc.NewServerHandler())
+}
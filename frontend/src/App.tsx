@@ -6,9 +6,10 @@ import { Box, List, ListItem, ListItemButton, ListItemText, Typography, Paper }
 import Login from './Login';
 // 统一的 axios 实例与拦截器在 api/client 中
 import { useAppStore } from './store/appStore';
+import { consumeSSOFragment } from './api/client';
 
 function App() {
-  const [authed, setAuthed] = useState(!!localStorage.getItem('token'));
+  const [authed, setAuthed] = useState(() => consumeSSOFragment() || !!localStorage.getItem('token'));
   const [selectedAgent, setSelectedAgent] = useState<any>(null);
   const { agents, messages, isLoading, error, fetchAgents, fetchMessagesForAgent } = useAppStore();
 
@@ -1,34 +1,98 @@
 import React, { useState } from 'react';
 import { Box, Button, TextField, Typography, Paper } from '@mui/material';
-import { login } from './api/client';
+import { login, saveTokens, enrollMFA, verifyMFA, ssoLoginUrl, MFAChallenge, MFAEnrollment, TokenPair } from './api/client';
 
 export default function Login({ onLogin }: { onLogin: () => void }) {
   const [username, setUsername] = useState('');
   const [password, setPassword] = useState('');
   const [error, setError] = useState('');
+  const [challenge, setChallenge] = useState<MFAChallenge | null>(null);
+  const [enrollment, setEnrollment] = useState<MFAEnrollment | null>(null);
+  const [code, setCode] = useState('');
+  const [recoveryCodes, setRecoveryCodes] = useState<string[]>([]);
+
+  const finish = (tokens: TokenPair) => {
+    saveTokens(tokens);
+    // 首次绑定返回的恢复码只显示这一次，确认保存后再进入系统
+    if (tokens.recovery_codes?.length) {
+      setRecoveryCodes(tokens.recovery_codes);
+      return;
+    }
+    onLogin();
+  };
 
   const handleSubmit = async (e: React.FormEvent) => {
     e.preventDefault();
     setError('');
     try {
-      const token = await login(username, password);
-      localStorage.setItem('token', token);
-      onLogin();
+      const resp = await login(username, password);
+      if ('mfa_required' in resp) {
+        setChallenge(resp);
+        if (resp.mfa_enrollment_required) {
+          setEnrollment(await enrollMFA(resp.mfa_token));
+        }
+        return;
+      }
+      finish(resp);
     } catch (err: any) {
-      setError(err?.response?.data || '登录失败');
+      setError(err?.message || '登录失败');
     }
   };
 
+  const handleVerify = async (e: React.FormEvent) => {
+    e.preventDefault();
+    if (!challenge) return;
+    setError('');
+    try {
+      const trimmed = code.trim();
+      const body = /^\d{6}$/.test(trimmed) ? { code: trimmed } : { recovery_code: trimmed };
+      finish(await verifyMFA(challenge.mfa_token, body));
+    } catch (err: any) {
+      setError(err?.message || '验证失败');
+    }
+  };
+
+  let content: React.ReactNode;
+  if (recoveryCodes.length) {
+    content = (
+      <>
+        <Typography variant="body2" gutterBottom>请妥善保存以下恢复码，每个只能使用一次，且不会再次显示：</Typography>
+        <Box component="pre" sx={{ fontFamily: 'monospace', bgcolor: '#fafafa', p: 1 }}>{recoveryCodes.join('\n')}</Box>
+        <Button variant="contained" fullWidth onClick={onLogin}>我已保存，进入系统</Button>
+      </>
+    );
+  } else if (challenge) {
+    content = (
+      <form onSubmit={handleVerify}>
+        {enrollment && (
+          <>
+            <Typography variant="body2" gutterBottom>请在验证器 App 中添加以下密钥（或打开绑定链接），然后输入 6 位验证码：</Typography>
+            <Typography variant="body2" sx={{ fontFamily: 'monospace', wordBreak: 'break-all' }}>{enrollment.secret}</Typography>
+            <Typography variant="caption" component="a" href={enrollment.provisioning_uri}>otpauth 绑定链接</Typography>
+          </>
+        )}
+        <TextField label={enrollment ? '验证码' : '验证码或恢复码'} fullWidth margin="normal" value={code} onChange={e => setCode(e.target.value)} autoFocus />
+        {error && <Typography color="error" variant="body2">{error}</Typography>}
+        <Button type="submit" variant="contained" color="primary" fullWidth sx={{ mt: 2 }}>验证</Button>
+      </form>
+    );
+  } else {
+    content = (
+      <form onSubmit={handleSubmit}>
+        <TextField label="用户名" fullWidth margin="normal" value={username} onChange={e => setUsername(e.target.value)} />
+        <TextField label="密码" type="password" fullWidth margin="normal" value={password} onChange={e => setPassword(e.target.value)} />
+        {error && <Typography color="error" variant="body2">{error}</Typography>}
+        <Button type="submit" variant="contained" color="primary" fullWidth sx={{ mt: 2 }}>登录</Button>
+        <Button variant="outlined" fullWidth sx={{ mt: 1 }} href={ssoLoginUrl}>企业账号登录（SSO）</Button>
+      </form>
+    );
+  }
+
   return (
     <Box sx={{ display: 'flex', height: '100vh', alignItems: 'center', justifyContent: 'center', bgcolor: '#f5f5f5' }}>
-      <Paper sx={{ p: 4, minWidth: 320 }}>
+      <Paper sx={{ p: 4, minWidth: 320, maxWidth: 420 }}>
         <Typography variant="h5" gutterBottom>系统登录</Typography>
-        <form onSubmit={handleSubmit}>
-          <TextField label="用户名" fullWidth margin="normal" value={username} onChange={e => setUsername(e.target.value)} />
-          <TextField label="密码" type="password" fullWidth margin="normal" value={password} onChange={e => setPassword(e.target.value)} />
-          {error && <Typography color="error" variant="body2">{error}</Typography>}
-          <Button type="submit" variant="contained" color="primary" fullWidth sx={{ mt: 2 }}>登录</Button>
-        </form>
+        {content}
       </Paper>
     </Box>
   );
@@ -25,6 +25,82 @@ api.interceptors.request.use((config) => {
   return config;
 });
 
+export interface TokenPair {
+  token: string;
+  refresh_token: string;
+  expires_in: number;
+  recovery_codes?: string[];
+}
+
+// 口令通过但需要 TOTP 二次验证时 /login 的响应
+export interface MFAChallenge {
+  mfa_required: true;
+  mfa_enrollment_required: boolean;
+  mfa_token: string;
+  expires_in: number;
+}
+
+export interface MFAEnrollment {
+  secret: string;
+  provisioning_uri: string;
+}
+
+export function saveTokens(pair: TokenPair) {
+  localStorage.setItem('token', pair.token);
+  localStorage.setItem('refresh_token', pair.refresh_token);
+}
+
+// SSO 登录入口：后端重定向到 IdP，完成后带令牌跳回前端
+export const ssoLoginUrl = `${apiBaseUrl}/login/oidc`;
+
+// consumeSSOFragment 读取 SSO 回跳时 URL fragment 中的令牌并保存，随后清除 fragment
+export function consumeSSOFragment(): boolean {
+  const params = new URLSearchParams(window.location.hash.replace(/^#/, ''));
+  const token = params.get('token');
+  const refreshToken = params.get('refresh_token');
+  if (!token || !refreshToken) return false;
+  saveTokens({ token, refresh_token: refreshToken, expires_in: Number(params.get('expires_in') || 0) });
+  window.history.replaceState(null, '', window.location.pathname + window.location.search);
+  return true;
+}
+
+export function clearTokens() {
+  localStorage.removeItem('token');
+  localStorage.removeItem('refresh_token');
+}
+
+// 访问令牌过期时用刷新令牌换取新令牌并重试一次；并发请求共享同一次刷新
+let refreshing: Promise<string> | null = null;
+
+async function refreshAccessToken(): Promise<string> {
+  const refreshToken = localStorage.getItem('refresh_token');
+  if (!refreshToken) throw new Error('no refresh token');
+  const resp = await axios.post(`${apiBaseUrl}/token/refresh`, { refresh_token: refreshToken });
+  const pair = resp.data as TokenPair;
+  saveTokens(pair);
+  return pair.token;
+}
+
+api.interceptors.response.use(undefined, async (error: AxiosError) => {
+  const original = error.config as any;
+  if (error.response?.status !== 401 || !original || original._retried || original.url === '/token/refresh') {
+    return Promise.reject(error);
+  }
+  original._retried = true;
+  try {
+    refreshing = refreshing || refreshAccessToken();
+    const token = await refreshing;
+    original.headers = original.headers || {};
+    original.headers['Authorization'] = `Bearer ${token}`;
+    return api(original);
+  } catch (e) {
+    clearTokens();
+    return Promise.reject(error);
+  } finally {
+    refreshing = null;
+  }
+});
+
 function toError(err: unknown): Error {
   const axErr = err as AxiosError<any>;
   const responseData = axErr?.response?.data;
@@ -35,15 +111,42 @@ function toError(err: unknown): Error {
   return new Error(message);
 }
 
-export async function login(username: string, password: string): Promise<string> {
+export async function login(username: string, password: string): Promise<TokenPair | MFAChallenge> {
   try {
     const resp = await api.post('/login', { username, password });
-    return (resp.data as { token: string }).token;
+    return resp.data as TokenPair | MFAChallenge;
+  } catch (e) {
+    throw toError(e);
+  }
+}
+
+export async function enrollMFA(mfaToken: string): Promise<MFAEnrollment> {
+  try {
+    const resp = await api.post('/login/mfa/enroll', { mfa_token: mfaToken });
+    return resp.data as MFAEnrollment;
   } catch (e) {
     throw toError(e);
   }
 }
 
+// verifyMFA 提交 TOTP 验证码（code）或恢复码（recovery_code）完成登录
+export async function verifyMFA(mfaToken: string, body: { code?: string; recovery_code?: string }): Promise<TokenPair> {
+  try {
+    const resp = await api.post('/login/mfa', { mfa_token: mfaToken, ...body });
+    return resp.data as TokenPair;
+  } catch (e) {
+    throw toError(e);
+  }
+}
+
+export async function logout(): Promise<void> {
+  try {
+    await api.post('/logout');
+  } finally {
+    clearTokens();
+  }
+}
+
 export async function fetchAgents(): Promise<Agent[]> {
   try {
     const resp = await api.get('/v1/agents');